@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	fmtlog "log"
+
+	"github.com/containous/flaeg"
+	"github.com/containous/traefik/server"
+)
+
+// newDockerCmd builds a new Docker command, whose validate sub-command reports the
+// errors of the labels of the Docker containers
+func newDockerCmd(traefikConfiguration *server.TraefikConfiguration, traefikPointersConfiguration interface{}, args []string) *flaeg.Command {
+
+	//docker Command init
+	return &flaeg.Command{
+		Name:                  "docker",
+		Description:           `Validate the labels of the Docker containers with "traefik docker validate". Traefik will not start.`,
+		Config:                traefikConfiguration,
+		DefaultPointersConfig: traefikPointersConfiguration,
+		Run: func() error {
+			if subCommand(args) != "validate" {
+				return fmt.Errorf("Error using command docker, expected the sub-command validate")
+			}
+			dockerProvider := traefikConfiguration.Docker
+			if dockerProvider == nil {
+				return fmt.Errorf("Error using command docker validate, no docker backend defined")
+			}
+			configErrors, err := dockerProvider.Validate()
+			if err != nil {
+				return err
+			}
+			for _, configError := range configErrors {
+				fmtlog.Println(configError)
+			}
+			if len(configErrors) > 0 {
+				return fmt.Errorf("%d docker configuration errors found", len(configErrors))
+			}
+			fmtlog.Println("No docker configuration error found")
+			return nil
+		},
+		Metadata: map[string]string{
+			"parseAllSources": "true",
+		},
+	}
+}
+
+// subCommand returns the first argument following the command which is not a flag
+func subCommand(args []string) string {
+	for _, arg := range args[1:] {
+		if len(arg) > 0 && arg[0] != '-' {
+			return arg
+		}
+	}
+	return ""
+}
@@ -112,6 +112,7 @@ Complete documentation is available at https://traefik.io`,
 	f.AddCommand(newVersionCmd())
 	f.AddCommand(newBugCmd(traefikConfiguration, traefikPointersConfiguration))
 	f.AddCommand(storeconfigCmd)
+	f.AddCommand(newDockerCmd(traefikConfiguration, traefikPointersConfiguration, os.Args[1:]))
 
 	usedCmd, err := f.GetCommand()
 	if err != nil {
@@ -24,15 +24,25 @@ func GetHealthCheck() *HealthCheck {
 	return singleton
 }
 
+// Actions taken on the servers failing their health check
+const (
+	// FailureActionRemove removes the server from the server list until it recovers
+	FailureActionRemove = "remove"
+	// FailureActionAlert keeps the server in the server list and counts its failures
+	FailureActionAlert = "alert"
+)
+
 // Options are the public health check options.
 type Options struct {
-	Path     string
-	Interval time.Duration
-	LB       LoadBalancer
+	Path          string
+	Interval      time.Duration
+	LB            LoadBalancer
+	FallbackURLs  []*url.URL
+	FailureAction string
 }
 
 func (opt Options) String() string {
-	return fmt.Sprintf("[Path: %s Interval: %s]", opt.Path, opt.Interval)
+	return fmt.Sprintf("[Path: %s Interval: %s FailureAction: %s]", opt.Path, opt.Interval, opt.FailureAction)
 }
 
 // BackendHealthCheck HealthCheck configuration for a backend
@@ -40,6 +50,25 @@ type BackendHealthCheck struct {
 	Options
 	disabledURLs   []*url.URL
 	requestTimeout time.Duration
+	failuresLock   sync.RWMutex
+	failures       map[string]int
+}
+
+// Failures returns the number of failed health checks of a server kept in the
+// server list by the alert failure action.
+func (b *BackendHealthCheck) Failures(u *url.URL) int {
+	b.failuresLock.RLock()
+	defer b.failuresLock.RUnlock()
+	return b.failures[u.String()]
+}
+
+func (b *BackendHealthCheck) countFailure(u *url.URL) {
+	b.failuresLock.Lock()
+	defer b.failuresLock.Unlock()
+	if b.failures == nil {
+		b.failures = make(map[string]int)
+	}
+	b.failures[u.String()]++
 }
 
 //HealthCheck struct
@@ -119,12 +148,65 @@ func checkBackend(currentBackend *BackendHealthCheck) {
 	currentBackend.disabledURLs = newDisabledURLs
 
 	for _, url := range enabledURLs {
-		if !checkHealth(url, currentBackend) {
+		if checkHealth(url, currentBackend) {
+			continue
+		}
+		switch currentBackend.FailureAction {
+		case FailureActionAlert:
+			log.Warnf("HealthCheck has failed [%s]: Keep in server list", url.String())
+			currentBackend.countFailure(url)
+		default:
 			log.Warnf("HealthCheck has failed [%s]: Remove from server list", url.String())
 			currentBackend.LB.RemoveServer(url)
 			currentBackend.disabledURLs = append(currentBackend.disabledURLs, url)
 		}
 	}
+
+	if len(currentBackend.FallbackURLs) > 0 {
+		updateFallbacks(currentBackend)
+	}
+}
+
+// updateFallbacks promotes the fallback servers into the server list while a primary server
+// is failing its health check, and demotes them once all primary servers are healthy again.
+func updateFallbacks(currentBackend *BackendHealthCheck) {
+	primaryFailing := false
+	for _, url := range currentBackend.disabledURLs {
+		if !containsURL(currentBackend.FallbackURLs, url) {
+			primaryFailing = true
+			break
+		}
+	}
+	enabledURLs := currentBackend.LB.Servers()
+	for _, url := range currentBackend.FallbackURLs {
+		enabled := containsURL(enabledURLs, url)
+		switch {
+		case primaryFailing && !enabled && !containsURL(currentBackend.disabledURLs, url):
+			log.Debugf("Primary server is failing, promoting fallback [%s]", url.String())
+			currentBackend.LB.UpsertServer(url, roundrobin.Weight(1))
+		case !primaryFailing && enabled:
+			log.Debugf("Primary servers are healthy, demoting fallback [%s]", url.String())
+			currentBackend.LB.RemoveServer(url)
+		}
+	}
+	if !primaryFailing {
+		var disabledURLs []*url.URL
+		for _, url := range currentBackend.disabledURLs {
+			if !containsURL(currentBackend.FallbackURLs, url) {
+				disabledURLs = append(disabledURLs, url)
+			}
+		}
+		currentBackend.disabledURLs = disabledURLs
+	}
+}
+
+func containsURL(urls []*url.URL, u *url.URL) bool {
+	for _, url := range urls {
+		if url.String() == u.String() {
+			return true
+		}
+	}
+	return false
 }
 
 func checkHealth(serverURL *url.URL, backend *BackendHealthCheck) bool {
@@ -193,6 +193,50 @@ func TestSetBackendsConfiguration(t *testing.T) {
 	}
 }
 
+func TestFailureActions(t *testing.T) {
+	tests := []struct {
+		failureAction    string
+		wantInServerList bool
+		wantFailures     int
+	}{
+		{failureAction: "", wantInServerList: false, wantFailures: 0},
+		{failureAction: FailureActionRemove, wantInServerList: false, wantFailures: 0},
+		{failureAction: FailureActionAlert, wantInServerList: true, wantFailures: 2},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.failureAction, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer ts.Close()
+
+			serverURL := MustParseURL(ts.URL)
+			lb := &testLoadBalancer{RWMutex: &sync.RWMutex{}, servers: []*url.URL{serverURL}}
+			backend := NewBackendHealthCheck(Options{
+				Path:          "/path",
+				Interval:      healthCheckInterval,
+				LB:            lb,
+				FailureAction: test.failureAction,
+			})
+
+			checkBackend(backend)
+			checkBackend(backend)
+
+			lb.Lock()
+			defer lb.Unlock()
+			if inServerList := containsURL(lb.servers, serverURL); inServerList != test.wantInServerList {
+				t.Errorf("got server in server list %t, want %t", inServerList, test.wantInServerList)
+			}
+			if failures := backend.Failures(serverURL); failures != test.wantFailures {
+				t.Errorf("got %d counted failures, want %d", failures, test.wantFailures)
+			}
+		})
+	}
+}
+
 func MustParseURL(rawurl string) *url.URL {
 	u, err := url.Parse(rawurl)
 	if err != nil {
@@ -200,3 +244,58 @@ func MustParseURL(rawurl string) *url.URL {
 	}
 	return u
 }
+
+func TestFallbackPromotion(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	fallback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer fallback.Close()
+
+	primaryURL := MustParseURL(primary.URL)
+	fallbackURL := MustParseURL(fallback.URL)
+	lb := &testLoadBalancer{RWMutex: &sync.RWMutex{}, servers: []*url.URL{primaryURL}}
+	backend := NewBackendHealthCheck(Options{
+		Path:         "/path",
+		Interval:     healthCheckInterval,
+		LB:           lb,
+		FallbackURLs: []*url.URL{fallbackURL},
+	})
+
+	checkBackend(backend)
+	if servers := lb.Servers(); len(servers) != 1 || *servers[0] != *fallbackURL {
+		t.Fatalf("got servers %v, want fallback %s to be promoted", servers, fallbackURL)
+	}
+
+	primary.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	checkBackend(backend)
+	if servers := lb.Servers(); len(servers) != 1 || *servers[0] != *primaryURL {
+		t.Fatalf("got servers %v, want fallback %s to be demoted", servers, fallbackURL)
+	}
+}
+
+func TestNoFallbackPromotionWhenPrimaryIsHealthy(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer primary.Close()
+
+	primaryURL := MustParseURL(primary.URL)
+	lb := &testLoadBalancer{RWMutex: &sync.RWMutex{}, servers: []*url.URL{primaryURL}}
+	backend := NewBackendHealthCheck(Options{
+		Path:         "/path",
+		Interval:     healthCheckInterval,
+		LB:           lb,
+		FallbackURLs: []*url.URL{MustParseURL("http://fallback:8080")},
+	})
+
+	checkBackend(backend)
+	if lb.numUpsertedServers != 0 {
+		t.Errorf("got %d upserted servers, wanted 0", lb.numUpsertedServers)
+	}
+}
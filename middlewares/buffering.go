@@ -0,0 +1,208 @@
+package middlewares
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	// defaultMemBodyBytes is the size of the bodies kept in memory when no limit is given
+	defaultMemBodyBytes = 1024 * 1024
+	// maxBufferingAttempts is the maximum number of attempts of a request matching the retry expression
+	maxBufferingAttempts = 10
+)
+
+var errBodyTooLarge = errors.New("body too large")
+
+// Buffering is a middleware reading the whole request before forwarding it, and the whole
+// response before sending it back to the client, so that the request can be replayed while
+// the retry expression matches. The bodies exceeding the memory limits are buffered to disk.
+type Buffering struct {
+	next      http.Handler
+	buffering types.Buffering
+	retry     bufferingPredicate
+}
+
+// NewBuffering returns a new Buffering middleware, or an error if the retry expression is invalid
+func NewBuffering(next http.Handler, buffering *types.Buffering) (*Buffering, error) {
+	b := &Buffering{next: next, buffering: *buffering}
+	if b.buffering.MemRequestBodyBytes <= 0 {
+		b.buffering.MemRequestBodyBytes = defaultMemBodyBytes
+	}
+	if b.buffering.MemResponseBodyBytes <= 0 {
+		b.buffering.MemResponseBodyBytes = defaultMemBodyBytes
+	}
+	if len(buffering.RetryExpression) > 0 {
+		retry, err := parseBufferingExpression(buffering.RetryExpression)
+		if err != nil {
+			return nil, err
+		}
+		b.retry = retry
+	}
+	return b, nil
+}
+
+func (b *Buffering) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if b.buffering.MaxRequestBodyBytes > 0 && r.ContentLength > b.buffering.MaxRequestBodyBytes {
+		http.Error(rw, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	body := &bodyBuffer{memBytes: b.buffering.MemRequestBodyBytes, maxBytes: b.buffering.MaxRequestBodyBytes}
+	defer body.Close()
+	if r.Body != nil {
+		if _, err := io.Copy(body, r.Body); err != nil {
+			log.Debugf("Error buffering request body: %v", err)
+			if err == errBodyTooLarge {
+				http.Error(rw, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			} else {
+				http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			}
+			return
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		outReq, err := copyBufferedRequest(r, body)
+		if err != nil {
+			log.Errorf("Error replaying buffered request: %v", err)
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		response := &bufferedResponse{
+			header: make(http.Header),
+			code:   http.StatusOK,
+			body:   &bodyBuffer{memBytes: b.buffering.MemResponseBodyBytes, maxBytes: b.buffering.MaxResponseBodyBytes},
+		}
+		b.next.ServeHTTP(response, outReq)
+		if response.err != nil {
+			response.body.Close()
+			log.Debugf("Error buffering response body: %v", response.err)
+			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		if b.retry == nil || attempt >= maxBufferingAttempts || !b.retry(&bufferingContext{request: r, attempt: attempt, responseCode: response.code}) {
+			err := response.writeTo(rw)
+			response.body.Close()
+			if err != nil {
+				log.Debugf("Error writing buffered response: %v", err)
+			}
+			return
+		}
+		response.body.Close()
+		log.Debugf("Retrying request %s %v, attempt %d", r.Method, r.URL, attempt+1)
+	}
+}
+
+func copyBufferedRequest(r *http.Request, body *bodyBuffer) (*http.Request, error) {
+	outReq := new(http.Request)
+	*outReq = *r
+	outReq.URL = utils.CopyURL(r.URL)
+	outReq.TransferEncoding = nil
+	outReq.ContentLength = body.size
+	if r.Body != nil {
+		reader, err := body.reader()
+		if err != nil {
+			return nil, err
+		}
+		outReq.Body = ioutil.NopCloser(reader)
+	}
+	return outReq, nil
+}
+
+// bufferedResponse is a ResponseWriter buffering the response
+type bufferedResponse struct {
+	header http.Header
+	code   int
+	body   *bodyBuffer
+	err    error
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	b.code = code
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	n, err := b.body.Write(p)
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *bufferedResponse) writeTo(rw http.ResponseWriter) error {
+	reader, err := b.body.reader()
+	if err != nil {
+		return err
+	}
+	utils.CopyHeaders(rw.Header(), b.header)
+	rw.WriteHeader(b.code)
+	_, err = io.Copy(rw, reader)
+	return err
+}
+
+// bodyBuffer holds a body in memory up to memBytes, the excess being written to a temporary
+// file, and fails once maxBytes are exceeded if maxBytes is positive
+type bodyBuffer struct {
+	memBytes int64
+	maxBytes int64
+	size     int64
+	mem      bytes.Buffer
+	file     *os.File
+}
+
+func (b *bodyBuffer) Write(p []byte) (int, error) {
+	if b.maxBytes > 0 && b.size+int64(len(p)) > b.maxBytes {
+		return 0, errBodyTooLarge
+	}
+	if b.file == nil && int64(b.mem.Len()+len(p)) > b.memBytes {
+		file, err := ioutil.TempFile("", "traefik-buffer-")
+		if err != nil {
+			return 0, err
+		}
+		b.file = file
+	}
+	var n int
+	var err error
+	if b.file == nil {
+		n, err = b.mem.Write(p)
+	} else {
+		n, err = b.file.Write(p)
+	}
+	b.size += int64(n)
+	return n, err
+}
+
+// reader returns a reader of the whole body from its start
+func (b *bodyBuffer) reader() (io.Reader, error) {
+	if b.file == nil {
+		return bytes.NewReader(b.mem.Bytes()), nil
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.MultiReader(bytes.NewReader(b.mem.Bytes()), b.file), nil
+}
+
+// Close removes the temporary file of the body if any
+func (b *bodyBuffer) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	b.file.Close()
+	err := os.Remove(b.file.Name())
+	b.file = nil
+	return err
+}
@@ -0,0 +1,139 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/vulcand/predicate"
+)
+
+// bufferingContext holds the state of a request attempt checked by the retry expression
+type bufferingContext struct {
+	request      *http.Request
+	attempt      int
+	responseCode int
+}
+
+type bufferingPredicate func(*bufferingContext) bool
+
+type bufferingString func(*bufferingContext) string
+
+type bufferingInt func(*bufferingContext) int
+
+// parseBufferingExpression parses a retry expression such as `IsNetworkError() && Attempts() <= 2`,
+// with the same functions as the oxy stream middleware: RequestMethod(), IsNetworkError(),
+// Attempts() and ResponseCode().
+func parseBufferingExpression(expression string) (bufferingPredicate, error) {
+	parser, err := predicate.NewParser(predicate.Def{
+		Operators: predicate.Operators{
+			AND: bufferingAnd,
+			OR:  bufferingOr,
+			EQ:  bufferingEQ,
+			NEQ: bufferingNEQ,
+			LT:  bufferingLT,
+			GT:  bufferingGT,
+			LE:  bufferingLE,
+			GE:  bufferingGE,
+		},
+		Functions: map[string]interface{}{
+			"RequestMethod": func() bufferingString {
+				return func(c *bufferingContext) string { return c.request.Method }
+			},
+			"IsNetworkError": func() bufferingPredicate {
+				return func(c *bufferingContext) bool {
+					return c.responseCode == http.StatusBadGateway || c.responseCode == http.StatusGatewayTimeout
+				}
+			},
+			"Attempts": func() bufferingInt {
+				return func(c *bufferingContext) int { return c.attempt }
+			},
+			"ResponseCode": func() bufferingInt {
+				return func(c *bufferingContext) int { return c.responseCode }
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	out, err := parser.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := out.(bufferingPredicate)
+	if !ok {
+		return nil, fmt.Errorf("expected predicate, got %T", out)
+	}
+	return p, nil
+}
+
+func bufferingAnd(predicates ...bufferingPredicate) bufferingPredicate {
+	return func(c *bufferingContext) bool {
+		for _, p := range predicates {
+			if !p(c) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func bufferingOr(predicates ...bufferingPredicate) bufferingPredicate {
+	return func(c *bufferingContext) bool {
+		for _, p := range predicates {
+			if p(c) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func bufferingEQ(m interface{}, value interface{}) (bufferingPredicate, error) {
+	switch mapper := m.(type) {
+	case bufferingString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return func(c *bufferingContext) bool { return mapper(c) == s }, nil
+	case bufferingInt:
+		return bufferingCompare(mapper, value, func(a, b int) bool { return a == b })
+	}
+	return nil, fmt.Errorf("unsupported argument: %T", m)
+}
+
+func bufferingNEQ(m interface{}, value interface{}) (bufferingPredicate, error) {
+	p, err := bufferingEQ(m, value)
+	if err != nil {
+		return nil, err
+	}
+	return func(c *bufferingContext) bool { return !p(c) }, nil
+}
+
+func bufferingLT(m interface{}, value interface{}) (bufferingPredicate, error) {
+	return bufferingCompare(m, value, func(a, b int) bool { return a < b })
+}
+
+func bufferingGT(m interface{}, value interface{}) (bufferingPredicate, error) {
+	return bufferingCompare(m, value, func(a, b int) bool { return a > b })
+}
+
+func bufferingLE(m interface{}, value interface{}) (bufferingPredicate, error) {
+	return bufferingCompare(m, value, func(a, b int) bool { return a <= b })
+}
+
+func bufferingGE(m interface{}, value interface{}) (bufferingPredicate, error) {
+	return bufferingCompare(m, value, func(a, b int) bool { return a >= b })
+}
+
+func bufferingCompare(m interface{}, value interface{}, compare func(a, b int) bool) (bufferingPredicate, error) {
+	mapper, ok := m.(bufferingInt)
+	if !ok {
+		return nil, fmt.Errorf("unsupported argument: %T", m)
+	}
+	i, ok := value.(int)
+	if !ok {
+		return nil, fmt.Errorf("expected int, got %T", value)
+	}
+	return func(c *bufferingContext) bool { return compare(mapper(c), i) }, nil
+}
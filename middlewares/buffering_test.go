@@ -0,0 +1,155 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/containous/traefik/types"
+)
+
+// echoHandler answers with the request body
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+}
+
+func TestBuffering(t *testing.T) {
+	tests := []struct {
+		desc         string
+		buffering    types.Buffering
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			desc:         "in memory",
+			buffering:    types.Buffering{},
+			body:         "payload",
+			expectedCode: http.StatusOK,
+			expectedBody: "payload",
+		},
+		{
+			desc:         "buffered to disk",
+			buffering:    types.Buffering{MemRequestBodyBytes: 2, MemResponseBodyBytes: 3},
+			body:         "payload",
+			expectedCode: http.StatusOK,
+			expectedBody: "payload",
+		},
+		{
+			desc:         "request too large",
+			buffering:    types.Buffering{MaxRequestBodyBytes: 4},
+			body:         "payload",
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			desc:         "response too large",
+			buffering:    types.Buffering{MaxResponseBodyBytes: 4},
+			body:         "payload",
+			expectedCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			handler, err := NewBuffering(echoHandler(), &test.buffering)
+			if err != nil {
+				t.Fatal(err)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("POST", "http://foo.bar/", strings.NewReader(test.body)))
+			if recorder.Code != test.expectedCode {
+				t.Errorf("got status %d, want %d", recorder.Code, test.expectedCode)
+			}
+			if test.expectedCode == http.StatusOK && recorder.Body.String() != test.expectedBody {
+				t.Errorf("got body %q, want %q", recorder.Body.String(), test.expectedBody)
+			}
+		})
+	}
+}
+
+func TestBufferingChunkedRequest(t *testing.T) {
+	var contentLength int64
+	handler, err := NewBuffering(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+	}), &types.Buffering{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("POST", "http://foo.bar/", strings.NewReader("payload"))
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if contentLength != int64(len("payload")) {
+		t.Errorf("got content length %d, want %d", contentLength, len("payload"))
+	}
+}
+
+func TestBufferingRetry(t *testing.T) {
+	var attempts int
+	var bodies []string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if attempts < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("done"))
+	})
+	handler, err := NewBuffering(next, &types.Buffering{RetryExpression: "IsNetworkError() && Attempts() < 5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("POST", "http://foo.bar/", strings.NewReader("payload")))
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "done" {
+		t.Errorf("got response %d %q, want 200 \"done\"", recorder.Code, recorder.Body.String())
+	}
+	if attempts != 3 {
+		t.Errorf("got %d attempts, want 3", attempts)
+	}
+	for _, body := range bodies {
+		if body != "payload" {
+			t.Errorf("got replayed body %q, want %q", body, "payload")
+		}
+	}
+}
+
+func TestParseBufferingExpression(t *testing.T) {
+	tests := []struct {
+		expression string
+		context    bufferingContext
+		expected   bool
+	}{
+		{expression: "IsNetworkError()", context: bufferingContext{responseCode: http.StatusGatewayTimeout}, expected: true},
+		{expression: "IsNetworkError() && Attempts() <= 2", context: bufferingContext{responseCode: http.StatusBadGateway, attempt: 3}, expected: false},
+		{expression: `RequestMethod() == "GET" && ResponseCode() >= 500`, context: bufferingContext{responseCode: 503}, expected: true},
+		{expression: `RequestMethod() != "GET" || ResponseCode() == 404`, context: bufferingContext{responseCode: 503}, expected: false},
+	}
+
+	for _, test := range tests {
+		predicate, err := parseBufferingExpression(test.expression)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.expression, err)
+			continue
+		}
+		test.context.request = httptest.NewRequest("GET", "http://foo.bar/", nil)
+		if actual := predicate(&test.context); actual != test.expected {
+			t.Errorf("%s: got %t, want %t", test.expression, actual, test.expected)
+		}
+	}
+
+	for _, expression := range []string{"Attempts(", "Unknown() > 2", `Attempts() == "two"`} {
+		if _, err := parseBufferingExpression(expression); err == nil {
+			t.Errorf("%s: expected an error", expression)
+		}
+	}
+}
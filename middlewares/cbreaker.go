@@ -3,6 +3,7 @@ package middlewares
 import (
 	"net/http"
 
+	"github.com/containous/traefik/types"
 	"github.com/vulcand/oxy/cbreaker"
 )
 
@@ -20,6 +21,27 @@ func NewCircuitBreaker(next http.Handler, expression string, options ...cbreaker
 	return &CircuitBreaker{circuitBreaker}, nil
 }
 
+// NewBackendCircuitBreaker returns the circuit breaker of a backend: the circuit breakers of the
+// expressions of its configuration, nested so that the backend trips when any of them trips.
+func NewBackendCircuitBreaker(next http.Handler, config *types.CircuitBreaker, options ...cbreaker.CircuitBreakerOption) (*CircuitBreaker, error) {
+	expressions, err := config.BuildExpressions()
+	if err != nil {
+		return nil, err
+	}
+	var circuitBreaker *cbreaker.CircuitBreaker
+	for i := len(expressions) - 1; i >= 0; i-- {
+		if match := expressions[i].Match; match != nil {
+			next = NewResponseMatchCheck(next, *match)
+		}
+		circuitBreaker, err = cbreaker.New(next, expressions[i].Expression, options...)
+		if err != nil {
+			return nil, err
+		}
+		next = circuitBreaker
+	}
+	return &CircuitBreaker{circuitBreaker}, nil
+}
+
 func (cb *CircuitBreaker) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	cb.circuitBreaker.ServeHTTP(rw, r)
 }
@@ -0,0 +1,98 @@
+package middlewares
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FlushInterval is a middleware flushing the response body to the client at a regular
+// interval while it is written, so that streamed responses are not held in buffers
+type FlushInterval struct {
+	next     http.Handler
+	interval time.Duration
+}
+
+// NewFlushInterval returns a new FlushInterval middleware
+func NewFlushInterval(next http.Handler, interval time.Duration) *FlushInterval {
+	return &FlushInterval{
+		next:     next,
+		interval: interval,
+	}
+}
+
+func (f *FlushInterval) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	flusher, ok := rw.(http.Flusher)
+	if !ok || f.interval <= 0 {
+		f.next.ServeHTTP(rw, r)
+		return
+	}
+	writer := &flushIntervalWriter{rw: rw, flusher: flusher}
+	done := make(chan struct{})
+	go writer.flushLoop(f.interval, done)
+	defer func() {
+		close(done)
+		writer.Flush()
+	}()
+	f.next.ServeHTTP(writer, r)
+}
+
+// flushIntervalWriter is a ResponseWriter whose writes are flushed by flushLoop
+type flushIntervalWriter struct {
+	rw      http.ResponseWriter
+	flusher http.Flusher
+	lock    sync.Mutex
+	dirty   bool
+}
+
+func (w *flushIntervalWriter) Header() http.Header {
+	return w.rw.Header()
+}
+
+func (w *flushIntervalWriter) Write(b []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.dirty = true
+	return w.rw.Write(b)
+}
+
+func (w *flushIntervalWriter) WriteHeader(code int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.rw.WriteHeader(code)
+}
+
+func (w *flushIntervalWriter) Flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.dirty = false
+	w.flusher.Flush()
+}
+
+func (w *flushIntervalWriter) flushLoop(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.lock.Lock()
+			if w.dirty {
+				w.dirty = false
+				w.flusher.Flush()
+			}
+			w.lock.Unlock()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (w *flushIntervalWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.rw.(http.Hijacker).Hijack()
+}
+
+func (w *flushIntervalWriter) CloseNotify() <-chan bool {
+	return w.rw.(http.CloseNotifier).CloseNotify()
+}
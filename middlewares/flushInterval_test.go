@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFlushInterval(t *testing.T) {
+	flushed := make(chan struct{})
+	handler := NewFlushInterval(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: event\n\n"))
+		// the response is flushed while the backend is still streaming
+		select {
+		case <-flushed:
+		case <-time.After(5 * time.Second):
+			t.Error("response not flushed before the end of the stream")
+		}
+	}), 10*time.Millisecond)
+
+	recorder := &flushRecorder{ResponseRecorder: httptest.NewRecorder(), flushed: flushed}
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://foo.bar/", nil))
+	if recorder.Body.String() != "data: event\n\n" {
+		t.Errorf("got body %q, want %q", recorder.Body.String(), "data: event\n\n")
+	}
+}
+
+// flushRecorder signals its first flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan struct{}
+	closed  bool
+}
+
+func (f *flushRecorder) Flush() {
+	f.ResponseRecorder.Flush()
+	if !f.closed {
+		f.closed = true
+		close(f.flushed)
+	}
+}
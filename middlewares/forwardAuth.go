@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+// ForwardAuth is a middleware delegating the authentication of the requests to an external service:
+// the requests are forwarded when the service answers with a 2XX status code, and its response is
+// returned to the client otherwise
+type ForwardAuth struct {
+	config *types.ForwardAuth
+	client *http.Client
+}
+
+// NewForwardAuth returns a new ForwardAuth middleware
+func NewForwardAuth(config *types.ForwardAuth) (*ForwardAuth, error) {
+	if config == nil {
+		return nil, fmt.Errorf("Error creating forward auth: config is nil")
+	}
+	address, err := url.Parse(config.Address)
+	if err != nil || (address.Scheme != "http" && address.Scheme != "https") || address.Host == "" {
+		return nil, fmt.Errorf("Error creating forward auth: invalid address %q, expected an http or https URL", config.Address)
+	}
+	return &ForwardAuth{
+		config: config,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			// the redirections to a login page are returned to the client
+			CheckRedirect: func(r *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}, nil
+}
+
+func (f *ForwardAuth) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	authReq, err := http.NewRequest(http.MethodGet, f.config.Address, nil)
+	if err != nil {
+		log.Errorf("Error creating the forward auth request to %s: %v", f.config.Address, err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	f.writeHeaders(r, authReq)
+
+	authResp, err := f.client.Do(authReq)
+	if err != nil {
+		log.Errorf("Error calling the forward auth service %s: %v", f.config.Address, err)
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer authResp.Body.Close()
+
+	if authResp.StatusCode < http.StatusOK || authResp.StatusCode >= http.StatusMultipleChoices {
+		log.Debugf("Forward auth denied the request with status %d", authResp.StatusCode)
+		for name, values := range authResp.Header {
+			rw.Header()[name] = values
+		}
+		rw.WriteHeader(authResp.StatusCode)
+		io.Copy(rw, authResp.Body)
+		return
+	}
+
+	for _, name := range f.config.AuthResponseHeaders {
+		if value := authResp.Header.Get(name); value != "" {
+			r.Header.Set(name, value)
+		} else {
+			r.Header.Del(name)
+		}
+	}
+	next.ServeHTTP(rw, r)
+}
+
+// writeHeaders copies the headers of the request to the auth request, along with the
+// X-Forwarded headers describing it, which are only kept if they are trusted
+func (f *ForwardAuth) writeHeaders(r *http.Request, authReq *http.Request) {
+	for name, values := range r.Header {
+		authReq.Header[name] = values
+	}
+
+	forwarded := map[string]string{
+		"X-Forwarded-Method": r.Method,
+		"X-Forwarded-Host":   r.Host,
+		"X-Forwarded-Uri":    r.URL.RequestURI(),
+		"X-Forwarded-Proto":  "http",
+	}
+	if r.TLS != nil {
+		forwarded["X-Forwarded-Proto"] = "https"
+	}
+	if clientIP, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		forwarded["X-Forwarded-For"] = clientIP
+	}
+	for name, value := range forwarded {
+		if f.config.TrustForwardHeader && authReq.Header.Get(name) != "" {
+			continue
+		}
+		authReq.Header.Set(name, value)
+	}
+}
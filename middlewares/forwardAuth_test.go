@@ -0,0 +1,110 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/types"
+)
+
+func TestForwardAuth(t *testing.T) {
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Uri", r.Header.Get("X-Forwarded-Uri"))
+		w.Header().Set("X-Seen-Host", r.Header.Get("X-Forwarded-Host"))
+		switch r.Header.Get("Authorization") {
+		case "valid":
+			w.Header().Set("X-Auth-User", "admin")
+			w.Header().Set("X-Auth-Role", "root")
+		case "redirect":
+			http.Redirect(w, r, "http://login.foo.bar/", http.StatusFound)
+		default:
+			w.Header().Set("WWW-Authenticate", "Basic realm=traefik")
+			http.Error(w, "denied", http.StatusUnauthorized)
+		}
+	}))
+	defer authServer.Close()
+
+	tests := []struct {
+		desc                    string
+		config                  types.ForwardAuth
+		requestHeaders          map[string]string
+		expectedCode            int
+		expectedRequestHeaders  map[string]string
+		expectedResponseHeaders map[string]string
+	}{
+		{
+			desc:                   "allowed",
+			config:                 types.ForwardAuth{AuthResponseHeaders: []string{"X-Auth-User"}},
+			requestHeaders:         map[string]string{"Authorization": "valid", "X-Auth-User": "forged"},
+			expectedCode:           http.StatusOK,
+			expectedRequestHeaders: map[string]string{"X-Auth-User": "admin", "X-Auth-Role": ""},
+		},
+		{
+			desc:                    "denied",
+			expectedCode:            http.StatusUnauthorized,
+			expectedResponseHeaders: map[string]string{"Www-Authenticate": "Basic realm=traefik"},
+		},
+		{
+			desc:                    "redirected to login",
+			requestHeaders:          map[string]string{"Authorization": "redirect"},
+			expectedCode:            http.StatusFound,
+			expectedResponseHeaders: map[string]string{"Location": "http://login.foo.bar/"},
+		},
+		{
+			desc:                    "untrusted forward headers",
+			requestHeaders:          map[string]string{"X-Forwarded-Uri": "/admin", "X-Forwarded-Host": "evil.com"},
+			expectedCode:            http.StatusUnauthorized,
+			expectedResponseHeaders: map[string]string{"X-Seen-Uri": "/path?query=1", "X-Seen-Host": "foo.bar"},
+		},
+		{
+			desc:                    "trusted forward headers",
+			config:                  types.ForwardAuth{TrustForwardHeader: true},
+			requestHeaders:          map[string]string{"X-Forwarded-Uri": "/admin"},
+			expectedCode:            http.StatusUnauthorized,
+			expectedResponseHeaders: map[string]string{"X-Seen-Uri": "/admin", "X-Seen-Host": "foo.bar"},
+		},
+	}
+
+	for _, test := range tests {
+		test.config.Address = authServer.URL
+		forwardAuth, err := NewForwardAuth(&test.config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.desc, err)
+		}
+		var requestHeaders http.Header
+		next := func(w http.ResponseWriter, r *http.Request) {
+			requestHeaders = r.Header
+			w.Write([]byte("ok"))
+		}
+		req := httptest.NewRequest("GET", "http://foo.bar/path?query=1", nil)
+		for name, value := range test.requestHeaders {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		forwardAuth.ServeHTTP(recorder, req, next)
+
+		if recorder.Code != test.expectedCode {
+			t.Errorf("%s: got status %d, want %d", test.desc, recorder.Code, test.expectedCode)
+			continue
+		}
+		for name, value := range test.expectedRequestHeaders {
+			if actual := requestHeaders.Get(name); actual != value {
+				t.Errorf("%s: got request header %s %q, want %q", test.desc, name, actual, value)
+			}
+		}
+		for name, value := range test.expectedResponseHeaders {
+			if actual := recorder.Header().Get(name); actual != value {
+				t.Errorf("%s: got response header %s %q, want %q", test.desc, name, actual, value)
+			}
+		}
+	}
+}
+
+func TestNewForwardAuthInvalidAddress(t *testing.T) {
+	for _, address := range []string{"", "auth:9000/verify"} {
+		if _, err := NewForwardAuth(&types.ForwardAuth{Address: address}); err == nil {
+			t.Errorf("%q: expected an error", address)
+		}
+	}
+}
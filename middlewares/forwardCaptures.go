@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/containous/mux"
+)
+
+const (
+	capturedHeaderPrefix = "X-Captured-"
+)
+
+// ForwardCaptures is a middleware used to forward the named variables captured by the route as request headers,
+// the captured headers sent by the client being removed so that the backends can trust them
+type ForwardCaptures struct {
+	Handler http.Handler
+}
+
+func (f *ForwardCaptures) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for key := range r.Header {
+		if strings.HasPrefix(http.CanonicalHeaderKey(key), capturedHeaderPrefix) {
+			r.Header.Del(key)
+		}
+	}
+	for name, value := range mux.Vars(r) {
+		r.Header.Set(capturedHeaderPrefix+name, value)
+	}
+	f.Handler.ServeHTTP(w, r)
+}
+
+// SetHandler sets handler
+func (f *ForwardCaptures) SetHandler(Handler http.Handler) {
+	f.Handler = Handler
+}
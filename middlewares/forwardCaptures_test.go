@@ -0,0 +1,79 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/mux"
+)
+
+func TestForwardCaptures(t *testing.T) {
+	var captured http.Header
+	handler := &ForwardCaptures{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured = r.Header
+		}),
+	}
+
+	router := mux.NewRouter()
+	router.PathPrefix("/api/{version:v[0-9]+}/{resource}").Handler(handler)
+
+	tests := []struct {
+		url      string
+		expected map[string]string
+	}{
+		{
+			url: "/api/v1/resource",
+			expected: map[string]string{
+				"X-Captured-version":  "v1",
+				"X-Captured-resource": "resource",
+			},
+		},
+		{
+			url: "/api/v22/users/42",
+			expected: map[string]string{
+				"X-Captured-version":  "v22",
+				"X-Captured-resource": "users",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		captured = nil
+		req := httptest.NewRequest("GET", test.url, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		if captured == nil {
+			t.Fatalf("Expected request %s to be routed", test.url)
+		}
+		for header, value := range test.expected {
+			if actual := captured.Get(header); actual != value {
+				t.Errorf("Expected header %s to be %q for %s, got %q", header, value, test.url, actual)
+			}
+		}
+	}
+}
+
+func TestForwardCapturesRemovesClientHeaders(t *testing.T) {
+	var captured http.Header
+	handler := &ForwardCaptures{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured = r.Header
+		}),
+	}
+
+	router := mux.NewRouter()
+	router.PathPrefix("/api/{version:v[0-9]+}").Handler(handler)
+
+	req := httptest.NewRequest("GET", "/api/v1", nil)
+	req.Header.Set("X-Captured-version", "v9")
+	req.Header.Set("X-Captured-admin", "true")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if actual := captured.Get("X-Captured-version"); actual != "v1" {
+		t.Errorf("Expected header X-Captured-version to be %q, got %q", "v1", actual)
+	}
+	if actual, ok := captured["X-Captured-Admin"]; ok {
+		t.Errorf("Expected header X-Captured-admin sent by the client to be removed, got %q", actual)
+	}
+}
@@ -0,0 +1,177 @@
+package middlewares
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+)
+
+// Headers is a middleware setting the custom request and response headers of a frontend,
+// and enforcing its security headers: allowed hosts, SSL redirect, HSTS, frame options...
+// The allowed hosts, SSL redirect, HSTS and HPKP are disabled in development mode.
+type Headers struct {
+	Handler http.Handler
+	Headers *types.Headers
+}
+
+func (h *Headers) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if !h.Headers.IsDevelopment {
+		if len(h.Headers.AllowedHosts) > 0 && !h.isAllowedHost(r) {
+			log.Debugf("Rejecting request for host %s not in the allowed hosts", r.Host)
+			http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		if h.Headers.SSLRedirect && !h.isSSL(r) {
+			h.redirectToSSL(rw, r)
+			return
+		}
+	}
+
+	for name, value := range h.Headers.CustomRequestHeaders {
+		if len(value) == 0 {
+			r.Header.Del(name)
+		} else {
+			r.Header.Set(name, value)
+		}
+	}
+
+	h.Handler.ServeHTTP(&headersResponseWriter{rw: rw, headers: h.responseHeaders(r)}, r)
+}
+
+func (h *Headers) isAllowedHost(r *http.Request) bool {
+	hosts := []string{r.Host}
+	for _, header := range h.Headers.HostsProxyHeaders {
+		if host := r.Header.Get(header); len(host) > 0 {
+			hosts = append(hosts, host)
+		}
+	}
+	for _, host := range hosts {
+		for _, allowedHost := range h.Headers.AllowedHosts {
+			if strings.EqualFold(host, allowedHost) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (h *Headers) isSSL(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	for name, value := range h.Headers.SSLProxyHeaders {
+		if r.Header.Get(name) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Headers) redirectToSSL(rw http.ResponseWriter, r *http.Request) {
+	url := *r.URL
+	url.Scheme = "https"
+	url.Host = r.Host
+	if len(h.Headers.SSLHost) > 0 {
+		url.Host = h.Headers.SSLHost
+	}
+	status := http.StatusMovedPermanently
+	if h.Headers.SSLTemporaryRedirect {
+		status = http.StatusTemporaryRedirect
+	}
+	http.Redirect(rw, r, url.String(), status)
+}
+
+// responseHeaders returns the headers to set on the response, an empty value removing the header
+func (h *Headers) responseHeaders(r *http.Request) map[string]string {
+	headers := make(map[string]string)
+	if !h.Headers.IsDevelopment && h.Headers.STSSeconds > 0 && (h.isSSL(r) || h.Headers.ForceSTSHeader) {
+		sts := fmt.Sprintf("max-age=%d", h.Headers.STSSeconds)
+		if h.Headers.STSIncludeSubdomains {
+			sts += "; includeSubdomains"
+		}
+		if h.Headers.STSPreload {
+			sts += "; preload"
+		}
+		headers["Strict-Transport-Security"] = sts
+	}
+	if len(h.Headers.CustomFrameOptionsValue) > 0 {
+		headers["X-Frame-Options"] = h.Headers.CustomFrameOptionsValue
+	} else if h.Headers.FrameDeny {
+		headers["X-Frame-Options"] = "DENY"
+	}
+	if h.Headers.ContentTypeNosniff {
+		headers["X-Content-Type-Options"] = "nosniff"
+	}
+	if h.Headers.BrowserXSSFilter {
+		headers["X-XSS-Protection"] = "1; mode=block"
+	}
+	if len(h.Headers.ContentSecurityPolicy) > 0 {
+		headers["Content-Security-Policy"] = h.Headers.ContentSecurityPolicy
+	}
+	if !h.Headers.IsDevelopment && len(h.Headers.PublicKey) > 0 && h.isSSL(r) {
+		headers["Public-Key-Pins"] = h.Headers.PublicKey
+	}
+	if len(h.Headers.ReferrerPolicy) > 0 {
+		headers["Referrer-Policy"] = h.Headers.ReferrerPolicy
+	}
+	for name, value := range h.Headers.CustomResponseHeaders {
+		headers[name] = value
+	}
+	return headers
+}
+
+// headersResponseWriter sets the response headers over the ones of the backend
+// when the response header is written
+type headersResponseWriter struct {
+	rw          http.ResponseWriter
+	headers     map[string]string
+	wroteHeader bool
+}
+
+func (hrw *headersResponseWriter) Header() http.Header {
+	return hrw.rw.Header()
+}
+
+func (hrw *headersResponseWriter) Write(b []byte) (int, error) {
+	if !hrw.wroteHeader {
+		hrw.WriteHeader(http.StatusOK)
+	}
+	return hrw.rw.Write(b)
+}
+
+func (hrw *headersResponseWriter) WriteHeader(code int) {
+	if hrw.wroteHeader {
+		return
+	}
+	hrw.wroteHeader = true
+	for name, value := range hrw.headers {
+		if len(value) == 0 {
+			hrw.rw.Header().Del(name)
+		} else {
+			hrw.rw.Header().Set(name, value)
+		}
+	}
+	hrw.rw.WriteHeader(code)
+}
+
+func (hrw *headersResponseWriter) Flush() {
+	if !hrw.wroteHeader {
+		hrw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := hrw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (hrw *headersResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hrw.rw.(http.Hijacker).Hijack()
+}
+
+func (hrw *headersResponseWriter) CloseNotify() <-chan bool {
+	return hrw.rw.(http.CloseNotifier).CloseNotify()
+}
@@ -0,0 +1,151 @@
+package middlewares
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/types"
+)
+
+func TestHeaders(t *testing.T) {
+	tests := []struct {
+		desc                    string
+		headers                 types.Headers
+		host                    string
+		tls                     bool
+		requestHeaders          map[string]string
+		expectedCode            int
+		expectedLocation        string
+		expectedRequestHeaders  map[string]string
+		expectedResponseHeaders map[string]string
+	}{
+		{
+			desc: "custom headers",
+			headers: types.Headers{
+				CustomRequestHeaders:  map[string]string{"X-Custom-Header": "value", "X-Remove": ""},
+				CustomResponseHeaders: map[string]string{"X-Powered-By": "", "X-Served-By": "traefik"},
+			},
+			requestHeaders:          map[string]string{"X-Remove": "me"},
+			expectedCode:            http.StatusOK,
+			expectedRequestHeaders:  map[string]string{"X-Custom-Header": "value", "X-Remove": ""},
+			expectedResponseHeaders: map[string]string{"X-Powered-By": "", "X-Served-By": "traefik"},
+		},
+		{
+			desc: "security headers",
+			headers: types.Headers{
+				STSSeconds:            315360000,
+				STSIncludeSubdomains:  true,
+				FrameDeny:             true,
+				ContentTypeNosniff:    true,
+				BrowserXSSFilter:      true,
+				ContentSecurityPolicy: "default-src 'self'",
+				ReferrerPolicy:        "same-origin",
+			},
+			tls:          true,
+			expectedCode: http.StatusOK,
+			expectedResponseHeaders: map[string]string{
+				"Strict-Transport-Security": "max-age=315360000; includeSubdomains",
+				"X-Frame-Options":           "DENY",
+				"X-Content-Type-Options":    "nosniff",
+				"X-Xss-Protection":          "1; mode=block",
+				"Content-Security-Policy":   "default-src 'self'",
+				"Referrer-Policy":           "same-origin",
+			},
+		},
+		{
+			desc:                    "no HSTS without SSL",
+			headers:                 types.Headers{STSSeconds: 60, CustomFrameOptionsValue: "SAMEORIGIN", FrameDeny: true},
+			expectedCode:            http.StatusOK,
+			expectedResponseHeaders: map[string]string{"Strict-Transport-Security": "", "X-Frame-Options": "SAMEORIGIN"},
+		},
+		{
+			desc:                    "forced HSTS",
+			headers:                 types.Headers{STSSeconds: 60, STSPreload: true, ForceSTSHeader: true},
+			expectedCode:            http.StatusOK,
+			expectedResponseHeaders: map[string]string{"Strict-Transport-Security": "max-age=60; preload"},
+		},
+		{
+			desc:             "SSL redirect",
+			headers:          types.Headers{SSLRedirect: true},
+			expectedCode:     http.StatusMovedPermanently,
+			expectedLocation: "https://foo.bar/path?query=1",
+		},
+		{
+			desc:             "SSL temporary redirect to SSL host",
+			headers:          types.Headers{SSLRedirect: true, SSLTemporaryRedirect: true, SSLHost: "secure.foo.bar"},
+			expectedCode:     http.StatusTemporaryRedirect,
+			expectedLocation: "https://secure.foo.bar/path?query=1",
+		},
+		{
+			desc:           "no SSL redirect behind an SSL proxy",
+			headers:        types.Headers{SSLRedirect: true, SSLProxyHeaders: map[string]string{"X-Forwarded-Proto": "https"}},
+			requestHeaders: map[string]string{"X-Forwarded-Proto": "https"},
+			expectedCode:   http.StatusOK,
+		},
+		{
+			desc:         "allowed host",
+			headers:      types.Headers{AllowedHosts: []string{"foo.bar"}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "host not allowed",
+			headers:      types.Headers{AllowedHosts: []string{"example.com"}},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			desc:           "host allowed through a proxy header",
+			headers:        types.Headers{AllowedHosts: []string{"example.com"}, HostsProxyHeaders: []string{"X-Forwarded-Host"}},
+			requestHeaders: map[string]string{"X-Forwarded-Host": "example.com"},
+			expectedCode:   http.StatusOK,
+		},
+		{
+			desc:         "development",
+			headers:      types.Headers{AllowedHosts: []string{"example.com"}, SSLRedirect: true, IsDevelopment: true},
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			var requestHeaders http.Header
+			headers := &Headers{
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					requestHeaders = r.Header
+					w.Header().Set("X-Powered-By", "backend")
+					w.Write([]byte("ok"))
+				}),
+				Headers: &test.headers,
+			}
+			req := httptest.NewRequest("GET", "http://foo.bar/path?query=1", nil)
+			if test.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for name, value := range test.requestHeaders {
+				req.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			headers.ServeHTTP(recorder, req)
+
+			if recorder.Code != test.expectedCode {
+				t.Fatalf("got status %d, want %d", recorder.Code, test.expectedCode)
+			}
+			if location := recorder.Header().Get("Location"); location != test.expectedLocation {
+				t.Errorf("got location %q, want %q", location, test.expectedLocation)
+			}
+			for name, value := range test.expectedRequestHeaders {
+				if actual := requestHeaders.Get(name); actual != value {
+					t.Errorf("got request header %s %q, want %q", name, actual, value)
+				}
+			}
+			for name, value := range test.expectedResponseHeaders {
+				if actual := recorder.Header().Get(name); actual != value {
+					t.Errorf("got response header %s %q, want %q", name, actual, value)
+				}
+			}
+		})
+	}
+}
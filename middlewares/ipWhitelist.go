@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/log"
+)
+
+// IPWhitelist is a middleware rejecting with 403 Forbidden the requests whose client IP
+// is not in one of the source ranges
+type IPWhitelist struct {
+	Handler     http.Handler
+	SourceRange []*net.IPNet
+}
+
+// ParseSourceRange parses the CIDRs of a whitelist source range
+func ParseSourceRange(sourceRange []string) ([]*net.IPNet, error) {
+	var ipNets []*net.IPNet
+	for _, cidr := range sourceRange {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, errors.New("Invalid CIDR '" + cidr + "' in whitelist source range")
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	return ipNets, nil
+}
+
+func (w *IPWhitelist) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		for _, ipNet := range w.SourceRange {
+			if ipNet.Contains(ip) {
+				w.Handler.ServeHTTP(rw, r)
+				return
+			}
+		}
+	}
+	log.Debugf("Rejecting request from %s not in the whitelist source range", r.RemoteAddr)
+	http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
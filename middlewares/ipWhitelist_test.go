@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPWhitelist(t *testing.T) {
+	sourceRange, err := ParseSourceRange([]string{"192.168.1.0/24", "10.0.0.0/8", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	whitelist := &IPWhitelist{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+		SourceRange: sourceRange,
+	}
+
+	tests := []struct {
+		remoteAddr   string
+		expectedCode int
+	}{
+		{remoteAddr: "192.168.1.42:1234", expectedCode: http.StatusOK},
+		{remoteAddr: "10.1.2.3:1234", expectedCode: http.StatusOK},
+		{remoteAddr: "[2001:db8::1]:1234", expectedCode: http.StatusOK},
+		{remoteAddr: "192.168.2.1:1234", expectedCode: http.StatusForbidden},
+		{remoteAddr: "[2001:db9::1]:1234", expectedCode: http.StatusForbidden},
+		{remoteAddr: "10.1.2.3", expectedCode: http.StatusOK},
+		{remoteAddr: "invalid", expectedCode: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.remoteAddr, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest("GET", "http://foo.bar/", nil)
+			req.RemoteAddr = test.remoteAddr
+			recorder := httptest.NewRecorder()
+			whitelist.ServeHTTP(recorder, req)
+			if recorder.Code != test.expectedCode {
+				t.Errorf("got status %d, want %d", recorder.Code, test.expectedCode)
+			}
+		})
+	}
+}
+
+func TestParseSourceRangeInvalid(t *testing.T) {
+	if _, err := ParseSourceRange([]string{"10.0.0.0/8", "10.0.0.300/8"}); err == nil {
+		t.Error("expected an error for an invalid CIDR")
+	}
+}
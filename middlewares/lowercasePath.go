@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+)
+
+// OriginalURLHeader is the header holding the request URI before the path is lowercased
+const OriginalURLHeader = "X-Original-URL"
+
+// LowercasePath is a middleware lowercasing the path of a URL request, the original
+// request URI being kept in the X-Original-URL header
+type LowercasePath struct {
+	Handler http.Handler
+}
+
+func (l *LowercasePath) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	originalURL := r.RequestURI
+	if originalURL == "" {
+		originalURL = r.URL.RequestURI()
+	}
+	r.Header.Set(OriginalURLHeader, originalURL)
+	r.URL.Path = strings.ToLower(r.URL.Path)
+	r.URL.RawPath = ""
+	r.RequestURI = r.URL.RequestURI()
+	l.Handler.ServeHTTP(w, r)
+}
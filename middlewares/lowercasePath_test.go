@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLowercasePath(t *testing.T) {
+	var path, requestURI, originalURL string
+	handler := &LowercasePath{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+			requestURI = r.RequestURI
+			originalURL = r.Header.Get(OriginalURLHeader)
+		}),
+	}
+
+	req := httptest.NewRequest("GET", "/Api/V1/Users?Name=Foo", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if path != "/api/v1/users" {
+		t.Errorf("got path %q, want %q", path, "/api/v1/users")
+	}
+	if requestURI != "/api/v1/users?Name=Foo" {
+		t.Errorf("got request URI %q, want %q", requestURI, "/api/v1/users?Name=Foo")
+	}
+	if originalURL != "/Api/V1/Users?Name=Foo" {
+		t.Errorf("got %s header %q, want %q", OriginalURLHeader, originalURL, "/Api/V1/Users?Name=Foo")
+	}
+}
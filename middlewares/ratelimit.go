@@ -0,0 +1,192 @@
+package middlewares
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/vulcand/oxy/utils"
+)
+
+// Rate holds the number of requests allowed over a period of time
+type Rate struct {
+	Average int64
+	Period  time.Duration
+}
+
+// RateLimit is a middleware that limits the rate of requests using a token bucket
+type RateLimit struct {
+	Handler http.Handler
+	bucket  *tokenBucket
+}
+
+// NewRateLimit builds a new RateLimit given a handler and a rate.
+// The bucket allows bursts of up to rate.Average requests.
+func NewRateLimit(handler http.Handler, rate Rate) *RateLimit {
+	return &RateLimit{
+		Handler: handler,
+		bucket:  newTokenBucket(rate, time.Now),
+	}
+}
+
+func (r *RateLimit) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !r.bucket.take() {
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	r.Handler.ServeHTTP(w, req)
+}
+
+// SetHandler sets handler
+func (r *RateLimit) SetHandler(Handler http.Handler) {
+	r.Handler = Handler
+}
+
+// SourceRateLimit is a middleware that limits the rate of requests of each source, as given by
+// the extractor, a request being rejected as soon as one of the rates is exceeded
+type SourceRateLimit struct {
+	Handler   http.Handler
+	extractor utils.SourceExtractor
+	rates     []Rate
+	clock     func() time.Time
+
+	lock      sync.Mutex
+	buckets   map[string][]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewSourceRateLimit builds a new SourceRateLimit given a handler, a source extractor and the rates
+func NewSourceRateLimit(handler http.Handler, extractor utils.SourceExtractor, rates []Rate) *SourceRateLimit {
+	return &SourceRateLimit{
+		Handler:   handler,
+		extractor: extractor,
+		rates:     rates,
+		clock:     time.Now,
+		buckets:   map[string][]*tokenBucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (r *SourceRateLimit) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	source, _, err := r.extractor.Extract(req)
+	if err != nil {
+		log.Debugf("Error extracting the rate limiting source: %v", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if !r.take(source) {
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+	r.Handler.ServeHTTP(w, req)
+}
+
+// take takes a token from each bucket of the source, only if none of them is empty
+func (r *SourceRateLimit) take(source string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	buckets := r.getBuckets(source)
+	for _, bucket := range buckets {
+		if !bucket.available() {
+			return false
+		}
+	}
+	for _, bucket := range buckets {
+		bucket.take()
+	}
+	return true
+}
+
+func (r *SourceRateLimit) getBuckets(source string) []*tokenBucket {
+	now := r.clock()
+	// forget the sources whose buckets are full again, as they would be created
+	if now.Sub(r.lastSweep) > r.maxPeriod() {
+		for key, buckets := range r.buckets {
+			if allFull(buckets) {
+				delete(r.buckets, key)
+			}
+		}
+		r.lastSweep = now
+	}
+
+	buckets, ok := r.buckets[source]
+	if !ok {
+		for _, rate := range r.rates {
+			buckets = append(buckets, newTokenBucket(rate, r.clock))
+		}
+		r.buckets[source] = buckets
+	}
+	return buckets
+}
+
+func (r *SourceRateLimit) maxPeriod() time.Duration {
+	var max time.Duration
+	for _, rate := range r.rates {
+		if rate.Period > max {
+			max = rate.Period
+		}
+	}
+	return max
+}
+
+func allFull(buckets []*tokenBucket) bool {
+	for _, bucket := range buckets {
+		if !bucket.full() {
+			return false
+		}
+	}
+	return true
+}
+
+type tokenBucket struct {
+	lock     sync.Mutex
+	clock    func() time.Time
+	capacity float64
+	refill   float64 // tokens per nanosecond
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate Rate, clock func() time.Time) *tokenBucket {
+	return &tokenBucket{
+		clock:    clock,
+		capacity: float64(rate.Average),
+		refill:   float64(rate.Average) / float64(rate.Period),
+		tokens:   float64(rate.Average),
+		last:     clock(),
+	}
+}
+
+func (b *tokenBucket) take() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.clock()
+	b.tokens += float64(now.Sub(b.last)) * b.refill
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *tokenBucket) available() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.tokens+float64(b.clock().Sub(b.last))*b.refill >= 1
+}
+
+func (b *tokenBucket) full() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.tokens+float64(b.clock().Sub(b.last))*b.refill >= b.capacity
+}
@@ -0,0 +1,111 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/vulcand/oxy/utils"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(Rate{Average: 2, Period: time.Second}, func() time.Time { return now })
+
+	for i := 0; i < 2; i++ {
+		if !bucket.take() {
+			t.Fatalf("Expected request %d to be allowed", i)
+		}
+	}
+	if bucket.take() {
+		t.Fatal("Expected request to be limited once the bucket is empty")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if !bucket.take() {
+		t.Fatal("Expected request to be allowed after the bucket has been refilled")
+	}
+	if bucket.take() {
+		t.Fatal("Expected request to be limited once the bucket is empty")
+	}
+
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if !bucket.take() {
+			t.Fatalf("Expected request %d to be allowed", i)
+		}
+	}
+	if bucket.take() {
+		t.Fatal("Expected the bucket not to exceed its capacity")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	handler := NewRateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), Rate{Average: 1, Period: time.Hour})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://foo.bar/", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://foo.bar/", nil))
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, recorder.Code)
+	}
+}
+
+func TestSourceRateLimit(t *testing.T) {
+	extractor, err := utils.NewExtractor("client.ip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	rates := []Rate{
+		{Average: 2, Period: time.Second},
+		{Average: 3, Period: time.Minute},
+	}
+	now := time.Now()
+	rateLimit := NewSourceRateLimit(handler, extractor, rates)
+	rateLimit.clock = func() time.Time { return now }
+
+	serve := func(remoteAddr string) int {
+		req := httptest.NewRequest("GET", "http://foo.bar/", nil)
+		req.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		rateLimit.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := serve("10.0.0.1:1234"); code != http.StatusOK {
+			t.Fatalf("request %d: got status %d, want %d", i, code, http.StatusOK)
+		}
+	}
+	if code := serve("10.0.0.1:1234"); code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, want %d once the per second rate is exceeded", code, http.StatusTooManyRequests)
+	}
+	if code := serve("10.0.0.2:1234"); code != http.StatusOK {
+		t.Errorf("got status %d, want %d for another client", code, http.StatusOK)
+	}
+
+	now = now.Add(2 * time.Second)
+	if code := serve("10.0.0.1:1234"); code != http.StatusOK {
+		t.Errorf("got status %d, want %d once the per second rate is refilled", code, http.StatusOK)
+	}
+	if code := serve("10.0.0.1:1234"); code != http.StatusTooManyRequests {
+		t.Errorf("got status %d, want %d once the per minute rate is exceeded", code, http.StatusTooManyRequests)
+	}
+
+	now = now.Add(time.Hour)
+	serve("10.0.0.3:1234")
+	rateLimit.lock.Lock()
+	defer rateLimit.lock.Unlock()
+	if _, ok := rateLimit.buckets["10.0.0.1"]; ok || len(rateLimit.buckets) != 1 {
+		t.Errorf("got buckets for %d sources, want the idle sources to be forgotten", len(rateLimit.buckets))
+	}
+}
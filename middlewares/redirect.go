@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"net"
+	"net/http"
+)
+
+// Redirect is a middleware that permanently redirects requests to another scheme and port,
+// preserving the requested host, path, query string and fragment
+type Redirect struct {
+	Scheme string
+	Port   string
+}
+
+// NewRedirect builds a new Redirect given a scheme and the address of the target entry point
+func NewRedirect(scheme, address string) (*Redirect, error) {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	return &Redirect{Scheme: scheme, Port: port}, nil
+}
+
+func (r *Redirect) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	http.Redirect(w, req, r.URL(req), http.StatusMovedPermanently)
+}
+
+// URL returns the URL the given request is redirected to
+func (r *Redirect) URL(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+	}
+	u := *req.URL
+	u.Scheme = r.Scheme
+	u.Host = host
+	if !(r.Scheme == "https" && r.Port == "443") && !(r.Scheme == "http" && r.Port == "80") {
+		u.Host = net.JoinHostPort(host, r.Port)
+	}
+	return u.String()
+}
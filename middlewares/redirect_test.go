@@ -0,0 +1,51 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectURL(t *testing.T) {
+	redirect, err := NewRedirect("https", ":443")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "http://foo.bar/", expected: "https://foo.bar/"},
+		{url: "http://foo.bar:80/path/to/resource", expected: "https://foo.bar/path/to/resource"},
+		{url: "http://foo.bar/search?q=traefik&page=2", expected: "https://foo.bar/search?q=traefik&page=2"},
+		{url: "http://foo.bar/docs#section", expected: "https://foo.bar/docs#section"},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := redirect.URL(req); actual != test.expected {
+			t.Errorf("Expected redirect of %s to %s, got %s", test.url, test.expected, actual)
+		}
+	}
+}
+
+func TestRedirectServeHTTP(t *testing.T) {
+	redirect, err := NewRedirect("https", "0.0.0.0:4443")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	redirect.ServeHTTP(recorder, httptest.NewRequest("GET", "http://foo.bar/path?a=b", nil))
+
+	if recorder.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected status %d, got %d", http.StatusMovedPermanently, recorder.Code)
+	}
+	if location := recorder.Header().Get("Location"); location != "https://foo.bar:4443/path?a=b" {
+		t.Errorf("Expected location https://foo.bar:4443/path?a=b, got %s", location)
+	}
+}
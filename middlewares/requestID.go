@@ -0,0 +1,46 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/containous/traefik/log"
+)
+
+// DefaultRequestIDHeader is the default header holding the request ID
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// RequestID is a middleware setting a random request ID header on the requests without one,
+// the request ID being returned in the same header of the response
+type RequestID struct {
+	Handler http.Handler
+	Header  string
+}
+
+func (s *RequestID) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestID := r.Header.Get(s.Header)
+	if len(requestID) == 0 {
+		var err error
+		requestID, err = newUUID()
+		if err != nil {
+			log.Errorf("Unable to generate request ID: %v", err)
+			s.Handler.ServeHTTP(w, r)
+			return
+		}
+		r.Header.Set(s.Header, requestID)
+	}
+	w.Header().Set(s.Header, requestID)
+	s.Handler.ServeHTTP(w, r)
+}
+
+// newUUID returns a random (version 4) UUID as defined by RFC 4122
+func newUUID() (string, error) {
+	uuid := make([]byte, 16)
+	if _, err := rand.Read(uuid); err != nil {
+		return "", err
+	}
+	uuid[6] = uuid[6]&0x0f | 0x40 // version 4
+	uuid[8] = uuid[8]&0x3f | 0x80 // variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:]), nil
+}
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		desc      string
+		header    string
+		requestID string
+	}{
+		{desc: "injected when absent", header: DefaultRequestIDHeader},
+		{desc: "passed through when present", header: DefaultRequestIDHeader, requestID: "my-request"},
+		{desc: "custom header", header: "X-Correlation-ID"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			var forwardedID string
+			handler := &RequestID{
+				Header: test.header,
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					forwardedID = r.Header.Get(test.header)
+				}),
+			}
+
+			req := httptest.NewRequest("GET", "http://foo.bar/", nil)
+			if len(test.requestID) > 0 {
+				req.Header.Set(test.header, test.requestID)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if len(test.requestID) > 0 {
+				if forwardedID != test.requestID {
+					t.Errorf("got forwarded request ID %q, want %q", forwardedID, test.requestID)
+				}
+			} else if !uuidV4.MatchString(forwardedID) {
+				t.Errorf("got forwarded request ID %q, want a version 4 UUID", forwardedID)
+			}
+			if responseID := recorder.Header().Get(test.header); responseID != forwardedID {
+				t.Errorf("got response request ID %q, want %q", responseID, forwardedID)
+			}
+		})
+	}
+}
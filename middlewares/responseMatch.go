@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+// ResponseMatchCheck is a middleware marking the responses counted apart by the circuit breaker
+// wrapping it: the status code of the matching responses is recorded with the
+// types.ResponseMatchCodeOffset offset, leaving the other circuit breakers of the backend unaffected.
+type ResponseMatchCheck struct {
+	next  http.Handler
+	match types.ResponseMatch
+}
+
+// NewResponseMatchCheck returns a new ResponseMatchCheck
+func NewResponseMatchCheck(next http.Handler, match types.ResponseMatch) *ResponseMatchCheck {
+	return &ResponseMatchCheck{
+		next:  next,
+		match: match,
+	}
+}
+
+func (c *ResponseMatchCheck) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	c.next.ServeHTTP(rw, r)
+
+	proxyWriter, ok := rw.(*utils.ProxyWriter)
+	if !ok {
+		return
+	}
+	if rw.Header().Get(c.match.HeaderName) == c.match.HeaderValue {
+		proxyWriter.Code = proxyWriter.StatusCode() + types.ResponseMatchCodeOffset
+	}
+}
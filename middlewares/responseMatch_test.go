@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/types"
+)
+
+func TestResponseMatchCheckCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		desc           string
+		expression     string
+		headerValue    string
+		statusCode     int
+		expectTripping bool
+	}{
+		{
+			desc:           "responses carrying the header",
+			expression:     `ResponseHeaderRatio("X-Error", "true", 200, 300) > 0.5`,
+			headerValue:    "true",
+			statusCode:     http.StatusOK,
+			expectTripping: true,
+		},
+		{
+			desc:           "responses carrying another header value",
+			expression:     `ResponseHeaderRatio("X-Error", "true", 200, 300) > 0.5`,
+			headerValue:    "false",
+			statusCode:     http.StatusOK,
+			expectTripping: false,
+		},
+		{
+			desc:           "responses carrying the header outside of the status code range",
+			expression:     `ResponseHeaderRatio("X-Error", "true", 200, 300) > 0.5`,
+			headerValue:    "true",
+			statusCode:     http.StatusServiceUnavailable,
+			expectTripping: false,
+		},
+		{
+			desc:           "responses carrying the header counted by the status code ratio",
+			expression:     `ResponseCodeRatio(500, 600, 0, 600) > 0.5 || ResponseHeaderRatio("X-Error", "true", 0, 600) > 1.5`,
+			headerValue:    "true",
+			statusCode:     http.StatusInternalServerError,
+			expectTripping: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Error", test.headerValue)
+				w.WriteHeader(test.statusCode)
+			})
+			config := &types.CircuitBreaker{Expression: test.expression}
+			circuitBreaker, err := NewBackendCircuitBreaker(backend, config)
+			if err != nil {
+				t.Fatalf("Error creating circuit breaker: %v", err)
+			}
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				circuitBreaker.ServeHTTP(w, r, nil)
+			})
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://foo.bar", nil))
+			if recorder.Code != test.statusCode {
+				t.Fatalf("expected first response code %d, got %d", test.statusCode, recorder.Code)
+			}
+
+			// the circuit breaker checks its condition after the first response
+			recorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://foo.bar", nil))
+			if tripped := recorder.Code == http.StatusServiceUnavailable && recorder.Header().Get("X-Error") == ""; tripped != test.expectTripping {
+				t.Errorf("expected circuit breaker tripping to be %v, got response code %d", test.expectTripping, recorder.Code)
+			}
+		})
+	}
+}
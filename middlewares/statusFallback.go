@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/containous/traefik/log"
+	"github.com/vulcand/oxy/utils"
+)
+
+// StatusFallback is a middleware forwarding the requests answered with one of the given status
+// codes to a fallback server instead, the fallback servers being picked in turn.
+// Unlike the circuit breaker, it acts on each request on its own and keeps no state.
+type StatusFallback struct {
+	next        http.Handler
+	forward     http.Handler
+	fallbacks   []*url.URL
+	statusCodes []int
+	counter     uint32
+}
+
+// NewStatusFallback returns a new StatusFallback forwarding the requests to next, and with
+// forward to the fallback servers when next answers with one of the status codes.
+func NewStatusFallback(next http.Handler, forward http.Handler, fallbacks []*url.URL, statusCodes []int) *StatusFallback {
+	return &StatusFallback{
+		next:        next,
+		forward:     forward,
+		fallbacks:   fallbacks,
+		statusCodes: statusCodes,
+	}
+}
+
+func (s *StatusFallback) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	// the body is buffered to be sent again to the fallback server
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	recorder := NewRecorder()
+	recorder.responseWriter = rw
+	s.next.ServeHTTP(recorder, r)
+
+	if s.fallbackStatus(recorder.Code) {
+		fallback := s.fallbacks[int(atomic.AddUint32(&s.counter, 1)-1)%len(s.fallbacks)]
+		log.Debugf("Got status %d for request %v, forwarding to fallback server %s", recorder.Code, r.URL, fallback)
+		outReq := new(http.Request)
+		*outReq = *r
+		outReq.URL = utils.CopyURL(fallback)
+		if body != nil {
+			outReq.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		recorder = NewRecorder()
+		recorder.responseWriter = rw
+		s.forward.ServeHTTP(recorder, outReq)
+	}
+
+	utils.CopyHeaders(rw.Header(), recorder.Header())
+	rw.WriteHeader(recorder.Code)
+	rw.Write(recorder.Body.Bytes())
+}
+
+func (s *StatusFallback) fallbackStatus(code int) bool {
+	if len(s.fallbacks) == 0 {
+		return false
+	}
+	for _, statusCode := range s.statusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package middlewares
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vulcand/oxy/cbreaker"
+)
+
+func statusHandler(code int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+		w.Write([]byte("primary"))
+	})
+}
+
+// fallbackHandler answers with the host of the fallback server and the request body
+func fallbackHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Fallback", r.URL.Host)
+		w.Write(body)
+	})
+}
+
+func fallbackURLs(hosts ...string) []*url.URL {
+	var urls []*url.URL
+	for _, host := range hosts {
+		urls = append(urls, &url.URL{Scheme: "http", Host: host})
+	}
+	return urls
+}
+
+func TestStatusFallback(t *testing.T) {
+	tests := []struct {
+		desc         string
+		primary      int
+		expectedCode int
+		expectedBody string
+	}{
+		{desc: "fallback on status code", primary: http.StatusServiceUnavailable, expectedCode: http.StatusOK, expectedBody: "payload"},
+		{desc: "other error kept", primary: http.StatusInternalServerError, expectedCode: http.StatusInternalServerError, expectedBody: "primary"},
+		{desc: "success kept", primary: http.StatusOK, expectedCode: http.StatusOK, expectedBody: "primary"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			handler := NewStatusFallback(statusHandler(test.primary), fallbackHandler(), fallbackURLs("backup:80"), []int{http.StatusServiceUnavailable})
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest("POST", "http://foo.bar/", strings.NewReader("payload")))
+			if recorder.Code != test.expectedCode {
+				t.Errorf("got status %d, want %d", recorder.Code, test.expectedCode)
+			}
+			if recorder.Body.String() != test.expectedBody {
+				t.Errorf("got body %q, want %q", recorder.Body.String(), test.expectedBody)
+			}
+		})
+	}
+}
+
+func TestStatusFallbackRoundRobin(t *testing.T) {
+	handler := NewStatusFallback(statusHandler(http.StatusServiceUnavailable), fallbackHandler(), fallbackURLs("backup1:80", "backup2:80"), []int{http.StatusServiceUnavailable})
+
+	var hosts []string
+	for i := 0; i < 4; i++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "http://foo.bar/", nil))
+		hosts = append(hosts, recorder.Header().Get("X-Fallback"))
+	}
+	if strings.Join(hosts, ",") != "backup1:80,backup2:80,backup1:80,backup2:80" {
+		t.Errorf("got fallback servers %v, want backup1 and backup2 in turn", hosts)
+	}
+}
+
+func TestStatusFallbackCircuitBreaker(t *testing.T) {
+	tripped := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	expression := "ResponseCodeRatio(500, 600, 0, 600) > 0.5"
+
+	handler := NewStatusFallback(statusHandler(http.StatusServiceUnavailable), fallbackHandler(), fallbackURLs("backup:80"), []int{http.StatusServiceUnavailable})
+	circuitBreaker, err := cbreaker.New(handler, expression, cbreaker.Fallback(tripped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		recorder := httptest.NewRecorder()
+		circuitBreaker.ServeHTTP(recorder, httptest.NewRequest("GET", "http://foo.bar/", nil))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("request %d: got status %d, want %d from the fallback server without tripping the circuit breaker", i, recorder.Code, http.StatusOK)
+		}
+	}
+
+	// without fallback, the same responses trip the circuit breaker
+	circuitBreaker, err = cbreaker.New(statusHandler(http.StatusServiceUnavailable), expression, cbreaker.Fallback(tripped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trippedCount int
+	for i := 0; i < 10; i++ {
+		recorder := httptest.NewRecorder()
+		circuitBreaker.ServeHTTP(recorder, httptest.NewRequest("GET", "http://foo.bar/", nil))
+		if recorder.Code == http.StatusTeapot {
+			trippedCount++
+		}
+	}
+	if trippedCount == 0 {
+		t.Error("expected the circuit breaker to trip without fallback")
+	}
+}
@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// The headers set by TLSClientHeaders, always removed from the incoming requests
+const (
+	TLSClientCertHeader          = "X-Forwarded-Tls-Client-Cert"
+	TLSClientCertSubjectHeader   = "X-Forwarded-Tls-Client-Cert-Subject"
+	TLSClientCertIssuerHeader    = "X-Forwarded-Tls-Client-Cert-Issuer"
+	TLSClientCertSerialHeader    = "X-Forwarded-Tls-Client-Cert-Serial"
+	TLSClientCertNotBeforeHeader = "X-Forwarded-Tls-Client-Cert-Not-Before"
+	TLSClientCertNotAfterHeader  = "X-Forwarded-Tls-Client-Cert-Not-After"
+	TLSClientCertSANsHeader      = "X-Forwarded-Tls-Client-Cert-Sans"
+)
+
+// TLSClientHeaders is a middleware forwarding the certificate of the TLS clients to the backend:
+// its details, and/or its PEM encoding without delimiters and line breaks
+type TLSClientHeaders struct {
+	info bool
+	pem  bool
+}
+
+// NewTLSClientHeaders returns a new TLSClientHeaders middleware
+func NewTLSClientHeaders(info bool, pem bool) *TLSClientHeaders {
+	return &TLSClientHeaders{
+		info: info,
+		pem:  pem,
+	}
+}
+
+func (t *TLSClientHeaders) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	for _, header := range []string{TLSClientCertHeader, TLSClientCertSubjectHeader, TLSClientCertIssuerHeader,
+		TLSClientCertSerialHeader, TLSClientCertNotBeforeHeader, TLSClientCertNotAfterHeader, TLSClientCertSANsHeader} {
+		r.Header.Del(header)
+	}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		cert := r.TLS.PeerCertificates[0]
+		if t.pem {
+			r.Header.Set(TLSClientCertHeader, base64.StdEncoding.EncodeToString(cert.Raw))
+		}
+		if t.info {
+			setTLSClientCertInfo(r.Header, cert)
+		}
+	}
+	next.ServeHTTP(rw, r)
+}
+
+// setTLSClientCertInfo sets the headers describing the certificate
+func setTLSClientCertInfo(header http.Header, cert *x509.Certificate) {
+	header.Set(TLSClientCertSubjectHeader, cert.Subject.CommonName)
+	header.Set(TLSClientCertIssuerHeader, cert.Issuer.CommonName)
+	header.Set(TLSClientCertSerialHeader, cert.SerialNumber.String())
+	header.Set(TLSClientCertNotBeforeHeader, cert.NotBefore.UTC().Format(time.RFC3339))
+	header.Set(TLSClientCertNotAfterHeader, cert.NotAfter.UTC().Format(time.RFC3339))
+	sans := append([]string{}, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	if len(sans) > 0 {
+		header.Set(TLSClientCertSANsHeader, strings.Join(sans, ","))
+	}
+}
@@ -0,0 +1,114 @@
+package middlewares
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTLSClientHeaders(t *testing.T) {
+	cert := newTestClientCert(t)
+
+	tests := []struct {
+		desc            string
+		info            bool
+		pem             bool
+		noTLS           bool
+		expectedHeaders map[string]string
+	}{
+		{
+			desc: "info",
+			info: true,
+			expectedHeaders: map[string]string{
+				TLSClientCertHeader:          "",
+				TLSClientCertSubjectHeader:   "client.foo.bar",
+				TLSClientCertIssuerHeader:    "client.foo.bar",
+				TLSClientCertSerialHeader:    "42",
+				TLSClientCertNotBeforeHeader: "2017-01-01T00:00:00Z",
+				TLSClientCertNotAfterHeader:  "2027-01-01T00:00:00Z",
+				TLSClientCertSANsHeader:      "client.foo.bar,admin@foo.bar,10.0.0.1",
+			},
+		},
+		{
+			desc: "pem",
+			pem:  true,
+			expectedHeaders: map[string]string{
+				TLSClientCertHeader:        base64.StdEncoding.EncodeToString(cert.Raw),
+				TLSClientCertSubjectHeader: "",
+			},
+		},
+		{
+			desc:  "without TLS",
+			info:  true,
+			pem:   true,
+			noTLS: true,
+			expectedHeaders: map[string]string{
+				TLSClientCertHeader:        "",
+				TLSClientCertSubjectHeader: "",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "https://foo.bar/", nil)
+			req.Header.Set(TLSClientCertHeader, "forged")
+			req.Header.Set(TLSClientCertSubjectHeader, "forged")
+			if test.noTLS {
+				req.TLS = nil
+			} else {
+				req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+			}
+
+			var seen http.Header
+			next := func(rw http.ResponseWriter, r *http.Request) {
+				seen = r.Header
+			}
+			NewTLSClientHeaders(test.info, test.pem).ServeHTTP(httptest.NewRecorder(), req, next)
+
+			for name, expected := range test.expectedHeaders {
+				if actual := seen.Get(name); actual != expected {
+					t.Errorf("expected header %s %q, got %q", name, expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func newTestClientCert(t *testing.T) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:   big.NewInt(42),
+		Subject:        pkix.Name{CommonName: "client.foo.bar"},
+		NotBefore:      time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:       time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+		DNSNames:       []string{"client.foo.bar"},
+		EmailAddresses: []string{"admin@foo.bar"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
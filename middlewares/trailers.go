@@ -0,0 +1,195 @@
+package middlewares
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/containous/traefik/log"
+	"github.com/vulcand/oxy/utils"
+)
+
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTrailerFlag     = 0x80
+	grpcWebFrameHeaderSize = 5
+	maxTrailerFrameSize    = 64 * 1024
+)
+
+var (
+	_ http.ResponseWriter = &trailerRecorder{}
+	_ http.Hijacker       = &trailerRecorder{}
+	_ http.Flusher        = &trailerRecorder{}
+	_ http.CloseNotifier  = &trailerRecorder{}
+)
+
+// TrailerCondition holds the accepted values of a response trailer
+type TrailerCondition struct {
+	Name   string
+	Values []string
+}
+
+func (c TrailerCondition) matches(value string) bool {
+	for _, accepted := range c.Values {
+		if value == accepted {
+			return true
+		}
+	}
+	return false
+}
+
+type trailerConditionKey struct{}
+
+// TrailerRule is a middleware setting the trailer condition of a frontend on its requests,
+// checked by the TrailerCheck of the backend shared with the other frontends
+type TrailerRule struct {
+	Handler   http.Handler
+	Condition TrailerCondition
+}
+
+func (t *TrailerRule) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	t.Handler.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), trailerConditionKey{}, t.Condition)))
+}
+
+// TrailerCheck is a middleware checking the trailer of the backend responses against the
+// condition set by the TrailerRule of the frontend, if any.
+// It must be wrapped by a circuit breaker: responses whose trailer does not hold one of
+// the accepted values are recorded as internal server errors by the circuit breakers.
+type TrailerCheck struct {
+	next http.Handler
+}
+
+// NewTrailerCheck returns a new TrailerCheck
+func NewTrailerCheck(next http.Handler) *TrailerCheck {
+	return &TrailerCheck{
+		next: next,
+	}
+}
+
+func (t *TrailerCheck) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	condition, ok := r.Context().Value(trailerConditionKey{}).(TrailerCondition)
+	if !ok {
+		t.next.ServeHTTP(rw, r)
+		return
+	}
+	recorder := &trailerRecorder{responseWriter: rw}
+	t.next.ServeHTTP(recorder, r)
+
+	value, found := recorder.trailer(condition.Name)
+	if !found || condition.matches(value) {
+		return
+	}
+	log.Debugf("Response to %s has trailer %s=%s, counting it as an error", r.URL, condition.Name, value)
+	// record the error in all the nested circuit breakers of the backend
+	for proxyWriter, ok := rw.(*utils.ProxyWriter); ok; proxyWriter, ok = proxyWriter.W.(*utils.ProxyWriter) {
+		proxyWriter.Code = http.StatusInternalServerError
+	}
+}
+
+// trailerRecorder forwards the response to the client while looking for trailers.
+// HTTP trailers are read from the headers once the response is complete, gRPC-Web
+// trailers are read from the trailer frame ending the response body.
+type trailerRecorder struct {
+	responseWriter http.ResponseWriter
+	grpcWeb        *bool
+
+	frameHeader  [grpcWebFrameHeaderSize]byte
+	headerLength int
+	remaining    uint32
+	inFrame      bool
+	trailerFrame bool
+	trailers     bytes.Buffer
+}
+
+func (r *trailerRecorder) Header() http.Header {
+	return r.responseWriter.Header()
+}
+
+func (r *trailerRecorder) WriteHeader(code int) {
+	r.responseWriter.WriteHeader(code)
+}
+
+func (r *trailerRecorder) Write(buf []byte) (int, error) {
+	if r.grpcWeb == nil {
+		contentType := r.Header().Get("Content-Type")
+		grpcWeb := strings.HasPrefix(contentType, grpcWebContentType) && !strings.HasPrefix(contentType, grpcWebContentType+"-text")
+		r.grpcWeb = &grpcWeb
+	}
+	if *r.grpcWeb {
+		r.scanFrames(buf)
+	}
+	return r.responseWriter.Write(buf)
+}
+
+// scanFrames follows the gRPC-Web length-prefixed frames and keeps the last trailer frame
+func (r *trailerRecorder) scanFrames(buf []byte) {
+	for len(buf) > 0 {
+		if !r.inFrame {
+			n := copy(r.frameHeader[r.headerLength:], buf)
+			r.headerLength += n
+			buf = buf[n:]
+			if r.headerLength < grpcWebFrameHeaderSize {
+				continue
+			}
+			r.headerLength = 0
+			r.remaining = binary.BigEndian.Uint32(r.frameHeader[1:])
+			r.trailerFrame = r.frameHeader[0]&grpcWebTrailerFlag != 0 && r.remaining <= maxTrailerFrameSize
+			if r.trailerFrame {
+				r.trailers.Reset()
+			}
+			r.inFrame = r.remaining > 0
+			continue
+		}
+		n := len(buf)
+		if uint32(n) > r.remaining {
+			n = int(r.remaining)
+		}
+		if r.trailerFrame {
+			r.trailers.Write(buf[:n])
+		}
+		r.remaining -= uint32(n)
+		r.inFrame = r.remaining > 0
+		buf = buf[n:]
+	}
+}
+
+// trailer returns the value of the given trailer, if the response had one
+func (r *trailerRecorder) trailer(name string) (string, bool) {
+	for _, line := range strings.Split(r.trailers.String(), "\r\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 && strings.EqualFold(strings.TrimSpace(parts[0]), name) {
+			return strings.TrimSpace(parts[1]), true
+		}
+	}
+	// trailers-only responses carry the trailers in the headers
+	header := r.Header()
+	for _, key := range []string{http.TrailerPrefix + name, name} {
+		if values, ok := header[http.CanonicalHeaderKey(key)]; ok && len(values) > 0 {
+			return values[0], true
+		}
+	}
+	return "", false
+}
+
+// Hijack hijacks the connection
+func (r *trailerRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return r.responseWriter.(http.Hijacker).Hijack()
+}
+
+// CloseNotify returns a channel that receives at most a
+// single value (true) when the client connection has gone
+// away.
+func (r *trailerRecorder) CloseNotify() <-chan bool {
+	return r.responseWriter.(http.CloseNotifier).CloseNotify()
+}
+
+// Flush sends any buffered data to the client.
+func (r *trailerRecorder) Flush() {
+	if flusher, ok := r.responseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
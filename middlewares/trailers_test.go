@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vulcand/oxy/utils"
+)
+
+func grpcWebFrame(flag byte, payload string) []byte {
+	frame := make([]byte, grpcWebFrameHeaderSize, grpcWebFrameHeaderSize+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+func TestTrailerCheck(t *testing.T) {
+	tests := []struct {
+		desc         string
+		contentType  string
+		header       http.Header
+		body         []byte
+		expectedCode int
+	}{
+		{
+			desc:         "grpc-web trailer frame with accepted status",
+			contentType:  "application/grpc-web+proto",
+			body:         append(grpcWebFrame(0, "message"), grpcWebFrame(grpcWebTrailerFlag, "grpc-status:0\r\ngrpc-message:\r\n")...),
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc:         "grpc-web trailer frame with failing status",
+			contentType:  "application/grpc-web+proto",
+			body:         append(grpcWebFrame(0, "message"), grpcWebFrame(grpcWebTrailerFlag, "grpc-status: 14\r\ngrpc-message: unavailable\r\n")...),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			desc:         "grpc-web trailers-only response with failing status",
+			contentType:  "application/grpc-web",
+			header:       http.Header{"Grpc-Status": {"5"}},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			desc:         "HTTP trailer with failing status",
+			contentType:  "application/grpc",
+			header:       http.Header{http.TrailerPrefix + "grpc-status": {"2"}},
+			body:         []byte("message"),
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			desc:         "response without trailer",
+			contentType:  "text/plain",
+			body:         []byte("grpc-status:2\r\n"),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+			backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				for name, values := range test.header {
+					w.Header()[name] = values
+				}
+				w.WriteHeader(http.StatusOK)
+				// write byte per byte to split the frames over several writes
+				for _, b := range test.body {
+					w.Write([]byte{b})
+				}
+			})
+			trailerRule := &TrailerRule{
+				Handler:   NewTrailerCheck(backend),
+				Condition: TrailerCondition{Name: "grpc-status", Values: []string{"0"}},
+			}
+
+			recorder := httptest.NewRecorder()
+			// the circuit breaker records the status code of its ProxyWriter
+			proxyWriter := &utils.ProxyWriter{W: recorder}
+			trailerRule.ServeHTTP(proxyWriter, httptest.NewRequest("POST", "http://foo.bar/service/Method", nil))
+
+			if proxyWriter.Code != test.expectedCode {
+				t.Errorf("expected recorded code %d, got %d", test.expectedCode, proxyWriter.Code)
+			}
+			if recorder.Code != http.StatusOK {
+				t.Errorf("expected client to receive %d, got %d", http.StatusOK, recorder.Code)
+			}
+			if !bytes.Equal(recorder.Body.Bytes(), test.body) && len(test.body) > 0 {
+				t.Errorf("expected body to be forwarded untouched")
+			}
+		})
+	}
+}
+
+func TestTrailerCheckWithoutRule(t *testing.T) {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(http.TrailerPrefix+"grpc-status", "2")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// requests of the frontends without Trailers rule are not checked
+	proxyWriter := &utils.ProxyWriter{W: httptest.NewRecorder()}
+	NewTrailerCheck(backend).ServeHTTP(proxyWriter, httptest.NewRequest("POST", "http://foo.bar/service/Method", nil))
+
+	if proxyWriter.Code != http.StatusOK {
+		t.Errorf("expected recorded code %d, got %d", http.StatusOK, proxyWriter.Code)
+	}
+}
@@ -1,6 +1,8 @@
 package docker
 
 import (
+	"time"
+
 	docker "github.com/docker/engine-api/types"
 	"github.com/docker/engine-api/types/container"
 	"github.com/docker/engine-api/types/network"
@@ -33,6 +35,12 @@ func name(name string) func(*docker.ContainerJSON) {
 	}
 }
 
+func withID(id string) func(*docker.ContainerJSON) {
+	return func(c *docker.ContainerJSON) {
+		c.ContainerJSONBase.ID = id
+	}
+}
+
 func networkMode(mode string) func(*docker.ContainerJSON) {
 	return func(c *docker.ContainerJSON) {
 		c.ContainerJSONBase.HostConfig.NetworkMode = container.NetworkMode(mode)
@@ -45,6 +53,12 @@ func labels(labels map[string]string) func(*docker.ContainerJSON) {
 	}
 }
 
+func env(vars ...string) func(*docker.ContainerJSON) {
+	return func(c *docker.ContainerJSON) {
+		c.Config.Env = vars
+	}
+}
+
 func ports(portMap nat.PortMap) func(*docker.ContainerJSON) {
 	return func(c *docker.ContainerJSON) {
 		c.NetworkSettings.NetworkSettingsBase.Ports = portMap
@@ -69,6 +83,12 @@ func ipv4(ip string) func(*network.EndpointSettings) {
 	}
 }
 
+func ipv6(ip string) func(*network.EndpointSettings) {
+	return func(s *network.EndpointSettings) {
+		s.GlobalIPv6Address = ip
+	}
+}
+
 func swarmTask(id string, ops ...func(*swarm.Task)) swarm.Task {
 	task := &swarm.Task{
 		ID: id,
@@ -87,6 +107,12 @@ func taskSlot(slot int) func(*swarm.Task) {
 	}
 }
 
+func taskNode(nodeID string) func(*swarm.Task) {
+	return func(task *swarm.Task) {
+		task.NodeID = nodeID
+	}
+}
+
 func taskStatus(ops ...func(*swarm.TaskStatus)) func(*swarm.Task) {
 	return func(task *swarm.Task) {
 		status := &swarm.TaskStatus{}
@@ -105,6 +131,21 @@ func taskState(state swarm.TaskState) func(*swarm.TaskStatus) {
 	}
 }
 
+func taskTimestamp(timestamp time.Time) func(*swarm.TaskStatus) {
+	return func(status *swarm.TaskStatus) {
+		status.Timestamp = timestamp
+	}
+}
+
+func taskNetworkAttachment(networkID string, addresses ...string) func(*swarm.Task) {
+	return func(task *swarm.Task) {
+		task.NetworksAttachments = append(task.NetworksAttachments, swarm.NetworkAttachment{
+			Network:   swarm.Network{ID: networkID},
+			Addresses: addresses,
+		})
+	}
+}
+
 func swarmService(ops ...func(*swarm.Service)) swarm.Service {
 	service := &swarm.Service{
 		ID: "serviceID",
@@ -128,6 +169,24 @@ func serviceName(name string) func(service *swarm.Service) {
 	}
 }
 
+func serviceImage(image string) func(service *swarm.Service) {
+	return func(service *swarm.Service) {
+		service.Spec.TaskTemplate.ContainerSpec.Image = image
+	}
+}
+
+func serviceEnv(vars ...string) func(service *swarm.Service) {
+	return func(service *swarm.Service) {
+		service.Spec.TaskTemplate.ContainerSpec.Env = vars
+	}
+}
+
+func serviceReplicas(replicas uint64) func(service *swarm.Service) {
+	return func(service *swarm.Service) {
+		service.Spec.Mode.Replicated = &swarm.ReplicatedService{Replicas: &replicas}
+	}
+}
+
 func serviceLabels(labels map[string]string) func(service *swarm.Service) {
 	return func(service *swarm.Service) {
 		service.Spec.Annotations.Labels = labels
@@ -158,6 +217,16 @@ func virtualIP(networkID, addr string) func(*swarm.Endpoint) {
 	}
 }
 
+func publishedPort(targetPort, publishedPort uint32) func(*swarm.Endpoint) {
+	return func(endpoint *swarm.Endpoint) {
+		endpoint.Ports = append(endpoint.Ports, swarm.PortConfig{
+			Protocol:      swarm.PortConfigProtocolTCP,
+			TargetPort:    targetPort,
+			PublishedPort: publishedPort,
+		})
+	}
+}
+
 func withEndpointSpec(ops ...func(*swarm.EndpointSpec)) func(*swarm.Service) {
 	return func(service *swarm.Service) {
 		endpointSpec := &swarm.EndpointSpec{}
@@ -0,0 +1,40 @@
+package docker
+
+import (
+	"sync"
+	"time"
+)
+
+// debouncer coalesces bursts of triggers into a single call of its function,
+// made once no trigger has been received for the given delay.
+type debouncer struct {
+	delay time.Duration
+	fn    func()
+	lock  sync.Mutex
+	timer *time.Timer
+}
+
+func newDebouncer(delay time.Duration, fn func()) *debouncer {
+	return &debouncer{delay: delay, fn: fn}
+}
+
+func (d *debouncer) trigger() {
+	if d.delay <= 0 {
+		d.fn()
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.delay, d.fn)
+}
+
+func (d *debouncer) stop() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+}
@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebouncerCoalescesBursts(t *testing.T) {
+	var reloads int32
+	d := newDebouncer(50*time.Millisecond, func() {
+		atomic.AddInt32(&reloads, 1)
+	})
+
+	for burst := 1; burst <= 2; burst++ {
+		// create, start and network connect events fired in rapid succession
+		for i := 0; i < 3; i++ {
+			d.trigger()
+			time.Sleep(5 * time.Millisecond)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if actual := atomic.LoadInt32(&reloads); actual != int32(burst) {
+			t.Fatalf("expected %d reloads after burst %d, got %d", burst, burst, actual)
+		}
+	}
+}
+
+func TestDebouncerWithoutDelay(t *testing.T) {
+	reloads := 0
+	d := newDebouncer(0, func() {
+		reloads++
+	})
+
+	for i := 0; i < 3; i++ {
+		d.trigger()
+	}
+	if reloads != 3 {
+		t.Fatalf("expected 3 reloads, got %d", reloads)
+	}
+}
+
+func TestDebouncerStop(t *testing.T) {
+	var reloads int32
+	d := newDebouncer(20*time.Millisecond, func() {
+		atomic.AddInt32(&reloads, 1)
+	})
+
+	d.trigger()
+	d.stop()
+	time.Sleep(100 * time.Millisecond)
+	if actual := atomic.LoadInt32(&reloads); actual != 0 {
+		t.Fatalf("expected no reload after stop, got %d", actual)
+	}
+}
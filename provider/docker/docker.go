@@ -1,19 +1,27 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"net"
 	"net/http"
+	"net/url"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
 	"time"
+	"unicode"
 
 	"github.com/BurntSushi/ty/fun"
+	"github.com/Sirupsen/logrus"
 	"github.com/cenk/backoff"
+	"github.com/containous/flaeg"
 	"github.com/containous/traefik/job"
 	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/provider"
@@ -29,7 +37,9 @@ import (
 	swarmtypes "github.com/docker/engine-api/types/swarm"
 	"github.com/docker/go-connections/nat"
 	"github.com/docker/go-connections/sockets"
+	"github.com/ryanuber/go-glob"
 	"github.com/vdemeester/docker-events"
+	"github.com/vulcand/oxy/cbreaker"
 )
 
 const (
@@ -37,19 +47,42 @@ const (
 	SwarmAPIVersion string = "1.24"
 	// SwarmDefaultWatchTime is the duration of the interval when polling docker
 	SwarmDefaultWatchTime = 15 * time.Second
+	// DockerEventsWatchTime is the duration of the interval when polling the containers while watching the events
+	DockerEventsWatchTime = 5 * time.Minute
+	// DockerDefaultEndpointTimeout is the timeout of the connection attempt to each docker endpoint
+	DockerDefaultEndpointTimeout = 5 * time.Second
 )
 
 var _ provider.Provider = (*Provider)(nil)
 
 // Provider holds configurations of the provider.
 type Provider struct {
-	provider.BaseProvider `mapstructure:",squash"`
-	Endpoint              string              `description:"Docker server endpoint. Can be a tcp or a unix socket endpoint"`
-	Domain                string              `description:"Default domain used"`
-	TLS                   *provider.ClientTLS `description:"Enable Docker TLS support"`
-	ExposedByDefault      bool                `description:"Expose containers by default"`
-	UseBindPortIP         bool                `description:"Use the ip address from the bound port, rather than from the inner network"`
-	SwarmMode             bool                `description:"Use Docker on Swarm Mode"`
+	provider.BaseProvider  `mapstructure:",squash"`
+	Endpoint               string              `description:"Docker server endpoint. Can be a tcp or a unix socket endpoint"`
+	Domain                 string              `description:"Default domain used"`
+	Network                string              `description:"Default Docker network used"`
+	TLS                    *types.ClientTLS    `description:"Enable Docker TLS support"`
+	ExposedByDefault       bool                `description:"Expose containers by default"`
+	UseBindPortIP          bool                `description:"Use the ip address from the bound port, rather than from the inner network"`
+	SwarmMode              bool                `description:"Use Docker on Swarm Mode"`
+	EventDebounceMs        int                 `description:"Delay in milliseconds to wait after the last docker event before reloading the configuration"`
+	MaxBodyBuffer          int64               `description:"Maximum size in bytes of the request body buffered by RequestBodyContains rules"`
+	TaskFilters            map[string][]string `description:"Additional filters passed to the Swarm task list requests (e.g. node, desired-state)"`
+	TrustedIPs             []string            `description:"IPs and CIDRs of the trusted proxies skipped by XFF rules in the X-Forwarded-For header"`
+	SwarmTaskWarmupSeconds int                 `description:"Seconds during which Swarm tasks still preparing or starting are kept, to avoid downtime during rollouts"`
+	SwarmPollInterval      flaeg.Duration      `description:"Interval between two listings of the Swarm services"`
+	SwarmRefreshOnEvents   bool                `description:"Reload the configuration on the events of the Swarm task containers, without waiting for the next poll"`
+	LabelPrefix            string              `description:"Prefix of the labels read by the provider instead of traefik, e.g. to run several instances on a host"`
+	LabelConstraints       []string            `description:"Label constraints (e.g. com.example.env==prod, com.example.tier!=internal) every Swarm service must satisfy to be exposed"`
+	SwarmTaskFilter        string              `description:"Go template evaluated on each Swarm task, the tasks for which it renders false being excluded"`
+	CaseInsensitiveLabels  bool                `description:"Match the label keys case-insensitively, e.g. traefik.Port being read as traefik.port"`
+	FrontendNameTemplate   string              `description:"Go template evaluated on each container to name its frontends, in place of their rule"`
+	BackendNameTemplate    string              `description:"Go template evaluated on each container to name its backend when traefik.backend is not set"`
+	EndpointFallbacks      []string            `description:"Docker endpoints tried in order when the connection to the endpoint fails"`
+	EndpointTimeout        flaeg.Duration      `description:"Timeout of the connection attempt to each docker endpoint"`
+	StrictLabelParsing     bool                `description:"Ignore the Swarm services with invalid label values instead of using the default values of the optional labels"`
+	drainer                *taskDrainer
+	dialer                 endpointDialer
 }
 
 // dockerData holds the need data to the Provider p
@@ -59,6 +92,45 @@ type dockerData struct {
 	Labels          map[string]string // List of labels set to container or service
 	NetworkSettings networkSettings
 	Health          string
+	Image           string
+	Env             map[string]string // Environment variables of the container or service
+	Replicas        uint64
+	ContainerID     string // ID of the container, empty for the Swarm services and tasks
+	ServiceID       string // ID of the Swarm service, or of the service of the task
+	TaskID          string
+	PublishedPorts  []swarmtypes.PortConfig
+	RuleIndex       string // Index of the traefik.frontend.rule.<N> label the frontend is built from
+	// NetworkContainerID is the ID of the container whose network stack is shared (network_mode: container:<id>)
+	NetworkContainerID string
+	// SharedTasks is the number of Swarm tasks merged in this one because they share its address
+	SharedTasks int
+	// LowerLabels holds the labels by lowercase key for the case-insensitive lookups of getLabel,
+	// set by withLabelPrefix if the Provider asks for them
+	LowerLabels map[string]string
+	// LabelErrors holds the invalid label values of the Swarm service, or of the service of the task, see parseService
+	LabelErrors []error
+}
+
+// logFields returns the fields identifying the container, or the Swarm service and task, in the logs
+func (d dockerData) logFields() logrus.Fields {
+	fields := logrus.Fields{}
+	if len(d.ServiceID) > 0 {
+		fields["service"] = d.ServiceName
+		fields["serviceID"] = d.ServiceID
+		if len(d.TaskID) > 0 {
+			fields["task"] = d.Name
+			fields["taskID"] = d.TaskID
+		}
+	} else {
+		fields["container"] = d.Name
+		if len(d.ContainerID) > 0 {
+			fields["containerID"] = d.ContainerID
+		}
+	}
+	if backend, ok := d.Labels["traefik.backend"]; ok {
+		fields["backend"] = backend
+	}
+	return fields
 }
 
 // NetworkSettings holds the networks data to the Provider p
@@ -77,7 +149,7 @@ type networkData struct {
 	ID       string
 }
 
-func (p *Provider) createClient() (client.APIClient, error) {
+func (p *Provider) createClient(endpoint string) (client.APIClient, error) {
 	var httpClient *http.Client
 	httpHeaders := map[string]string{
 		"User-Agent": "Traefik " + version.Version,
@@ -90,7 +162,7 @@ func (p *Provider) createClient() (client.APIClient, error) {
 		tr := &http.Transport{
 			TLSClientConfig: config,
 		}
-		proto, addr, _, err := client.ParseHost(p.Endpoint)
+		proto, addr, _, err := client.ParseHost(endpoint)
 		if err != nil {
 			return nil, err
 		}
@@ -108,28 +180,52 @@ func (p *Provider) createClient() (client.APIClient, error) {
 	} else {
 		version = DockerAPIVersion
 	}
-	return client.NewClient(p.Endpoint, version, httpClient, httpHeaders)
+	return client.NewClient(endpoint, version, httpClient, httpHeaders)
 
 }
 
 // Provide allows the docker provider to provide configurations to traefik
 // using the given configuration channel.
 func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *safe.Pool, constraints types.Constraints) error {
+	// an invalid TLS configuration would only fail the connection retries
+	if _, err := p.TLS.CreateTLSConfig(); err != nil {
+		return fmt.Errorf("invalid docker TLS configuration: %v", err)
+	}
+	if _, err := p.parseSwarmTaskFilter(); err != nil {
+		return fmt.Errorf("invalid docker swarm task filter: %v", err)
+	}
+	if _, err := template.New("frontendName").Parse(p.FrontendNameTemplate); err != nil {
+		return fmt.Errorf("invalid docker frontend name template: %v", err)
+	}
+	if _, err := template.New("backendName").Parse(p.BackendNameTemplate); err != nil {
+		return fmt.Errorf("invalid docker backend name template: %v", err)
+	}
 	p.Constraints = append(p.Constraints, constraints...)
+	if len(p.Domain) == 0 {
+		log.Info("No domain defined for the docker provider, using PathPrefix:/<containerName> as default frontend rule")
+	}
+	if p.SwarmMode {
+		p.drainer = newTaskDrainer()
+	}
 	// TODO register this routine in pool, and watch for stop channel
 	safe.Go(func() {
+		startup := true
 		operation := func() error {
 			var err error
 
-			dockerClient, err := p.createClient()
+			dockerClient, endpoint, err := p.connect()
 			if err != nil {
-				log.Errorf("Failed to create a client for docker, error: %s", err)
+				// all the endpoints failing at startup is fatal, reconnections are retried by the backoff
+				if startup && len(p.EndpointFallbacks) > 0 {
+					log.Fatalf("Failed to connect to docker, error: %s", err)
+				}
 				return err
 			}
+			startup = false
 
 			ctx := context.Background()
 			version, err := dockerClient.ServerVersion(ctx)
-			log.Debugf("Provider connection established with docker %s (API %s)", version.Version, version.APIVersion)
+			log.Debugf("Provider connection established with docker %s (API %s) on %s", version.Version, version.APIVersion, endpoint)
 			var dockerDataList []dockerData
 			if p.SwarmMode {
 				dockerDataList, err = p.listServices(ctx, dockerClient)
@@ -145,59 +241,45 @@ func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *s
 				}
 			}
 
-			configuration := p.loadDockerConfig(dockerDataList)
-			configurationChan <- types.ConfigMessage{
-				ProviderName:  "docker",
-				Configuration: configuration,
-			}
+			configurationChan <- p.buildConfigMessage(dockerDataList)
 			if p.Watch {
 				ctx, cancel := context.WithCancel(ctx)
+				defer cancel()
 				if p.SwarmMode {
 					// TODO: This need to be change. Linked to Swarm events docker/docker#23827
-					ticker := time.NewTicker(SwarmDefaultWatchTime)
+					ticker := time.NewTicker(p.getSwarmPollInterval())
+					defer ticker.Stop()
+					refresh := make(chan struct{}, 1)
+					if p.SwarmRefreshOnEvents {
+						p.monitorSwarmEvents(ctx, dockerClient, refresh)
+					}
 					pool.Go(func(stop chan bool) {
-						for {
-							select {
-							case <-ticker.C:
-								services, err := p.listServices(ctx, dockerClient)
-								if err != nil {
-									log.Errorf("Failed to list services for docker, error %s", err)
-									return
-								}
-								configuration := p.loadDockerConfig(services)
-								if configuration != nil {
-									configurationChan <- types.ConfigMessage{
-										ProviderName:  "docker",
-										Configuration: configuration,
-									}
-								}
-
-							case <-stop:
-								ticker.Stop()
-								cancel()
-								return
-							}
+						select {
+						case <-stop:
+							cancel()
+						case <-ctx.Done():
 						}
 					})
-
-				} else {
-					pool.Go(func(stop chan bool) {
-						for {
-							select {
-							case <-stop:
-								cancel()
-								return
-							}
+					for {
+						select {
+						case <-ticker.C:
+						case <-refresh:
+						case <-ctx.Done():
+							return nil
+						}
+						services, err := p.listServices(ctx, dockerClient)
+						if err != nil {
+							log.Errorf("Failed to list services for docker, error %s", err)
+							// the watch is restarted by the backoff
+							return err
+						}
+						configMessage := p.buildConfigMessage(services)
+						if configMessage.Configuration != nil {
+							configurationChan <- configMessage
 						}
-					})
-					f := filters.NewArgs()
-					f.Add("type", "container")
-					options := dockertypes.EventsOptions{
-						Filters: f,
 					}
-					eventHandler := events.NewHandler(events.ByAction)
-					startStopHandle := func(m eventtypes.Message) {
-						log.Debugf("Provider event received %+v", m)
+				} else {
+					reload := newDebouncer(time.Duration(p.EventDebounceMs)*time.Millisecond, func() {
 						containers, err := listContainers(ctx, dockerClient)
 						if err != nil {
 							log.Errorf("Failed to list containers for docker, error %s", err)
@@ -205,21 +287,33 @@ func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *s
 							cancel()
 							return
 						}
-						configuration := p.loadDockerConfig(containers)
-						if configuration != nil {
-							configurationChan <- types.ConfigMessage{
-								ProviderName:  "docker",
-								Configuration: configuration,
+						configMessage := p.buildConfigMessage(containers)
+						if configMessage.Configuration != nil {
+							configurationChan <- configMessage
+						}
+					})
+					// the containers are still polled while watching the events, in case some are missed
+					ticker := time.NewTicker(DockerEventsWatchTime)
+					pool.Go(func(stop chan bool) {
+						for {
+							select {
+							case <-ticker.C:
+								reload.trigger()
+							case <-ctx.Done():
+								// the monitor failed, the watch is restarted by the backoff
+								ticker.Stop()
+								reload.stop()
+								return
+							case <-stop:
+								ticker.Stop()
+								reload.stop()
+								cancel()
+								return
 							}
 						}
-					}
-					eventHandler.Handle("start", startStopHandle)
-					eventHandler.Handle("die", startStopHandle)
-					eventHandler.Handle("health_status: healthy", startStopHandle)
-					eventHandler.Handle("health_status: unhealthy", startStopHandle)
-					eventHandler.Handle("health_status: starting", startStopHandle)
+					})
 
-					errChan := events.MonitorWithHandler(ctx, dockerClient, options, eventHandler)
+					errChan := p.monitorContainerEvents(ctx, dockerClient, reload.trigger)
 					if err := <-errChan; err != nil {
 						return err
 					}
@@ -239,68 +333,217 @@ func (p *Provider) Provide(configurationChan chan<- types.ConfigMessage, pool *s
 	return nil
 }
 
-func (p *Provider) loadDockerConfig(containersInspected []dockerData) *types.Configuration {
-	var DockerFuncMap = template.FuncMap{
-		"getBackend":                  p.getBackend,
-		"getIPAddress":                p.getIPAddress,
-		"getPort":                     p.getPort,
-		"getWeight":                   p.getWeight,
-		"getDomain":                   p.getDomain,
-		"getProtocol":                 p.getProtocol,
-		"getPassHostHeader":           p.getPassHostHeader,
-		"getPriority":                 p.getPriority,
-		"getEntryPoints":              p.getEntryPoints,
-		"getBasicAuth":                p.getBasicAuth,
-		"getFrontendRule":             p.getFrontendRule,
-		"hasCircuitBreakerLabel":      p.hasCircuitBreakerLabel,
-		"getCircuitBreakerExpression": p.getCircuitBreakerExpression,
-		"hasLoadBalancerLabel":        p.hasLoadBalancerLabel,
-		"getLoadBalancerMethod":       p.getLoadBalancerMethod,
-		"hasMaxConnLabels":            p.hasMaxConnLabels,
-		"getMaxConnAmount":            p.getMaxConnAmount,
-		"getMaxConnExtractorFunc":     p.getMaxConnExtractorFunc,
-		"getSticky":                   p.getSticky,
-		"getIsBackendLBSwarm":         p.getIsBackendLBSwarm,
-		"hasServices":                 p.hasServices,
-		"getServiceNames":             p.getServiceNames,
-		"getServicePort":              p.getServicePort,
-		"getServiceWeight":            p.getServiceWeight,
-		"getServiceProtocol":          p.getServiceProtocol,
-		"getServiceEntryPoints":       p.getServiceEntryPoints,
-		"getServiceBasicAuth":         p.getServiceBasicAuth,
-		"getServiceFrontendRule":      p.getServiceFrontendRule,
-		"getServicePassHostHeader":    p.getServicePassHostHeader,
-		"getServicePriority":          p.getServicePriority,
-		"getServiceBackend":           p.getServiceBackend,
-	}
-	// filter containers
-	filteredContainers := fun.Filter(func(container dockerData) bool {
+// monitorContainerEvents calls reload on the start, stop, die, destroy and health status
+// events of the containers, until the context is done or the event stream fails
+func (p *Provider) monitorContainerEvents(ctx context.Context, dockerClient client.SystemAPIClient, reload func()) chan error {
+	f := filters.NewArgs()
+	f.Add("type", "container")
+	options := dockertypes.EventsOptions{
+		Filters: f,
+	}
+	eventHandler := events.NewHandler(events.ByAction)
+	startStopHandle := func(m eventtypes.Message) {
+		log.Debugf("Provider event received %+v", m)
+		reload()
+	}
+	eventHandler.Handle("start", startStopHandle)
+	eventHandler.Handle("stop", startStopHandle)
+	eventHandler.Handle("die", startStopHandle)
+	eventHandler.Handle("destroy", startStopHandle)
+	eventHandler.Handle("health_status: healthy", startStopHandle)
+	eventHandler.Handle("health_status: unhealthy", startStopHandle)
+	eventHandler.Handle("health_status: starting", startStopHandle)
+	return events.MonitorWithHandler(ctx, dockerClient, options, eventHandler)
+}
+
+// getSwarmPollInterval returns the interval between two listings of the Swarm services
+func (p *Provider) getSwarmPollInterval() time.Duration {
+	if p.SwarmPollInterval <= 0 {
+		return SwarmDefaultWatchTime
+	}
+	return time.Duration(p.SwarmPollInterval)
+}
+
+// monitorSwarmEvents requests a listing of the Swarm services on the refresh channel when a
+// task container starts, dies or changes health status on the node of the Docker endpoint,
+// the events being debounced with EventDebounceMs.
+func (p *Provider) monitorSwarmEvents(ctx context.Context, dockerClient client.APIClient, refresh chan<- struct{}) {
+	f := filters.NewArgs()
+	f.Add("type", "container")
+	f.Add("label", "com.docker.swarm.service.id")
+	options := dockertypes.EventsOptions{
+		Filters: f,
+	}
+	eventHandler := events.NewHandler(events.ByAction)
+	reload := newDebouncer(time.Duration(p.EventDebounceMs)*time.Millisecond, func() {
+		select {
+		case refresh <- struct{}{}:
+		default:
+			// a listing is already pending
+		}
+	})
+	taskHandle := func(m eventtypes.Message) {
+		log.Debugf("Provider swarm event received %+v", m)
+		reload.trigger()
+	}
+	eventHandler.Handle("start", taskHandle)
+	eventHandler.Handle("die", taskHandle)
+	eventHandler.Handle("health_status: healthy", taskHandle)
+	eventHandler.Handle("health_status: unhealthy", taskHandle)
+	eventHandler.Handle("health_status: starting", taskHandle)
+
+	errChan := events.MonitorWithHandler(ctx, dockerClient, options, eventHandler)
+	safe.Go(func() {
+		if err := <-errChan; err != nil && ctx.Err() == nil {
+			log.Errorf("Failed to monitor docker swarm events, error %s", err)
+		}
+		reload.stop()
+	})
+}
+
+func (p *Provider) buildConfigMessage(containersInspected []dockerData) types.ConfigMessage {
+	filteredContainers := p.filterContainers(containersInspected)
+	return types.ConfigMessage{
+		ProviderName:  "docker",
+		Configuration: p.loadFilteredDockerConfig(filteredContainers),
+		Errors:        p.getConfigErrors(filteredContainers),
+	}
+}
+
+// filterContainers returns the exposed containers, with their labels prefixed by applyLabelPrefix
+func (p *Provider) filterContainers(containersInspected []dockerData) []dockerData {
+	return fun.Filter(func(container dockerData) bool {
 		return p.containerFilter(container)
-	}, containersInspected).([]dockerData)
+	}, p.applyLabelPrefix(containersInspected)).([]dockerData)
+}
+
+func (p *Provider) loadDockerConfig(containersInspected []dockerData) *types.Configuration {
+	return p.loadFilteredDockerConfig(p.filterContainers(containersInspected))
+}
 
+// loadFilteredDockerConfig builds the configuration of the containers returned by filterContainers
+func (p *Provider) loadFilteredDockerConfig(filteredContainers []dockerData) *types.Configuration {
+	var DockerFuncMap = template.FuncMap{
+		"getBackend":                         p.getBackend,
+		"getIPAddress":                       p.getIPAddress,
+		"getPort":                            p.getPort,
+		"getServerURL":                       p.getServerURL,
+		"getServerURLChain":                  p.getServerURLChain,
+		"getServers":                         p.getServers,
+		"getWeight":                          p.getWeight,
+		"isFallbackServer":                   p.isFallbackServer,
+		"getDomain":                          p.getDomain,
+		"getProtocol":                        p.getProtocol,
+		"getPassHostHeader":                  p.getPassHostHeader,
+		"getPriority":                        p.getPriority,
+		"getEntryPoints":                     p.getEntryPoints,
+		"getBasicAuth":                       p.getBasicAuth,
+		"getDigestAuth":                      p.getDigestAuth,
+		"getForwardAuth":                     p.getForwardAuth,
+		"getWhitelistSourceRange":            p.getWhitelistSourceRange,
+		"getHeaders":                         p.getHeaders,
+		"hasRateLimitLabels":                 p.hasRateLimitLabels,
+		"getRateLimitExtractorFunc":          p.getRateLimitExtractorFunc,
+		"getRateLimits":                      p.getRateLimits,
+		"getFrontendRule":                    p.getFrontendRule,
+		"getForwardCaptures":                 p.getForwardCaptures,
+		"getCaseInsensitive":                 p.getCaseInsensitive,
+		"getPassTLSCert":                     p.getPassTLSCert,
+		"getPassTLSCertPEM":                  p.getPassTLSCertPEM,
+		"getRedirect":                        p.getRedirect,
+		"getSeed":                            p.getSeed,
+		"getRequestIDHeader":                 p.getRequestIDHeader,
+		"hasCircuitBreakerLabel":             p.hasCircuitBreakerLabel,
+		"getCircuitBreakerExpression":        p.getCircuitBreakerExpression,
+		"getCircuitBreakerStatusCodeRanges":  p.getCircuitBreakerStatusCodeRanges,
+		"hasLoadBalancerLabel":               p.hasLoadBalancerLabel,
+		"getLoadBalancerMethod":              p.getLoadBalancerMethod,
+		"hasKeepAliveLabel":                  p.hasKeepAliveLabel,
+		"getDisableKeepAlives":               p.getDisableKeepAlives,
+		"hasDNSRetryLabels":                  p.hasDNSRetryLabels,
+		"getDNSRetryCount":                   p.getDNSRetryCount,
+		"getDNSRetryDelay":                   p.getDNSRetryDelay,
+		"hasDNSResolverLabel":                p.hasDNSResolverLabel,
+		"getDNSResolver":                     p.getDNSResolver,
+		"hasWebsocketTimeoutLabel":           p.hasWebsocketTimeoutLabel,
+		"getWebsocketTimeout":                p.getWebsocketTimeout,
+		"getResponseTimeout":                 p.getResponseTimeout,
+		"hasResponseTimeoutLabels":           p.hasResponseTimeoutLabels,
+		"getReadHeaderTimeout":               p.getReadHeaderTimeout,
+		"getResponseBodyTimeout":             p.getResponseBodyTimeout,
+		"hasMultiplexH2Label":                p.hasMultiplexH2Label,
+		"getMaxConcurrentStreams":            p.getMaxConcurrentStreams,
+		"hasFallbackStatusCodesLabel":        p.hasFallbackStatusCodesLabel,
+		"getFallbackStatusCodes":             p.getFallbackStatusCodes,
+		"hasMaxConnLabels":                   p.hasMaxConnLabels,
+		"getMaxConnAmount":                   p.getMaxConnAmount,
+		"getMaxConnExtractorFunc":            p.getMaxConnExtractorFunc,
+		"hasBufferingLabels":                 p.hasBufferingLabels,
+		"getMaxRequestBodyBytes":             p.getMaxRequestBodyBytes,
+		"getMemRequestBodyBytes":             p.getMemRequestBodyBytes,
+		"getMaxResponseBodyBytes":            p.getMaxResponseBodyBytes,
+		"getMemResponseBodyBytes":            p.getMemResponseBodyBytes,
+		"getBufferingRetryExpression":        p.getBufferingRetryExpression,
+		"getResponseForwardingFlushInterval": p.getResponseForwardingFlushInterval,
+		"getBackendTLS":                      p.getBackendTLS,
+		"hasHealthCheckLabels":               p.hasHealthCheckLabels,
+		"getHealthCheckPath":                 p.getHealthCheckPath,
+		"getHealthCheckInterval":             p.getHealthCheckInterval,
+		"getHealthCheckFailureAction":        p.getHealthCheckFailureAction,
+		"getSticky":                          p.getSticky,
+		"getIsBackendLBSwarm":                p.getIsBackendLBSwarm,
+		"hasServices":                        p.hasServices,
+		"getServiceNames":                    p.getServiceNames,
+		"getServicePort":                     p.getServicePort,
+		"getServiceURL":                      p.getServiceURL,
+		"getServiceWeight":                   p.getServiceWeight,
+		"getServiceProtocol":                 p.getServiceProtocol,
+		"getServiceEntryPoints":              p.getServiceEntryPoints,
+		"getServiceBasicAuth":                p.getServiceBasicAuth,
+		"getServiceFrontendRule":             p.getServiceFrontendRule,
+		"getServicePassHostHeader":           p.getServicePassHostHeader,
+		"getServicePriority":                 p.getServicePriority,
+		"getServiceBackend":                  p.getServiceBackend,
+	}
 	frontends := map[string][]dockerData{}
+	frontendBackends := map[string]string{}
 	backends := map[string]dockerData{}
 	servers := map[string][]dockerData{}
-	for _, container := range filteredContainers {
-		frontendName := p.getFrontendName(container)
-		frontends[frontendName] = append(frontends[frontendName], container)
-		backendName := p.getBackend(container)
-		backends[backendName] = container
-		servers[backendName] = append(servers[backendName], container)
+	for _, filteredContainer := range filteredContainers {
+		for _, container := range p.getBackendContainers(filteredContainer) {
+			for _, frontend := range p.getRuleFrontends(container) {
+				frontendName := uniqueFrontendName(p.getFrontendName(frontend), p.getBackend(frontend), frontendBackends)
+				frontends[frontendName] = append(frontends[frontendName], frontend)
+			}
+			backendName := p.getBackend(container)
+			backends[backendName] = container
+			servers[backendName] = append(servers[backendName], container)
+		}
+	}
+	stickiness := map[string]backendStickiness{}
+	for backendName, containers := range servers {
+		stickiness[backendName] = p.getBackendStickiness(backendName, containers)
+		servers[backendName] = p.deduplicateServers(containers)
 	}
 
 	templateObjects := struct {
-		Containers []dockerData
-		Frontends  map[string][]dockerData
-		Backends   map[string]dockerData
-		Servers    map[string][]dockerData
-		Domain     string
+		Containers    []dockerData
+		Frontends     map[string][]dockerData
+		Backends      map[string]dockerData
+		Servers       map[string][]dockerData
+		Domain        string
+		MaxBodyBuffer int64
+		TrustedIPs    []string
+		Stickiness    map[string]backendStickiness
 	}{
 		filteredContainers,
 		frontends,
 		backends,
 		servers,
 		p.Domain,
+		p.MaxBodyBuffer,
+		p.TrustedIPs,
+		stickiness,
 	}
 
 	configuration, err := p.GetConfiguration("templates/docker.tmpl", DockerFuncMap, templateObjects)
@@ -310,13 +553,212 @@ func (p *Provider) loadDockerConfig(containersInspected []dockerData) *types.Con
 	return configuration
 }
 
+// applyLabelPrefix returns the containers with the labels of the provider LabelPrefix, see withLabelPrefix
+func (p *Provider) applyLabelPrefix(containers []dockerData) []dockerData {
+	prefixed := make([]dockerData, 0, len(containers))
+	for _, container := range containers {
+		prefixed = append(prefixed, p.withLabelPrefix(container))
+	}
+	return prefixed
+}
+
+// withLabelPrefix returns the container with its <LabelPrefix>.* labels renamed to traefik.*
+// and its own traefik.* labels dropped, so that all the label lookups use the LabelPrefix.
+// It also enables the case-insensitive label lookups of the container if the provider asks for them,
+// and drops the optional labels with invalid values unless StrictLabelParsing is set, their default
+// values being used instead.
+func (p *Provider) withLabelPrefix(container dockerData) dockerData {
+	if p.CaseInsensitiveLabels {
+		container.LowerLabels = lowerLabels(container.Labels)
+	}
+	invalidLabels := map[string]bool{}
+	if !p.StrictLabelParsing {
+		for _, labelErr := range p.getLabelErrors(container) {
+			if !labelErr.critical {
+				invalidLabels[labelErr.label] = true
+			}
+		}
+	}
+	hasPrefix := len(p.LabelPrefix) > 0 && p.LabelPrefix != "traefik"
+	if !hasPrefix && len(invalidLabels) == 0 {
+		return container
+	}
+	labels := make(map[string]string, len(container.Labels))
+	for key, value := range container.Labels {
+		switch {
+		case invalidLabels[key]:
+			continue
+		case !hasPrefix:
+			labels[key] = value
+		case strings.HasPrefix(key, p.LabelPrefix+"."):
+			labels["traefik."+strings.TrimPrefix(key, p.LabelPrefix+".")] = value
+		case strings.HasPrefix(key, "traefik."):
+			continue
+		default:
+			labels[key] = value
+		}
+	}
+	container.Labels = labels
+	if p.CaseInsensitiveLabels {
+		container.LowerLabels = lowerLabels(labels)
+	}
+	return container
+}
+
+// lowerLabels returns the labels by lowercase key
+func lowerLabels(labels map[string]string) map[string]string {
+	lower := make(map[string]string, len(labels))
+	for key, value := range labels {
+		lower[strings.ToLower(key)] = value
+	}
+	return lower
+}
+
+// labelsView returns the labels of the container to iterate on, by lowercase key when its label
+// lookups are case-insensitive, the keys searched in them being given by labelKey
+func labelsView(container dockerData) map[string]string {
+	if container.LowerLabels != nil {
+		return container.LowerLabels
+	}
+	return container.Labels
+}
+
+// labelKey returns the key, or label key prefix, to search in the labelsView of the container
+func labelKey(container dockerData, key string) string {
+	if container.LowerLabels != nil {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+// withLabel returns a copy of the container with the label set to the value, replacing the labels
+// with the same key in another case when its label lookups are case-insensitive
+func withLabel(container dockerData, key string, value string) dockerData {
+	labels := make(map[string]string, len(container.Labels)+1)
+	for k, v := range container.Labels {
+		if container.LowerLabels != nil && strings.EqualFold(k, key) {
+			continue
+		}
+		labels[k] = v
+	}
+	labels[key] = value
+	container.Labels = labels
+	if container.LowerLabels != nil {
+		container.LowerLabels = lowerLabels(labels)
+	}
+	return container
+}
+
+// getConfigErrors reports the containers whose backend server URL cannot be built properly,
+// among the containers returned by filterContainers
+func (p *Provider) getConfigErrors(filteredContainers []dockerData) []types.ConfigError {
+	var configErrors []types.ConfigError
+	addError := func(container dockerData, format string, args ...interface{}) {
+		configErrors = append(configErrors, types.ConfigError{
+			Source:  container.Name,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	for _, container := range filteredContainers {
+		if label, err := getLabel(container, "traefik.docker.network"); err == nil && label != "" {
+			if getLabeledNetwork(container, label) == nil {
+				addError(container, "network %s set by traefik.docker.network not found", label)
+			}
+		}
+		if _, err := parseServerURLChain(container); err != nil {
+			addError(container, "invalid traefik.backend.server.urlChain: %v", err)
+		}
+		if _, ok := getCustomServerURL(container); ok {
+			continue
+		}
+		if !p.isBackendLBSwarm(container) {
+			ip := p.getIPAddress(container)
+			if len(ip) == 0 {
+				addError(container, "no IP address found")
+			} else if net.ParseIP(ip) == nil {
+				addError(container, "invalid IP address %s", ip)
+			}
+		}
+		if p.hasServices(container) {
+			continue
+		}
+		port := p.getPort(container)
+		if len(port) == 0 {
+			addError(container, "no port found")
+		} else if portNumber, err := strconv.Atoi(port); err != nil || portNumber <= 0 || portNumber > 65535 {
+			addError(container, "invalid port %s", port)
+		}
+	}
+	return configErrors
+}
+
+// deduplicateServers keeps a single container per server URL, preferring the one with the highest weight.
+// Containers defining services are kept as-is since each service has its own backend.
+func (p *Provider) deduplicateServers(containers []dockerData) []dockerData {
+	var deduplicated []dockerData
+	serverIndexes := map[string]int{}
+	for _, container := range containers {
+		if p.hasServices(container) {
+			deduplicated = append(deduplicated, container)
+			continue
+		}
+		url := p.getServerURL(container)
+		index, ok := serverIndexes[url]
+		if !ok {
+			serverIndexes[url] = len(deduplicated)
+			deduplicated = append(deduplicated, container)
+			continue
+		}
+		log.Debugf("Container %s has the same server URL %s as container %s", container.Name, url, deduplicated[index].Name)
+		if weightOf(p.getWeight(container)) > weightOf(p.getWeight(deduplicated[index])) {
+			deduplicated[index] = container
+		}
+	}
+	return deduplicated
+}
+
+func weightOf(weight string) int {
+	i, err := strconv.Atoi(weight)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+// hasCircuitBreakerLabel returns whether the backend has a circuit breaker, which is
+// omitted when its expression cannot be compiled
 func (p *Provider) hasCircuitBreakerLabel(container dockerData) bool {
-	if _, err := getLabel(container, "traefik.backend.circuitbreaker.expression"); err != nil {
+	_, errExpression := getLabel(container, "traefik.backend.circuitbreaker.expression")
+	_, errResponseCode := getLabel(container, "traefik.backend.circuitbreaker.responseCode")
+	if errExpression != nil && errResponseCode != nil {
+		return false
+	}
+	if err := p.checkCircuitBreakerExpression(container); err != nil {
+		log.WithFields(container.logFields()).Errorf("Ignoring the circuit breaker of container %s with invalid expression: %v", container.Name, err)
 		return false
 	}
 	return true
 }
 
+// checkCircuitBreakerExpression compiles the circuit breaker expression of the container
+// the same way as the server does when creating the circuit breaker of the backend
+func (p *Provider) checkCircuitBreakerExpression(container dockerData) error {
+	circuitBreaker := &types.CircuitBreaker{
+		Expression:       p.getCircuitBreakerExpression(container),
+		StatusCodeRanges: p.getCircuitBreakerStatusCodeRanges(container),
+	}
+	expressions, err := circuitBreaker.BuildExpressions()
+	if err != nil {
+		return err
+	}
+	for _, expression := range expressions {
+		if _, err := cbreaker.New(http.NotFoundHandler(), expression.Expression); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Regexp used to extract the name of the service and the name of the property for this service
 // All properties are under the format traefik.<servicename>.frontent.*= except the port/weight/protocol directly after traefik.<servicename>.
 var servicesPropertiesRegexp = regexp.MustCompile(`^traefik\.(?P<service_name>.*?)\.(?P<property_name>port|weight|protocol|frontend\.(.*))$`)
@@ -327,7 +769,7 @@ type labelServiceProperties map[string]map[string]string
 
 // Check if for the given container, we find labels that are defining services
 func (p *Provider) hasServices(container dockerData) bool {
-	return len(extractServicesLabels(container.Labels)) > 0
+	return len(extractServicesLabels(labelsView(container))) > 0
 }
 
 // Extract the service labels from container labels of dockerData struct
@@ -356,13 +798,13 @@ func extractServicesLabels(labels map[string]string) labelServiceProperties {
 
 // Gets the entry for a service label searching in all labels of the given container
 func getContainerServiceLabel(container dockerData, serviceName string, entry string) (string, bool) {
-	value, ok := extractServicesLabels(container.Labels)[serviceName][entry]
+	value, ok := extractServicesLabels(labelsView(container))[serviceName][labelKey(container, entry)]
 	return value, ok
 }
 
 // Gets array of service names for a given container
 func (p *Provider) getServiceNames(container dockerData) []string {
-	labelServiceProperties := extractServicesLabels(container.Labels)
+	labelServiceProperties := extractServicesLabels(labelsView(container))
 	keys := make([]string, 0, len(labelServiceProperties))
 	for k := range labelServiceProperties {
 		keys = append(keys, k)
@@ -373,7 +815,7 @@ func (p *Provider) getServiceNames(container dockerData) []string {
 // Extract entrypoints from labels for a given service and a given docker container
 func (p *Provider) getServiceEntryPoints(container dockerData, serviceName string) []string {
 	if entryPoints, ok := getContainerServiceLabel(container, serviceName, "frontend.entryPoints"); ok {
-		return strings.Split(entryPoints, ",")
+		return splitEntryPoints(entryPoints)
 	}
 	return p.getEntryPoints(container)
 
@@ -398,7 +840,7 @@ func (p *Provider) getServicePassHostHeader(container dockerData, serviceName st
 
 // Extract priority from labels for a given service and a given docker container
 func (p *Provider) getServicePriority(container dockerData, serviceName string) string {
-	if value, ok := getContainerServiceLabel(container, serviceName, "frontend.priority"); ok {
+	if value, ok := getContainerServiceLabel(container, serviceName, "frontend.priority"); ok && isValidPriority(value, container.Name) {
 		return value
 	}
 	return p.getPriority(container)
@@ -410,7 +852,7 @@ func (p *Provider) getServiceBackend(container dockerData, serviceName string) s
 	if value, ok := getContainerServiceLabel(container, serviceName, "frontend.backend"); ok {
 		return value
 	}
-	return p.getBackend(container) + "-" + provider.Normalize(serviceName)
+	return p.getBackend(container) + "-" + normalizeBackendName(serviceName)
 }
 
 // Extract rule from labels for a given service and a given docker container
@@ -418,18 +860,29 @@ func (p *Provider) getServiceFrontendRule(container dockerData, serviceName stri
 	if value, ok := getContainerServiceLabel(container, serviceName, "frontend.rule"); ok {
 		return value
 	}
-	return p.getFrontendRule(container)
+	rule, _ := p.getFrontendRule(container)
+	return rule
 
 }
 
 // Extract port from labels for a given service and a given docker container
 func (p *Provider) getServicePort(container dockerData, serviceName string) string {
 	if value, ok := getContainerServiceLabel(container, serviceName, "port"); ok {
+		if p.UseBindPortIP {
+			if binding := getPortBinding(container, value); binding != nil {
+				return binding.HostPort
+			}
+		}
 		return value
 	}
 	return p.getPort(container)
 }
 
+// Build the server URL for a given service and a given docker container
+func (p *Provider) getServiceURL(container dockerData, serviceName string) string {
+	return p.getServiceProtocol(container, serviceName) + "://" + net.JoinHostPort(p.getIPAddress(container), p.getServicePort(container, serviceName))
+}
+
 // Extract weight from labels for a given service and a given docker container
 func (p *Provider) getServiceWeight(container dockerData, serviceName string) string {
 	if value, ok := getContainerServiceLabel(container, serviceName, "weight"); ok {
@@ -455,167 +908,1141 @@ func (p *Provider) hasLoadBalancerLabel(container dockerData) bool {
 	return true
 }
 
-func (p *Provider) hasMaxConnLabels(container dockerData) bool {
-	if _, err := getLabel(container, "traefik.backend.maxconn.amount"); err != nil {
+func (p *Provider) hasKeepAliveLabel(container dockerData) bool {
+	if _, err := getLabel(container, "traefik.backend.server.keepalive"); err != nil {
 		return false
 	}
-	if _, err := getLabel(container, "traefik.backend.maxconn.extractorfunc"); err != nil {
+	return true
+}
+
+func (p *Provider) hasDNSRetryLabels(container dockerData) bool {
+	if _, err := getLabel(container, "traefik.backend.server.dnsRetryCount"); err != nil {
 		return false
 	}
 	return true
 }
 
-func (p *Provider) getCircuitBreakerExpression(container dockerData) string {
-	if label, err := getLabel(container, "traefik.backend.circuitbreaker.expression"); err == nil {
-		return label
+func (p *Provider) hasDNSResolverLabel(container dockerData) bool {
+	if _, err := getLabel(container, "traefik.backend.server.dnsResolver"); err != nil {
+		return false
 	}
-	return "NetworkErrorRatio() > 1"
+	return true
 }
 
-func (p *Provider) getLoadBalancerMethod(container dockerData) string {
-	if label, err := getLabel(container, "traefik.backend.loadbalancer.method"); err == nil {
-		return label
+func (p *Provider) hasWebsocketTimeoutLabel(container dockerData) bool {
+	if _, err := getLabel(container, "traefik.backend.server.websocketTimeout"); err != nil {
+		return false
 	}
-	return "wrr"
+	return true
 }
 
-func (p *Provider) getMaxConnAmount(container dockerData) int64 {
-	if label, err := getLabel(container, "traefik.backend.maxconn.amount"); err == nil {
-		i, errConv := strconv.ParseInt(label, 10, 64)
-		if errConv != nil {
-			log.Errorf("Unable to parse traefik.backend.maxconn.amount %s", label)
-			return math.MaxInt64
-		}
-		return i
+func (p *Provider) hasResponseTimeoutLabels(container dockerData) bool {
+	if _, err := getLabel(container, "traefik.backend.readHeaderTimeout"); err == nil {
+		return true
 	}
-	return math.MaxInt64
+	if _, err := getLabel(container, "traefik.backend.responseBodyTimeout"); err == nil {
+		return true
+	}
+	return false
 }
 
-func (p *Provider) getMaxConnExtractorFunc(container dockerData) string {
-	if label, err := getLabel(container, "traefik.backend.maxconn.extractorfunc"); err == nil {
-		return label
-	}
-	return "request.host"
+func (p *Provider) hasMultiplexH2Label(container dockerData) bool {
+	label, err := getLabel(container, "traefik.backend.server.multiplexH2")
+	return err == nil && label == "true"
 }
 
-func (p *Provider) containerFilter(container dockerData) bool {
-	_, err := strconv.Atoi(container.Labels["traefik.port"])
-	if len(container.NetworkSettings.Ports) == 0 && err != nil {
-		log.Debugf("Filtering container without port and no traefik.port label %s", container.Name)
+func (p *Provider) hasFallbackStatusCodesLabel(container dockerData) bool {
+	return len(p.getFallbackStatusCodes(container)) > 0
+}
+
+func (p *Provider) hasMaxConnLabels(container dockerData) bool {
+	label, err := getLabel(container, "traefik.backend.maxconn.amount")
+	if err != nil {
 		return false
 	}
-
-	if !isContainerEnabled(container, p.ExposedByDefault) {
-		log.Debugf("Filtering disabled container %s", container.Name)
+	if _, err := getLabel(container, "traefik.backend.maxconn.extractorfunc"); err != nil && !isIPHashLoadBalancer(container) {
 		return false
 	}
-
-	constraintTags := strings.Split(container.Labels["traefik.tags"], ",")
-	if ok, failingConstraint := p.MatchConstraints(constraintTags); !ok {
-		if failingConstraint != nil {
-			log.Debugf("Container %v pruned by '%v' constraint", container.Name, failingConstraint.String())
-		}
+	if amount, errConv := strconv.ParseInt(label, 10, 64); errConv != nil || amount <= 0 {
+		log.Errorf("Invalid traefik.backend.maxconn.amount %s for container %s: must be a positive integer, ignoring the maxconn labels", label, container.Name)
 		return false
 	}
+	return true
+}
 
-	if container.Health != "" && container.Health != "healthy" {
-		log.Debugf("Filtering unhealthy or starting container %s", container.Name)
-		return false
+func (p *Provider) hasBufferingLabels(container dockerData) bool {
+	prefix := labelKey(container, "traefik.backend.buffering.")
+	for label := range labelsView(container) {
+		if strings.HasPrefix(label, prefix) {
+			return true
+		}
 	}
+	return false
+}
 
-	return true
+func (p *Provider) getMaxRequestBodyBytes(container dockerData) int64 {
+	return getBufferingBytes(container, "traefik.backend.buffering.maxRequestBodyBytes")
 }
 
-func (p *Provider) getFrontendName(container dockerData) string {
-	// Replace '.' with '-' in quoted keys because of this issue https://github.com/BurntSushi/toml/issues/78
-	return provider.Normalize(p.getFrontendRule(container))
+func (p *Provider) getMemRequestBodyBytes(container dockerData) int64 {
+	return getBufferingBytes(container, "traefik.backend.buffering.memRequestBodyBytes")
 }
 
-// GetFrontendRule returns the frontend rule for the specified container, using
-// it's label. It returns a default one (Host) if the label is not present.
-func (p *Provider) getFrontendRule(container dockerData) string {
-	if label, err := getLabel(container, "traefik.frontend.rule"); err == nil {
+func (p *Provider) getMaxResponseBodyBytes(container dockerData) int64 {
+	return getBufferingBytes(container, "traefik.backend.buffering.maxResponseBodyBytes")
+}
+
+func (p *Provider) getMemResponseBodyBytes(container dockerData) int64 {
+	return getBufferingBytes(container, "traefik.backend.buffering.memResponseBodyBytes")
+}
+
+func (p *Provider) getBufferingRetryExpression(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.buffering.retryExpression"); err == nil {
 		return label
 	}
-	if labels, err := getLabels(container, []string{"com.docker.compose.project", "com.docker.compose.service"}); err == nil {
-		return "Host:" + p.getSubDomain(labels["com.docker.compose.service"]+"."+labels["com.docker.compose.project"]) + "." + p.Domain
-	}
-
-	return "Host:" + p.getSubDomain(container.ServiceName) + "." + p.Domain
+	return ""
 }
 
-func (p *Provider) getBackend(container dockerData) string {
-	if label, err := getLabel(container, "traefik.backend"); err == nil {
-		return provider.Normalize(label)
+// getResponseForwardingFlushInterval returns the flush interval of the backend responses, or an
+// empty string if the label is missing or is no valid duration
+func (p *Provider) getResponseForwardingFlushInterval(container dockerData) string {
+	label, err := getLabel(container, "traefik.backend.responseForwarding.flushInterval")
+	if err != nil {
+		return ""
 	}
-	if labels, err := getLabels(container, []string{"com.docker.compose.project", "com.docker.compose.service"}); err == nil {
-		return provider.Normalize(labels["com.docker.compose.service"] + "_" + labels["com.docker.compose.project"])
+	if _, errParse := time.ParseDuration(label); errParse != nil {
+		log.Errorf("Unable to parse traefik.backend.responseForwarding.flushInterval %s for container %s: %s", label, container.Name, errParse)
+		return ""
 	}
-	return provider.Normalize(container.ServiceName)
+	return label
 }
 
-func (p *Provider) getIPAddress(container dockerData) string {
-	if label, err := getLabel(container, "traefik.docker.network"); err == nil && label != "" {
-		networkSettings := container.NetworkSettings
-		if networkSettings.Networks != nil {
-			network := networkSettings.Networks[label]
-			if network != nil {
-				return network.Addr
-			}
-
-			log.Warnf("Could not find network named '%s' for container '%s'! Maybe you're missing the project's prefix in the label? Defaulting to first available network.", label, container.Name)
-		}
+// getBufferingBytes returns the size of the given buffering label, 0 standing for the default
+func getBufferingBytes(container dockerData, labelName string) int64 {
+	label, err := getLabel(container, labelName)
+	if err != nil {
+		return 0
 	}
-
-	// If net==host, quick n' dirty, we return 127.0.0.1
-	// This will work locally, but will fail with swarm.
-	if "host" == container.NetworkSettings.NetworkMode {
-		return "127.0.0.1"
+	i, errConv := strconv.ParseInt(label, 10, 64)
+	if errConv != nil || i < 0 {
+		log.Errorf("Unable to parse %s %s of container %s", labelName, label, container.Name)
+		return 0
 	}
+	return i
+}
 
-	if p.UseBindPortIP {
-		port := p.getPort(container)
-		for netport, portBindings := range container.NetworkSettings.Ports {
-			if string(netport) == port+"/TCP" || string(netport) == port+"/UDP" {
-				for _, p := range portBindings {
-					return p.HostIP
-				}
-			}
-		}
+func (p *Provider) hasHealthCheckLabels(container dockerData) bool {
+	if _, err := getLabel(container, "traefik.backend.healthcheck.path"); err != nil {
+		return false
 	}
+	return true
+}
 
-	for _, network := range container.NetworkSettings.Networks {
-		return network.Addr
+func (p *Provider) getHealthCheckPath(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.healthcheck.path"); err == nil {
+		return label
 	}
 	return ""
 }
 
-func (p *Provider) getPort(container dockerData) string {
-	if label, err := getLabel(container, "traefik.port"); err == nil {
+func (p *Provider) getHealthCheckInterval(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.healthcheck.interval"); err == nil {
 		return label
 	}
+	return ""
+}
 
-	// See iteration order in https://blog.golang.org/go-maps-in-action
-	var ports []nat.Port
-	for p := range container.NetworkSettings.Ports {
-		ports = append(ports, p)
+func (p *Provider) getHealthCheckFailureAction(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.healthcheck.failureAction"); err == nil {
+		return label
 	}
+	return ""
+}
 
+func (p *Provider) getCircuitBreakerExpression(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.circuitbreaker.expression"); err == nil {
+		if _, errCode := getLabel(container, "traefik.backend.circuitbreaker.responseCode"); errCode == nil {
+			log.Warnf("Both traefik.backend.circuitbreaker.expression and traefik.backend.circuitbreaker.responseCode are set on container %s, using the expression", container.Name)
+		}
+		return label
+	}
+	if label, err := getLabel(container, "traefik.backend.circuitbreaker.responseCode"); err == nil {
+		expression, errParse := responseCodeExpression(label)
+		if errParse != nil {
+			log.Errorf("Unable to parse traefik.backend.circuitbreaker.responseCode %s for container %s: %s", label, container.Name, errParse)
+		} else {
+			return expression
+		}
+	}
+	return "NetworkErrorRatio() > 1"
+}
+
+// responseCodeExpression translates a list of status codes and status code ranges (e.g. 500,502-504)
+// into a circuit breaker expression tripping when the ratio of responses with one of these codes exceeds 0.5
+func responseCodeExpression(codes string) (string, error) {
+	ranges, err := types.ParseStatusCodeRanges(codes)
+	if err != nil {
+		return "", err
+	}
+	var ratios []string
+	for _, codeRange := range ranges {
+		ratios = append(ratios, fmt.Sprintf("ResponseCodeRatio(%d, %d, 0, 600) > 0.5", codeRange.Min, codeRange.Max+1))
+	}
+	return strings.Join(ratios, " || "), nil
+}
+
+func (p *Provider) getCircuitBreakerStatusCodeRanges(container dockerData) []types.StatusCodeRange {
+	if label, err := getLabel(container, "traefik.backend.circuitbreaker.statusCodeRanges"); err == nil {
+		ranges, errParse := types.ParseStatusCodeRanges(label)
+		if errParse != nil {
+			log.Errorf("Unable to parse traefik.backend.circuitbreaker.statusCodeRanges %s: %s", label, errParse)
+			return nil
+		}
+		return ranges
+	}
+	return nil
+}
+
+func (p *Provider) getLoadBalancerMethod(container dockerData) string {
+	if isIPHashLoadBalancer(container) {
+		return "wrr"
+	}
+	if label, err := getLabel(container, "traefik.backend.loadbalancer.method"); err == nil {
+		if _, errMethod := types.NewLoadBalancerMethod(&types.LoadBalancer{Method: label}); errMethod != nil {
+			log.Warnf("Unknown traefik.backend.loadbalancer.method %s for container %s, expected wrr, drr or ip_hash", label, container.Name)
+		}
+		return label
+	}
+	return "wrr"
+}
+
+// isIPHashLoadBalancer returns true for the ip_hash load balancer method, a shorthand for the wrr
+// method along with the client.ip extractor function of the maxconn labels
+func isIPHashLoadBalancer(container dockerData) bool {
+	label, err := getLabel(container, "traefik.backend.loadbalancer.method")
+	return err == nil && label == "ip_hash"
+}
+
+func (p *Provider) getDisableKeepAlives(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.server.keepalive"); err == nil {
+		keepAlive, errConv := strconv.ParseBool(label)
+		if errConv != nil {
+			log.Errorf("Unable to parse traefik.backend.server.keepalive %s", label)
+			return "false"
+		}
+		return strconv.FormatBool(!keepAlive)
+	}
+	return "false"
+}
+
+func (p *Provider) getDNSRetryCount(container dockerData) int {
+	if label, err := getLabel(container, "traefik.backend.server.dnsRetryCount"); err == nil {
+		i, errConv := strconv.Atoi(label)
+		if errConv != nil {
+			log.Errorf("Unable to parse traefik.backend.server.dnsRetryCount %s", label)
+			return 0
+		}
+		return i
+	}
+	return 0
+}
+
+func (p *Provider) getDNSRetryDelay(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.server.dnsRetryDelay"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (p *Provider) getDNSResolver(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.server.dnsResolver"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (p *Provider) getWebsocketTimeout(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.server.websocketTimeout"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (p *Provider) getResponseTimeout(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.server.responseTimeout"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (p *Provider) getReadHeaderTimeout(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.readHeaderTimeout"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (p *Provider) getResponseBodyTimeout(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.responseBodyTimeout"); err == nil {
+		return label
+	}
+	return ""
+}
+
+func (p *Provider) getMaxConcurrentStreams(container dockerData) int64 {
+	if label, err := getLabel(container, "traefik.backend.server.maxConcurrentStreams"); err == nil {
+		i, errConv := strconv.ParseUint(label, 10, 32)
+		if errConv != nil {
+			log.Errorf("Unable to parse traefik.backend.server.maxConcurrentStreams %s", label)
+			return 0
+		}
+		return int64(i)
+	}
+	return 0
+}
+
+// getFallbackStatusCodes returns the status codes of the traefik.backend.fallback.statusCodes label,
+// a comma separated list
+func (p *Provider) getFallbackStatusCodes(container dockerData) []int {
+	label, err := getLabel(container, "traefik.backend.fallback.statusCodes")
+	if err != nil {
+		return nil
+	}
+	var statusCodes []int
+	for _, value := range strings.Split(label, ",") {
+		statusCode, errConv := strconv.Atoi(strings.TrimSpace(value))
+		if errConv != nil || statusCode < 100 || statusCode > 599 {
+			log.Errorf("Unable to parse traefik.backend.fallback.statusCodes %s", label)
+			return nil
+		}
+		statusCodes = append(statusCodes, statusCode)
+	}
+	return statusCodes
+}
+
+func (p *Provider) getMaxConnAmount(container dockerData) int64 {
+	if label, err := getLabel(container, "traefik.backend.maxconn.amount"); err == nil {
+		i, errConv := strconv.ParseInt(label, 10, 64)
+		if errConv != nil {
+			log.Errorf("Unable to parse traefik.backend.maxconn.amount %s for container %s", label, container.Name)
+			return math.MaxInt64
+		}
+		return i
+	}
+	return math.MaxInt64
+}
+
+// getMaxConnExtractorFunc returns the extractor function of the maxconn labels, warning about
+// the unknown ones, which are still passed on
+func (p *Provider) getMaxConnExtractorFunc(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.maxconn.extractorfunc"); err == nil && len(label) > 0 {
+		if !isKnownExtractorFunc(label) {
+			log.Warnf("Unknown traefik.backend.maxconn.extractorfunc %s for container %s, expected client.ip, request.host or request.header.<name>", label, container.Name)
+		}
+		if isIPHashLoadBalancer(container) && label != "client.ip" {
+			log.Warnf("Using traefik.backend.maxconn.extractorfunc %s for container %s instead of client.ip, set by the ip_hash load balancer method", label, container.Name)
+		}
+		return label
+	}
+	if isIPHashLoadBalancer(container) {
+		return "client.ip"
+	}
+	return "request.host"
+}
+
+// isKnownExtractorFunc returns true if the extractor function is client.ip, request.host or
+// request.header.<name>
+func isKnownExtractorFunc(extractorFunc string) bool {
+	switch extractorFunc {
+	case "client.ip", "request.host":
+		return true
+	}
+	return strings.HasPrefix(extractorFunc, "request.header.") && len(extractorFunc) > len("request.header.")
+}
+
+// containerFilter returns whether the container is exposed, counting the included and excluded
+// containers in the docker filtered containers metric
+func (p *Provider) containerFilter(container dockerData) bool {
+	reason := p.filterReason(container)
+	if len(reason) > 0 {
+		containersFilteredCounter.WithLabelValues("excluded", reason).Inc()
+		return false
+	}
+	containersFilteredCounter.WithLabelValues("included", "").Inc()
+	return true
+}
+
+// filterReason returns the reason why the container is not exposed, or an empty string if it is
+func (p *Provider) filterReason(container dockerData) string {
+	for _, labelErr := range p.getLabelErrors(container) {
+		if p.StrictLabelParsing || labelErr.critical {
+			log.WithFields(container.logFields()).Errorf("Filtering service with invalid label: %v", labelErr)
+			return filterReasonInvalidLabel
+		}
+	}
+
+	port, _ := getLabel(container, "traefik.port")
+	_, err := strconv.Atoi(port)
+	if _, ok := getCustomServerURL(container); ok {
+		// the custom server URL replaces the address and the port of the container
+		err = nil
+	}
+	if p.SwarmMode && len(container.NetworkSettings.Ports) == 0 && err != nil {
+		// without target port on its endpoint, the service has no backend port at all
+		if isContainerEnabled(container, p.ExposedByDefault) {
+			log.WithFields(container.logFields()).Warn("Filtering service without traefik.port label nor endpoint port, one of them being required in Swarm mode")
+			return filterReasonPort
+		}
+		log.WithFields(container.logFields()).Debug("Filtering disabled service without traefik.port label nor endpoint port")
+		return filterReasonDisabled
+	}
+	if len(container.NetworkSettings.Ports) == 0 && err != nil {
+		log.WithFields(container.logFields()).Debug("Filtering container without port and no traefik.port label")
+		return filterReasonPort
+	}
+
+	if !isContainerEnabled(container, p.ExposedByDefault) {
+		log.WithFields(container.logFields()).Debug("Filtering disabled container")
+		return filterReasonDisabled
+	}
+
+	if _, ok := getCustomServerURL(container); !ok && p.UseBindPortIP && !p.SwarmMode && getPortBinding(container, p.getContainerPort(container)) == nil {
+		log.WithFields(container.logFields()).Warnf("Filtering container without host binding of port %s, which is required by usebindportip", p.getContainerPort(container))
+		return filterReasonPortBinding
+	}
+
+	tags, _ := getLabel(container, "traefik.tags")
+	constraintTags := strings.Split(tags, ",")
+	if ok, failingConstraint := p.MatchConstraints(constraintTags); !ok {
+		if failingConstraint != nil {
+			log.WithFields(container.logFields()).Debugf("Container pruned by '%v' constraint", failingConstraint.String())
+		}
+		return filterReasonConstraint
+	}
+
+	if p.SwarmMode {
+		if ok, failingConstraint := matchLabelConstraints(container.Labels, p.LabelConstraints); !ok {
+			log.WithFields(container.logFields()).Debugf("Service pruned by '%s' label constraint", failingConstraint)
+			return filterReasonLabelConstraint
+		}
+	}
+
+	if container.Health != "" && container.Health != "healthy" {
+		log.WithFields(container.logFields()).Debug("Filtering unhealthy or starting container")
+		return filterReasonUnhealthy
+	}
+
+	for _, frontend := range p.getRuleFrontends(container) {
+		if _, err := p.getFrontendRule(frontend); err != nil {
+			log.WithFields(container.logFields()).Errorf("Filtering container with invalid traefik.frontend.rule label: %v", err)
+			return filterReasonInvalidLabel
+		}
+	}
+
+	if _, err := parseWhitelistSourceRange(container); err != nil {
+		log.WithFields(container.logFields()).Errorf("Filtering container with invalid traefik.frontend.whitelistSourceRange label: %v", err)
+		return filterReasonInvalidLabel
+	}
+
+	if _, err := parseRateLimits(container); err != nil {
+		log.WithFields(container.logFields()).Errorf("Filtering container with invalid traefik.frontend.ratelimit labels: %v", err)
+		return filterReasonInvalidLabel
+	}
+
+	return ""
+}
+
+// matchLabelConstraints checks that the labels satisfy every key==value and key!=value constraint,
+// the values being globs, and returns the first failing or invalid constraint otherwise.
+// A missing label satisfies the key!=value constraints only.
+func matchLabelConstraints(labels map[string]string, constraints []string) (bool, string) {
+	for _, constraint := range constraints {
+		sep, mustMatch := "==", true
+		if !strings.Contains(constraint, "==") {
+			sep, mustMatch = "!=", false
+		}
+		kv := strings.SplitN(constraint, sep, 2)
+		if len(kv) != 2 || len(strings.TrimSpace(kv[0])) == 0 {
+			log.Errorf("Invalid label constraint %q, expected key==value or key!=value", constraint)
+			return false, constraint
+		}
+		value, ok := labels[strings.TrimSpace(kv[0])]
+		if (ok && glob.Glob(strings.TrimSpace(kv[1]), value)) != mustMatch {
+			return false, constraint
+		}
+	}
+	return true, ""
+}
+
+func (p *Provider) getFrontendName(container dockerData) string {
+	name, ok := renderName("frontendName", p.FrontendNameTemplate, container)
+	if !ok {
+		// Replace '.' with '-' in quoted keys because of this issue https://github.com/BurntSushi/toml/issues/78
+		rule, _ := p.getFrontendRule(container)
+		name = sanitiseFrontendName(rule)
+	}
+	if container.RuleIndex != "" {
+		name += "-" + container.RuleIndex
+	}
+	return name
+}
+
+// sanitiseFrontendName normalizes a frontend rule into a frontend name. The variables of the
+// regexp rules, e.g. {subdomain:[a-z]+} in HostRegexp:{subdomain:[a-z]+}.example.com, are
+// replaced by their name, their pattern adding no meaning to the frontend name.
+func sanitiseFrontendName(rule string) string {
+	if !strings.Contains(rule, "Regex") {
+		return provider.Normalize(rule)
+	}
+	var buffer bytes.Buffer
+	for i := 0; i < len(rule); i++ {
+		if rule[i] != '{' {
+			buffer.WriteByte(rule[i])
+			continue
+		}
+		// the patterns may hold braces themselves, e.g. {id:[0-9]{3}}
+		end, depth := -1, 0
+		for j := i; j < len(rule) && end < 0; j++ {
+			switch rule[j] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = j
+				}
+			}
+		}
+		if end < 0 {
+			buffer.WriteString(rule[i:])
+			break
+		}
+		variable := rule[i+1 : end]
+		if index := strings.Index(variable, ":"); index >= 0 {
+			variable = variable[:index]
+		}
+		buffer.WriteString(variable)
+		i = end
+	}
+	return provider.Normalize(buffer.String())
+}
+
+// renderName renders a frontend or backend name template on the container, returning false
+// if there is no template or if it fails to render a name, the default name being used then
+func renderName(templateName string, text string, container dockerData) (string, bool) {
+	if len(text) == 0 {
+		return "", false
+	}
+	tmpl, err := template.New(templateName).Parse(text)
+	if err != nil {
+		log.WithFields(container.logFields()).Errorf("Unable to parse the docker %s template, using the default name: %s", templateName, err)
+		return "", false
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, container); err != nil {
+		log.WithFields(container.logFields()).Warnf("Unable to execute the docker %s template, using the default name: %s", templateName, err)
+		return "", false
+	}
+	name := provider.Normalize(strings.TrimSpace(buffer.String()))
+	if len(name) == 0 {
+		log.WithFields(container.logFields()).Warnf("Empty name rendered by the docker %s template, using the default name", templateName)
+		return "", false
+	}
+	return name, true
+}
+
+// uniqueFrontendName returns the frontend name, suffixed with -2, -3... if the frontend
+// is already used by another backend, the containers of a same backend sharing their frontend.
+// frontendBackends holds the backend of each frontend name already used.
+func uniqueFrontendName(name string, backend string, frontendBackends map[string]string) string {
+	candidate := name
+	for i := 2; ; i++ {
+		usedBy, ok := frontendBackends[candidate]
+		if !ok {
+			if candidate != name {
+				log.Warnf("Frontend %s is already used by another backend, using frontend %s for backend %s", name, candidate, backend)
+			}
+			frontendBackends[candidate] = backend
+			return candidate
+		}
+		if usedBy == backend {
+			return candidate
+		}
+		candidate = name + "-" + strconv.Itoa(i)
+	}
+}
+
+// getRuleFrontends returns a copy of the container for each of its traefik.frontend.rule.<N> labels,
+// ordered by index, with traefik.frontend.rule set to the indexed rule.
+// It returns the container itself when there is no indexed rule.
+func (p *Provider) getRuleFrontends(container dockerData) []dockerData {
+	if p.hasServices(container) {
+		return []dockerData{container}
+	}
+	rules := map[int]string{}
+	var indexes []int
+	prefix := labelKey(container, "traefik.frontend.rule.")
+	for label, rule := range labelsView(container) {
+		if !strings.HasPrefix(label, prefix) {
+			continue
+		}
+		index, err := strconv.Atoi(strings.TrimPrefix(label, prefix))
+		if err != nil || index < 0 {
+			continue
+		}
+		if _, ok := rules[index]; !ok {
+			indexes = append(indexes, index)
+		}
+		rules[index] = rule
+	}
+	if len(indexes) == 0 {
+		return []dockerData{container}
+	}
+	sort.Ints(indexes)
+
+	var frontends []dockerData
+	for _, index := range indexes {
+		frontend := withLabel(container, "traefik.frontend.rule", rules[index])
+		frontend.RuleIndex = strconv.Itoa(index)
+		frontends = append(frontends, frontend)
+	}
+	return frontends
+}
+
+// GetFrontendRule returns the frontend rule for the specified container, using
+// it's label. It returns a default one (Host) if the label is not present, or
+// a PathPrefix one if no domain is defined.
+func (p *Provider) getFrontendRule(container dockerData) (string, error) {
+	if label, err := getLabel(container, "traefik.frontend.rule"); err == nil {
+		rule := executeFrontendRuleTemplate(container, label)
+		return rule, validateFrontendRule(rule)
+	}
+	if label := getShorthandFrontendRule(container); len(label) > 0 {
+		rule := executeFrontendRuleTemplate(container, label)
+		return rule, validateFrontendRule(rule)
+	}
+	name := container.ServiceName
+	if labels, err := getLabels(container, []string{"com.docker.compose.project", "com.docker.compose.service"}); err == nil {
+		name = labels["com.docker.compose.service"] + "." + labels["com.docker.compose.project"]
+	}
+	if len(p.Domain) == 0 {
+		return "PathPrefix:/" + p.getSubDomain(name), nil
+	}
+	return "Host:" + p.getSubDomain(name) + "." + p.Domain, nil
+}
+
+// the shorthand labels of the frontend rule, in the order of their matchers in the rule
+var shorthandFrontendRuleLabels = []struct {
+	label   string
+	matcher string
+}{
+	{label: "traefik.frontend.rule.host", matcher: "Host"},
+	{label: "traefik.frontend.rule.path", matcher: "Path"},
+	{label: "traefik.frontend.rule.pathprefix", matcher: "PathPrefix"},
+}
+
+// getShorthandFrontendRule returns the rule ANDing the matchers of the traefik.frontend.rule.host,
+// traefik.frontend.rule.path and traefik.frontend.rule.pathprefix labels, or an empty string
+func getShorthandFrontendRule(container dockerData) string {
+	var matchers []string
+	for _, shorthand := range shorthandFrontendRuleLabels {
+		if label, err := getLabel(container, shorthand.label); err == nil && len(label) > 0 {
+			matchers = append(matchers, shorthand.matcher+":"+label)
+		}
+	}
+	return strings.Join(matchers, " && ")
+}
+
+var frontendRuleTypeRegexp = regexp.MustCompile(`^[A-Za-z]+$`)
+
+// validateFrontendRule checks that each of the ; or && separated parts of the rule
+// is of the form Type:value, the value being a comma separated list of arguments
+func validateFrontendRule(rule string) error {
+	parts := strings.FieldsFunc(strings.Replace(rule, "&&", ";", -1), func(c rune) bool {
+		return c == ';'
+	})
+	if len(parts) == 0 {
+		return fmt.Errorf("empty frontend rule %q", rule)
+	}
+	for _, part := range parts {
+		index := strings.Index(part, ":")
+		if index < 0 {
+			return fmt.Errorf("missing colon in %q of frontend rule %q, expected Type:value", strings.TrimSpace(part), rule)
+		}
+		if ruleType := strings.TrimSpace(part[:index]); !frontendRuleTypeRegexp.MatchString(ruleType) {
+			return fmt.Errorf("invalid type %q in frontend rule %q", ruleType, rule)
+		}
+		if len(strings.Trim(part[index+1:], ", \t")) == 0 {
+			return fmt.Errorf("missing value in %q of frontend rule %q", strings.TrimSpace(part), rule)
+		}
+	}
+	return nil
+}
+
+// frontendRuleTemplateData holds the data available to the traefik.frontend.rule label
+type frontendRuleTemplateData struct {
+	ServiceName string
+	Image       imageData
+	Replicas    uint64
+	Labels      map[string]string
+	Env         map[string]string
+}
+
+// imageData holds the parts of an image reference such as registry/name:tag@digest
+type imageData struct {
+	Name   string
+	Tag    string
+	Digest string
+}
+
+func parseImage(image string) imageData {
+	data := imageData{Name: image}
+	if index := strings.Index(data.Name, "@"); index >= 0 {
+		data.Name, data.Digest = data.Name[:index], data.Name[index+1:]
+	}
+	// the tag follows the last colon, unless it separates the registry host from its port
+	if index := strings.LastIndex(data.Name, ":"); index > strings.LastIndex(data.Name, "/") {
+		data.Name, data.Tag = data.Name[:index], data.Name[index+1:]
+	}
+	return data
+}
+
+// executeFrontendRuleTemplate executes the frontend rule label as a template of the
+// service metadata, the label being used as is if the template fails.
+func executeFrontendRuleTemplate(container dockerData, rule string) string {
+	if !strings.Contains(rule, "{{") {
+		return rule
+	}
+	data := frontendRuleTemplateData{
+		ServiceName: container.ServiceName,
+		Image:       parseImage(container.Image),
+		Replicas:    container.Replicas,
+		Labels:      container.Labels,
+		Env:         container.Env,
+	}
+	tmpl, err := template.New("frontendRule").Option("missingkey=error").Parse(rule)
+	if err != nil {
+		log.Warnf("Unable to parse traefik.frontend.rule %s for container %s: %s", rule, container.Name, err)
+		return rule
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		log.Warnf("Unable to execute traefik.frontend.rule %s for container %s: %s", rule, container.Name, err)
+		return rule
+	}
+	return buffer.String()
+}
+
+// parseEnv maps the KEY=VALUE environment variables of a container, a variable
+// without value being mapped to an empty string
+func parseEnv(env []string) map[string]string {
+	vars := make(map[string]string, len(env))
+	for _, v := range env {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) == 2 {
+			vars[parts[0]] = parts[1]
+		} else {
+			vars[parts[0]] = ""
+		}
+	}
+	return vars
+}
+
+func (p *Provider) getForwardCaptures(container dockerData) string {
+	if forwardCaptures, err := getLabel(container, "traefik.frontend.rule.forwardCaptures"); err == nil {
+		return forwardCaptures
+	}
+	return "false"
+}
+
+func (p *Provider) getCaseInsensitive(container dockerData) string {
+	if caseInsensitive, err := getLabel(container, "traefik.frontend.rule.caseInsensitive"); err == nil {
+		return caseInsensitive
+	}
+	return "false"
+}
+
+// getPassTLSCert returns whether the details of the client TLS certificate are forwarded to the backend
+func (p *Provider) getPassTLSCert(container dockerData) bool {
+	return getHeadersBoolLabel(container, "traefik.frontend.passTLSCert")
+}
+
+// getPassTLSCertPEM returns whether the PEM encoded client TLS certificate is forwarded to the backend
+func (p *Provider) getPassTLSCertPEM(container dockerData) bool {
+	return getHeadersBoolLabel(container, "traefik.frontend.passTLSCert.pem")
+}
+
+func (p *Provider) getRequestIDHeader(container dockerData) string {
+	if label, err := getLabel(container, "traefik.frontend.headers.requestIDHeader"); err == nil {
+		return label
+	}
+	return "X-Request-ID"
+}
+
+func (p *Provider) getSeed(container dockerData) string {
+	if label, err := getLabel(container, "traefik.frontend.rule.seed"); err == nil {
+		if _, errParse := strconv.ParseInt(label, 10, 64); errParse != nil {
+			log.Errorf("Unable to parse traefik.frontend.rule.seed %s", label)
+			return "0"
+		}
+		return label
+	}
+	return "0"
+}
+
+// getRedirect returns the entry point the frontend requests are redirected to, traefik.frontend.redirect
+// being a shorthand for traefik.frontend.redirect.entryPoint
+func (p *Provider) getRedirect(container dockerData) string {
+	if entryPoint, err := getLabel(container, "traefik.frontend.redirect.entryPoint"); err == nil {
+		return entryPoint
+	}
+	if entryPoint, err := getLabel(container, "traefik.frontend.redirect"); err == nil {
+		return entryPoint
+	}
+	return ""
+}
+
+// getBackendContainers returns a copy of the container for each backend of the comma separated
+// traefik.backend label, with the name of this backend as label. The container is only registered
+// in several backends when the traefik.port label sets the port they share.
+func (p *Provider) getBackendContainers(container dockerData) []dockerData {
+	label, err := getLabel(container, "traefik.backend")
+	if err != nil || !strings.Contains(label, ",") || p.hasServices(container) {
+		return []dockerData{container}
+	}
+	names := splitBackends(label)
+	if len(names) == 0 {
+		return []dockerData{container}
+	}
+	_, hasServerURL := getCustomServerURL(container)
+	if _, err := getLabel(container, "traefik.port"); err != nil && !hasServerURL && len(names) > 1 {
+		log.WithFields(container.logFields()).Warnf("Registering container in its first backend %s only, the traefik.port label being required by several backends", names[0])
+		names = names[:1]
+	}
+
+	var containers []dockerData
+	for _, name := range names {
+		containers = append(containers, withLabel(container, "traefik.backend", name))
+	}
+	return containers
+}
+
+// splitBackends splits a comma separated list of backends, skipping the empty and duplicated names
+func splitBackends(label string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(label, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+func (p *Provider) getBackend(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend"); err == nil {
+		return normalizeBackendName(label)
+	}
+	if name, ok := renderName("backendName", p.BackendNameTemplate, container); ok {
+		return normalizeBackendName(name)
+	}
+	if labels, err := getLabels(container, []string{"com.docker.compose.project", "com.docker.compose.service"}); err == nil {
+		return normalizeBackendName(labels["com.docker.compose.service"] + "_" + labels["com.docker.compose.project"])
+	}
+	return normalizeBackendName(container.ServiceName)
+}
+
+// normalizeBackendName returns the lowercase backend name, its runs of non-alphanumeric characters
+// being replaced by a single dash, so that e.g. My_App and my-app share the backend my-app
+func normalizeBackendName(name string) string {
+	return strings.ToLower(provider.Normalize(name))
+}
+
+func (p *Provider) getIPAddress(container dockerData) string {
+	// the host IP goes along with the host port returned by getPort
+	if p.UseBindPortIP {
+		if binding := getPortBinding(container, p.getContainerPort(container)); binding != nil {
+			return binding.HostIP
+		}
+	}
+
+	if label, err := getLabel(container, "traefik.docker.network"); err == nil && label != "" {
+		networkSettings := container.NetworkSettings
+		if networkSettings.Networks != nil {
+			// the first network of the list attached to the container is used
+			if network := getLabeledNetwork(container, label); network != nil {
+				return network.Addr
+			}
+
+			log.Warnf("Could not find network named '%s' for container '%s'! Maybe you're missing the project's prefix in the label? Defaulting to the provider network or the first available one.", label, container.Name)
+		}
+	}
+
+	// the default network of the provider is used for the containers attached to it
+	if p.Network != "" {
+		if network := container.NetworkSettings.Networks[p.Network]; network != nil {
+			return network.Addr
+		}
+	}
+
+	// If net==host, quick n' dirty, we return 127.0.0.1
+	// This will work locally, but will fail with swarm.
+	if "host" == container.NetworkSettings.NetworkMode {
+		return "127.0.0.1"
+	}
+
+	for _, network := range container.NetworkSettings.Networks {
+		return network.Addr
+	}
+	return ""
+}
+
+// getLabeledNetwork returns the first network of the comma separated traefik.docker.network
+// label attached to the container, or nil if none of them is
+func getLabeledNetwork(container dockerData, label string) *networkData {
+	for _, name := range strings.Split(label, ",") {
+		if network := container.NetworkSettings.Networks[strings.TrimSpace(name)]; network != nil {
+			return network
+		}
+	}
+	return nil
+}
+
+// getPort returns the port of the backend server, the host port bound to the container port
+// when UseBindPortIP is set
+func (p *Provider) getPort(container dockerData) string {
+	port := p.getContainerPort(container)
+	if p.UseBindPortIP {
+		if binding := getPortBinding(container, port); binding != nil {
+			return binding.HostPort
+		}
+	}
+	return port
+}
+
+// getPortBinding returns the first host binding with a host port of the given TCP or UDP
+// container port, or nil if the port is not published on the host
+func getPortBinding(container dockerData, port string) *nat.PortBinding {
+	for _, proto := range []string{"tcp", "udp"} {
+		natPort, err := nat.NewPort(proto, port)
+		if err != nil {
+			return nil
+		}
+		for _, binding := range container.NetworkSettings.Ports[natPort] {
+			if binding.HostPort != "" {
+				return &binding
+			}
+		}
+	}
+	return nil
+}
+
+// getContainerPort returns the port of the traefik.port label, or the lowest port exposed by the container
+func (p *Provider) getContainerPort(container dockerData) string {
+	if label, err := getLabel(container, "traefik.port"); err == nil {
+		return label
+	}
+
+	// See iteration order in https://blog.golang.org/go-maps-in-action
+	var ports []nat.Port
+	for p := range container.NetworkSettings.Ports {
+		ports = append(ports, p)
+	}
+
+	// the lowest TCP port is the default, other protocols come after
 	less := func(i, j nat.Port) bool {
+		if i.Proto() != j.Proto() {
+			return i.Proto() == "tcp"
+		}
 		return i.Int() < j.Int()
 	}
-	nat.Sort(ports, less)
+	nat.Sort(ports, less)
+
+	if len(ports) > 0 {
+		min := ports[0]
+		return min.Port()
+	}
+
+	return ""
+}
+
+// getPublishedPort returns the port of the traefik.publishedPort label if the Swarm service
+// publishes it on the routing mesh, which reaches the service through any node.
+func (p *Provider) getPublishedPort(container dockerData) (string, bool) {
+	label, err := getLabel(container, "traefik.publishedPort")
+	if err != nil || !p.SwarmMode {
+		return "", false
+	}
+	for _, port := range container.PublishedPorts {
+		if strconv.FormatUint(uint64(port.PublishedPort), 10) == label {
+			log.Debugf("Using published port %s of service %s, targeting port %d", label, container.ServiceName, port.TargetPort)
+			return label, true
+		}
+	}
+	log.Warnf("Port %s of traefik.publishedPort is not published by service %s", label, container.ServiceName)
+	return "", false
+}
+
+// getSwarmManagerIP returns the host of the Docker endpoint, the local host for unix sockets
+func (p *Provider) getSwarmManagerIP() string {
+	endpoint, err := url.Parse(p.Endpoint)
+	if err != nil || endpoint.Scheme != "tcp" || len(endpoint.Hostname()) == 0 {
+		return "127.0.0.1"
+	}
+	return endpoint.Hostname()
+}
 
-	if len(ports) > 0 {
-		min := ports[0]
-		return min.Port()
+// serverURLTemplateData holds the data available to the traefik.backend.server.urlTemplate label
+type serverURLTemplateData struct {
+	IP       string
+	Port     string
+	Protocol string
+	Name     string
+	Labels   map[string]string
+}
+
+// getServerURL returns the URL of the backend server for the container, built from the
+// traefik.backend.server.urlTemplate label if present.
+func (p *Provider) getServerURL(container dockerData) string {
+	if serverURL, ok := getCustomServerURL(container); ok {
+		return serverURL
+	}
+	data := serverURLTemplateData{
+		IP:       p.getIPAddress(container),
+		Port:     p.getPort(container),
+		Protocol: p.getProtocol(container),
+		Name:     container.Name,
+		Labels:   container.Labels,
+	}
+	if p.isBackendLBSwarm(container) {
+		// the service name is resolved by the Swarm DNS, to the virtual IP or to the tasks in DNS round-robin mode
+		data.IP = container.ServiceName
+	}
+	if publishedPort, ok := p.getPublishedPort(container); ok {
+		data.IP, data.Port = p.getSwarmManagerIP(), publishedPort
 	}
+	defaultURL := data.Protocol + "://" + net.JoinHostPort(data.IP, data.Port)
 
-	return ""
+	label, err := getLabel(container, "traefik.backend.server.urlTemplate")
+	if err != nil {
+		return defaultURL
+	}
+	tmpl, err := template.New("urlTemplate").Option("missingkey=error").Parse(label)
+	if err != nil {
+		log.Errorf("Unable to parse traefik.backend.server.urlTemplate %s for container %s: %s", label, container.Name, err)
+		return defaultURL
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		log.Errorf("Unable to execute traefik.backend.server.urlTemplate %s for container %s: %s", label, container.Name, err)
+		return defaultURL
+	}
+	return buffer.String()
+}
+
+// getCustomServerURL returns the URL of the traefik.backend.server.url label, used verbatim as the
+// server URL in place of the address and the port of the container. Invalid URLs are ignored.
+func getCustomServerURL(container dockerData) (string, bool) {
+	label, err := getLabel(container, "traefik.backend.server.url")
+	if err != nil {
+		return "", false
+	}
+	if err := checkServerURL(label); err != nil {
+		log.WithFields(container.logFields()).Errorf("Ignoring traefik.backend.server.url: %s", err)
+		return "", false
+	}
+	return label, true
+}
+
+// checkServerURL checks that a server URL is an http or https URL with a host
+func checkServerURL(rawURL string) error {
+	serverURL, err := url.Parse(rawURL)
+	if err != nil || (serverURL.Scheme != "http" && serverURL.Scheme != "https") || len(serverURL.Host) == 0 {
+		return fmt.Errorf("invalid server URL %q, expected an http or https URL", rawURL)
+	}
+	return nil
+}
+
+// getServerURLChain returns the URLs of the traefik.backend.server.urlChain label.
+// The first URL is the primary server, the following ones are fallback servers
+// only used while the primary server fails its health check.
+func (p *Provider) getServerURLChain(container dockerData) []string {
+	urls, err := parseServerURLChain(container)
+	if err != nil {
+		log.WithFields(container.logFields()).Errorf("Ignoring traefik.backend.server.urlChain: %s", err)
+		return nil
+	}
+	return urls
+}
+
+// parseServerURLChain parses the traefik.backend.server.urlChain label, each URL being checked
+// the same way as traefik.backend.server.url
+func parseServerURLChain(container dockerData) ([]string, error) {
+	label, err := getLabel(container, "traefik.backend.server.urlChain")
+	if err != nil {
+		return nil, nil
+	}
+	var urls []string
+	for _, rawURL := range strings.Split(label, ";") {
+		rawURL = strings.TrimSpace(rawURL)
+		if len(rawURL) == 0 {
+			continue
+		}
+		if err := checkServerURL(rawURL); err != nil {
+			return nil, err
+		}
+		urls = append(urls, rawURL)
+	}
+	return urls, nil
+}
+
+// getServers returns the servers of the traefik.backend.server.urls label, a JSON array of URLs.
+// The servers share the container weight unless traefik.backend.server.weights, a JSON array of
+// integers, sets the weight of each one of them.
+func (p *Provider) getServers(container dockerData) []types.Server {
+	label, err := getLabel(container, "traefik.backend.server.urls")
+	if err != nil {
+		return nil
+	}
+	var urls []string
+	if err := json.Unmarshal([]byte(label), &urls); err != nil {
+		log.Errorf("Unable to parse traefik.backend.server.urls %s: %s", label, err)
+		return nil
+	}
+	weights := make([]int, len(urls))
+	for i := range weights {
+		weights[i] = weightOf(p.getWeight(container))
+	}
+	if label, err := getLabel(container, "traefik.backend.server.weights"); err == nil {
+		var customWeights []int
+		if err := json.Unmarshal([]byte(label), &customWeights); err != nil || len(customWeights) != len(urls) {
+			log.Errorf("Unable to parse traefik.backend.server.weights %s: expected a JSON array of %d integers", label, len(urls))
+		} else {
+			weights = customWeights
+		}
+	}
+	var servers []types.Server
+	for i, rawURL := range urls {
+		servers = append(servers, types.Server{URL: rawURL, Weight: weights[i]})
+	}
+	return servers
+}
+
+// isFallbackServer returns whether the container is a fallback server of its backend,
+// receiving the requests answered with one of the fallback status codes of the backend
+func (p *Provider) isFallbackServer(container dockerData) bool {
+	label, err := getLabel(container, "traefik.backend.server.fallback")
+	return err == nil && label == "true"
 }
 
 func (p *Provider) getWeight(container dockerData) string {
 	if label, err := getLabel(container, "traefik.weight"); err == nil {
+		// the server of merged tasks gets the sum of their weights
+		if weight, errConv := strconv.Atoi(label); errConv == nil && container.SharedTasks > 1 {
+			return strconv.Itoa(weight * container.SharedTasks)
+		}
 		return label
 	}
 	return "0"
@@ -625,9 +2052,54 @@ func (p *Provider) getSticky(container dockerData) string {
 	if label, err := getLabel(container, "traefik.backend.loadbalancer.sticky"); err == nil {
 		return label
 	}
+	if label, err := getLabel(container, "traefik.backend.sticky"); err == nil {
+		return label
+	}
+	// a sticky session cookie name implicitly enables the sticky sessions
+	if p.getStickyCookieName(container) != "" {
+		return "true"
+	}
 	return "false"
 }
 
+func (p *Provider) getStickyCookieName(container dockerData) string {
+	if label, err := getLabel(container, "traefik.backend.loadbalancer.sticky.cookieName"); err == nil {
+		return label
+	}
+	if label, err := getLabel(container, "traefik.backend.loadbalancer.stickiness.cookieName"); err == nil {
+		return label
+	}
+	return ""
+}
+
+// backendStickiness holds the sticky session settings of a backend
+type backendStickiness struct {
+	Sticky     bool
+	CookieName string
+}
+
+// getBackendStickiness returns the sticky session settings of the first container of the backend
+// in alphabetical order, warning about the containers with conflicting settings
+func (p *Provider) getBackendStickiness(backendName string, containers []dockerData) backendStickiness {
+	sorted := make([]dockerData, len(containers))
+	copy(sorted, containers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var stickiness backendStickiness
+	for i, container := range sorted {
+		current := backendStickiness{
+			Sticky:     p.getSticky(container) == "true",
+			CookieName: p.getStickyCookieName(container),
+		}
+		if i == 0 {
+			stickiness = current
+		} else if current != stickiness {
+			log.Warnf("Container %s of backend %s has conflicting sticky session settings, using the ones of container %s", container.Name, backendName, sorted[0].Name)
+		}
+	}
+	return stickiness
+}
+
 func (p *Provider) getIsBackendLBSwarm(container dockerData) string {
 	if label, err := getLabel(container, "traefik.backend.loadbalancer.swarm"); err == nil {
 		return label
@@ -635,6 +2107,13 @@ func (p *Provider) getIsBackendLBSwarm(container dockerData) string {
 	return "false"
 }
 
+// isBackendLBSwarm returns true if the Swarm service is reached through Swarm's inbuilt load
+// balancer instead of its tasks
+func (p *Provider) isBackendLBSwarm(container dockerData) bool {
+	useSwarmLB, _ := strconv.ParseBool(p.getIsBackendLBSwarm(container))
+	return p.SwarmMode && useSwarmLB
+}
+
 func (p *Provider) getDomain(container dockerData) string {
 	if label, err := getLabel(container, "traefik.domain"); err == nil {
 		return label
@@ -646,9 +2125,42 @@ func (p *Provider) getProtocol(container dockerData) string {
 	if label, err := getLabel(container, "traefik.protocol"); err == nil {
 		return label
 	}
+	if p.getBackendTLS(container) != nil {
+		return "https"
+	}
 	return "http"
 }
 
+// getBackendTLS returns the TLS configuration of the connections to the backend, or nil
+// if no traefik.backend.tls label is set or if traefik.backend.tls is false
+func (p *Provider) getBackendTLS(container dockerData) *types.ClientTLS {
+	enabled, errEnabled := getLabel(container, "traefik.backend.tls")
+	ca, errCA := getLabel(container, "traefik.backend.tls.ca")
+	insecureSkipVerify, errInsecure := getLabel(container, "traefik.backend.tls.insecureSkipVerify")
+	if errEnabled != nil && errCA != nil && errInsecure != nil {
+		return nil
+	}
+	if errEnabled == nil {
+		value, errConv := strconv.ParseBool(enabled)
+		if errConv != nil {
+			log.Errorf("Unable to parse traefik.backend.tls %s for container %s: %s", enabled, container.Name, errConv)
+			return nil
+		}
+		if !value {
+			return nil
+		}
+	}
+	clientTLS := &types.ClientTLS{CA: ca}
+	if errInsecure == nil {
+		value, errConv := strconv.ParseBool(insecureSkipVerify)
+		if errConv != nil {
+			log.Errorf("Unable to parse traefik.backend.tls.insecureSkipVerify %s for container %s: %s", insecureSkipVerify, container.Name, errConv)
+		}
+		clientTLS.InsecureSkipVerify = value
+	}
+	return clientTLS
+}
+
 func (p *Provider) getPassHostHeader(container dockerData) string {
 	if passHostHeader, err := getLabel(container, "traefik.frontend.passHostHeader"); err == nil {
 		return passHostHeader
@@ -656,20 +2168,39 @@ func (p *Provider) getPassHostHeader(container dockerData) string {
 	return "true"
 }
 
+// getPriority returns the priority of the frontend, 0 letting the server default it to
+// the length of the frontend rule
 func (p *Provider) getPriority(container dockerData) string {
-	if priority, err := getLabel(container, "traefik.frontend.priority"); err == nil {
+	if priority, err := getLabel(container, "traefik.frontend.priority"); err == nil && isValidPriority(priority, container.Name) {
 		return priority
 	}
 	return "0"
 }
 
+// isValidPriority checks that the priority label is an integer, logging it otherwise
+func isValidPriority(priority string, containerName string) bool {
+	if _, err := strconv.Atoi(priority); err != nil {
+		log.Errorf("Unable to parse frontend priority %s for container %s, using the default priority: %s", priority, containerName, err)
+		return false
+	}
+	return true
+}
+
 func (p *Provider) getEntryPoints(container dockerData) []string {
 	if entryPoints, err := getLabel(container, "traefik.frontend.entryPoints"); err == nil {
-		return strings.Split(entryPoints, ",")
+		return splitEntryPoints(entryPoints)
 	}
 	return []string{}
 }
 
+// splitEntryPoints splits a list of entry points separated by commas and/or whitespaces
+func splitEntryPoints(entryPoints string) []string {
+	fargs := func(c rune) bool {
+		return c == ',' || unicode.IsSpace(c)
+	}
+	return append([]string{}, strings.FieldsFunc(entryPoints, fargs)...)
+}
+
 func (p *Provider) getBasicAuth(container dockerData) []string {
 	if basicAuth, err := getLabel(container, "traefik.frontend.auth.basic"); err == nil {
 		return strings.Split(basicAuth, ",")
@@ -678,8 +2209,258 @@ func (p *Provider) getBasicAuth(container dockerData) []string {
 	return []string{}
 }
 
+// getDigestAuth returns the htdigest users of the frontend, skipping the invalid ones
+func (p *Provider) getDigestAuth(container dockerData) []string {
+	if digestAuth, err := getLabel(container, "traefik.frontend.auth.digest"); err == nil {
+		return parseDigestAuth(digestAuth, container.Name)
+	}
+	return nil
+}
+
+// parseDigestAuth splits a comma separated list of htdigest users, each of them
+// having to be of the form user:realm:hash
+func parseDigestAuth(digestAuth string, containerName string) []string {
+	var users []string
+	for _, user := range strings.Split(digestAuth, ",") {
+		user = strings.TrimSpace(user)
+		if len(user) == 0 {
+			continue
+		}
+		if strings.Count(user, ":") != 2 {
+			log.Errorf("Invalid digest auth user %q on container %s, expected user:realm:hash", user, containerName)
+			continue
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// getForwardAuth returns the forward authentication of the frontend, or nil if there
+// is no traefik.frontend.auth.forward.address label
+func (p *Provider) getForwardAuth(container dockerData) *types.ForwardAuth {
+	address, err := getLabel(container, "traefik.frontend.auth.forward.address")
+	if err != nil {
+		return nil
+	}
+	forwardAuth := &types.ForwardAuth{Address: address}
+	if label, err := getLabel(container, "traefik.frontend.auth.forward.trustForwardHeader"); err == nil {
+		value, errConv := strconv.ParseBool(label)
+		if errConv != nil {
+			log.Errorf("Unable to parse traefik.frontend.auth.forward.trustForwardHeader %s for container %s: %s", label, container.Name, errConv)
+		}
+		forwardAuth.TrustForwardHeader = value
+	}
+	if label, err := getLabel(container, "traefik.frontend.auth.forward.authResponseHeaders"); err == nil {
+		for _, header := range strings.Split(label, ",") {
+			if header = strings.TrimSpace(header); len(header) > 0 {
+				forwardAuth.AuthResponseHeaders = append(forwardAuth.AuthResponseHeaders, header)
+			}
+		}
+	}
+	return forwardAuth
+}
+
+// getWhitelistSourceRange returns the CIDRs allowed to reach the frontend, any client being allowed if empty
+func (p *Provider) getWhitelistSourceRange(container dockerData) []string {
+	sourceRange, _ := parseWhitelistSourceRange(container)
+	return sourceRange
+}
+
+// parseWhitelistSourceRange parses the comma separated CIDRs of the traefik.frontend.whitelistSourceRange label
+func parseWhitelistSourceRange(container dockerData) ([]string, error) {
+	label, err := getLabel(container, "traefik.frontend.whitelistSourceRange")
+	if err != nil {
+		return nil, nil
+	}
+	var sourceRange []string
+	for _, cidr := range strings.Split(label, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+		sourceRange = append(sourceRange, cidr)
+	}
+	return sourceRange, nil
+}
+
+func (p *Provider) hasRateLimitLabels(container dockerData) bool {
+	return len(p.getRateLimits(container)) > 0
+}
+
+func (p *Provider) getRateLimitExtractorFunc(container dockerData) string {
+	if label, err := getLabel(container, "traefik.frontend.ratelimit.extractorfunc"); err == nil {
+		return label
+	}
+	return ""
+}
+
+// getRateLimits returns the rate sets of the frontend by name
+func (p *Provider) getRateLimits(container dockerData) map[string]*types.Rate {
+	rates, _ := parseRateLimits(container)
+	return rates
+}
+
+// parseRateLimits parses the traefik.frontend.ratelimit.rateset.<name>.period and .average labels,
+// the name of a rate set possibly containing dots
+func parseRateLimits(container dockerData) (map[string]*types.Rate, error) {
+	prefix := labelKey(container, "traefik.frontend.ratelimit.rateset.")
+	rates := map[string]*types.Rate{}
+	for label, value := range labelsView(container) {
+		if !strings.HasPrefix(label, prefix) {
+			continue
+		}
+		separator := strings.LastIndex(label, ".")
+		name, key := label[len(prefix):separator+1], label[separator+1:]
+		name = strings.TrimSuffix(name, ".")
+		if name == "" {
+			return nil, fmt.Errorf("missing rate set name in label %s", label)
+		}
+		if rates[name] == nil {
+			rates[name] = &types.Rate{}
+		}
+		switch key {
+		case "period":
+			if period, err := time.ParseDuration(value); err != nil || period <= 0 {
+				return nil, fmt.Errorf("invalid period %q of rate set %s", value, name)
+			}
+			rates[name].Period = value
+		case "average":
+			average, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || average <= 0 {
+				return nil, fmt.Errorf("invalid average %q of rate set %s", value, name)
+			}
+			rates[name].Average = average
+		default:
+			return nil, fmt.Errorf("unknown rate set label %s", label)
+		}
+	}
+	for name, rate := range rates {
+		if rate.Period == "" {
+			return nil, fmt.Errorf("missing period of rate set %s", name)
+		}
+		if rate.Average == 0 {
+			return nil, fmt.Errorf("missing average of rate set %s", name)
+		}
+	}
+	_, err := getLabel(container, "traefik.frontend.ratelimit.extractorfunc")
+	switch {
+	case err == nil && len(rates) == 0:
+		return nil, errors.New("missing rate set for traefik.frontend.ratelimit.extractorfunc")
+	case err != nil && len(rates) > 0:
+		return nil, errors.New("missing traefik.frontend.ratelimit.extractorfunc label")
+	}
+	return rates, nil
+}
+
+// getHeaders returns the custom and security headers of the traefik.frontend.headers.* labels,
+// or nil if there is none
+func (p *Provider) getHeaders(container dockerData) *types.Headers {
+	found := false
+	prefix := labelKey(container, "traefik.frontend.headers.")
+	for key := range labelsView(container) {
+		if strings.HasPrefix(key, prefix) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &types.Headers{
+		CustomRequestHeaders:    getHeadersMapLabel(container, "traefik.frontend.headers.customRequestHeaders"),
+		CustomResponseHeaders:   getHeadersMapLabel(container, "traefik.frontend.headers.customResponseHeaders"),
+		AllowedHosts:            getHeadersListLabel(container, "traefik.frontend.headers.allowedHosts"),
+		HostsProxyHeaders:       getHeadersListLabel(container, "traefik.frontend.headers.hostsProxyHeaders"),
+		SSLRedirect:             getHeadersBoolLabel(container, "traefik.frontend.headers.SSLRedirect"),
+		SSLTemporaryRedirect:    getHeadersBoolLabel(container, "traefik.frontend.headers.SSLTemporaryRedirect"),
+		SSLHost:                 getHeadersStringLabel(container, "traefik.frontend.headers.SSLHost"),
+		SSLProxyHeaders:         getHeadersMapLabel(container, "traefik.frontend.headers.SSLProxyHeaders"),
+		STSSeconds:              getHeadersInt64Label(container, "traefik.frontend.headers.STSSeconds"),
+		STSIncludeSubdomains:    getHeadersBoolLabel(container, "traefik.frontend.headers.STSIncludeSubdomains"),
+		STSPreload:              getHeadersBoolLabel(container, "traefik.frontend.headers.STSPreload"),
+		ForceSTSHeader:          getHeadersBoolLabel(container, "traefik.frontend.headers.forceSTSHeader"),
+		FrameDeny:               getHeadersBoolLabel(container, "traefik.frontend.headers.frameDeny"),
+		CustomFrameOptionsValue: getHeadersStringLabel(container, "traefik.frontend.headers.customFrameOptionsValue"),
+		ContentTypeNosniff:      getHeadersBoolLabel(container, "traefik.frontend.headers.contentTypeNosniff"),
+		BrowserXSSFilter:        getHeadersBoolLabel(container, "traefik.frontend.headers.browserXSSFilter"),
+		ContentSecurityPolicy:   getHeadersStringLabel(container, "traefik.frontend.headers.contentSecurityPolicy"),
+		PublicKey:               getHeadersStringLabel(container, "traefik.frontend.headers.publicKey"),
+		ReferrerPolicy:          getHeadersStringLabel(container, "traefik.frontend.headers.referrerPolicy"),
+		IsDevelopment:           getHeadersBoolLabel(container, "traefik.frontend.headers.isDevelopment"),
+	}
+}
+
+func getHeadersStringLabel(container dockerData, labelName string) string {
+	label, _ := getLabel(container, labelName)
+	return label
+}
+
+func getHeadersBoolLabel(container dockerData, labelName string) bool {
+	label, err := getLabel(container, labelName)
+	if err != nil {
+		return false
+	}
+	value, errConv := strconv.ParseBool(label)
+	if errConv != nil {
+		log.Errorf("Unable to parse %s %s for container %s: %s", labelName, label, container.Name, errConv)
+		return false
+	}
+	return value
+}
+
+func getHeadersInt64Label(container dockerData, labelName string) int64 {
+	label, err := getLabel(container, labelName)
+	if err != nil {
+		return 0
+	}
+	value, errConv := strconv.ParseInt(label, 10, 64)
+	if errConv != nil || value < 0 {
+		log.Errorf("Unable to parse %s %s for container %s", labelName, label, container.Name)
+		return 0
+	}
+	return value
+}
+
+// getHeadersListLabel returns the values of a comma separated label
+func getHeadersListLabel(container dockerData, labelName string) []string {
+	label, err := getLabel(container, labelName)
+	if err != nil {
+		return nil
+	}
+	var values []string
+	for _, value := range strings.Split(label, ",") {
+		if value = strings.TrimSpace(value); len(value) > 0 {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getHeadersMapLabel returns the headers of a label such as X-Custom-Header:value||Another:val
+func getHeadersMapLabel(container dockerData, labelName string) map[string]string {
+	label, err := getLabel(container, labelName)
+	if err != nil {
+		return nil
+	}
+	headers := make(map[string]string)
+	for _, header := range strings.Split(label, "||") {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 {
+			log.Errorf("Unable to parse header %q of %s for container %s", header, labelName, container.Name)
+			continue
+		}
+		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return headers
+}
+
 func isContainerEnabled(container dockerData, exposedByDefault bool) bool {
-	return exposedByDefault && container.Labels["traefik.enable"] != "false" || container.Labels["traefik.enable"] == "true"
+	enable, err := getLabel(container, "traefik.enable")
+	if err != nil {
+		// traefik.expose is an alias of traefik.enable, which takes precedence
+		enable, _ = getLabel(container, "traefik.expose")
+	}
+	return exposedByDefault && enable != "false" || enable == "true"
 }
 
 func getLabel(container dockerData, label string) (string, error) {
@@ -688,6 +2469,9 @@ func getLabel(container dockerData, label string) (string, error) {
 			return value, nil
 		}
 	}
+	if value, ok := container.LowerLabels[strings.ToLower(label)]; ok {
+		return value, nil
+	}
 	return "", errors.New("Label not found:" + label)
 }
 
@@ -718,15 +2502,64 @@ func listContainers(ctx context.Context, dockerClient client.ContainerAPIClient)
 	for _, container := range containerList {
 		containerInspected, err := dockerClient.ContainerInspect(ctx, container.ID)
 		if err != nil {
-			log.Warnf("Failed to inspect container %s, error: %s", container.ID, err)
+			log.WithField("containerID", container.ID).Warnf("Failed to inspect container, error: %s", err)
 		} else {
 			dockerData := parseContainer(containerInspected)
+			dockerData = resolveSharedNetwork(ctx, dockerClient, dockerData)
 			containersInspected = append(containersInspected, dockerData)
 		}
 	}
 	return containersInspected, nil
 }
 
+// resolveSharedNetwork sets the network settings of a container sharing the network stack
+// of another one (network_mode: service:<name> or container:<id>), since docker does not report them.
+func resolveSharedNetwork(ctx context.Context, dockerClient client.ContainerAPIClient, container dockerData) dockerData {
+	networkMode := string(container.NetworkSettings.NetworkMode)
+	var name string
+	switch {
+	case len(container.NetworkContainerID) > 0:
+		name = container.NetworkContainerID
+	case strings.HasPrefix(networkMode, "service:"):
+		service := strings.TrimPrefix(networkMode, "service:")
+		id, err := findComposeServiceContainer(ctx, dockerClient, service, container.Labels["com.docker.compose.project"])
+		if err != nil {
+			log.WithFields(container.logFields()).Warnf("Failed to find a container of the compose service %s whose network is shared, error: %s", service, err)
+			return container
+		}
+		name = id
+	default:
+		return container
+	}
+	sharedInspected, err := dockerClient.ContainerInspect(ctx, name)
+	if err != nil {
+		log.WithFields(container.logFields()).Warnf("Failed to inspect container %s whose network is shared, error: %s", name, err)
+		return container
+	}
+	shared := parseContainer(sharedInspected)
+	container.NetworkSettings.NetworkMode = shared.NetworkSettings.NetworkMode
+	container.NetworkSettings.Networks = shared.NetworkSettings.Networks
+	return container
+}
+
+// findComposeServiceContainer returns the ID of a container of the given compose service,
+// looked up in the same compose project when the project is known.
+func findComposeServiceContainer(ctx context.Context, dockerClient client.ContainerAPIClient, service string, project string) (string, error) {
+	f := filters.NewArgs()
+	f.Add("label", "com.docker.compose.service="+service)
+	if len(project) > 0 {
+		f.Add("label", "com.docker.compose.project="+project)
+	}
+	containerList, err := dockerClient.ContainerList(ctx, dockertypes.ContainerListOptions{Filter: f})
+	if err != nil {
+		return "", err
+	}
+	if len(containerList) == 0 {
+		return "", fmt.Errorf("no container found for service %s", service)
+	}
+	return containerList[0].ID, nil
+}
+
 func parseContainer(container dockertypes.ContainerJSON) dockerData {
 	dockerData := dockerData{
 		NetworkSettings: networkSettings{},
@@ -734,10 +2567,14 @@ func parseContainer(container dockertypes.ContainerJSON) dockerData {
 
 	if container.ContainerJSONBase != nil {
 		dockerData.Name = container.ContainerJSONBase.Name
+		dockerData.ContainerID = container.ContainerJSONBase.ID
 		dockerData.ServiceName = dockerData.Name //Default ServiceName to be the container's Name.
 
 		if container.ContainerJSONBase.HostConfig != nil {
 			dockerData.NetworkSettings.NetworkMode = container.ContainerJSONBase.HostConfig.NetworkMode
+			if dockerData.NetworkSettings.NetworkMode.IsContainer() {
+				dockerData.NetworkContainerID = dockerData.NetworkSettings.NetworkMode.ConnectedContainer()
+			}
 		}
 
 		if container.State != nil && container.State.Health != nil {
@@ -745,8 +2582,10 @@ func parseContainer(container dockertypes.ContainerJSON) dockerData {
 		}
 	}
 
-	if container.Config != nil && container.Config.Labels != nil {
+	if container.Config != nil {
 		dockerData.Labels = container.Config.Labels
+		dockerData.Image = container.Config.Image
+		dockerData.Env = parseEnv(container.Config.Env)
 	}
 
 	if container.NetworkSettings != nil {
@@ -756,10 +2595,15 @@ func parseContainer(container dockertypes.ContainerJSON) dockerData {
 		if container.NetworkSettings.Networks != nil {
 			dockerData.NetworkSettings.Networks = make(map[string]*networkData)
 			for name, containerNetwork := range container.NetworkSettings.Networks {
+				addr := containerNetwork.IPAddress
+				if addr == "" && containerNetwork.GlobalIPv6Address != "" {
+					// IPv6-only network
+					addr = containerNetwork.GlobalIPv6Address
+				}
 				dockerData.NetworkSettings.Networks[name] = &networkData{
 					ID:   containerNetwork.NetworkID,
 					Name: name,
-					Addr: containerNetwork.IPAddress,
+					Addr: addr,
 				}
 			}
 		}
@@ -786,7 +2630,7 @@ func (p *Provider) listServices(ctx context.Context, dockerClient client.APIClie
 
 	networkMap := make(map[string]*dockertypes.NetworkResource)
 	if err != nil {
-		log.Debug("Failed to network inspect on client for docker, error: %s", err)
+		log.Debugf("Failed to network inspect on client for docker, error: %s", err)
 		return []dockerData{}, err
 	}
 	for _, network := range networkList {
@@ -794,18 +2638,31 @@ func (p *Provider) listServices(ctx context.Context, dockerClient client.APIClie
 		networkMap[network.ID] = &networkToAdd
 	}
 
+	taskFilter, err := p.parseSwarmTaskFilter()
+	if err != nil {
+		log.Errorf("Unable to parse the docker swarm task filter, no task is excluded: %s", err)
+	}
+
 	var dockerDataList []dockerData
 	var dockerDataListTasks []dockerData
 
 	for _, service := range serviceList {
-		dockerData := parseService(service, networkMap)
-		useSwarmLB, _ := strconv.ParseBool(p.getIsBackendLBSwarm(dockerData))
+		dockerData, labelErrors := parseService(service, networkMap)
+		dockerData.LabelErrors = labelErrors
+		for _, labelErr := range p.getLabelErrors(dockerData) {
+			log.WithFields(dockerData.logFields()).Warnf("Invalid label value: %v", labelErr)
+		}
 		isGlobalSvc := service.Spec.Mode.Global != nil
 
-		if useSwarmLB {
+		if p.isBackendLBSwarm(p.withLabelPrefix(dockerData)) {
 			dockerDataList = append(dockerDataList, dockerData)
 		} else {
-			dockerDataListTasks, err = listTasks(ctx, dockerClient, service.ID, dockerData, networkMap, isGlobalSvc)
+			dockerDataListTasks, err = listTasks(ctx, dockerClient, service.ID, dockerData, networkMap, isGlobalSvc, p.TaskFilters, taskFilter, p.SwarmTaskWarmupSeconds)
+			if err != nil {
+				log.WithFields(dockerData.logFields()).Errorf("Failed to list the tasks of service, error: %s", err)
+			} else if p.drainer != nil {
+				dockerDataListTasks = p.drainer.update(service, dockerDataListTasks, time.Now())
+			}
 
 			for _, dockerDataTask := range dockerDataListTasks {
 				dockerDataList = append(dockerDataList, dockerDataTask)
@@ -816,18 +2673,33 @@ func (p *Provider) listServices(ctx context.Context, dockerClient client.APIClie
 
 }
 
-func parseService(service swarmtypes.Service, networkMap map[string]*dockertypes.NetworkResource) dockerData {
+// parseService returns the dockerData of the Swarm service, along with the errors of the conversion of
+// its label values, see parseLabelValues
+func parseService(service swarmtypes.Service, networkMap map[string]*dockertypes.NetworkResource) (dockerData, []error) {
 	dockerData := dockerData{
 		ServiceName:     service.Spec.Annotations.Name,
 		Name:            service.Spec.Annotations.Name,
 		Labels:          service.Spec.Annotations.Labels,
 		NetworkSettings: networkSettings{},
+		Image:           service.Spec.TaskTemplate.ContainerSpec.Image,
+		Env:             parseEnv(service.Spec.TaskTemplate.ContainerSpec.Env),
+		ServiceID:       service.ID,
+		PublishedPorts:  service.Endpoint.Ports,
+	}
+	for _, port := range service.Endpoint.Ports {
+		if dockerData.NetworkSettings.Ports == nil {
+			dockerData.NetworkSettings.Ports = nat.PortMap{}
+		}
+		dockerData.NetworkSettings.Ports[nat.Port(fmt.Sprintf("%d/%s", port.TargetPort, port.Protocol))] = nil
+	}
+	if service.Spec.Mode.Replicated != nil && service.Spec.Mode.Replicated.Replicas != nil {
+		dockerData.Replicas = *service.Spec.Mode.Replicated.Replicas
 	}
 
 	if service.Spec.EndpointSpec != nil {
 		switch service.Spec.EndpointSpec.Mode {
 		case swarm.ResolutionModeDNSRR:
-			log.Debug("Ignored endpoint-mode not supported, service name: %s", dockerData.Name)
+			log.WithFields(dockerData.logFields()).Debug("Ignored endpoint-mode not supported")
 		case swarm.ResolutionModeVIP:
 			dockerData.NetworkSettings.Networks = make(map[string]*networkData)
 			for _, virtualIP := range service.Endpoint.VirtualIPs {
@@ -841,19 +2713,85 @@ func parseService(service swarmtypes.Service, networkMap map[string]*dockertypes
 					}
 					dockerData.NetworkSettings.Networks[network.Name] = network
 				} else {
-					log.Debug("Network not found, id: %s", virtualIP.NetworkID)
+					log.WithFields(dockerData.logFields()).Debugf("Network not found, id: %s", virtualIP.NetworkID)
 				}
 			}
 		}
 	}
-	return dockerData
+	return dockerData, parseLabelValues(dockerData.Name, dockerData.Labels)
+}
+
+// parseLabelValues returns the errors of the port, integer and boolean labels whose value cannot be
+// converted, whatever their prefix. An invalid port is critical, leaving the service without backend
+// port, while the other labels are optional and have a default value.
+func parseLabelValues(name string, labels map[string]string) []error {
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		index := strings.Index(key, ".")
+		if index < 0 {
+			continue
+		}
+		value, label := labels[key], "traefik."+key[index+1:]
+		switch {
+		case label == "traefik.port":
+			if port, err := strconv.Atoi(value); err != nil || port <= 0 || port > 65535 {
+				errs = append(errs, &labelError{container: name, label: key, message: fmt.Sprintf("invalid port %q", value), critical: true})
+			}
+		case containsString(validatedIntLabels, label):
+			if _, err := strconv.Atoi(value); err != nil {
+				errs = append(errs, &labelError{container: name, label: key, message: fmt.Sprintf("invalid integer %q", value)})
+			}
+		case containsString(validatedBoolLabels, label):
+			if _, err := strconv.ParseBool(value); err != nil {
+				errs = append(errs, &labelError{container: name, label: key, message: fmt.Sprintf("invalid boolean %q", value)})
+			}
+		}
+	}
+	return errs
+}
+
+// getLabelErrors returns the label errors of the container concerning the labels read by the provider,
+// i.e. the labels of its LabelPrefix
+func (p *Provider) getLabelErrors(container dockerData) []*labelError {
+	prefix := "traefik."
+	if len(p.LabelPrefix) > 0 {
+		prefix = p.LabelPrefix + "."
+	}
+	var labelErrs []*labelError
+	for _, err := range container.LabelErrors {
+		if labelErr, ok := err.(*labelError); ok && strings.HasPrefix(labelErr.label, prefix) {
+			labelErrs = append(labelErrs, labelErr)
+		}
+	}
+	return labelErrs
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 func listTasks(ctx context.Context, dockerClient client.APIClient, serviceID string,
-	serviceDockerData dockerData, networkMap map[string]*dockertypes.NetworkResource, isGlobalSvc bool) ([]dockerData, error) {
+	serviceDockerData dockerData, networkMap map[string]*dockertypes.NetworkResource, isGlobalSvc bool, taskFilters map[string][]string,
+	taskFilter *template.Template, warmupSeconds int) ([]dockerData, error) {
 	serviceIDFilter := filters.NewArgs()
 	serviceIDFilter.Add("service", serviceID)
 	serviceIDFilter.Add("desired-state", "running")
+	for name, values := range taskFilters {
+		for _, value := range values {
+			serviceIDFilter.Add(name, value)
+		}
+	}
 	taskList, err := dockerClient.TaskList(ctx, dockertypes.TaskListOptions{Filter: serviceIDFilter})
 
 	if err != nil {
@@ -861,26 +2799,134 @@ func listTasks(ctx context.Context, dockerClient client.APIClient, serviceID str
 	}
 	var dockerDataList []dockerData
 
+	var nodeHostnames map[string]string
+	if isGlobalSvc {
+		var errNodes error
+		if nodeHostnames, errNodes = listNodeHostnames(ctx, dockerClient, taskList); errNodes != nil {
+			log.WithFields(serviceDockerData.logFields()).Debugf("Failed to list the Swarm nodes, naming the global tasks by ID: %s", errNodes)
+		}
+	}
+
+	now := time.Now()
 	for _, task := range taskList {
-		if task.Status.State != swarm.TaskStateRunning {
+		if !isTaskAvailable(task, warmupSeconds, now) || !isTaskIncluded(task, taskFilter) {
 			continue
 		}
-		dockerData := parseTasks(task, serviceDockerData, networkMap, isGlobalSvc)
+		dockerData := parseTasks(task, serviceDockerData, networkMap, isGlobalSvc, nodeHostnames)
 		dockerDataList = append(dockerDataList, dockerData)
 	}
-	return dockerDataList, err
+	return mergeSharedTasks(serviceDockerData, dockerDataList), err
+}
+
+// parseSwarmTaskFilter parses the SwarmTaskFilter template, returning nil if there is none
+func (p *Provider) parseSwarmTaskFilter() (*template.Template, error) {
+	if len(p.SwarmTaskFilter) == 0 {
+		return nil, nil
+	}
+	return template.New("swarmTaskFilter").Parse(p.SwarmTaskFilter)
+}
+
+// isTaskIncluded returns false if the task filter renders "false" for the task.
+// The tasks are kept when the filter cannot be executed.
+func isTaskIncluded(task swarmtypes.Task, taskFilter *template.Template) bool {
+	if taskFilter == nil {
+		return true
+	}
+	var buffer bytes.Buffer
+	if err := taskFilter.Execute(&buffer, task); err != nil {
+		log.Warnf("Unable to execute the docker swarm task filter on task %s, keeping it: %s", task.ID, err)
+		return true
+	}
+	return strings.TrimSpace(buffer.String()) != "false"
+}
+
+// mergeSharedTasks returns a single task when all the tasks of a service in VIP mode
+// resolve to the same addresses, to register a single server instead of duplicates.
+// The tasks of DNSRR services, having no VIP, are left untouched.
+func mergeSharedTasks(serviceDockerData dockerData, tasks []dockerData) []dockerData {
+	if serviceDockerData.NetworkSettings.Networks == nil || len(tasks) < 2 {
+		return tasks
+	}
+	addresses := taskAddresses(tasks[0])
+	if len(addresses) == 0 {
+		return tasks
+	}
+	for _, task := range tasks[1:] {
+		if taskAddresses(task) != addresses {
+			return tasks
+		}
+	}
+	log.WithFields(serviceDockerData.logFields()).Debugf("The %d tasks of service share the address %s, registering a single server", len(tasks), addresses)
+	merged := tasks[0]
+	merged.SharedTasks = len(tasks)
+	return []dockerData{merged}
+}
+
+// taskAddresses returns the sorted network addresses of a task
+func taskAddresses(task dockerData) string {
+	var addresses []string
+	for name, network := range task.NetworkSettings.Networks {
+		addresses = append(addresses, name+"="+network.Addr)
+	}
+	sort.Strings(addresses)
+	return strings.Join(addresses, ",")
+}
+
+// listNodeHostnames returns the hostnames of the nodes of the tasks by task ID
+func listNodeHostnames(ctx context.Context, dockerClient client.APIClient, taskList []swarmtypes.Task) (map[string]string, error) {
+	nodeList, err := dockerClient.NodeList(ctx, dockertypes.NodeListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	hostnames := make(map[string]string)
+	for _, node := range nodeList {
+		hostnames[node.ID] = node.Description.Hostname
+	}
+	nodeHostnames := make(map[string]string)
+	for _, task := range taskList {
+		if hostname := hostnames[task.NodeID]; hostname != "" {
+			nodeHostnames[task.ID] = hostname
+		}
+	}
+	return nodeHostnames, nil
 }
 
-func parseTasks(task swarmtypes.Task, serviceDockerData dockerData, networkMap map[string]*dockertypes.NetworkResource, isGlobalSvc bool) dockerData {
+// isTaskAvailable returns true if the task is running, or if it has been preparing or starting
+// for less than the warm-up period
+func isTaskAvailable(task swarmtypes.Task, warmupSeconds int, now time.Time) bool {
+	switch task.Status.State {
+	case swarm.TaskStateRunning:
+		return true
+	case swarm.TaskStatePreparing, swarm.TaskStateStarting:
+		return now.Sub(task.Status.Timestamp) < time.Duration(warmupSeconds)*time.Second
+	}
+	return false
+}
+
+// parseTasks returns the data of a task, the tasks of global services being named after the
+// hostname of their node if it is in nodeHostnames, followed by their ID which tells apart the
+// tasks of a node during a rolling update, and after their ID only otherwise
+func parseTasks(task swarmtypes.Task, serviceDockerData dockerData, networkMap map[string]*dockertypes.NetworkResource, isGlobalSvc bool, nodeHostnames map[string]string) dockerData {
 	dockerData := dockerData{
 		ServiceName:     serviceDockerData.Name,
 		Name:            serviceDockerData.Name + "." + strconv.Itoa(task.Slot),
 		Labels:          serviceDockerData.Labels,
 		NetworkSettings: networkSettings{},
+		Image:           serviceDockerData.Image,
+		Env:             serviceDockerData.Env,
+		Replicas:        serviceDockerData.Replicas,
+		ServiceID:       serviceDockerData.ServiceID,
+		TaskID:          task.ID,
+		PublishedPorts:  serviceDockerData.PublishedPorts,
+		LabelErrors:     serviceDockerData.LabelErrors,
 	}
+	dockerData.NetworkSettings.Ports = serviceDockerData.NetworkSettings.Ports
 
 	if isGlobalSvc == true {
 		dockerData.Name = serviceDockerData.Name + "." + task.ID
+		if hostname, ok := nodeHostnames[task.ID]; ok {
+			dockerData.Name = serviceDockerData.Name + "." + hostname + "." + task.ID
+		}
 	}
 
 	if task.NetworksAttachments != nil {
@@ -1,14 +1,24 @@
 package docker
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Sirupsen/logrus"
+	"github.com/containous/traefik/log"
 	"github.com/containous/traefik/types"
+	dockerclient "github.com/docker/engine-api/client"
 	docker "github.com/docker/engine-api/types"
+	eventtypes "github.com/docker/engine-api/types/events"
 	"github.com/docker/go-connections/nat"
+	"golang.org/x/net/context"
 )
 
 func TestDockerGetFrontendName(t *testing.T) {
@@ -26,6 +36,54 @@ func TestDockerGetFrontendName(t *testing.T) {
 			})),
 			expected: "Headers-User-Agent-bat-0-1-0",
 		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule": "Header:X-Version,v1,v2,v3",
+			})),
+			expected: "Header-X-Version-v1-v2-v3",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule": "MethodPrefix:GET,/api",
+			})),
+			expected: "MethodPrefix-GET-api",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule": "CookiePresent:session_id",
+			})),
+			expected: "CookiePresent-session-id",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule": "Probability:0.1",
+			})),
+			expected: "Probability-0-1",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule": "AcceptEncoding:gzip",
+			})),
+			expected: "AcceptEncoding-gzip",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule": "ContentType:application/json",
+			})),
+			expected: "ContentType-application-json",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule": "HTTPVersion:2",
+			})),
+			expected: "HTTPVersion-2",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule": "RemotePortRange:1024-65535",
+			})),
+			expected: "RemotePortRange-1024-65535",
+		},
 		{
 			container: containerJSON(labels(map[string]string{
 				"com.docker.compose.project": "foo",
@@ -100,6 +158,51 @@ func TestDockerGetFrontendRule(t *testing.T) {
 			})),
 			expected: "Path:/test",
 		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.frontend.rule": "Host:{{.Env.VIRTUAL_HOST}};PathPrefix:{{.Env.BASE_PATH}}",
+				}),
+				env("VIRTUAL_HOST=foo.example.com", "BASE_PATH=/api", "EMPTY")),
+			expected: "Host:foo.example.com;PathPrefix:/api",
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.frontend.rule": "Host:{{.Env.MISSING}}",
+				}),
+				env("VIRTUAL_HOST=foo.example.com")),
+			expected: "Host:{{.Env.MISSING}}",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule.host":       "foo.bar",
+				"traefik.frontend.rule.pathprefix": "/api",
+			})),
+			expected: "Host:foo.bar && PathPrefix:/api",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule.pathprefix": "/api",
+				"traefik.frontend.rule.path":       "/api/status",
+				"traefik.frontend.rule.host":       "foo.bar",
+			})),
+			expected: "Host:foo.bar && Path:/api/status && PathPrefix:/api",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule.path": "/test",
+			})),
+			expected: "Path:/test",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule":            "Host:foo.bar",
+				"traefik.frontend.rule.host":       "bar.foo",
+				"traefik.frontend.rule.pathprefix": "/api",
+			})),
+			expected: "Host:foo.bar",
+		},
 	}
 
 	for containerID, e := range containers {
@@ -110,7 +213,10 @@ func TestDockerGetFrontendRule(t *testing.T) {
 			provider := &Provider{
 				Domain: "docker.localhost",
 			}
-			actual := provider.getFrontendRule(dockerData)
+			actual, err := provider.getFrontendRule(dockerData)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
@@ -118,31 +224,27 @@ func TestDockerGetFrontendRule(t *testing.T) {
 	}
 }
 
-func TestDockerGetBackend(t *testing.T) {
+func TestDockerGetFrontendRuleWithoutDomain(t *testing.T) {
 	containers := []struct {
 		container docker.ContainerJSON
 		expected  string
 	}{
 		{
 			container: containerJSON(name("foo")),
-			expected:  "foo",
-		},
-		{
-			container: containerJSON(name("bar")),
-			expected:  "bar",
+			expected:  "PathPrefix:/foo",
 		},
 		{
 			container: containerJSON(labels(map[string]string{
-				"traefik.backend": "foobar",
+				"com.docker.compose.project": "foo",
+				"com.docker.compose.service": "bar",
 			})),
-			expected: "foobar",
+			expected: "PathPrefix:/bar.foo",
 		},
 		{
 			container: containerJSON(labels(map[string]string{
-				"com.docker.compose.project": "foo",
-				"com.docker.compose.service": "bar",
+				"traefik.frontend.rule": "Host:foo.bar",
 			})),
-			expected: "bar-foo",
+			expected: "Host:foo.bar",
 		},
 	}
 
@@ -152,7 +254,10 @@ func TestDockerGetBackend(t *testing.T) {
 			t.Parallel()
 			dockerData := parseContainer(e.container)
 			provider := &Provider{}
-			actual := provider.getBackend(dockerData)
+			actual, err := provider.getFrontendRule(dockerData)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
@@ -160,102 +265,192 @@ func TestDockerGetBackend(t *testing.T) {
 	}
 }
 
-func TestDockerGetIPAddress(t *testing.T) {
-	containers := []struct {
-		container docker.ContainerJSON
-		expected  string
+func TestValidateFrontendRule(t *testing.T) {
+	validRules := []string{
+		"Host:foo.bar",
+		"Host: foo.bar",
+		"Host:foo.bar,bar.foo",
+		"HostRegexp:{subdomain:[a-z]+}.foo.bar",
+		"PathPrefix:/api",
+		"PathPrefixStrip:/api;Host:foo.bar",
+		"Host:foo.bar && Path:/health",
+		"Method:GET,POST",
+		"Headers:Content-Type,application/json",
+		"Query:foo=bar",
+		"Host:foo.bar;",
+		"AddPrefix:/v1;PathPrefix:/",
+	}
+	for _, rule := range validRules {
+		if err := validateFrontendRule(rule); err != nil {
+			t.Errorf("%q: unexpected error %v", rule, err)
+		}
+	}
+
+	invalidRules := []string{
+		"",
+		"Host foo.bar",
+		"Host:foo.bar;Path /api",
+		"Host:",
+		":foo.bar",
+		"Host Name:foo.bar",
+		"Host:,",
+		";",
+	}
+	for _, rule := range invalidRules {
+		if err := validateFrontendRule(rule); err == nil {
+			t.Errorf("%q: expected an error", rule)
+		}
+	}
+}
+
+func TestDockerSanitiseFrontendName(t *testing.T) {
+	cases := []struct {
+		rule     string
+		expected string
 	}{
 		{
-			container: containerJSON(withNetwork("testnet", ipv4("10.11.12.13"))),
-			expected:  "10.11.12.13",
+			rule:     "Host:foo.bar",
+			expected: "Host-foo-bar",
 		},
 		{
-			container: containerJSON(
-				labels(map[string]string{
-					"traefik.docker.network": "testnet",
-				}),
-				withNetwork("testnet", ipv4("10.11.12.13")),
-			),
-			expected: "10.11.12.13",
+			rule:     "HostRegexp:{subdomain:[a-z]+}.example.com",
+			expected: "HostRegexp-subdomain-example-com",
 		},
 		{
-			container: containerJSON(
-				labels(map[string]string{
-					"traefik.docker.network": "testnet2",
-				}),
-				withNetwork("testnet", ipv4("10.11.12.13")),
-				withNetwork("testnet2", ipv4("10.11.12.14")),
-			),
-			expected: "10.11.12.14",
+			rule:     "HostRegexp:{subdomain}.example.com",
+			expected: "HostRegexp-subdomain-example-com",
 		},
 		{
-			container: containerJSON(
-				networkMode("host"),
-				withNetwork("testnet", ipv4("10.11.12.13")),
-				withNetwork("testnet2", ipv4("10.11.12.14")),
-			),
-			expected: "127.0.0.1",
+			rule:     "PathRegexp:/api/{version:v[0-9]{1,2}}/{id:[0-9]+}",
+			expected: "PathRegexp-api-version-id",
+		},
+		{
+			rule:     "PathPrefixStripRegex:/users/{id:[0-9]+}",
+			expected: "PathPrefixStripRegex-users-id",
+		},
+		{
+			rule:     "HostRegexp:{tenant:[a-z]+}.example.com;PathPrefix:/api",
+			expected: "HostRegexp-tenant-example-com-PathPrefix-api",
+		},
+		{
+			rule:     "Host:example.com&&PathPrefixRegex:/{lang:(en|fr)}/",
+			expected: "Host-example-com-PathPrefixRegex-lang",
+		},
+		{
+			rule:     "HostRegexp:{subdomain:[a-z]+.example.com",
+			expected: "HostRegexp-subdomain-a-z-example-com",
 		},
 	}
 
-	for containerID, e := range containers {
-		e := e
-		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseContainer(e.container)
-			provider := &Provider{}
-			actual := provider.getIPAddress(dockerData)
-			if actual != e.expected {
-				t.Errorf("expected %q, got %q", e.expected, actual)
+			if actual := sanitiseFrontendName(c.rule); actual != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, actual)
 			}
 		})
 	}
 }
 
-func TestDockerGetPort(t *testing.T) {
+func TestDockerGetNameTemplates(t *testing.T) {
+	cases := []struct {
+		container            docker.ContainerJSON
+		frontendNameTemplate string
+		backendNameTemplate  string
+		expectedFrontend     string
+		expectedBackend      string
+	}{
+		{
+			container:            containerJSON(name("foo")),
+			frontendNameTemplate: `{{.Name}}-web`,
+			backendNameTemplate:  `{{index .Labels "com.example.team"}}_{{.Name}}`,
+			expectedFrontend:     "foo-web",
+			expectedBackend:      "foo",
+		},
+		{
+			container: containerJSON(name("foo"), labels(map[string]string{
+				"com.example.team": "billing",
+			})),
+			frontendNameTemplate: `{{index .Labels "com.example.team"}}.{{.Name}}`,
+			backendNameTemplate:  `{{index .Labels "com.example.team"}}_{{.Name}}`,
+			expectedFrontend:     "billing-foo",
+			expectedBackend:      "billing-foo",
+		},
+		{
+			container: containerJSON(name("foo"), labels(map[string]string{
+				"com.example.team": "billing",
+				"traefik.backend":  "bar",
+			})),
+			backendNameTemplate: `{{index .Labels "com.example.team"}}`,
+			expectedFrontend:    "Host-foo-docker-localhost",
+			expectedBackend:     "bar",
+		},
+		{
+			container:            containerJSON(name("foo")),
+			frontendNameTemplate: `{{.Unknown}}`,
+			backendNameTemplate:  `{{index .Labels "com.example.team"}}`,
+			expectedFrontend:     "Host-foo-docker-localhost",
+			expectedBackend:      "foo",
+		},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(c.container)
+			provider := &Provider{
+				Domain:               "docker.localhost",
+				FrontendNameTemplate: c.frontendNameTemplate,
+				BackendNameTemplate:  c.backendNameTemplate,
+			}
+			if actual := provider.getFrontendName(dockerData); actual != c.expectedFrontend {
+				t.Errorf("expected frontend %q, got %q", c.expectedFrontend, actual)
+			}
+			if actual := provider.getBackend(dockerData); actual != c.expectedBackend {
+				t.Errorf("expected backend %q, got %q", c.expectedBackend, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetBackend(t *testing.T) {
 	containers := []struct {
 		container docker.ContainerJSON
 		expected  string
 	}{
 		{
 			container: containerJSON(name("foo")),
-			expected:  "",
+			expected:  "foo",
 		},
 		{
-			container: containerJSON(ports(nat.PortMap{
-				"80/tcp": {},
-			})),
-			expected: "80",
+			container: containerJSON(name("bar")),
+			expected:  "bar",
 		},
 		{
-			container: containerJSON(ports(nat.PortMap{
-				"80/tcp":  {},
-				"443/tcp": {},
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend": "foobar",
 			})),
-			expected: "80",
+			expected: "foobar",
 		},
 		{
 			container: containerJSON(labels(map[string]string{
-				"traefik.port": "8080",
+				"com.docker.compose.project": "foo",
+				"com.docker.compose.service": "bar",
 			})),
-			expected: "8080",
+			expected: "bar-foo",
 		},
 		{
-			container: containerJSON(labels(map[string]string{
-				"traefik.port": "8080",
-			}), ports(nat.PortMap{
-				"80/tcp": {},
-			})),
-			expected: "8080",
+			container: containerJSON(name("My_App")),
+			expected:  "my-app",
 		},
 		{
 			container: containerJSON(labels(map[string]string{
-				"traefik.port": "8080",
-			}), ports(nat.PortMap{
-				"8080/tcp": {},
-				"80/tcp":   {},
+				"com.docker.compose.project": "Shop",
+				"com.docker.compose.service": "Web.API",
 			})),
-			expected: "8080",
+			expected: "web-api-shop",
 		},
 	}
 
@@ -265,7 +460,7 @@ func TestDockerGetPort(t *testing.T) {
 			t.Parallel()
 			dockerData := parseContainer(e.container)
 			provider := &Provider{}
-			actual := provider.getPort(dockerData)
+			actual := provider.getBackend(dockerData)
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
@@ -273,20 +468,40 @@ func TestDockerGetPort(t *testing.T) {
 	}
 }
 
-func TestDockerGetWeight(t *testing.T) {
+func TestDockerGetBackendContainers(t *testing.T) {
 	containers := []struct {
 		container docker.ContainerJSON
-		expected  string
+		expected  []string
 	}{
 		{
-			container: containerJSON(),
-			expected:  "0",
+			container: containerJSON(name("foo")),
+			expected:  []string{"foo"},
 		},
 		{
 			container: containerJSON(labels(map[string]string{
-				"traefik.weight": "10",
+				"traefik.backend": "api1",
 			})),
-			expected: "10",
+			expected: []string{"api1"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend": "api1,api2",
+				"traefik.port":    "80",
+			})),
+			expected: []string{"api1", "api2"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend": " api1, ,api2,api1 ",
+				"traefik.port":    "80",
+			})),
+			expected: []string{"api1", "api2"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend": "api1,api2",
+			})),
+			expected: []string{"api1"},
 		},
 	}
 
@@ -294,63 +509,153 @@ func TestDockerGetWeight(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseContainer(e.container)
 			provider := &Provider{}
-			actual := provider.getWeight(dockerData)
-			if actual != e.expected {
+			var actual []string
+			for _, container := range provider.getBackendContainers(parseContainer(e.container)) {
+				actual = append(actual, provider.getBackend(container))
+			}
+			if !reflect.DeepEqual(actual, e.expected) {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
 		})
 	}
 }
 
-func TestDockerGetDomain(t *testing.T) {
-	containers := []struct {
-		container docker.ContainerJSON
-		expected  string
+func TestDockerNormalizeBackendName(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected string
 	}{
-		{
-			container: containerJSON(),
-			expected:  "docker.localhost",
-		},
-		{
-			container: containerJSON(labels(map[string]string{
-				"traefik.domain": "foo.bar",
-			})),
-			expected: "foo.bar",
-		},
+		{name: "my-app", expected: "my-app"},
+		{name: "MyApp", expected: "myapp"},
+		{name: "my_app", expected: "my-app"},
+		{name: "my.app.v2", expected: "my-app-v2"},
+		{name: "/team/my-app", expected: "team-my-app"},
+		{name: "my__app--_.v2", expected: "my-app-v2"},
+		{name: "--My App!--", expected: "my-app"},
 	}
 
-	for containerID, e := range containers {
-		e := e
-		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseContainer(e.container)
-			provider := &Provider{
-				Domain: "docker.localhost",
-			}
-			actual := provider.getDomain(dockerData)
-			if actual != e.expected {
-				t.Errorf("expected %q, got %q", e.expected, actual)
+			if actual := normalizeBackendName(c.name); actual != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, actual)
 			}
 		})
 	}
 }
 
-func TestDockerGetProtocol(t *testing.T) {
+func TestDockerGetIPAddress(t *testing.T) {
 	containers := []struct {
 		container docker.ContainerJSON
+		linked    map[string]docker.ContainerJSON
+		network   string
 		expected  string
 	}{
 		{
-			container: containerJSON(),
-			expected:  "http",
+			container: containerJSON(withNetwork("testnet", ipv4("10.11.12.13"))),
+			expected:  "10.11.12.13",
 		},
 		{
-			container: containerJSON(labels(map[string]string{
-				"traefik.protocol": "https",
-			})),
-			expected: "https",
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.docker.network": "testnet",
+				}),
+				withNetwork("testnet", ipv4("10.11.12.13")),
+			),
+			expected: "10.11.12.13",
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.docker.network": "testnet2",
+				}),
+				withNetwork("testnet", ipv4("10.11.12.13")),
+				withNetwork("testnet2", ipv4("10.11.12.14")),
+			),
+			expected: "10.11.12.14",
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.docker.network": "missingnet,testnet2",
+				}),
+				withNetwork("testnet", ipv4("10.11.12.13")),
+				withNetwork("testnet2", ipv4("10.11.12.14")),
+			),
+			expected: "10.11.12.14",
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.docker.network": "missingnet, othernet",
+				}),
+				withNetwork("testnet", ipv4("10.11.12.13")),
+			),
+			expected: "10.11.12.13",
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.docker.network": "testnet2",
+				}),
+				withNetwork("testnet", ipv4("10.11.12.13")),
+				withNetwork("testnet2", ipv4("10.11.12.14")),
+			),
+			network:  "testnet",
+			expected: "10.11.12.14",
+		},
+		{
+			container: containerJSON(
+				withNetwork("testnet", ipv4("10.11.12.13")),
+				withNetwork("testnet2", ipv4("10.11.12.14")),
+			),
+			network:  "testnet2",
+			expected: "10.11.12.14",
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.docker.network": "missingnet",
+				}),
+				withNetwork("testnet", ipv4("10.11.12.13")),
+				withNetwork("testnet2", ipv4("10.11.12.14")),
+			),
+			network:  "testnet",
+			expected: "10.11.12.13",
+		},
+		{
+			container: containerJSON(withNetwork("testnet", ipv4("10.11.12.13"))),
+			network:   "missingnet",
+			expected:  "10.11.12.13",
+		},
+		{
+			container: containerJSON(
+				networkMode("host"),
+				withNetwork("testnet", ipv4("10.11.12.13")),
+				withNetwork("testnet2", ipv4("10.11.12.14")),
+			),
+			expected: "127.0.0.1",
+		},
+		{
+			container: containerJSON(withNetwork("overlay6", ipv6("2001:db8::42"))),
+			expected:  "2001:db8::42",
+		},
+		{
+			container: containerJSON(networkMode("container:0123456789ab")),
+			linked: map[string]docker.ContainerJSON{
+				"0123456789ab": containerJSON(
+					name("vpn"),
+					withNetwork("testnet", ipv4("10.11.12.15")),
+				),
+			},
+			expected: "10.11.12.15",
+		},
+		{
+			container: containerJSON(networkMode("container:0123456789ab")),
+			linked:    map[string]docker.ContainerJSON{},
+			expected:  "",
 		},
 	}
 
@@ -359,8 +664,11 @@ func TestDockerGetProtocol(t *testing.T) {
 		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
 			t.Parallel()
 			dockerData := parseContainer(e.container)
-			provider := &Provider{}
-			actual := provider.getProtocol(dockerData)
+			if e.linked != nil {
+				dockerData = resolveSharedNetwork(context.Background(), &fakeContainersClient{containers: e.linked}, dockerData)
+			}
+			provider := &Provider{Network: e.network}
+			actual := provider.getIPAddress(dockerData)
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
@@ -368,20 +676,65 @@ func TestDockerGetProtocol(t *testing.T) {
 	}
 }
 
-func TestDockerGetPassHostHeader(t *testing.T) {
+func TestDockerGetPort(t *testing.T) {
 	containers := []struct {
 		container docker.ContainerJSON
 		expected  string
 	}{
 		{
-			container: containerJSON(),
-			expected:  "true",
+			container: containerJSON(name("foo")),
+			expected:  "",
+		},
+		{
+			container: containerJSON(ports(nat.PortMap{
+				"80/tcp": {},
+			})),
+			expected: "80",
+		},
+		{
+			container: containerJSON(ports(nat.PortMap{
+				"80/tcp":  {},
+				"443/tcp": {},
+			})),
+			expected: "80",
+		},
+		{
+			container: containerJSON(ports(nat.PortMap{
+				"8080/tcp": {},
+				"443/tcp":  {},
+				"80/tcp":   {},
+			})),
+			expected: "80",
+		},
+		{
+			container: containerJSON(ports(nat.PortMap{
+				"53/udp":   {},
+				"8080/tcp": {},
+			})),
+			expected: "8080",
 		},
 		{
 			container: containerJSON(labels(map[string]string{
-				"traefik.frontend.passHostHeader": "false",
+				"traefik.port": "8080",
 			})),
-			expected: "false",
+			expected: "8080",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.port": "8080",
+			}), ports(nat.PortMap{
+				"80/tcp": {},
+			})),
+			expected: "8080",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.port": "8080",
+			}), ports(nat.PortMap{
+				"8080/tcp": {},
+				"80/tcp":   {},
+			})),
+			expected: "8080",
 		},
 	}
 
@@ -391,7 +744,7 @@ func TestDockerGetPassHostHeader(t *testing.T) {
 			t.Parallel()
 			dockerData := parseContainer(e.container)
 			provider := &Provider{}
-			actual := provider.getPassHostHeader(dockerData)
+			actual := provider.getPort(dockerData)
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
@@ -399,72 +752,75 @@ func TestDockerGetPassHostHeader(t *testing.T) {
 	}
 }
 
-func TestDockerGetLabel(t *testing.T) {
+func TestDockerGetServerURL(t *testing.T) {
 	containers := []struct {
 		container docker.ContainerJSON
 		expected  string
 	}{
 		{
-			container: containerJSON(),
-			expected:  "Label not found:",
+			container: containerJSON(
+				name("foo"),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
+			),
+			expected: "http://10.11.12.13:80",
 		},
 		{
-			container: containerJSON(labels(map[string]string{
-				"foo": "bar",
-			})),
-			expected: "",
+			container: containerJSON(
+				name("foo"),
+				labels(map[string]string{
+					"traefik.protocol":                   "https",
+					"traefik.port":                       "8443",
+					"com.example.path":                   "api",
+					"traefik.backend.server.urlTemplate": `{{.Protocol}}://{{.IP}}:{{.Port}}/{{index .Labels "com.example.path"}}{{.Name}}`,
+				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
+			),
+			expected: "https://10.11.12.13:8443/apifoo",
 		},
-	}
-
-	for containerID, e := range containers {
-		e := e
-		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
-			t.Parallel()
-			dockerData := parseContainer(e.container)
-			label, err := getLabel(dockerData, "foo")
-			if e.expected != "" {
-				if err == nil || !strings.Contains(err.Error(), e.expected) {
-					t.Errorf("expected an error with %q, got %v", e.expected, err)
-				}
-			} else {
-				if label != "bar" {
-					t.Errorf("expected label 'bar', got %s", label)
-				}
-			}
-		})
-	}
-}
-
-func TestDockerGetLabels(t *testing.T) {
-	containers := []struct {
-		container      docker.ContainerJSON
-		expectedLabels map[string]string
-		expectedError  string
-	}{
 		{
-			container:      containerJSON(),
-			expectedLabels: map[string]string{},
-			expectedError:  "Label not found:",
+			container: containerJSON(
+				name("foo"),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+				withNetwork("overlay6", ipv6("2001:db8::42")),
+			),
+			expected: "http://[2001:db8::42]:80",
 		},
 		{
-			container: containerJSON(labels(map[string]string{
-				"foo": "fooz",
-			})),
-			expectedLabels: map[string]string{
-				"foo": "fooz",
-			},
-			expectedError: "Label not found: bar",
+			container: containerJSON(
+				name("foo"),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+				withNetwork("dualstack", ipv4("10.11.12.13"), ipv6("2001:db8::42")),
+			),
+			expected: "http://10.11.12.13:80",
 		},
 		{
-			container: containerJSON(labels(map[string]string{
-				"foo": "fooz",
-				"bar": "barz",
-			})),
-			expectedLabels: map[string]string{
-				"foo": "fooz",
-				"bar": "barz",
-			},
-			expectedError: "",
+			container: containerJSON(
+				name("foo"),
+				labels(map[string]string{
+					"traefik.port":                       "80",
+					"traefik.backend.server.urlTemplate": "http://{{.IP}:{{.Port}}",
+				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
+			),
+			expected: "http://10.11.12.13:80",
+		},
+		{
+			container: containerJSON(
+				name("foo"),
+				labels(map[string]string{
+					"traefik.port":                       "80",
+					"traefik.backend.server.urlTemplate": "http://{{.Host}}:{{.Port}}",
+				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
+			),
+			expected: "http://10.11.12.13:80",
 		},
 	}
 
@@ -473,142 +829,139 @@ func TestDockerGetLabels(t *testing.T) {
 		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
 			t.Parallel()
 			dockerData := parseContainer(e.container)
-			labels, err := getLabels(dockerData, []string{"foo", "bar"})
-			if !reflect.DeepEqual(labels, e.expectedLabels) {
-				t.Errorf("expect %v, got %v", e.expectedLabels, labels)
-			}
-			if e.expectedError != "" {
-				if err == nil || !strings.Contains(err.Error(), e.expectedError) {
-					t.Errorf("expected an error with %q, got %v", e.expectedError, err)
-				}
+			provider := &Provider{}
+			actual := provider.getServerURL(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
 		})
 	}
 }
 
-func TestDockerTraefikFilter(t *testing.T) {
+func TestDockerGetServerURLUseBindPortIP(t *testing.T) {
 	containers := []struct {
-		container        docker.ContainerJSON
-		exposedByDefault bool
-		expected         bool
+		container docker.ContainerJSON
+		expected  string
 	}{
 		{
-			container:        containerJSON(),
-			exposedByDefault: true,
-			expected:         false,
+			container: containerJSON(
+				name("foo"),
+				ports(nat.PortMap{
+					"80/tcp": {{HostIP: "1.2.3.4", HostPort: "8080"}},
+				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
+			),
+			expected: "http://1.2.3.4:8080",
 		},
 		{
 			container: containerJSON(
-				labels(map[string]string{
-					"traefik.enable": "false",
-				}),
+				name("foo"),
 				ports(nat.PortMap{
-					"80/tcp": {},
+					"80/tcp":  {{HostIP: "1.2.3.4", HostPort: "8080"}},
+					"443/tcp": {{HostIP: "1.2.3.4", HostPort: "8443"}},
 				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
 			),
-			exposedByDefault: true,
-			expected:         false,
+			expected: "http://1.2.3.4:8080",
 		},
 		{
 			container: containerJSON(
+				name("foo"),
 				labels(map[string]string{
-					"traefik.frontend.rule": "Host:foo.bar",
+					"traefik.port": "443",
 				}),
 				ports(nat.PortMap{
-					"80/tcp": {},
+					"80/tcp":  {{HostIP: "1.2.3.4", HostPort: "8080"}},
+					"443/tcp": {{HostIP: "5.6.7.8", HostPort: "8443"}},
 				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
 			),
-			exposedByDefault: true,
-			expected:         true,
+			expected: "http://5.6.7.8:8443",
 		},
 		{
 			container: containerJSON(
+				name("foo"),
 				ports(nat.PortMap{
-					"80/tcp":  {},
-					"443/tcp": {},
+					"80/tcp": {{HostIP: "0.0.0.0", HostPort: ""}, {HostIP: "1.2.3.4", HostPort: "8080"}},
 				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
 			),
-			exposedByDefault: true,
-			expected:         true,
+			expected: "http://1.2.3.4:8080",
 		},
 		{
 			container: containerJSON(
+				name("foo"),
 				ports(nat.PortMap{
-					"80/tcp": {},
+					"53/udp": {{HostIP: "1.2.3.4", HostPort: "5353"}},
 				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
 			),
-			exposedByDefault: true,
-			expected:         true,
+			expected: "http://1.2.3.4:5353",
 		},
 		{
 			container: containerJSON(
-				labels(map[string]string{
-					"traefik.port": "80",
-				}),
+				name("foo"),
 				ports(nat.PortMap{
-					"80/tcp":  {},
-					"443/tcp": {},
+					"80/tcp": {},
 				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
 			),
-			exposedByDefault: true,
-			expected:         true,
+			expected: "http://10.11.12.13:80",
 		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{UseBindPortIP: true}
+			actual := provider.getServerURL(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetServerURLChain(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  []string
+	}{
 		{
-			container: containerJSON(
-				labels(map[string]string{
-					"traefik.enable": "true",
-				}),
-				ports(nat.PortMap{
-					"80/tcp": {},
-				}),
-			),
-			exposedByDefault: true,
-			expected:         true,
+			container: containerJSON(name("foo")),
+			expected:  nil,
 		},
 		{
-			container: containerJSON(
-				labels(map[string]string{
-					"traefik.enable": "anything",
-				}),
-				ports(nat.PortMap{
-					"80/tcp": {},
-				}),
-			),
-			exposedByDefault: true,
-			expected:         true,
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.urlChain": "http://primary:8080",
+			})),
+			expected: []string{"http://primary:8080"},
 		},
 		{
-			container: containerJSON(
-				labels(map[string]string{
-					"traefik.frontend.rule": "Host:foo.bar",
-				}),
-				ports(nat.PortMap{
-					"80/tcp": {},
-				}),
-			),
-			exposedByDefault: true,
-			expected:         true,
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.urlChain": "http://primary:8080; http://fallback:8080;",
+			})),
+			expected: []string{"http://primary:8080", "http://fallback:8080"},
 		},
 		{
-			container: containerJSON(
-				ports(nat.PortMap{
-					"80/tcp": {},
-				}),
-			),
-			exposedByDefault: false,
-			expected:         false,
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.urlChain": "http://primary:8080;http://%zz",
+			})),
+			expected: nil,
 		},
 		{
-			container: containerJSON(
-				labels(map[string]string{
-					"traefik.enable": "true",
-				}),
-				ports(nat.PortMap{
-					"80/tcp": {},
-				}),
-			),
-			exposedByDefault: false,
-			expected:         true,
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.urlChain": "http://primary:8080;fallback:8080",
+			})),
+			expected: nil,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.urlChain": "http://primary:8080;ftp://fallback",
+			})),
+			expected: nil,
 		},
 	}
 
@@ -616,32 +969,2973 @@ func TestDockerTraefikFilter(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
 			t.Parallel()
-			provider := Provider{}
-			provider.ExposedByDefault = e.exposedByDefault
 			dockerData := parseContainer(e.container)
-			actual := provider.containerFilter(dockerData)
+			provider := &Provider{}
+			actual := provider.getServerURLChain(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetConfigErrorsServerURLChain(t *testing.T) {
+	provider := &Provider{ExposedByDefault: true}
+	container := parseContainer(containerJSON(
+		name("test"),
+		labels(map[string]string{
+			"traefik.port":                    "80",
+			"traefik.backend.server.urlChain": "http://primary:8080;fallback:8080",
+		}),
+		withNetwork("bridge", ipv4("127.0.0.1")),
+	))
+
+	errors := provider.getConfigErrors(provider.filterContainers([]dockerData{container}))
+	if len(errors) != 1 || errors[0].Source != "test" || !strings.Contains(errors[0].Message, "urlChain") {
+		t.Errorf("expected an invalid traefik.backend.server.urlChain config error, got %v", errors)
+	}
+}
+
+func TestDockerGetServers(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  []types.Server
+	}{
+		{
+			container: containerJSON(name("foo")),
+			expected:  nil,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.weight":              "5",
+				"traefik.backend.server.urls": `["http://10.0.0.1:8080","http://10.0.0.1:8081"]`,
+			})),
+			expected: []types.Server{
+				{URL: "http://10.0.0.1:8080", Weight: 5},
+				{URL: "http://10.0.0.1:8081", Weight: 5},
+			},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.urls":    `["http://10.0.0.1:8080","http://10.0.0.1:8081"]`,
+				"traefik.backend.server.weights": `[3, 1]`,
+			})),
+			expected: []types.Server{
+				{URL: "http://10.0.0.1:8080", Weight: 3},
+				{URL: "http://10.0.0.1:8081", Weight: 1},
+			},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.urls":    `["http://10.0.0.1:8080","http://10.0.0.1:8081"]`,
+				"traefik.backend.server.weights": `[3]`,
+			})),
+			expected: []types.Server{
+				{URL: "http://10.0.0.1:8080", Weight: 0},
+				{URL: "http://10.0.0.1:8081", Weight: 0},
+			},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.urls": `http://10.0.0.1:8080`,
+			})),
+			expected: nil,
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getServers(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %+v, got %+v", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetWeight(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "0",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.weight": "10",
+			})),
+			expected: "10",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getWeight(dockerData)
 			if actual != e.expected {
-				t.Errorf("expected %v for %+v (%+v, %+v), got %+v", e.expected, e.container, e.container.NetworkSettings, e.container.ContainerJSONBase, actual)
+				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
 		})
 	}
 }
 
-func TestDockerLoadDockerConfig(t *testing.T) {
-	cases := []struct {
-		containers        []docker.ContainerJSON
-		expectedFrontends map[string]*types.Frontend
-		expectedBackends  map[string]*types.Backend
+func TestDockerGetDomain(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
 	}{
 		{
-			containers:        []docker.ContainerJSON{},
-			expectedFrontends: map[string]*types.Frontend{},
-			expectedBackends:  map[string]*types.Backend{},
+			container: containerJSON(),
+			expected:  "docker.localhost",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.domain": "foo.bar",
+			})),
+			expected: "foo.bar",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{
+				Domain: "docker.localhost",
+			}
+			actual := provider.getDomain(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetProtocol(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "http",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.protocol": "https",
+			})),
+			expected: "https",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getProtocol(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetPassHostHeader(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "true",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.passHostHeader": "false",
+			})),
+			expected: "false",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getPassHostHeader(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetCaseInsensitive(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "false",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule.caseInsensitive": "true",
+			})),
+			expected: "true",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getCaseInsensitive(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetPriority(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "0",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.priority": "10",
+			})),
+			expected: "10",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.priority": "ten",
+			})),
+			expected: "0",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getPriority(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetForwardAuth(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  *types.ForwardAuth
+	}{
+		{
+			container: containerJSON(),
+			expected:  nil,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.auth.forward.authResponseHeaders": "X-Auth-User",
+			})),
+			expected: nil,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.auth.forward.address": "http://auth:9000/verify",
+			})),
+			expected: &types.ForwardAuth{Address: "http://auth:9000/verify"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.auth.forward.address":             "http://auth:9000/verify",
+				"traefik.frontend.auth.forward.trustForwardHeader":  "true",
+				"traefik.frontend.auth.forward.authResponseHeaders": "X-Auth-User,X-Auth-Role",
+			})),
+			expected: &types.ForwardAuth{
+				Address:             "http://auth:9000/verify",
+				TrustForwardHeader:  true,
+				AuthResponseHeaders: []string{"X-Auth-User", "X-Auth-Role"},
+			},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.auth.forward.address":             "http://auth:9000/verify",
+				"traefik.frontend.auth.forward.trustForwardHeader":  "false",
+				"traefik.frontend.auth.forward.authResponseHeaders": " , ",
+			})),
+			expected: &types.ForwardAuth{Address: "http://auth:9000/verify"},
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getForwardAuth(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %+v, got %+v", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetBackendStickiness(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  backendStickiness
+	}{
+		{
+			container: containerJSON(),
+			expected:  backendStickiness{},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.loadbalancer.sticky": "true",
+			})),
+			expected: backendStickiness{Sticky: true},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.loadbalancer.sticky":            "true",
+				"traefik.backend.loadbalancer.sticky.cookieName": "_app_session",
+			})),
+			expected: backendStickiness{Sticky: true, CookieName: "_app_session"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.loadbalancer.sticky.cookieName": "_app_session",
+			})),
+			expected: backendStickiness{Sticky: true, CookieName: "_app_session"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.loadbalancer.sticky":            "false",
+				"traefik.backend.loadbalancer.sticky.cookieName": "_app_session",
+			})),
+			expected: backendStickiness{CookieName: "_app_session"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.loadbalancer.stickiness.cookieName": "_app_session",
+			})),
+			expected: backendStickiness{Sticky: true, CookieName: "_app_session"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.loadbalancer.sticky.cookieName":     "_api_session",
+				"traefik.backend.loadbalancer.stickiness.cookieName": "_app_session",
+			})),
+			expected: backendStickiness{Sticky: true, CookieName: "_api_session"},
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			provider := &Provider{}
+			actual := provider.getBackendStickiness("backend", []dockerData{parseContainer(e.container)})
+			if actual != e.expected {
+				t.Errorf("expected %+v, got %+v", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetMaxConnExtractorFunc(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "request.host",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.maxconn.extractorfunc": "client.ip",
+			})),
+			expected: "client.ip",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.maxconn.extractorfunc": "request.header.X-Real-Ip",
+			})),
+			expected: "request.header.X-Real-Ip",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.maxconn.extractorfunc": "request.ip",
+			})),
+			expected: "request.ip",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.maxconn.extractorfunc": "",
+			})),
+			expected: "request.host",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.loadbalancer.method": "ip_hash",
+			})),
+			expected: "client.ip",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.loadbalancer.method":   "ip_hash",
+				"traefik.backend.maxconn.extractorfunc": "request.header.X-Real-Ip",
+			})),
+			expected: "request.header.X-Real-Ip",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getMaxConnExtractorFunc(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsKnownExtractorFunc(t *testing.T) {
+	known := []string{"client.ip", "request.host", "request.header.X-Real-Ip"}
+	for _, extractorFunc := range known {
+		if !isKnownExtractorFunc(extractorFunc) {
+			t.Errorf("%q: expected a known extractor function", extractorFunc)
+		}
+	}
+	unknown := []string{"", "somethingelse", "request.header.", "client.IP"}
+	for _, extractorFunc := range unknown {
+		if isKnownExtractorFunc(extractorFunc) {
+			t.Errorf("%q: expected an unknown extractor function", extractorFunc)
+		}
+	}
+}
+
+func TestDockerGetEntryPoints(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  []string
+	}{
+		{
+			container: containerJSON(),
+			expected:  []string{},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.entryPoints": "http,https",
+			})),
+			expected: []string{"http", "https"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.entryPoints": "http, https",
+			})),
+			expected: []string{"http", "https"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.entryPoints": " http , https ",
+			})),
+			expected: []string{"http", "https"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.entryPoints": "http\thttps",
+			})),
+			expected: []string{"http", "https"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.entryPoints": "http,\thttps,",
+			})),
+			expected: []string{"http", "https"},
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.entryPoints": "",
+			})),
+			expected: []string{},
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getEntryPoints(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetWhitelistSourceRange(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  []string
+	}{
+		{
+			container: containerJSON(),
+			expected:  nil,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.whitelistSourceRange": "192.168.1.0/24, 10.0.0.0/8",
+			})),
+			expected: []string{"192.168.1.0/24", "10.0.0.0/8"},
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getWhitelistSourceRange(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetRateLimits(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  map[string]*types.Rate
+		valid     bool
+	}{
+		{
+			container: containerJSON(),
+			expected:  map[string]*types.Rate{},
+			valid:     true,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.ratelimit.extractorfunc":           "client.ip",
+				"traefik.frontend.ratelimit.rateset.default.period":  "10s",
+				"traefik.frontend.ratelimit.rateset.default.average": "100",
+			})),
+			expected: map[string]*types.Rate{
+				"default": {Period: "10s", Average: 100},
+			},
+			valid: true,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.ratelimit.extractorfunc":            "request.host",
+				"traefik.frontend.ratelimit.rateset.burst.period":     "1s",
+				"traefik.frontend.ratelimit.rateset.burst.average":    "5",
+				"traefik.frontend.ratelimit.rateset.api.hour.period":  "1h",
+				"traefik.frontend.ratelimit.rateset.api.hour.average": "1000",
+			})),
+			expected: map[string]*types.Rate{
+				"burst":    {Period: "1s", Average: 5},
+				"api.hour": {Period: "1h", Average: 1000},
+			},
+			valid: true,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.ratelimit.extractorfunc":           "client.ip",
+				"traefik.frontend.ratelimit.rateset.default.period":  "0s",
+				"traefik.frontend.ratelimit.rateset.default.average": "100",
+			})),
+			valid: false,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.ratelimit.extractorfunc":          "client.ip",
+				"traefik.frontend.ratelimit.rateset.default.period": "10s",
+			})),
+			valid: false,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.ratelimit.rateset.default.period":  "10s",
+				"traefik.frontend.ratelimit.rateset.default.average": "100",
+			})),
+			valid: false,
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual, err := parseRateLimits(dockerData)
+			if !e.valid {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %+v, got %+v", e.expected, actual)
+			}
+			if provider.hasRateLimitLabels(dockerData) != (len(e.expected) > 0) {
+				t.Errorf("expected hasRateLimitLabels to be %t", len(e.expected) > 0)
+			}
+		})
+	}
+}
+
+func TestDockerGetBufferingBytes(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  int64
+	}{
+		{
+			container: containerJSON(),
+			expected:  0,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.buffering.maxRequestBodyBytes": "1048576",
+			})),
+			expected: 1048576,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.buffering.maxRequestBodyBytes": "10MB",
+			})),
+			expected: 0,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.buffering.maxRequestBodyBytes": "-1",
+			})),
+			expected: 0,
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getMaxRequestBodyBytes(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %d, got %d", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetResponseForwardingFlushInterval(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.responseForwarding.flushInterval": "100ms",
+			})),
+			expected: "100ms",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.responseForwarding.flushInterval": "1s",
+			})),
+			expected: "1s",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.responseForwarding.flushInterval": "garbage",
+			})),
+			expected: "",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getResponseForwardingFlushInterval(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetBackendTLS(t *testing.T) {
+	containers := []struct {
+		container        docker.ContainerJSON
+		expected         *types.ClientTLS
+		expectedProtocol string
+	}{
+		{
+			container:        containerJSON(),
+			expected:         nil,
+			expectedProtocol: "http",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.tls": "true",
+			})),
+			expected:         &types.ClientTLS{},
+			expectedProtocol: "https",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.protocol":                       "https",
+				"traefik.backend.tls.insecureSkipVerify": "true",
+			})),
+			expected:         &types.ClientTLS{InsecureSkipVerify: true},
+			expectedProtocol: "https",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.tls":    "true",
+				"traefik.backend.tls.ca": "/certs/ca.pem",
+			})),
+			expected:         &types.ClientTLS{CA: "/certs/ca.pem"},
+			expectedProtocol: "https",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.tls":    "false",
+				"traefik.backend.tls.ca": "/certs/ca.pem",
+			})),
+			expected:         nil,
+			expectedProtocol: "http",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.tls": "yes please",
+			})),
+			expected:         nil,
+			expectedProtocol: "http",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getBackendTLS(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %+v, got %+v", e.expected, actual)
+			}
+			if protocol := provider.getProtocol(dockerData); protocol != e.expectedProtocol {
+				t.Errorf("expected protocol %q, got %q", e.expectedProtocol, protocol)
+			}
+		})
+	}
+}
+
+func TestDockerGetForwardCaptures(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "false",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule.forwardCaptures": "true",
+			})),
+			expected: "true",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getForwardCaptures(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetPassTLSCert(t *testing.T) {
+	containers := []struct {
+		container    docker.ContainerJSON
+		expectedInfo bool
+		expectedPEM  bool
+	}{
+		{
+			container: containerJSON(),
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.passTLSCert": "true",
+			})),
+			expectedInfo: true,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.passTLSCert.pem": "1",
+			})),
+			expectedPEM: true,
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.passTLSCert":     "false",
+				"traefik.frontend.passTLSCert.pem": "0",
+			})),
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.passTLSCert":     "yes",
+				"traefik.frontend.passTLSCert.pem": "true",
+			})),
+			expectedPEM: true,
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			if actual := provider.getPassTLSCert(dockerData); actual != e.expectedInfo {
+				t.Errorf("expected passTLSCert %t, got %t", e.expectedInfo, actual)
+			}
+			if actual := provider.getPassTLSCertPEM(dockerData); actual != e.expectedPEM {
+				t.Errorf("expected passTLSCert.pem %t, got %t", e.expectedPEM, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetCircuitBreakerExpression(t *testing.T) {
+	containers := []struct {
+		container   docker.ContainerJSON
+		expectedHas bool
+		expected    string
+	}{
+		{
+			container:   containerJSON(),
+			expectedHas: false,
+			expected:    "NetworkErrorRatio() > 1",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.circuitbreaker.expression": "NetworkErrorRatio() > 0.5",
+			})),
+			expectedHas: true,
+			expected:    "NetworkErrorRatio() > 0.5",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.circuitbreaker.responseCode": "500,502-503",
+			})),
+			expectedHas: true,
+			expected:    "ResponseCodeRatio(500, 501, 0, 600) > 0.5 || ResponseCodeRatio(502, 504, 0, 600) > 0.5",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.circuitbreaker.expression":   "NetworkErrorRatio() > 0.5",
+				"traefik.backend.circuitbreaker.responseCode": "500,502,503",
+			})),
+			expectedHas: true,
+			expected:    "NetworkErrorRatio() > 0.5",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.circuitbreaker.responseCode": "5xx",
+			})),
+			expectedHas: false,
+			expected:    "NetworkErrorRatio() > 1",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.circuitbreaker.expression": "NetworkErrorRatio() > 0.5 ||",
+			})),
+			expectedHas: false,
+			expected:    "NetworkErrorRatio() > 0.5 ||",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.circuitbreaker.expression": "NetworkErrorRatioX() > 0.5",
+			})),
+			expectedHas: false,
+			expected:    "NetworkErrorRatioX() > 0.5",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.circuitbreaker.expression": "ResponseCodeRatio() > 0.5",
+			})),
+			expectedHas: false,
+			expected:    "ResponseCodeRatio() > 0.5",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.circuitbreaker.expression":       "ResponseCodeRatio() > 0.5",
+				"traefik.backend.circuitbreaker.statusCodeRanges": "500-599",
+			})),
+			expectedHas: true,
+			expected:    "ResponseCodeRatio() > 0.5",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			if has := provider.hasCircuitBreakerLabel(dockerData); has != e.expectedHas {
+				t.Errorf("expected %t, got %t", e.expectedHas, has)
+			}
+			actual := provider.getCircuitBreakerExpression(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetDisableKeepAlives(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "false",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.keepalive": "true",
+			})),
+			expected: "false",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.keepalive": "false",
+			})),
+			expected: "true",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.backend.server.keepalive": "anything",
+			})),
+			expected: "false",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getDisableKeepAlives(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetRequestIDHeader(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "X-Request-ID",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.headers.requestIDHeader": "X-Correlation-ID",
+			})),
+			expected: "X-Correlation-ID",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getRequestIDHeader(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetSeed(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "0",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule.seed": "42",
+			})),
+			expected: "42",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.rule.seed": "random",
+			})),
+			expected: "0",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getSeed(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetRedirect(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.redirect.entryPoint": "https",
+			})),
+			expected: "https",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.redirect": "https",
+			})),
+			expected: "https",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.redirect":            "https",
+				"traefik.frontend.redirect.entryPoint": "secure",
+			})),
+			expected: "secure",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			provider := &Provider{}
+			actual := provider.getRedirect(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerGetLabel(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  string
+	}{
+		{
+			container: containerJSON(),
+			expected:  "Label not found:",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"foo": "bar",
+			})),
+			expected: "",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			label, err := getLabel(dockerData, "foo")
+			if e.expected != "" {
+				if err == nil || !strings.Contains(err.Error(), e.expected) {
+					t.Errorf("expected an error with %q, got %v", e.expected, err)
+				}
+			} else {
+				if label != "bar" {
+					t.Errorf("expected label 'bar', got %s", label)
+				}
+			}
+		})
+	}
+}
+
+func TestDockerGetLabelCaseInsensitive(t *testing.T) {
+	cases := []struct {
+		labels                map[string]string
+		caseInsensitiveLabels bool
+		expected              string
+		expectedFound         bool
+	}{
+		{
+			labels:        map[string]string{"traefik.Port": "8080"},
+			expectedFound: false,
+		},
+		{
+			labels:                map[string]string{"traefik.Port": "8080"},
+			caseInsensitiveLabels: true,
+			expected:              "8080",
+			expectedFound:         true,
+		},
+		{
+			labels:                map[string]string{"TRAEFIK.PORT": "8080"},
+			caseInsensitiveLabels: true,
+			expected:              "8080",
+			expectedFound:         true,
+		},
+		{
+			labels:                map[string]string{"traefik.Port": "8080", "traefik.port": "80"},
+			caseInsensitiveLabels: true,
+			expected:              "80",
+			expectedFound:         true,
+		},
+		{
+			labels:                map[string]string{"traefik.portal": "8080"},
+			caseInsensitiveLabels: true,
+			expectedFound:         false,
+		},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			t.Parallel()
+			provider := &Provider{CaseInsensitiveLabels: c.caseInsensitiveLabels}
+			dockerData := provider.withLabelPrefix(parseContainer(containerJSON(labels(c.labels))))
+			label, err := getLabel(dockerData, "traefik.port")
+			if (err == nil) != c.expectedFound {
+				t.Fatalf("expected found %t, got error %v", c.expectedFound, err)
+			}
+			if label != c.expected {
+				t.Errorf("expected label %q, got %q", c.expected, label)
+			}
+		})
+	}
+}
+
+func TestDockerGetPortCaseInsensitiveLabels(t *testing.T) {
+	provider := &Provider{CaseInsensitiveLabels: true}
+	container := containerJSON(
+		name("test"),
+		labels(map[string]string{
+			"traefik.Port": "8080",
+		}),
+		ports(nat.PortMap{
+			"80/tcp":   {},
+			"8080/tcp": {},
+		}),
+		withNetwork("bridge", ipv4("127.0.0.1")),
+	)
+	dockerDataList := provider.applyLabelPrefix([]dockerData{parseContainer(container)})
+	if actual := provider.getPort(dockerDataList[0]); actual != "8080" {
+		t.Errorf("expected port %q, got %q", "8080", actual)
+	}
+}
+
+func TestDockerFilterReasonCaseInsensitiveLabels(t *testing.T) {
+	cases := []struct {
+		desc     string
+		provider *Provider
+		labels   map[string]string
+		expected string
+	}{
+		{
+			desc:     "port label in Swarm mode",
+			provider: &Provider{CaseInsensitiveLabels: true, SwarmMode: true, ExposedByDefault: true},
+			labels:   map[string]string{"traefik.Port": "8080"},
+			expected: "",
+		},
+		{
+			desc:     "enable label",
+			provider: &Provider{CaseInsensitiveLabels: true, ExposedByDefault: true},
+			labels:   map[string]string{"traefik.port": "8080", "traefik.Enable": "false"},
+			expected: filterReasonDisabled,
+		},
+		{
+			desc:     "enable label without case-insensitive labels",
+			provider: &Provider{ExposedByDefault: true},
+			labels:   map[string]string{"traefik.port": "8080", "traefik.Enable": "false"},
+			expected: "",
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			container := containerJSON(name("test"), labels(c.labels), withNetwork("bridge", ipv4("127.0.0.1")))
+			dockerDataList := c.provider.applyLabelPrefix([]dockerData{parseContainer(container)})
+			if actual := c.provider.filterReason(dockerDataList[0]); actual != c.expected {
+				t.Errorf("expected filter reason %q, got %q", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerCaseInsensitivePrefixedLabels(t *testing.T) {
+	provider := &Provider{CaseInsensitiveLabels: true, ExposedByDefault: true}
+	container := containerJSON(name("test"), labels(map[string]string{
+		"traefik.port": "8080",
+		"traefik.frontend.rateLimit.extractorFunc":       "client.ip",
+		"traefik.frontend.rateLimit.rateSet.api.Period":  "10s",
+		"traefik.frontend.rateLimit.rateSet.api.Average": "100",
+		"traefik.Frontend.Headers.customResponseHeaders": "X-Served-By:traefik",
+		"traefik.Backend.Buffering.maxRequestBodyBytes":  "1024",
+		"traefik.Web.Port":                 "80",
+		"traefik.Web.Frontend.EntryPoints": "https",
+	}), withNetwork("bridge", ipv4("127.0.0.1")))
+	data := provider.applyLabelPrefix([]dockerData{parseContainer(container)})[0]
+
+	if !provider.hasBufferingLabels(data) {
+		t.Error("expected buffering labels")
+	}
+	if headers := provider.getHeaders(data); headers == nil || headers.CustomResponseHeaders["X-Served-By"] != "traefik" {
+		t.Errorf("expected custom response headers, got %+v", headers)
+	}
+	if rates := provider.getRateLimits(data); len(rates) != 1 || rates["api"] == nil || rates["api"].Average != 100 {
+		t.Errorf("expected the api rate set, got %v", rates)
+	}
+	if !provider.hasServices(data) {
+		t.Fatal("expected services")
+	}
+	if entryPoints := provider.getServiceEntryPoints(data, "web"); !reflect.DeepEqual(entryPoints, []string{"https"}) {
+		t.Errorf("expected service entry points [https], got %v", entryPoints)
+	}
+
+	ruleContainer := containerJSON(name("rules"), labels(map[string]string{
+		"traefik.port":            "8080",
+		"traefik.Frontend.Rule.0": "Host:a.example.com",
+		"traefik.Frontend.Rule.1": "Host:b.example.com",
+	}), withNetwork("bridge", ipv4("127.0.0.1")))
+	data = provider.applyLabelPrefix([]dockerData{parseContainer(ruleContainer)})[0]
+	var rules []string
+	for _, frontend := range provider.getRuleFrontends(data) {
+		rule, _ := provider.getFrontendRule(frontend)
+		rules = append(rules, rule)
+	}
+	if expected := []string{"Host:a.example.com", "Host:b.example.com"}; !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected rules %v, got %v", expected, rules)
+	}
+}
+
+func TestDockerGetLabels(t *testing.T) {
+	containers := []struct {
+		container      docker.ContainerJSON
+		expectedLabels map[string]string
+		expectedError  string
+	}{
+		{
+			container:      containerJSON(),
+			expectedLabels: map[string]string{},
+			expectedError:  "Label not found:",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"foo": "fooz",
+			})),
+			expectedLabels: map[string]string{
+				"foo": "fooz",
+			},
+			expectedError: "Label not found: bar",
+		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"foo": "fooz",
+				"bar": "barz",
+			})),
+			expectedLabels: map[string]string{
+				"foo": "fooz",
+				"bar": "barz",
+			},
+			expectedError: "",
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(e.container)
+			labels, err := getLabels(dockerData, []string{"foo", "bar"})
+			if !reflect.DeepEqual(labels, e.expectedLabels) {
+				t.Errorf("expect %v, got %v", e.expectedLabels, labels)
+			}
+			if e.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), e.expectedError) {
+					t.Errorf("expected an error with %q, got %v", e.expectedError, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDockerTraefikFilter(t *testing.T) {
+	containers := []struct {
+		container        docker.ContainerJSON
+		exposedByDefault bool
+		useBindPortIP    bool
+		expected         bool
+	}{
+		{
+			container:        containerJSON(),
+			exposedByDefault: true,
+			expected:         false,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.enable": "false",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         false,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.frontend.rule": "Host:foo.bar",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         true,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.frontend.rule": "Host foo.bar",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         false,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.frontend.rule.1": "Host:foo.bar",
+					"traefik.frontend.rule.2": "Path/api",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         false,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.frontend.whitelistSourceRange": "192.168.1.0/24,10.0.0.300/8",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         false,
+		},
+		{
+			container: containerJSON(
+				ports(nat.PortMap{
+					"80/tcp":  {},
+					"443/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         true,
+		},
+		{
+			container: containerJSON(
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         true,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.port": "80",
+				}),
+				ports(nat.PortMap{
+					"80/tcp":  {},
+					"443/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         true,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.enable": "true",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         true,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.enable": "anything",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         true,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.frontend.rule": "Host:foo.bar",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			expected:         true,
+		},
+		{
+			container: containerJSON(
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: false,
+			expected:         false,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.enable": "true",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: false,
+			expected:         true,
+		},
+		{
+			container: containerJSON(
+				ports(nat.PortMap{
+					"80/tcp": {{HostIP: "1.2.3.4", HostPort: "8080"}},
+				}),
+			),
+			exposedByDefault: true,
+			useBindPortIP:    true,
+			expected:         true,
+		},
+		{
+			container: containerJSON(
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			useBindPortIP:    true,
+			expected:         false,
+		},
+		{
+			container: containerJSON(
+				labels(map[string]string{
+					"traefik.port": "443",
+				}),
+				ports(nat.PortMap{
+					"80/tcp":  {{HostIP: "1.2.3.4", HostPort: "8080"}},
+					"443/tcp": {},
+				}),
+			),
+			exposedByDefault: true,
+			useBindPortIP:    true,
+			expected:         false,
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			provider := Provider{}
+			provider.ExposedByDefault = e.exposedByDefault
+			provider.UseBindPortIP = e.useBindPortIP
+			dockerData := parseContainer(e.container)
+			actual := provider.containerFilter(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %v for %+v (%+v, %+v), got %+v", e.expected, e.container, e.container.NetworkSettings, e.container.ContainerJSONBase, actual)
+			}
+		})
+	}
+}
+
+func TestDockerIsContainerEnabled(t *testing.T) {
+	containers := []struct {
+		labels           map[string]string
+		exposedByDefault bool
+		expected         bool
+	}{
+		{
+			labels:           map[string]string{},
+			exposedByDefault: true,
+			expected:         true,
+		},
+		{
+			labels:           map[string]string{},
+			exposedByDefault: false,
+			expected:         false,
+		},
+		{
+			labels:           map[string]string{"traefik.enable": "false"},
+			exposedByDefault: true,
+			expected:         false,
+		},
+		{
+			labels:           map[string]string{"traefik.enable": "true"},
+			exposedByDefault: false,
+			expected:         true,
+		},
+		{
+			labels:           map[string]string{"traefik.expose": "false"},
+			exposedByDefault: true,
+			expected:         false,
+		},
+		{
+			labels:           map[string]string{"traefik.expose": "true"},
+			exposedByDefault: false,
+			expected:         true,
+		},
+		{
+			labels:           map[string]string{"traefik.enable": "true", "traefik.expose": "false"},
+			exposedByDefault: false,
+			expected:         true,
+		},
+		{
+			labels:           map[string]string{"traefik.enable": "false", "traefik.expose": "true"},
+			exposedByDefault: true,
+			expected:         false,
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			dockerData := parseContainer(containerJSON(labels(e.labels)))
+			actual := isContainerEnabled(dockerData, e.exposedByDefault)
+			if actual != e.expected {
+				t.Errorf("expected %v, got %v", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDockerLogFields(t *testing.T) {
+	service, _ := parseService(swarmService(serviceName("foo")), nil)
+	containers := []struct {
+		dockerData dockerData
+		expected   logrus.Fields
+	}{
+		{
+			dockerData: parseContainer(containerJSON(name("foo"), withID("123456789"))),
+			expected:   logrus.Fields{"container": "foo", "containerID": "123456789"},
+		},
+		{
+			dockerData: parseContainer(containerJSON(name("foo"), withID("123456789"), labels(map[string]string{
+				"traefik.backend": "bar",
+			}))),
+			expected: logrus.Fields{"container": "foo", "containerID": "123456789", "backend": "bar"},
+		},
+		{
+			dockerData: service,
+			expected:   logrus.Fields{"service": "foo", "serviceID": "serviceID"},
+		},
+		{
+			dockerData: parseTasks(swarmTask("taskID", taskSlot(1)), service, nil, false, nil),
+			expected:   logrus.Fields{"service": "foo", "serviceID": "serviceID", "task": "foo.1", "taskID": "taskID"},
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			actual := e.dockerData.logFields()
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %v, got %v", e.expected, actual)
+			}
+		})
+	}
+}
+
+// logEntriesHook records the log entries, to check their fields
+type logEntriesHook struct {
+	lock    sync.Mutex
+	entries []logrus.Entry
+}
+
+func (h *logEntriesHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *logEntriesHook) Fire(entry *logrus.Entry) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.entries = append(h.entries, *entry)
+	return nil
+}
+
+// find returns the first entry with the given field value and message prefix
+func (h *logEntriesHook) find(field string, value string, message string) *logrus.Entry {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, entry := range h.entries {
+		if entry.Data[field] == value && strings.HasPrefix(entry.Message, message) {
+			return &entry
+		}
+	}
+	return nil
+}
+
+func TestDockerContainerFilterLogFields(t *testing.T) {
+	hook := &logEntriesHook{}
+	log.AddHook(hook)
+
+	container := parseContainer(containerJSON(
+		name("logged-container"),
+		withID("123456789"),
+		labels(map[string]string{
+			"traefik.frontend.rule": "Host foo.bar",
+		}),
+		ports(nat.PortMap{
+			"80/tcp": {},
+		}),
+	))
+	provider := &Provider{ExposedByDefault: true}
+	if provider.containerFilter(container) {
+		t.Fatal("expected the container to be filtered")
+	}
+	entry := hook.find("container", "logged-container", "Filtering container with invalid traefik.frontend.rule label")
+	if entry == nil {
+		t.Fatalf("no log entry with the container field found in %v", hook.entries)
+	}
+	if entry.Data["containerID"] != "123456789" {
+		t.Errorf("expected the containerID field 123456789, got %v", entry.Data["containerID"])
+	}
+
+	service, _ := parseService(swarmService(serviceName("logged-service")), nil)
+	provider = &Provider{ExposedByDefault: true, SwarmMode: true}
+	if provider.containerFilter(service) {
+		t.Fatal("expected the service to be filtered")
+	}
+	entry = hook.find("service", "logged-service", "Filtering service without traefik.port label")
+	if entry == nil {
+		t.Fatalf("no log entry with the service field found in %v", hook.entries)
+	}
+	if entry.Data["serviceID"] != "serviceID" {
+		t.Errorf("expected the serviceID field serviceID, got %v", entry.Data["serviceID"])
+	}
+}
+
+func TestDockerBuildConfigMessageErrors(t *testing.T) {
+	containers := []struct {
+		container docker.ContainerJSON
+		expected  []types.ConfigError
+	}{
+		{
+			container: containerJSON(
+				name("valid"),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
+			),
+			expected: nil,
+		},
+		{
+			container: containerJSON(
+				name("unknown-network"),
+				labels(map[string]string{
+					"traefik.docker.network": "webnet",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
+			),
+			expected: []types.ConfigError{
+				{Source: "unknown-network", Message: "network webnet set by traefik.docker.network not found"},
+			},
+		},
+		{
+			container: containerJSON(
+				name("no-ip"),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+				withNetwork("bridge"),
+			),
+			expected: []types.ConfigError{
+				{Source: "no-ip", Message: "no IP address found"},
+			},
+		},
+		{
+			container: containerJSON(
+				name("invalid-ip"),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+				withNetwork("bridge", ipv4("10.11.12")),
+			),
+			expected: []types.ConfigError{
+				{Source: "invalid-ip", Message: "invalid IP address 10.11.12"},
+			},
+		},
+		{
+			container: containerJSON(
+				name("invalid-port"),
+				labels(map[string]string{
+					"traefik.port": "http",
+				}),
+				ports(nat.PortMap{
+					"80/tcp": {},
+				}),
+				withNetwork("bridge", ipv4("10.11.12.13")),
+			),
+			expected: []types.ConfigError{
+				{Source: "invalid-port", Message: "invalid port http"},
+			},
+		},
+		{
+			container: containerJSON(
+				name("disabled"),
+				labels(map[string]string{
+					"traefik.enable": "false",
+				}),
+				withNetwork("bridge"),
+			),
+			expected: nil,
+		},
+	}
+
+	for containerID, e := range containers {
+		e := e
+		t.Run(strconv.Itoa(containerID), func(t *testing.T) {
+			t.Parallel()
+			provider := &Provider{
+				Domain:           "docker.localhost",
+				ExposedByDefault: true,
+			}
+			actual := provider.buildConfigMessage([]dockerData{parseContainer(e.container)})
+			if actual.ProviderName != "docker" {
+				t.Errorf("expected provider name docker, got %q", actual.ProviderName)
+			}
+			if !reflect.DeepEqual(actual.Errors, e.expected) {
+				t.Errorf("expected %+v, got %+v", e.expected, actual.Errors)
+			}
+		})
+	}
+}
+
+func TestDockerLoadDockerConfig(t *testing.T) {
+	cases := []struct {
+		containers        []docker.ContainerJSON
+		expectedFrontends map[string]*types.Frontend
+		expectedBackends  map[string]*types.Backend
+	}{
+		{
+			containers:        []docker.ContainerJSON{},
+			expectedFrontends: map[string]*types.Frontend{},
+			expectedBackends:  map[string]*types.Backend{},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.frontend.whitelistSourceRange": "192.168.1.0/24,10.0.0.0/8",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:              "backend-test",
+					PassHostHeader:       true,
+					RequestIDHeader:      "X-Request-ID",
+					EntryPoints:          []string{},
+					BasicAuth:            []string{},
+					WhitelistSourceRange: []string{"192.168.1.0/24", "10.0.0.0/8"},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend.responseForwarding.flushInterval": "100ms",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					ResponseForwarding: &types.ResponseForwarding{
+						FlushInterval: "100ms",
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test1"),
+					labels(map[string]string{
+						"traefik.frontend.rule": "Host:foo.bar",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+				containerJSON(
+					name("test2"),
+					labels(map[string]string{
+						"traefik.frontend.rule": "Host:foo.bar",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.2")),
+				),
+				containerJSON(
+					name("test3"),
+					labels(map[string]string{
+						"traefik.frontend.rule": "Host:foo.bar",
+						"traefik.backend":       "test1",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.3")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-foo-bar": {
+					Backend:         "backend-test1",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-foo-bar": {
+							Rule: "Host:foo.bar",
+						},
+					},
+				},
+				"frontend-Host-foo-bar-2": {
+					Backend:         "backend-test2",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-foo-bar-2": {
+							Rule: "Host:foo.bar",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test1": {
+					Servers: map[string]types.Server{
+						"server-test1": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+						"server-test3": {
+							URL:    "http://127.0.0.3:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+				"backend-test2": {
+					Servers: map[string]types.Server{
+						"server-test2": {
+							URL:    "http://127.0.0.2:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend":       "api1, api2",
+						"traefik.port":          "80",
+						"traefik.frontend.rule": "Host:foo.bar",
+					}),
+					ports(nat.PortMap{
+						"80/tcp":  {},
+						"443/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-foo-bar": {
+					Backend:         "backend-api1",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-foo-bar": {
+							Rule: "Host:foo.bar",
+						},
+					},
+				},
+				"frontend-Host-foo-bar-2": {
+					Backend:         "backend-api2",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-foo-bar-2": {
+							Rule: "Host:foo.bar",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-api1": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+				"backend-api2": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.frontend.rule":                     "Host:foo.bar",
+						"traefik.backend.circuitbreaker.expression": "NetworkErrorRatioX() > 0.5",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-foo-bar": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-foo-bar": {
+							Rule: "Host:foo.bar",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.frontend.auth.forward.address":             "http://auth:9000/verify",
+						"traefik.frontend.auth.forward.trustForwardHeader":  "true",
+						"traefik.frontend.auth.forward.authResponseHeaders": "X-Auth-User,X-Auth-Role",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					ForwardAuth: &types.ForwardAuth{
+						Address:             "http://auth:9000/verify",
+						TrustForwardHeader:  true,
+						AuthResponseHeaders: []string{"X-Auth-User", "X-Auth-Role"},
+					},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend.tls":                    "true",
+						"traefik.backend.tls.insecureSkipVerify": "true",
+						"traefik.backend.tls.ca":                 "/certs/ca.pem",
+					}),
+					ports(nat.PortMap{
+						"443/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "https://127.0.0.1:443",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					TLS: &types.ClientTLS{
+						CA:                 "/certs/ca.pem",
+						InsecureSkipVerify: true,
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend.loadbalancer.method": "badvalue",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					LoadBalancer: &types.LoadBalancer{
+						Method: "badvalue",
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend.maxconn.amount":        "0",
+						"traefik.backend.maxconn.extractorfunc": "client.ip",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					MaxConn:        nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend.maxconn.amount":        "-10",
+						"traefik.backend.maxconn.extractorfunc": "client.ip",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					MaxConn:        nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend.maxconn.amount":        "ten",
+						"traefik.backend.maxconn.extractorfunc": "client.ip",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					MaxConn:        nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.frontend.headers.SSLRedirect":             "true",
+						"traefik.frontend.headers.SSLHost":                 "secure.example.com",
+						"traefik.frontend.headers.STSSeconds":              "315360000",
+						"traefik.frontend.headers.STSIncludeSubdomains":    "true",
+						"traefik.frontend.headers.frameDeny":               "true",
+						"traefik.frontend.headers.contentTypeNosniff":      "true",
+						"traefik.frontend.headers.browserXSSFilter":        "true",
+						"traefik.frontend.headers.contentSecurityPolicy":   `default-src 'self'; img-src "data:"`,
+						"traefik.frontend.headers.allowedHosts":            "test.docker.localhost, www.example.com",
+						"traefik.frontend.headers.customRequestHeaders":    "X-Custom-Header:value||Another:val",
+						"traefik.frontend.headers.customResponseHeaders":   "X-Powered-By:",
+						"traefik.frontend.headers.SSLProxyHeaders":         "X-Forwarded-Proto:https",
+						"traefik.frontend.headers.customFrameOptionsValue": "SAMEORIGIN",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Headers: &types.Headers{
+						SSLRedirect:             true,
+						SSLHost:                 "secure.example.com",
+						STSSeconds:              315360000,
+						STSIncludeSubdomains:    true,
+						FrameDeny:               true,
+						ContentTypeNosniff:      true,
+						BrowserXSSFilter:        true,
+						ContentSecurityPolicy:   `default-src 'self'; img-src "data:"`,
+						AllowedHosts:            []string{"test.docker.localhost", "www.example.com"},
+						CustomRequestHeaders:    map[string]string{"X-Custom-Header": "value", "Another": "val"},
+						CustomResponseHeaders:   map[string]string{"X-Powered-By": ""},
+						SSLProxyHeaders:         map[string]string{"X-Forwarded-Proto": "https"},
+						CustomFrameOptionsValue: "SAMEORIGIN",
+					},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.frontend.ratelimit.extractorfunc":          "client.ip",
+						"traefik.frontend.ratelimit.rateset.second.period":  "1s",
+						"traefik.frontend.ratelimit.rateset.second.average": "10",
+						"traefik.frontend.ratelimit.rateset.minute.period":  "1m",
+						"traefik.frontend.ratelimit.rateset.minute.average": "100",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					RateLimit: &types.RateLimit{
+						ExtractorFunc: "client.ip",
+						RateSet: map[string]*types.Rate{
+							"second": {Period: "1s", Average: 10},
+							"minute": {Period: "1m", Average: 100},
+						},
+					},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend.buffering.maxRequestBodyBytes":  "10485760",
+						"traefik.backend.buffering.memRequestBodyBytes":  "2097152",
+						"traefik.backend.buffering.maxResponseBodyBytes": "10485760",
+						"traefik.backend.buffering.memResponseBodyBytes": "2097152",
+						"traefik.backend.buffering.retryExpression":      `IsNetworkError() && RequestMethod() == "GET"`,
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					Buffering: &types.Buffering{
+						MaxRequestBodyBytes:  10485760,
+						MemRequestBodyBytes:  2097152,
+						MaxResponseBodyBytes: 10485760,
+						MemResponseBodyBytes: 2097152,
+						RetryExpression:      `IsNetworkError() && RequestMethod() == "GET"`,
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.frontend.rule.0":      "Host:api.example.com",
+						"traefik.frontend.rule.1":      "PathPrefix:/api",
+						"traefik.frontend.entryPoints": "http",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-api-example-com-0": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{"http"},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-api-example-com-0": {
+							Rule: "Host:api.example.com",
+						},
+					},
+				},
+				"frontend-PathPrefix-api-1": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{"http"},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-PathPrefix-api-1": {
+							Rule: "PathPrefix:/api",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test1"),
+					labels(map[string]string{
+						"traefik.backend":                      "foobar",
+						"traefik.frontend.entryPoints":         "http,https",
+						"traefik.frontend.auth.basic":          "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/,test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0",
+						"traefik.frontend.redirect.entryPoint": "https",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+				containerJSON(
+					name("test2"),
+					labels(map[string]string{
+						"traefik.backend": "foobar",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.2")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test1-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{"http", "https"},
+					BasicAuth:       []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
+					Redirect:        "https",
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test1-docker-localhost": {
+							Rule: "Host:test1.docker.localhost",
+						},
+					},
+				},
+				"frontend-Host-test2-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test2-docker-localhost": {
+							Rule: "Host:test2.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-foobar": {
+					Servers: map[string]types.Server{
+						"server-test1": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+						"server-test2": {
+							URL:    "http://127.0.0.2:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test1"),
+					labels(map[string]string{
+						"traefik.backend":                                 "foobar",
+						"traefik.frontend.entryPoints":                    "http,https",
+						"traefik.backend.maxconn.amount":                  "1000",
+						"traefik.backend.maxconn.extractorfunc":           "somethingelse",
+						"traefik.backend.loadbalancer.method":             "drr",
+						"traefik.backend.circuitbreaker.expression":       "NetworkErrorRatio() > 0.5 || ResponseCodeRatio() > 0.5",
+						"traefik.backend.circuitbreaker.statusCodeRanges": "500-503,429",
+						"traefik.backend.server.keepalive":                "false",
+						"traefik.backend.server.dnsRetryCount":            "3",
+						"traefik.backend.server.dnsRetryDelay":            "500ms",
+						"traefik.backend.server.dnsResolver":              "10.0.0.53:53",
+						"traefik.backend.server.websocketTimeout":         "5s",
+						"traefik.backend.server.responseTimeout":          "1h",
+						"traefik.backend.readHeaderTimeout":               "5s",
+						"traefik.backend.responseBodyTimeout":             "1m",
+						"traefik.backend.server.multiplexH2":              "true",
+						"traefik.backend.server.maxConcurrentStreams":     "50",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test1-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{"http", "https"},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test1-docker-localhost": {
+							Rule: "Host:test1.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-foobar": {
+					Servers: map[string]types.Server{
+						"server-test1": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: &types.CircuitBreaker{
+						Expression: "NetworkErrorRatio() > 0.5 || ResponseCodeRatio() > 0.5",
+						StatusCodeRanges: []types.StatusCodeRange{
+							{Min: 500, Max: 503},
+							{Min: 429, Max: 429},
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method: "drr",
+					},
+					MaxConn: &types.MaxConn{
+						Amount:        1000,
+						ExtractorFunc: "somethingelse",
+					},
+					DisableKeepAlives:   true,
+					DNSRetryCount:       3,
+					DNSRetryDelay:       "500ms",
+					DNSResolver:         "10.0.0.53:53",
+					WebsocketTimeout:    "5s",
+					ResponseTimeout:     "1h",
+					ReadHeaderTimeout:   "5s",
+					ResponseBodyTimeout: "1m",
+					H2Options: &types.H2Options{
+						MaxConcurrentStreams: 50,
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.weight":                       "10",
+						"traefik.backend.healthcheck.path":     "/health",
+						"traefik.backend.healthcheck.interval": "5s",
+						"traefik.backend.server.urlChain":      "http://primary:8080; http://fallback:8080",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://primary:8080",
+							Weight: 10,
+						},
+						"server-test-fallback-1": {
+							URL:      "http://fallback:8080",
+							Weight:   0,
+							Fallback: true,
+						},
+					},
+					HealthCheck: &types.HealthCheck{
+						Path:     "/health",
+						Interval: "5s",
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend.server.urls":    `["http://10.0.0.1:8080","http://10.0.0.1:8081"]`,
+						"traefik.backend.server.weights": `[2,1]`,
+					}),
+					ports(nat.PortMap{
+						"8080/tcp": {},
+						"8081/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("10.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test-0": {
+							URL:    "http://10.0.0.1:8080",
+							Weight: 2,
+						},
+						"server-test-1": {
+							URL:    "http://10.0.0.1:8081",
+							Weight: 1,
+						},
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test1"),
+					labels(map[string]string{
+						"traefik.backend": "foobar",
+					}),
+					networkMode("host"),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+				),
+				containerJSON(
+					name("test2"),
+					labels(map[string]string{
+						"traefik.backend": "foobar",
+						"traefik.weight":  "10",
+					}),
+					networkMode("host"),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test1-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test1-docker-localhost": {
+							Rule: "Host:test1.docker.localhost",
+						},
+					},
+				},
+				"frontend-Host-test2-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test2-docker-localhost": {
+							Rule: "Host:test2.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-foobar": {
+					Servers: map[string]types.Server{
+						"server-test2": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 10,
+						},
+					},
+					CircuitBreaker: nil,
+				},
+			},
 		},
 		{
 			containers: []docker.ContainerJSON{
 				containerJSON(
 					name("test"),
+					labels(map[string]string{
+						"traefik.frontend.entryPoints": "http,https",
+						"traefik.frontend.redirect":    "https",
+					}),
 					ports(nat.PortMap{
 						"80/tcp": {},
 					}),
@@ -650,10 +3944,12 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 			},
 			expectedFrontends: map[string]*types.Frontend{
 				"frontend-Host-test-docker-localhost": {
-					Backend:        "backend-test",
-					PassHostHeader: true,
-					EntryPoints:    []string{},
-					BasicAuth:      []string{},
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					Redirect:        "https",
+					EntryPoints:     []string{"http", "https"},
+					BasicAuth:       []string{},
 					Routes: map[string]types.Route{
 						"route-frontend-Host-test-docker-localhost": {
 							Rule: "Host:test.docker.localhost",
@@ -669,28 +3965,28 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 							Weight: 0,
 						},
 					},
-					CircuitBreaker: nil,
 				},
 			},
 		},
 		{
 			containers: []docker.ContainerJSON{
 				containerJSON(
-					name("test1"),
+					name("test2"),
 					labels(map[string]string{
-						"traefik.backend":              "foobar",
-						"traefik.frontend.entryPoints": "http,https",
-						"traefik.frontend.auth.basic":  "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/,test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0",
+						"traefik.backend":        "foobar",
+						"traefik.backend.sticky": "false",
 					}),
 					ports(nat.PortMap{
 						"80/tcp": {},
 					}),
-					withNetwork("bridge", ipv4("127.0.0.1")),
+					withNetwork("bridge", ipv4("127.0.0.2")),
 				),
 				containerJSON(
-					name("test2"),
+					name("test1"),
 					labels(map[string]string{
-						"traefik.backend": "foobar",
+						"traefik.backend":        "foobar",
+						"traefik.backend.sticky": "true",
+						"traefik.backend.loadbalancer.stickiness.cookieName": "_app_session",
 					}),
 					ports(nat.PortMap{
 						"80/tcp": {},
@@ -700,10 +3996,11 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 			},
 			expectedFrontends: map[string]*types.Frontend{
 				"frontend-Host-test1-docker-localhost": {
-					Backend:        "backend-foobar",
-					PassHostHeader: true,
-					EntryPoints:    []string{"http", "https"},
-					BasicAuth:      []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
 					Routes: map[string]types.Route{
 						"route-frontend-Host-test1-docker-localhost": {
 							Rule: "Host:test1.docker.localhost",
@@ -711,10 +4008,11 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 					},
 				},
 				"frontend-Host-test2-docker-localhost": {
-					Backend:        "backend-foobar",
-					PassHostHeader: true,
-					EntryPoints:    []string{},
-					BasicAuth:      []string{},
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
 					Routes: map[string]types.Route{
 						"route-frontend-Host-test2-docker-localhost": {
 							Rule: "Host:test2.docker.localhost",
@@ -730,11 +4028,159 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 							Weight: 0,
 						},
 						"server-test2": {
+							URL:    "http://127.0.0.2:80",
+							Weight: 0,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method:     "wrr",
+						Sticky:     true,
+						CookieName: "_app_session",
+					},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("primary"),
+					labels(map[string]string{
+						"traefik.backend":                      "foobar",
+						"traefik.backend.fallback.statusCodes": "503, 429",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("10.0.0.1")),
+				),
+				containerJSON(
+					name("backup"),
+					labels(map[string]string{
+						"traefik.backend":                      "foobar",
+						"traefik.backend.fallback.statusCodes": "503, 429",
+						"traefik.backend.server.fallback":      "true",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("10.0.0.2")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-primary-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-primary-docker-localhost": {
+							Rule: "Host:primary.docker.localhost",
+						},
+					},
+				},
+				"frontend-Host-backup-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-backup-docker-localhost": {
+							Rule: "Host:backup.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-foobar": {
+					Servers: map[string]types.Server{
+						"server-primary": {
+							URL:    "http://10.0.0.1:80",
+							Weight: 0,
+						},
+						"server-backup": {
+							URL:      "http://10.0.0.2:80",
+							Weight:   0,
+							Fallback: true,
+						},
+					},
+					FallbackStatusCodes: []int{503, 429},
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.frontend.passTLSCert":     "true",
+						"traefik.frontend.passTLSCert.pem": "true",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					PassTLSCert:     true,
+					PassTLSCertPEM:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
 							URL:    "http://127.0.0.1:80",
 							Weight: 0,
 						},
 					},
-					CircuitBreaker: nil,
+				},
+			},
+		},
+		{
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend.server.url": "http://my-service:8080/api",
+					}),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://my-service:8080/api",
+							Weight: 0,
+						},
+					},
 				},
 			},
 		},
@@ -743,49 +4189,84 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 				containerJSON(
 					name("test1"),
 					labels(map[string]string{
-						"traefik.backend":                           "foobar",
-						"traefik.frontend.entryPoints":              "http,https",
-						"traefik.backend.maxconn.amount":            "1000",
-						"traefik.backend.maxconn.extractorfunc":     "somethingelse",
-						"traefik.backend.loadbalancer.method":       "drr",
-						"traefik.backend.circuitbreaker.expression": "NetworkErrorRatio() > 0.5",
+						"traefik.backend":                     "iphash",
+						"traefik.backend.loadbalancer.method": "ip_hash",
+						"traefik.backend.maxconn.amount":      "10",
 					}),
 					ports(nat.PortMap{
 						"80/tcp": {},
 					}),
 					withNetwork("bridge", ipv4("127.0.0.1")),
 				),
+				containerJSON(
+					name("test2"),
+					labels(map[string]string{
+						"traefik.backend":                       "explicit",
+						"traefik.backend.loadbalancer.method":   "ip_hash",
+						"traefik.backend.maxconn.amount":        "20",
+						"traefik.backend.maxconn.extractorfunc": "request.host",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.2")),
+				),
 			},
 			expectedFrontends: map[string]*types.Frontend{
 				"frontend-Host-test1-docker-localhost": {
-					Backend:        "backend-foobar",
-					PassHostHeader: true,
-					EntryPoints:    []string{"http", "https"},
-					BasicAuth:      []string{},
+					Backend:         "backend-iphash",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
 					Routes: map[string]types.Route{
 						"route-frontend-Host-test1-docker-localhost": {
 							Rule: "Host:test1.docker.localhost",
 						},
 					},
 				},
+				"frontend-Host-test2-docker-localhost": {
+					Backend:         "backend-explicit",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test2-docker-localhost": {
+							Rule: "Host:test2.docker.localhost",
+						},
+					},
+				},
 			},
 			expectedBackends: map[string]*types.Backend{
-				"backend-foobar": {
+				"backend-iphash": {
 					Servers: map[string]types.Server{
 						"server-test1": {
 							URL:    "http://127.0.0.1:80",
 							Weight: 0,
 						},
 					},
-					CircuitBreaker: &types.CircuitBreaker{
-						Expression: "NetworkErrorRatio() > 0.5",
+					LoadBalancer: &types.LoadBalancer{
+						Method: "wrr",
+					},
+					MaxConn: &types.MaxConn{
+						Amount:        10,
+						ExtractorFunc: "client.ip",
+					},
+				},
+				"backend-explicit": {
+					Servers: map[string]types.Server{
+						"server-test2": {
+							URL:    "http://127.0.0.2:80",
+							Weight: 0,
+						},
 					},
 					LoadBalancer: &types.LoadBalancer{
-						Method: "drr",
+						Method: "wrr",
 					},
 					MaxConn: &types.MaxConn{
-						Amount:        1000,
-						ExtractorFunc: "somethingelse",
+						Amount:        20,
+						ExtractorFunc: "request.host",
 					},
 				},
 			},
@@ -817,3 +4298,307 @@ func TestDockerLoadDockerConfig(t *testing.T) {
 		})
 	}
 }
+
+type fakeContainersClient struct {
+	dockerclient.APIClient
+	containers map[string]docker.ContainerJSON
+}
+
+func (c *fakeContainersClient) ContainerList(ctx context.Context, options docker.ContainerListOptions) ([]docker.Container, error) {
+	var containers []docker.Container
+	for id, container := range c.containers {
+		if options.Filter.Len() > 0 && !matchLabelFilters(container, options.Filter.Get("label")) {
+			continue
+		}
+		containers = append(containers, docker.Container{ID: id})
+	}
+	return containers, nil
+}
+
+func matchLabelFilters(container docker.ContainerJSON, labelFilters []string) bool {
+	for _, labelFilter := range labelFilters {
+		parts := strings.SplitN(labelFilter, "=", 2)
+		if len(parts) != 2 || container.Config.Labels[parts[0]] != parts[1] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *fakeContainersClient) ContainerInspect(ctx context.Context, containerID string) (docker.ContainerJSON, error) {
+	container, ok := c.containers[containerID]
+	if !ok {
+		return docker.ContainerJSON{}, errors.New("No such container: " + containerID)
+	}
+	return container, nil
+}
+
+func TestDockerListContainersWithSharedNetwork(t *testing.T) {
+	dockerClient := &fakeContainersClient{
+		containers: map[string]docker.ContainerJSON{
+			"app": containerJSON(
+				name("demo_app_1"),
+				networkMode("service:proxy"),
+				labels(map[string]string{
+					"traefik.port":               "8080",
+					"com.docker.compose.project": "demo",
+					"com.docker.compose.service": "app",
+				}),
+			),
+			"proxy": containerJSON(
+				name("demo_proxy_1"),
+				withNetwork("bridge", ipv4("10.0.0.5")),
+				labels(map[string]string{
+					"com.docker.compose.project": "demo",
+					"com.docker.compose.service": "proxy",
+				}),
+			),
+			"other-proxy": containerJSON(
+				name("other_proxy_1"),
+				withNetwork("bridge", ipv4("10.0.0.9")),
+				labels(map[string]string{
+					"com.docker.compose.project": "other",
+					"com.docker.compose.service": "proxy",
+				}),
+			),
+			"orphan": containerJSON(
+				name("demo_orphan_1"),
+				networkMode("service:missing"),
+				labels(map[string]string{
+					"com.docker.compose.project": "demo",
+					"com.docker.compose.service": "orphan",
+				}),
+			),
+		},
+	}
+
+	containers, err := listContainers(context.Background(), dockerClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &Provider{}
+	expected := map[string]string{
+		"demo_app_1":    "10.0.0.5",
+		"demo_proxy_1":  "10.0.0.5",
+		"other_proxy_1": "10.0.0.9",
+		"demo_orphan_1": "",
+	}
+	for _, container := range containers {
+		if actual := provider.getIPAddress(container); actual != expected[container.Name] {
+			t.Errorf("expected IP %q for container %s, got %q", expected[container.Name], container.Name, actual)
+		}
+	}
+	if len(containers) != len(expected) {
+		t.Errorf("expected %d containers, got %d", len(expected), len(containers))
+	}
+}
+
+func TestDockerParseImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected imageData
+	}{
+		{image: "nginx", expected: imageData{Name: "nginx"}},
+		{image: "nginx:1.13", expected: imageData{Name: "nginx", Tag: "1.13"}},
+		{image: "registry.local:5000/acme/api", expected: imageData{Name: "registry.local:5000/acme/api"}},
+		{image: "registry.local:5000/acme/api:v2", expected: imageData{Name: "registry.local:5000/acme/api", Tag: "v2"}},
+		{image: "acme/api:v2@sha256:abcdef", expected: imageData{Name: "acme/api", Tag: "v2", Digest: "sha256:abcdef"}},
+	}
+
+	for _, test := range tests {
+		if actual := parseImage(test.image); actual != test.expected {
+			t.Errorf("parseImage(%q): expected %+v, got %+v", test.image, test.expected, actual)
+		}
+	}
+}
+
+func TestDockerLoadDockerConfigTrustedIPs(t *testing.T) {
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+		TrustedIPs:       []string{"10.0.0.1", "172.16.0.0/12"},
+	}
+	container := containerJSON(
+		name("test"),
+		ports(nat.PortMap{
+			"80/tcp": {},
+		}),
+		withNetwork("bridge", ipv4("127.0.0.1")),
+	)
+	actualConfig := provider.loadDockerConfig([]dockerData{parseContainer(container)})
+	frontend, ok := actualConfig.Frontends["frontend-Host-test-docker-localhost"]
+	if !ok {
+		t.Fatalf("expected frontend-Host-test-docker-localhost, got %v", actualConfig.Frontends)
+	}
+	if !reflect.DeepEqual(frontend.TrustedIPs, provider.TrustedIPs) {
+		t.Errorf("expected trusted IPs %v, got %v", provider.TrustedIPs, frontend.TrustedIPs)
+	}
+}
+
+func TestDockerLoadDockerConfigQuotedFrontendRule(t *testing.T) {
+	rules := []string{
+		`RequestBodyContains:"event_type":"payment"`,
+		`CustomMatcher:myPlugin:{"claim":"admin"}`,
+		`Path:/{id:[0-9]+}\\d`,
+	}
+
+	for _, rule := range rules {
+		provider := &Provider{
+			Domain:           "docker.localhost",
+			ExposedByDefault: true,
+		}
+		container := containerJSON(
+			name("test"),
+			labels(map[string]string{
+				"traefik.frontend.rule": rule,
+			}),
+			ports(nat.PortMap{
+				"80/tcp": {},
+			}),
+			withNetwork("bridge", ipv4("127.0.0.1")),
+		)
+		actualConfig := provider.loadDockerConfig([]dockerData{parseContainer(container)})
+		if actualConfig == nil || len(actualConfig.Frontends) != 1 {
+			t.Fatalf("expected one frontend for rule %s, got %v", rule, actualConfig)
+		}
+		for _, frontend := range actualConfig.Frontends {
+			for _, route := range frontend.Routes {
+				if route.Rule != rule {
+					t.Errorf("expected rule %s, got %s", rule, route.Rule)
+				}
+			}
+		}
+	}
+}
+
+func TestDockerLoadDockerConfigLabelPrefix(t *testing.T) {
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: false,
+		LabelPrefix:      "ext",
+	}
+	containers := []docker.ContainerJSON{
+		containerJSON(
+			name("internal"),
+			labels(map[string]string{
+				"traefik.enable":        "true",
+				"traefik.frontend.rule": "Host:internal.example.com",
+			}),
+			ports(nat.PortMap{
+				"80/tcp": {},
+			}),
+			withNetwork("bridge", ipv4("127.0.0.1")),
+		),
+		containerJSON(
+			name("external"),
+			labels(map[string]string{
+				"ext.enable":            "true",
+				"ext.frontend.rule":     "Host:external.example.com",
+				"ext.backend":           "public",
+				"ext.port":              "8080",
+				"traefik.frontend.rule": "Host:ignored.example.com",
+			}),
+			ports(nat.PortMap{
+				"80/tcp":   {},
+				"8080/tcp": {},
+			}),
+			withNetwork("bridge", ipv4("127.0.0.2")),
+		),
+	}
+	var dockerDataList []dockerData
+	for _, container := range containers {
+		dockerDataList = append(dockerDataList, parseContainer(container))
+	}
+
+	actualConfig := provider.loadDockerConfig(dockerDataList)
+
+	expectedFrontends := map[string]*types.Frontend{
+		"frontend-Host-external-example-com": {
+			Backend:         "backend-public",
+			PassHostHeader:  true,
+			RequestIDHeader: "X-Request-ID",
+			EntryPoints:     []string{},
+			BasicAuth:       []string{},
+			Routes: map[string]types.Route{
+				"route-frontend-Host-external-example-com": {
+					Rule: "Host:external.example.com",
+				},
+			},
+		},
+	}
+	expectedBackends := map[string]*types.Backend{
+		"backend-public": {
+			Servers: map[string]types.Server{
+				"server-external": {
+					URL:    "http://127.0.0.2:8080",
+					Weight: 0,
+				},
+			},
+			CircuitBreaker: nil,
+		},
+	}
+	if !reflect.DeepEqual(actualConfig.Frontends, expectedFrontends) {
+		t.Errorf("expected %#v, got %#v", expectedFrontends, actualConfig.Frontends)
+	}
+	if !reflect.DeepEqual(actualConfig.Backends, expectedBackends) {
+		t.Errorf("expected %#v, got %#v", expectedBackends, actualConfig.Backends)
+	}
+	if errors := provider.getConfigErrors(provider.filterContainers(dockerDataList)); len(errors) > 0 {
+		t.Errorf("expected no config errors, got %v", errors)
+	}
+	// the original labels are left untouched
+	if dockerDataList[1].Labels["ext.port"] != "8080" {
+		t.Errorf("expected the container labels to be left untouched, got %v", dockerDataList[1].Labels)
+	}
+}
+
+type fakeEventsClient struct {
+	dockerclient.APIClient
+	events io.ReadCloser
+}
+
+func (c *fakeEventsClient) Events(ctx context.Context, options docker.EventsOptions) (io.ReadCloser, error) {
+	return c.events, nil
+}
+
+func TestDockerMonitorContainerEvents(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloads := make(chan struct{}, 10)
+	provider := &Provider{}
+	provider.monitorContainerEvents(ctx, &fakeEventsClient{events: reader}, func() {
+		reloads <- struct{}{}
+	})
+
+	encoder := json.NewEncoder(writer)
+	for _, action := range []string{"exec_start", "stop"} {
+		if err := encoder.Encode(eventtypes.Message{Type: "container", Action: action, ID: "c1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-reloads:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a reload on the stop event")
+	}
+	select {
+	case <-reloads:
+		t.Error("expected exactly one reload")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDockerProvideInvalidTLS(t *testing.T) {
+	provider := &Provider{
+		Endpoint: "tcp://127.0.0.1:2376",
+		TLS:      &types.ClientTLS{Cert: "/certs/cert.pem"},
+	}
+	if err := provider.Provide(nil, nil, nil); err == nil {
+		t.Error("expected an error for a TLS cert without key")
+	}
+}
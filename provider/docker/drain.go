@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/containous/traefik/log"
+	swarmtypes "github.com/docker/engine-api/types/swarm"
+)
+
+// taskDrainer keeps the tasks replaced by a rolling update of their service in the backends
+// for the update delay, so that the new tasks can warm up. At most the update parallelism of
+// tasks are draining at the same time.
+type taskDrainer struct {
+	lock     sync.Mutex
+	seen     map[string]map[string]dockerData   // running tasks by task ID, by service ID
+	draining map[string]map[string]drainingTask // draining tasks by task ID, by service ID
+}
+
+type drainingTask struct {
+	data  dockerData
+	until time.Time
+}
+
+func newTaskDrainer() *taskDrainer {
+	return &taskDrainer{
+		seen:     make(map[string]map[string]dockerData),
+		draining: make(map[string]map[string]drainingTask),
+	}
+}
+
+// update records the running tasks of the service and returns them along with its draining tasks
+func (d *taskDrainer) update(service swarmtypes.Service, tasks []dockerData, now time.Time) []dockerData {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	running := make(map[string]dockerData, len(tasks))
+	for _, task := range tasks {
+		running[task.TaskID] = task
+	}
+
+	draining := d.draining[service.ID]
+	if draining == nil {
+		draining = make(map[string]drainingTask)
+	}
+	for taskID, task := range draining {
+		if _, ok := running[taskID]; ok || !now.Before(task.until) {
+			delete(draining, taskID)
+		}
+	}
+
+	updateConfig := service.Spec.UpdateConfig
+	if updateConfig != nil && service.UpdateStatus.State == swarmtypes.UpdateStateUpdating {
+		// the removed tasks are drained in a stable order
+		var removed []string
+		for taskID := range d.seen[service.ID] {
+			if _, ok := running[taskID]; !ok {
+				if _, ok := draining[taskID]; !ok {
+					removed = append(removed, taskID)
+				}
+			}
+		}
+		sort.Strings(removed)
+		for _, taskID := range removed {
+			if updateConfig.Parallelism > 0 && uint64(len(draining)) >= updateConfig.Parallelism {
+				break
+			}
+			task := d.seen[service.ID][taskID]
+			// the replacing task may run in the same slot, hence with the same name
+			task.Name = task.ServiceName + "." + taskID
+			log.Debugf("Draining task %s of service %s for %s", taskID, task.ServiceName, updateConfig.Delay)
+			draining[taskID] = drainingTask{data: task, until: now.Add(updateConfig.Delay)}
+		}
+	}
+
+	d.seen[service.ID] = running
+	d.draining[service.ID] = draining
+
+	var drainingIDs []string
+	for taskID := range draining {
+		drainingIDs = append(drainingIDs, taskID)
+	}
+	sort.Strings(drainingIDs)
+	for _, taskID := range drainingIDs {
+		tasks = append(tasks, draining[taskID].data)
+	}
+	return tasks
+}
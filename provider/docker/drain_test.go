@@ -0,0 +1,90 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/docker/engine-api/types/swarm"
+)
+
+func withUpdate(parallelism uint64, delay time.Duration, state swarm.UpdateState) func(*swarm.Service) {
+	return func(service *swarm.Service) {
+		service.Spec.UpdateConfig = &swarm.UpdateConfig{Parallelism: parallelism, Delay: delay}
+		service.UpdateStatus.State = state
+	}
+}
+
+func taskData(taskID string, slot string) dockerData {
+	return dockerData{ServiceName: "api", Name: "api." + slot, TaskID: taskID}
+}
+
+func taskNames(tasks []dockerData) []string {
+	var names []string
+	for _, task := range tasks {
+		names = append(names, task.Name)
+	}
+	return names
+}
+
+func TestTaskDrainerRollingUpdate(t *testing.T) {
+	drainer := newTaskDrainer()
+	now := time.Now()
+	idle := swarmService(serviceName("api"), withUpdate(2, 10*time.Second, swarm.UpdateStateCompleted))
+	updating := swarmService(serviceName("api"), withUpdate(2, 10*time.Second, swarm.UpdateStateUpdating))
+
+	steps := []struct {
+		desc     string
+		service  swarm.Service
+		tasks    []dockerData
+		at       time.Duration
+		expected []string
+	}{
+		{
+			desc:     "before the update",
+			service:  idle,
+			tasks:    []dockerData{taskData("old1", "1"), taskData("old2", "2"), taskData("old3", "3")},
+			expected: []string{"api.1", "api.2", "api.3"},
+		},
+		{
+			desc:     "first tasks replaced, drained up to the parallelism",
+			service:  updating,
+			tasks:    []dockerData{taskData("new1", "1")},
+			at:       time.Second,
+			expected: []string{"api.1", "api.old1", "api.old2"},
+		},
+		{
+			desc:     "draining tasks kept during the update delay",
+			service:  updating,
+			tasks:    []dockerData{taskData("new1", "1"), taskData("new2", "2")},
+			at:       5 * time.Second,
+			expected: []string{"api.1", "api.2", "api.old1", "api.old2"},
+		},
+		{
+			desc:     "draining tasks removed after the update delay",
+			service:  updating,
+			tasks:    []dockerData{taskData("new1", "1"), taskData("new2", "2"), taskData("new3", "3")},
+			at:       11 * time.Second,
+			expected: []string{"api.1", "api.2", "api.3"},
+		},
+	}
+
+	for _, step := range steps {
+		actual := taskNames(drainer.update(step.service, step.tasks, now.Add(step.at)))
+		if !reflect.DeepEqual(actual, step.expected) {
+			t.Errorf("%s: expected tasks %v, got %v", step.desc, step.expected, actual)
+		}
+	}
+}
+
+func TestTaskDrainerWithoutUpdate(t *testing.T) {
+	drainer := newTaskDrainer()
+	now := time.Now()
+	service := swarmService(serviceName("api"))
+
+	drainer.update(service, []dockerData{taskData("old1", "1"), taskData("old2", "2")}, now)
+	actual := taskNames(drainer.update(service, []dockerData{taskData("old1", "1")}, now.Add(time.Second)))
+	if expected := []string{"api.1"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected tasks %v, got %v", expected, actual)
+	}
+}
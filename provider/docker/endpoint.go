@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/docker/engine-api/client"
+)
+
+// endpointDialer returns a client of a docker endpoint once the daemon answered
+type endpointDialer func(ctx context.Context, endpoint string) (client.APIClient, error)
+
+// connect returns a client of the first docker endpoint answering, the Endpoint being tried
+// before the EndpointFallbacks, along with the endpoint
+func (p *Provider) connect() (client.APIClient, string, error) {
+	dialer := p.dialer
+	if dialer == nil {
+		dialer = p.dialEndpoint
+	}
+	var failures []string
+	for _, endpoint := range append([]string{p.Endpoint}, p.EndpointFallbacks...) {
+		ctx, cancel := context.WithTimeout(context.Background(), p.getEndpointTimeout())
+		dockerClient, err := dialer(ctx, endpoint)
+		cancel()
+		if err == nil {
+			if len(failures) > 0 {
+				log.Warnf("Using docker endpoint %s, the previous endpoints failing", endpoint)
+			}
+			return dockerClient, endpoint, nil
+		}
+		log.Errorf("Failed to connect to docker endpoint %s, error: %s", endpoint, err)
+		failures = append(failures, fmt.Sprintf("%s: %s", endpoint, err))
+	}
+	return nil, "", fmt.Errorf("unable to connect to any docker endpoint (%s)", strings.Join(failures, ", "))
+}
+
+// dialEndpoint creates a client of the docker endpoint and checks that the daemon answers
+func (p *Provider) dialEndpoint(ctx context.Context, endpoint string) (client.APIClient, error) {
+	dockerClient, err := p.createClient(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dockerClient.ServerVersion(ctx); err != nil {
+		return nil, err
+	}
+	return dockerClient, nil
+}
+
+// getEndpointTimeout returns the timeout of the connection attempt to each docker endpoint
+func (p *Provider) getEndpointTimeout() time.Duration {
+	if p.EndpointTimeout <= 0 {
+		return DockerDefaultEndpointTimeout
+	}
+	return time.Duration(p.EndpointTimeout)
+}
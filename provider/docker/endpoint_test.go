@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/docker/engine-api/client"
+)
+
+func TestDockerConnectEndpointFallbacks(t *testing.T) {
+	provider := &Provider{
+		Endpoint:          "tcp://first:2375",
+		EndpointFallbacks: []string{"tcp://second:2375", "tcp://third:2375"},
+	}
+	var dialed []string
+	provider.dialer = func(ctx context.Context, endpoint string) (client.APIClient, error) {
+		dialed = append(dialed, endpoint)
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > DockerDefaultEndpointTimeout {
+			t.Errorf("expected a deadline within %s, got %v", DockerDefaultEndpointTimeout, deadline)
+		}
+		if endpoint == "tcp://first:2375" {
+			return nil, errors.New("connection refused")
+		}
+		return provider.createClient(endpoint)
+	}
+
+	dockerClient, endpoint, err := provider.connect()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dockerClient == nil {
+		t.Error("expected a docker client")
+	}
+	if endpoint != "tcp://second:2375" {
+		t.Errorf("expected endpoint tcp://second:2375, got %s", endpoint)
+	}
+	if expected := []string{"tcp://first:2375", "tcp://second:2375"}; !reflect.DeepEqual(dialed, expected) {
+		t.Errorf("expected the endpoints %v to be dialed, got %v", expected, dialed)
+	}
+}
+
+func TestDockerConnectAllEndpointsFailing(t *testing.T) {
+	provider := &Provider{
+		Endpoint:          "tcp://first:2375",
+		EndpointFallbacks: []string{"tcp://second:2375"},
+		dialer: func(ctx context.Context, endpoint string) (client.APIClient, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	_, _, err := provider.connect()
+	expected := "unable to connect to any docker endpoint (tcp://first:2375: connection refused, tcp://second:2375: connection refused)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,32 @@
+package docker
+
+import (
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const containersFilteredName = "traefik_docker_containers_filtered_total"
+
+// The reasons of the exclusion of the containers reported by the filtered containers metric
+const (
+	filterReasonPort            = "port"
+	filterReasonDisabled        = "disabled"
+	filterReasonPortBinding     = "port_binding"
+	filterReasonConstraint      = "constraint"
+	filterReasonLabelConstraint = "label_constraint"
+	filterReasonUnhealthy       = "unhealthy"
+	filterReasonInvalidLabel    = "invalid_label"
+)
+
+// containersFilteredCounter counts the containers, or the Swarm services and tasks, included or
+// excluded by containerFilter, partitioned by the reason of their exclusion
+var containersFilteredCounter = stdprometheus.NewCounterVec(
+	stdprometheus.CounterOpts{
+		Name: containersFilteredName,
+		Help: "How many docker containers were included or excluded by the provider, partitioned by the reason of their exclusion.",
+	},
+	[]string{"result", "reason"},
+)
+
+func init() {
+	stdprometheus.MustRegister(containersFilteredCounter)
+}
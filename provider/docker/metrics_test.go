@@ -0,0 +1,115 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	docker "github.com/docker/engine-api/types"
+	"github.com/docker/go-connections/nat"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+func TestDockerContainerFilterMetrics(t *testing.T) {
+	containers := []docker.ContainerJSON{
+		containerJSON(
+			name("included"),
+			ports(nat.PortMap{
+				"80/tcp": {},
+			}),
+		),
+		containerJSON(
+			name("without-port"),
+		),
+		containerJSON(
+			name("disabled"),
+			labels(map[string]string{
+				"traefik.enable": "false",
+			}),
+			ports(nat.PortMap{
+				"80/tcp": {},
+			}),
+		),
+		containerJSON(
+			name("constrained"),
+			labels(map[string]string{
+				"traefik.tags": "internal",
+			}),
+			ports(nat.PortMap{
+				"80/tcp": {},
+			}),
+		),
+		containerJSON(
+			name("invalid-rule"),
+			labels(map[string]string{
+				"traefik.frontend.rule": "Host foo.bar",
+			}),
+			ports(nat.PortMap{
+				"80/tcp": {},
+			}),
+		),
+		containerJSON(
+			name("invalid-whitelist"),
+			labels(map[string]string{
+				"traefik.frontend.whitelistSourceRange": "10.0.0.0/33",
+			}),
+			ports(nat.PortMap{
+				"80/tcp": {},
+			}),
+		),
+	}
+	provider := &Provider{
+		Domain:           "docker.localhost",
+		ExposedByDefault: true,
+	}
+	constraint, err := types.NewConstraint("tag!=internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.Constraints = types.Constraints{constraint}
+
+	before := gatherContainersFiltered(t)
+	for _, container := range containers {
+		provider.containerFilter(parseContainer(container))
+	}
+	after := gatherContainersFiltered(t)
+
+	actual := map[string]float64{}
+	for key, value := range after {
+		if delta := value - before[key]; delta != 0 {
+			actual[key] = delta
+		}
+	}
+	expected := map[string]float64{
+		"included/":              1,
+		"excluded/port":          1,
+		"excluded/disabled":      1,
+		"excluded/constraint":    1,
+		"excluded/invalid_label": 2,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+// gatherContainersFiltered returns the values of the filtered containers counter by result/reason
+func gatherContainersFiltered(t *testing.T) map[string]float64 {
+	metricFamilies, err := stdprometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("could not gather metrics families: %s", err)
+	}
+	values := map[string]float64{}
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != containersFilteredName {
+			continue
+		}
+		for _, metric := range metricFamily.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			values[labels["result"]+"/"+labels["reason"]] = metric.GetCounter().GetValue()
+		}
+	}
+	return values
+}
@@ -133,7 +133,7 @@ func TestDockerGetServiceFrontendRule(t *testing.T) {
 	}{
 		{
 			container: containerJSON(name("foo")),
-			expected:  "Host:foo.",
+			expected:  "PathPrefix:/foo",
 		},
 		{
 			container: containerJSON(labels(map[string]string{
@@ -223,6 +223,13 @@ func TestDockerGetServicePriority(t *testing.T) {
 			})),
 			expected: "2503",
 		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.frontend.priority":           "33",
+				"traefik.myservice.frontend.priority": "high",
+			})),
+			expected: "33",
+		},
 	}
 
 	for containerID, e := range containers {
@@ -299,6 +306,12 @@ func TestDockerGetServiceEntryPoints(t *testing.T) {
 			})),
 			expected: []string{"http", "https"},
 		},
+		{
+			container: containerJSON(labels(map[string]string{
+				"traefik.myservice.frontend.entryPoints": " http , https ",
+			})),
+			expected: []string{"http", "https"},
+		},
 	}
 
 	for containerID, e := range containers {
@@ -342,10 +355,11 @@ func TestDockerLoadDockerServiceConfig(t *testing.T) {
 			},
 			expectedFrontends: map[string]*types.Frontend{
 				"frontend-foo-service": {
-					Backend:        "backend-foo-service",
-					PassHostHeader: true,
-					EntryPoints:    []string{"http", "https"},
-					BasicAuth:      []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
+					Backend:         "backend-foo-service",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{"http", "https"},
+					BasicAuth:       []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
 					Routes: map[string]types.Route{
 						"service-service": {
 							Rule: "Host:foo.docker.localhost",
@@ -400,11 +414,12 @@ func TestDockerLoadDockerServiceConfig(t *testing.T) {
 			},
 			expectedFrontends: map[string]*types.Frontend{
 				"frontend-foobar": {
-					Backend:        "backend-foobar",
-					PassHostHeader: false,
-					Priority:       5000,
-					EntryPoints:    []string{"http", "https", "ws"},
-					BasicAuth:      []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
+					Backend:         "backend-foobar",
+					PassHostHeader:  false,
+					RequestIDHeader: "X-Request-ID",
+					Priority:        5000,
+					EntryPoints:     []string{"http", "https", "ws"},
+					BasicAuth:       []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
 					Routes: map[string]types.Route{
 						"service-service": {
 							Rule: "Path:/mypath",
@@ -412,10 +427,11 @@ func TestDockerLoadDockerServiceConfig(t *testing.T) {
 					},
 				},
 				"frontend-test2-anotherservice": {
-					Backend:        "backend-test2-anotherservice",
-					PassHostHeader: true,
-					EntryPoints:    []string{},
-					BasicAuth:      []string{},
+					Backend:         "backend-test2-anotherservice",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
 					Routes: map[string]types.Route{
 						"service-anotherservice": {
 							Rule: "Path:/anotherpath",
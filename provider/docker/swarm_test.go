@@ -2,10 +2,13 @@ package docker
 
 import (
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/containous/flaeg"
 	"github.com/containous/traefik/types"
 	"github.com/davecgh/go-spew/spew"
 	dockerclient "github.com/docker/engine-api/client"
@@ -33,6 +36,20 @@ func TestSwarmGetFrontendName(t *testing.T) {
 			expected: "Headers-User-Agent-bat-0-1-0",
 			networks: map[string]*docker.NetworkResource{},
 		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.frontend.rule": "CookiePresent:session_id",
+			})),
+			expected: "CookiePresent-session-id",
+			networks: map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.frontend.rule": "HTTPVersion:2",
+			})),
+			expected: "HTTPVersion-2",
+			networks: map[string]*docker.NetworkResource{},
+		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
 				"traefik.frontend.rule": "Host:foo.bar",
@@ -63,7 +80,7 @@ func TestSwarmGetFrontendName(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			provider := &Provider{
 				Domain:    "docker.localhost",
 				SwarmMode: true,
@@ -106,18 +123,62 @@ func TestSwarmGetFrontendRule(t *testing.T) {
 			expected: "Path:/test",
 			networks: map[string]*docker.NetworkResource{},
 		},
+		{
+			service: swarmService(
+				serviceName("api"),
+				serviceImage("registry.local:5000/acme/api:1.2@sha256:abcdef"),
+				serviceReplicas(3),
+				serviceLabels(map[string]string{
+					"traefik.frontend.rule": "Host:{{.ServiceName}}-{{.Image.Tag}}.example.com;Headers:X-Replicas,{{.Replicas}};Headers:X-Env,{{.Labels.env}}",
+					"env":                   "prod",
+				})),
+			expected: "Host:api-1.2.example.com;Headers:X-Replicas,3;Headers:X-Env,prod",
+			networks: map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(
+				serviceName("api"),
+				serviceImage("acme/api"),
+				serviceLabels(map[string]string{
+					"traefik.frontend.rule": "Host:{{.ServiceName}}-{{.Image.Version}}.example.com",
+				})),
+			expected: "Host:{{.ServiceName}}-{{.Image.Version}}.example.com",
+			networks: map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(
+				serviceName("api"),
+				serviceLabels(map[string]string{
+					"traefik.frontend.rule": "Host:{{.Labels.missing}}.example.com",
+				})),
+			expected: "Host:{{.Labels.missing}}.example.com",
+			networks: map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(
+				serviceName("api"),
+				serviceEnv("VIRTUAL_HOST=api.example.com", "DEBUG"),
+				serviceLabels(map[string]string{
+					"traefik.frontend.rule": "Host:{{.Env.VIRTUAL_HOST}}",
+				})),
+			expected: "Host:api.example.com",
+			networks: map[string]*docker.NetworkResource{},
+		},
 	}
 
 	for serviceID, e := range services {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			provider := &Provider{
 				Domain:    "docker.localhost",
 				SwarmMode: true,
 			}
-			actual := provider.getFrontendRule(dockerData)
+			actual, err := provider.getFrontendRule(dockerData)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
@@ -148,13 +209,25 @@ func TestSwarmGetBackend(t *testing.T) {
 			expected: "foobar",
 			networks: map[string]*docker.NetworkResource{},
 		},
+		{
+			service:  swarmService(serviceName("Stack_Web.API")),
+			expected: "stack-web-api",
+			networks: map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.backend": "Foo//Bar",
+			})),
+			expected: "foo-bar",
+			networks: map[string]*docker.NetworkResource{},
+		},
 	}
 
 	for serviceID, e := range services {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			provider := &Provider{
 				SwarmMode: true,
 			}
@@ -166,6 +239,63 @@ func TestSwarmGetBackend(t *testing.T) {
 	}
 }
 
+func TestSwarmGetNameTemplates(t *testing.T) {
+	services := []struct {
+		service              swarm.Service
+		frontendNameTemplate string
+		backendNameTemplate  string
+		expectedFrontend     string
+		expectedBackend      string
+	}{
+		{
+			service: swarmService(serviceName("foo"), serviceLabels(map[string]string{
+				"com.docker.stack.namespace": "shop",
+			})),
+			frontendNameTemplate: `{{index .Labels "com.docker.stack.namespace"}}-{{.ServiceName}}`,
+			backendNameTemplate:  `{{index .Labels "com.docker.stack.namespace"}}/{{.ServiceName}}`,
+			expectedFrontend:     "shop-foo",
+			expectedBackend:      "shop-foo",
+		},
+		{
+			service: swarmService(serviceName("foo"), serviceLabels(map[string]string{
+				"traefik.frontend.rule.0": "Host:foo.bar",
+				"traefik.frontend.rule.1": "Path:/foo",
+			})),
+			frontendNameTemplate: `{{.ServiceName}}`,
+			expectedFrontend:     "foo-0",
+			expectedBackend:      "foo",
+		},
+		{
+			service:              swarmService(serviceName("foo")),
+			frontendNameTemplate: `{{.Unknown}}`,
+			backendNameTemplate:  ` `,
+			expectedFrontend:     "Host-foo-docker-localhost",
+			expectedBackend:      "foo",
+		},
+	}
+
+	for serviceID, e := range services {
+		e := e
+		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(e.service, map[string]*docker.NetworkResource{})
+			provider := &Provider{
+				Domain:               "docker.localhost",
+				SwarmMode:            true,
+				FrontendNameTemplate: e.frontendNameTemplate,
+				BackendNameTemplate:  e.backendNameTemplate,
+			}
+			frontends := provider.getRuleFrontends(dockerData)
+			if actual := provider.getFrontendName(frontends[0]); actual != e.expectedFrontend {
+				t.Errorf("expected frontend %q, got %q", e.expectedFrontend, actual)
+			}
+			if actual := provider.getBackend(dockerData); actual != e.expectedBackend {
+				t.Errorf("expected backend %q, got %q", e.expectedBackend, actual)
+			}
+		})
+	}
+}
+
 func TestSwarmGetIPAddress(t *testing.T) {
 	services := []struct {
 		service  swarm.Service
@@ -210,13 +340,34 @@ func TestSwarmGetIPAddress(t *testing.T) {
 				},
 			},
 		},
+		{
+			service: swarmService(
+				serviceLabels(map[string]string{
+					"traefik.docker.network": "missingnet,barnet",
+				}),
+				withEndpointSpec(modeVIP),
+				withEndpoint(
+					virtualIP("1", "10.11.12.13/24"),
+					virtualIP("2", "10.11.12.99/24"),
+				),
+			),
+			expected: "10.11.12.99",
+			networks: map[string]*docker.NetworkResource{
+				"1": {
+					Name: "foonet",
+				},
+				"2": {
+					Name: "barnet",
+				},
+			},
+		},
 	}
 
 	for serviceID, e := range services {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			provider := &Provider{
 				SwarmMode: true,
 			}
@@ -244,13 +395,48 @@ func TestSwarmGetPort(t *testing.T) {
 			expected: "8080",
 			networks: map[string]*docker.NetworkResource{},
 		},
+		{
+			service: swarmService(
+				withEndpointSpec(modeVIP),
+				withEndpoint(
+					publishedPort(8080, 30080),
+					publishedPort(443, 30443),
+					publishedPort(80, 30000),
+				),
+			),
+			expected: "80",
+			networks: map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(
+				withEndpointSpec(modeVIP),
+				withEndpoint(
+					publishedPort(8080, 30080),
+					publishedPort(80, 30000),
+				),
+			),
+			expected: "80",
+			networks: map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(
+				withEndpointSpec(modeDNSSR),
+				withEndpoint(
+					publishedPort(8443, 30443),
+					publishedPort(9000, 30900),
+					publishedPort(443, 30444),
+				),
+			),
+			expected: "443",
+			networks: map[string]*docker.NetworkResource{},
+		},
 	}
 
 	for serviceID, e := range services {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			provider := &Provider{
 				SwarmMode: true,
 			}
@@ -262,23 +448,73 @@ func TestSwarmGetPort(t *testing.T) {
 	}
 }
 
-func TestSwarmGetWeight(t *testing.T) {
+func TestSwarmGetServerURLPublishedPort(t *testing.T) {
 	services := []struct {
 		service  swarm.Service
+		endpoint string
 		expected string
 		networks map[string]*docker.NetworkResource
 	}{
 		{
-			service:  swarmService(),
-			expected: "0",
-			networks: map[string]*docker.NetworkResource{},
+			service: swarmService(
+				serviceName("foo"),
+				serviceLabels(map[string]string{
+					"traefik.port":          "80",
+					"traefik.publishedPort": "8080",
+				}),
+				withEndpoint(
+					virtualIP("1", "10.11.12.13/24"),
+					publishedPort(80, 8080),
+					publishedPort(443, 8443),
+				),
+				withEndpointSpec(modeVIP),
+			),
+			endpoint: "tcp://192.168.1.10:2375",
+			expected: "http://192.168.1.10:8080",
+			networks: map[string]*docker.NetworkResource{
+				"1": {Name: "foo"},
+			},
 		},
 		{
-			service: swarmService(serviceLabels(map[string]string{
-				"traefik.weight": "10",
-			})),
-			expected: "10",
-			networks: map[string]*docker.NetworkResource{},
+			service: swarmService(
+				serviceName("foo"),
+				serviceLabels(map[string]string{
+					"traefik.port":          "443",
+					"traefik.protocol":      "https",
+					"traefik.publishedPort": "8443",
+				}),
+				withEndpoint(
+					virtualIP("1", "10.11.12.13/24"),
+					publishedPort(80, 8080),
+					publishedPort(443, 8443),
+				),
+				withEndpointSpec(modeVIP),
+			),
+			endpoint: "unix:///var/run/docker.sock",
+			expected: "https://127.0.0.1:8443",
+			networks: map[string]*docker.NetworkResource{
+				"1": {Name: "foo"},
+			},
+		},
+		{
+			service: swarmService(
+				serviceName("foo"),
+				serviceLabels(map[string]string{
+					"traefik.port":          "80",
+					"traefik.publishedPort": "9090",
+				}),
+				withEndpoint(
+					virtualIP("1", "10.11.12.13/24"),
+					publishedPort(80, 8080),
+					publishedPort(443, 8443),
+				),
+				withEndpointSpec(modeVIP),
+			),
+			endpoint: "tcp://192.168.1.10:2375",
+			expected: "http://10.11.12.13:80",
+			networks: map[string]*docker.NetworkResource{
+				"1": {Name: "foo"},
+			},
 		},
 	}
 
@@ -286,11 +522,12 @@ func TestSwarmGetWeight(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			provider := &Provider{
 				SwarmMode: true,
 			}
-			actual := provider.getWeight(dockerData)
+			provider.Endpoint = e.endpoint
+			actual := provider.getServerURL(dockerData)
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
@@ -298,23 +535,48 @@ func TestSwarmGetWeight(t *testing.T) {
 	}
 }
 
-func TestSwarmGetDomain(t *testing.T) {
+func TestSwarmGetServerURLSwarmLB(t *testing.T) {
 	services := []struct {
 		service  swarm.Service
 		expected string
 		networks map[string]*docker.NetworkResource
 	}{
 		{
-			service:  swarmService(serviceName("foo")),
-			expected: "docker.localhost",
+			service: swarmService(
+				serviceName("foo"),
+				serviceLabels(map[string]string{
+					"traefik.port":                       "80",
+					"traefik.backend.loadbalancer.swarm": "true",
+				}),
+				withEndpointSpec(modeDNSSR),
+			),
+			expected: "http://foo:80",
 			networks: map[string]*docker.NetworkResource{},
 		},
 		{
-			service: swarmService(serviceLabels(map[string]string{
-				"traefik.domain": "foo.bar",
-			})),
-			expected: "foo.bar",
-			networks: map[string]*docker.NetworkResource{},
+			service: swarmService(
+				serviceName("foo"),
+				serviceLabels(map[string]string{
+					"traefik.port":                       "80",
+					"traefik.backend.loadbalancer.swarm": "true",
+				}),
+				withEndpointSpec(modeVIP),
+				withEndpoint(virtualIP("1", "10.11.12.13/24")),
+			),
+			expected: "http://foo:80",
+			networks: map[string]*docker.NetworkResource{"1": {Name: "foonet"}},
+		},
+		{
+			service: swarmService(
+				serviceName("foo"),
+				serviceLabels(map[string]string{
+					"traefik.port": "80",
+				}),
+				withEndpointSpec(modeVIP),
+				withEndpoint(virtualIP("1", "10.11.12.13/24")),
+			),
+			expected: "http://10.11.12.13:80",
+			networks: map[string]*docker.NetworkResource{"1": {Name: "foonet"}},
 		},
 	}
 
@@ -322,20 +584,22 @@ func TestSwarmGetDomain(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			service, _ := parseService(e.service, e.networks)
 			provider := &Provider{
-				Domain:    "docker.localhost",
 				SwarmMode: true,
 			}
-			actual := provider.getDomain(dockerData)
+			actual := provider.getServerURL(service)
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
+			if errors := provider.getConfigErrors(provider.filterContainers([]dockerData{service})); len(errors) > 0 {
+				t.Errorf("unexpected config errors %+v", errors)
+			}
 		})
 	}
 }
 
-func TestSwarmGetProtocol(t *testing.T) {
+func TestSwarmGetWeight(t *testing.T) {
 	services := []struct {
 		service  swarm.Service
 		expected string
@@ -343,14 +607,14 @@ func TestSwarmGetProtocol(t *testing.T) {
 	}{
 		{
 			service:  swarmService(),
-			expected: "http",
+			expected: "0",
 			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.protocol": "https",
+				"traefik.weight": "10",
 			})),
-			expected: "https",
+			expected: "10",
 			networks: map[string]*docker.NetworkResource{},
 		},
 	}
@@ -359,11 +623,11 @@ func TestSwarmGetProtocol(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			provider := &Provider{
 				SwarmMode: true,
 			}
-			actual := provider.getProtocol(dockerData)
+			actual := provider.getWeight(dockerData)
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
@@ -371,7 +635,7 @@ func TestSwarmGetProtocol(t *testing.T) {
 	}
 }
 
-func TestSwarmGetPassHostHeader(t *testing.T) {
+func TestSwarmGetRequestIDHeader(t *testing.T) {
 	services := []struct {
 		service  swarm.Service
 		expected string
@@ -379,14 +643,14 @@ func TestSwarmGetPassHostHeader(t *testing.T) {
 	}{
 		{
 			service:  swarmService(),
-			expected: "true",
+			expected: "X-Request-ID",
 			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.frontend.passHostHeader": "false",
+				"traefik.frontend.headers.requestIDHeader": "X-Correlation-ID",
 			})),
-			expected: "false",
+			expected: "X-Correlation-ID",
 			networks: map[string]*docker.NetworkResource{},
 		},
 	}
@@ -395,11 +659,11 @@ func TestSwarmGetPassHostHeader(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			provider := &Provider{
 				SwarmMode: true,
 			}
-			actual := provider.getPassHostHeader(dockerData)
+			actual := provider.getRequestIDHeader(dockerData)
 			if actual != e.expected {
 				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
@@ -407,7 +671,7 @@ func TestSwarmGetPassHostHeader(t *testing.T) {
 	}
 }
 
-func TestSwarmGetLabel(t *testing.T) {
+func TestSwarmGetCaseInsensitive(t *testing.T) {
 	services := []struct {
 		service  swarm.Service
 		expected string
@@ -415,14 +679,14 @@ func TestSwarmGetLabel(t *testing.T) {
 	}{
 		{
 			service:  swarmService(),
-			expected: "Label not found:",
+			expected: "false",
 			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"foo": "bar",
+				"traefik.frontend.rule.caseInsensitive": "true",
 			})),
-			expected: "",
+			expected: "true",
 			networks: map[string]*docker.NetworkResource{},
 		},
 	}
@@ -431,55 +695,61 @@ func TestSwarmGetLabel(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
-			label, err := getLabel(dockerData, "foo")
-			if e.expected != "" {
-				if err == nil || !strings.Contains(err.Error(), e.expected) {
-					t.Errorf("expected an error with %q, got %v", e.expected, err)
-				}
-			} else {
-				if label != "bar" {
-					t.Errorf("expected label 'bar', got %s", label)
-				}
+			dockerData, _ := parseService(e.service, e.networks)
+			provider := &Provider{
+				SwarmMode: true,
+			}
+			actual := provider.getCaseInsensitive(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
 		})
 	}
 }
 
-func TestSwarmGetLabels(t *testing.T) {
+func TestSwarmGetHeaders(t *testing.T) {
 	services := []struct {
-		service        swarm.Service
-		expectedLabels map[string]string
-		expectedError  string
-		networks       map[string]*docker.NetworkResource
+		service  swarm.Service
+		expected *types.Headers
+		networks map[string]*docker.NetworkResource
 	}{
 		{
-			service:        swarmService(),
-			expectedLabels: map[string]string{},
-			expectedError:  "Label not found:",
-			networks:       map[string]*docker.NetworkResource{},
+			service:  swarmService(),
+			expected: nil,
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"foo": "fooz",
+				"traefik.frontend.headers.SSLTemporaryRedirect": "true",
+				"traefik.frontend.headers.STSSeconds":           "31536000",
+				"traefik.frontend.headers.STSPreload":           "true",
+				"traefik.frontend.headers.forceSTSHeader":       "true",
+				"traefik.frontend.headers.hostsProxyHeaders":    "X-Forwarded-Host",
+				"traefik.frontend.headers.referrerPolicy":       "same-origin",
+				"traefik.frontend.headers.publicKey":            `pin-sha256="base64=="; max-age=5184000`,
+				"traefik.frontend.headers.isDevelopment":        "true",
+				"traefik.frontend.headers.customRequestHeaders": "X-Script-Name:/api||invalid",
 			})),
-			expectedLabels: map[string]string{
-				"foo": "fooz",
+			expected: &types.Headers{
+				SSLTemporaryRedirect: true,
+				STSSeconds:           31536000,
+				STSPreload:           true,
+				ForceSTSHeader:       true,
+				HostsProxyHeaders:    []string{"X-Forwarded-Host"},
+				ReferrerPolicy:       "same-origin",
+				PublicKey:            `pin-sha256="base64=="; max-age=5184000`,
+				IsDevelopment:        true,
+				CustomRequestHeaders: map[string]string{"X-Script-Name": "/api"},
 			},
-			expectedError: "Label not found: bar",
-			networks:      map[string]*docker.NetworkResource{},
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"foo": "fooz",
-				"bar": "barz",
+				"traefik.frontend.headers.frameDeny":  "yes please",
+				"traefik.frontend.headers.STSSeconds": "-1",
 			})),
-			expectedLabels: map[string]string{
-				"foo": "fooz",
-				"bar": "barz",
-			},
-			expectedError: "",
-			networks:      map[string]*docker.NetworkResource{},
+			expected: &types.Headers{},
+			networks: map[string]*docker.NetworkResource{},
 		},
 	}
 
@@ -487,102 +757,187 @@ func TestSwarmGetLabels(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
-			labels, err := getLabels(dockerData, []string{"foo", "bar"})
-			if !reflect.DeepEqual(labels, e.expectedLabels) {
-				t.Errorf("expect %v, got %v", e.expectedLabels, labels)
+			dockerData, _ := parseService(e.service, e.networks)
+			provider := &Provider{
+				SwarmMode: true,
 			}
-			if e.expectedError != "" {
-				if err == nil || !strings.Contains(err.Error(), e.expectedError) {
-					t.Errorf("expected an error with %q, got %v", e.expectedError, err)
-				}
+			actual := provider.getHeaders(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %+v, got %+v", e.expected, actual)
 			}
 		})
 	}
 }
 
-func TestSwarmTraefikFilter(t *testing.T) {
+func TestSwarmGetForwardAuth(t *testing.T) {
 	services := []struct {
-		service          swarm.Service
-		exposedByDefault bool
-		expected         bool
-		networks         map[string]*docker.NetworkResource
+		service  swarm.Service
+		expected *types.ForwardAuth
+		networks map[string]*docker.NetworkResource
 	}{
 		{
-			service:          swarmService(),
-			exposedByDefault: true,
-			expected:         false,
-			networks:         map[string]*docker.NetworkResource{},
+			service:  swarmService(),
+			expected: nil,
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.enable": "false",
-				"traefik.port":   "80",
+				"traefik.frontend.auth.forward.trustForwardHeader": "true",
 			})),
-			exposedByDefault: true,
-			expected:         false,
-			networks:         map[string]*docker.NetworkResource{},
+			expected: nil,
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.frontend.rule": "Host:foo.bar",
-				"traefik.port":          "80",
+				"traefik.frontend.auth.forward.address": "http://auth:9000/verify",
 			})),
-			exposedByDefault: true,
-			expected:         true,
-			networks:         map[string]*docker.NetworkResource{},
+			expected: &types.ForwardAuth{Address: "http://auth:9000/verify"},
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.port": "80",
+				"traefik.frontend.auth.forward.address":             "https://auth.example.com/verify",
+				"traefik.frontend.auth.forward.trustForwardHeader":  "true",
+				"traefik.frontend.auth.forward.authResponseHeaders": "X-Auth-User, X-Auth-Role,",
 			})),
-			exposedByDefault: true,
-			expected:         true,
-			networks:         map[string]*docker.NetworkResource{},
+			expected: &types.ForwardAuth{
+				Address:             "https://auth.example.com/verify",
+				TrustForwardHeader:  true,
+				AuthResponseHeaders: []string{"X-Auth-User", "X-Auth-Role"},
+			},
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.enable": "true",
-				"traefik.port":   "80",
+				"traefik.frontend.auth.forward.address":            "http://auth:9000/verify",
+				"traefik.frontend.auth.forward.trustForwardHeader": "yes please",
 			})),
-			exposedByDefault: true,
-			expected:         true,
-			networks:         map[string]*docker.NetworkResource{},
+			expected: &types.ForwardAuth{Address: "http://auth:9000/verify"},
+			networks: map[string]*docker.NetworkResource{},
+		},
+	}
+
+	for serviceID, e := range services {
+		e := e
+		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(e.service, e.networks)
+			provider := &Provider{
+				SwarmMode: true,
+			}
+			actual := provider.getForwardAuth(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %+v, got %+v", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSwarmGetWhitelistSourceRange(t *testing.T) {
+	services := []struct {
+		service  swarm.Service
+		expected []string
+		networks map[string]*docker.NetworkResource
+	}{
+		{
+			service:  swarmService(),
+			expected: nil,
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.enable": "anything",
-				"traefik.port":   "80",
+				"traefik.frontend.whitelistSourceRange": "192.168.1.0/24,10.0.0.0/8",
 			})),
-			exposedByDefault: true,
-			expected:         true,
-			networks:         map[string]*docker.NetworkResource{},
+			expected: []string{"192.168.1.0/24", "10.0.0.0/8"},
+			networks: map[string]*docker.NetworkResource{},
+		},
+	}
+
+	for serviceID, e := range services {
+		e := e
+		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(e.service, e.networks)
+			provider := &Provider{
+				SwarmMode: true,
+			}
+			actual := provider.getWhitelistSourceRange(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSwarmGetRateLimits(t *testing.T) {
+	services := []struct {
+		service  swarm.Service
+		expected map[string]*types.Rate
+		networks map[string]*docker.NetworkResource
+	}{
+		{
+			service:  swarmService(),
+			expected: map[string]*types.Rate{},
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.frontend.rule": "Host:foo.bar",
-				"traefik.port":          "80",
+				"traefik.frontend.ratelimit.extractorfunc":          "client.ip",
+				"traefik.frontend.ratelimit.rateset.second.period":  "1s",
+				"traefik.frontend.ratelimit.rateset.second.average": "10",
+				"traefik.frontend.ratelimit.rateset.minute.period":  "1m",
+				"traefik.frontend.ratelimit.rateset.minute.average": "100",
 			})),
-			exposedByDefault: true,
-			expected:         true,
-			networks:         map[string]*docker.NetworkResource{},
+			expected: map[string]*types.Rate{
+				"second": {Period: "1s", Average: 10},
+				"minute": {Period: "1m", Average: 100},
+			},
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.port": "80",
+				"traefik.frontend.ratelimit.extractorfunc":           "client.ip",
+				"traefik.frontend.ratelimit.rateset.default.average": "0",
+				"traefik.frontend.ratelimit.rateset.default.period":  "1s",
 			})),
-			exposedByDefault: false,
-			expected:         false,
-			networks:         map[string]*docker.NetworkResource{},
+			expected: nil,
+			networks: map[string]*docker.NetworkResource{},
+		},
+	}
+
+	for serviceID, e := range services {
+		e := e
+		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(e.service, e.networks)
+			provider := &Provider{
+				SwarmMode: true,
+			}
+			actual := provider.getRateLimits(dockerData)
+			if !reflect.DeepEqual(actual, e.expected) {
+				t.Errorf("expected %+v, got %+v", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSwarmGetDomain(t *testing.T) {
+	services := []struct {
+		service  swarm.Service
+		expected string
+		networks map[string]*docker.NetworkResource
+	}{
+		{
+			service:  swarmService(serviceName("foo")),
+			expected: "docker.localhost",
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
 			service: swarmService(serviceLabels(map[string]string{
-				"traefik.enable": "true",
-				"traefik.port":   "80",
+				"traefik.domain": "foo.bar",
 			})),
-			exposedByDefault: false,
-			expected:         true,
-			networks:         map[string]*docker.NetworkResource{},
+			expected: "foo.bar",
+			networks: map[string]*docker.NetworkResource{},
 		},
 	}
 
@@ -590,66 +945,758 @@ func TestSwarmTraefikFilter(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			provider := &Provider{
+				Domain:    "docker.localhost",
 				SwarmMode: true,
 			}
-			provider.ExposedByDefault = e.exposedByDefault
-			actual := provider.containerFilter(dockerData)
+			actual := provider.getDomain(dockerData)
 			if actual != e.expected {
-				t.Errorf("expected %v for %+v, got %+v", e.expected, e, actual)
+				t.Errorf("expected %q, got %q", e.expected, actual)
 			}
 		})
 	}
 }
 
-func TestSwarmLoadDockerConfig(t *testing.T) {
-	cases := []struct {
-		services          []swarm.Service
-		expectedFrontends map[string]*types.Frontend
-		expectedBackends  map[string]*types.Backend
-		networks          map[string]*docker.NetworkResource
+func TestSwarmGetProtocol(t *testing.T) {
+	services := []struct {
+		service  swarm.Service
+		expected string
+		networks map[string]*docker.NetworkResource
 	}{
 		{
-			services:          []swarm.Service{},
-			expectedFrontends: map[string]*types.Frontend{},
-			expectedBackends:  map[string]*types.Backend{},
-			networks:          map[string]*docker.NetworkResource{},
+			service:  swarmService(),
+			expected: "http",
+			networks: map[string]*docker.NetworkResource{},
 		},
 		{
-			services: []swarm.Service{
-				swarmService(
-					serviceName("test"),
-					serviceLabels(map[string]string{
-						"traefik.port": "80",
-					}),
-					withEndpointSpec(modeVIP),
-					withEndpoint(virtualIP("1", "127.0.0.1/24")),
-				),
-			},
-			expectedFrontends: map[string]*types.Frontend{
-				"frontend-Host-test-docker-localhost": {
-					Backend:        "backend-test",
-					PassHostHeader: true,
-					EntryPoints:    []string{},
-					BasicAuth:      []string{},
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.protocol": "https",
+			})),
+			expected: "https",
+			networks: map[string]*docker.NetworkResource{},
+		},
+	}
+
+	for serviceID, e := range services {
+		e := e
+		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(e.service, e.networks)
+			provider := &Provider{
+				SwarmMode: true,
+			}
+			actual := provider.getProtocol(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSwarmGetPassHostHeader(t *testing.T) {
+	services := []struct {
+		service  swarm.Service
+		expected string
+		networks map[string]*docker.NetworkResource
+	}{
+		{
+			service:  swarmService(),
+			expected: "true",
+			networks: map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.frontend.passHostHeader": "false",
+			})),
+			expected: "false",
+			networks: map[string]*docker.NetworkResource{},
+		},
+	}
+
+	for serviceID, e := range services {
+		e := e
+		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(e.service, e.networks)
+			provider := &Provider{
+				SwarmMode: true,
+			}
+			actual := provider.getPassHostHeader(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %q, got %q", e.expected, actual)
+			}
+		})
+	}
+}
+
+func TestSwarmGetLabel(t *testing.T) {
+	services := []struct {
+		service  swarm.Service
+		expected string
+		networks map[string]*docker.NetworkResource
+	}{
+		{
+			service:  swarmService(),
+			expected: "Label not found:",
+			networks: map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"foo": "bar",
+			})),
+			expected: "",
+			networks: map[string]*docker.NetworkResource{},
+		},
+	}
+
+	for serviceID, e := range services {
+		e := e
+		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(e.service, e.networks)
+			label, err := getLabel(dockerData, "foo")
+			if e.expected != "" {
+				if err == nil || !strings.Contains(err.Error(), e.expected) {
+					t.Errorf("expected an error with %q, got %v", e.expected, err)
+				}
+			} else {
+				if label != "bar" {
+					t.Errorf("expected label 'bar', got %s", label)
+				}
+			}
+		})
+	}
+}
+
+func TestSwarmGetLabels(t *testing.T) {
+	services := []struct {
+		service        swarm.Service
+		expectedLabels map[string]string
+		expectedError  string
+		networks       map[string]*docker.NetworkResource
+	}{
+		{
+			service:        swarmService(),
+			expectedLabels: map[string]string{},
+			expectedError:  "Label not found:",
+			networks:       map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"foo": "fooz",
+			})),
+			expectedLabels: map[string]string{
+				"foo": "fooz",
+			},
+			expectedError: "Label not found: bar",
+			networks:      map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"foo": "fooz",
+				"bar": "barz",
+			})),
+			expectedLabels: map[string]string{
+				"foo": "fooz",
+				"bar": "barz",
+			},
+			expectedError: "",
+			networks:      map[string]*docker.NetworkResource{},
+		},
+	}
+
+	for serviceID, e := range services {
+		e := e
+		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(e.service, e.networks)
+			labels, err := getLabels(dockerData, []string{"foo", "bar"})
+			if !reflect.DeepEqual(labels, e.expectedLabels) {
+				t.Errorf("expect %v, got %v", e.expectedLabels, labels)
+			}
+			if e.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), e.expectedError) {
+					t.Errorf("expected an error with %q, got %v", e.expectedError, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSwarmTraefikFilter(t *testing.T) {
+	services := []struct {
+		service          swarm.Service
+		exposedByDefault bool
+		expected         bool
+		networks         map[string]*docker.NetworkResource
+	}{
+		{
+			service:          swarmService(),
+			exposedByDefault: true,
+			expected:         false,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.enable": "false",
+				"traefik.port":   "80",
+			})),
+			exposedByDefault: true,
+			expected:         false,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.frontend.rule": "Host:foo.bar",
+				"traefik.port":          "80",
+			})),
+			exposedByDefault: true,
+			expected:         true,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.enable": "true",
+			})),
+			exposedByDefault: false,
+			expected:         false,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(
+				serviceLabels(map[string]string{
+					"traefik.enable": "true",
+				}),
+				withEndpointSpec(modeVIP),
+				withEndpoint(publishedPort(80, 8080)),
+			),
+			exposedByDefault: true,
+			expected:         true,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.enable": "true",
+				"traefik.port":   "80",
+			})),
+			exposedByDefault: false,
+			expected:         true,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.frontend.whitelistSourceRange": "not-a-cidr",
+				"traefik.port":                          "80",
+			})),
+			exposedByDefault: true,
+			expected:         false,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.port": "80",
+			})),
+			exposedByDefault: true,
+			expected:         true,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.enable": "true",
+				"traefik.port":   "80",
+			})),
+			exposedByDefault: true,
+			expected:         true,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.enable": "anything",
+				"traefik.port":   "80",
+			})),
+			exposedByDefault: true,
+			expected:         true,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.frontend.rule": "Host:foo.bar",
+				"traefik.port":          "80",
+			})),
+			exposedByDefault: true,
+			expected:         true,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.port": "80",
+			})),
+			exposedByDefault: false,
+			expected:         false,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+		{
+			service: swarmService(serviceLabels(map[string]string{
+				"traefik.enable": "true",
+				"traefik.port":   "80",
+			})),
+			exposedByDefault: false,
+			expected:         true,
+			networks:         map[string]*docker.NetworkResource{},
+		},
+	}
+
+	for serviceID, e := range services {
+		e := e
+		t.Run(strconv.Itoa(serviceID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(e.service, e.networks)
+			provider := &Provider{
+				SwarmMode: true,
+			}
+			provider.ExposedByDefault = e.exposedByDefault
+			actual := provider.containerFilter(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %v for %+v, got %+v", e.expected, e, actual)
+			}
+		})
+	}
+}
+
+func TestSwarmTraefikFilterLabelConstraints(t *testing.T) {
+	service := swarmService(serviceLabels(map[string]string{
+		"traefik.enable":   "true",
+		"traefik.port":     "80",
+		"com.example.env":  "prod",
+		"com.example.tier": "public",
+	}))
+	cases := []struct {
+		constraints []string
+		expected    bool
+	}{
+		{
+			constraints: nil,
+			expected:    true,
+		},
+		{
+			constraints: []string{},
+			expected:    true,
+		},
+		{
+			constraints: []string{"com.example.env==prod"},
+			expected:    true,
+		},
+		{
+			constraints: []string{"com.example.env==prod", "com.example.tier!=internal"},
+			expected:    true,
+		},
+		{
+			constraints: []string{"com.example.env==pr*", "com.example.missing!=foo"},
+			expected:    true,
+		},
+		{
+			constraints: []string{"com.example.env==staging"},
+			expected:    false,
+		},
+		{
+			constraints: []string{"com.example.env==prod", "com.example.tier!=public"},
+			expected:    false,
+		},
+		{
+			constraints: []string{"com.example.missing==foo"},
+			expected:    false,
+		},
+		{
+			constraints: []string{"com.example.env"},
+			expected:    false,
+		},
+	}
+
+	for caseID, e := range cases {
+		e := e
+		t.Run(strconv.Itoa(caseID), func(t *testing.T) {
+			t.Parallel()
+			dockerData, _ := parseService(service, map[string]*docker.NetworkResource{})
+			provider := &Provider{
+				SwarmMode:        true,
+				LabelConstraints: e.constraints,
+			}
+			actual := provider.containerFilter(dockerData)
+			if actual != e.expected {
+				t.Errorf("expected %v for constraints %q, got %v", e.expected, e.constraints, actual)
+			}
+		})
+	}
+}
+
+func TestSwarmLoadDockerConfig(t *testing.T) {
+	cases := []struct {
+		services          []swarm.Service
+		expectedFrontends map[string]*types.Frontend
+		expectedBackends  map[string]*types.Backend
+		networks          map[string]*docker.NetworkResource
+	}{
+		{
+			services:          []swarm.Service{},
+			expectedFrontends: map[string]*types.Frontend{},
+			expectedBackends:  map[string]*types.Backend{},
+			networks:          map[string]*docker.NetworkResource{},
+		},
+		{
+			services: []swarm.Service{
+				swarmService(
+					serviceName("test"),
+					serviceLabels(map[string]string{
+						"traefik.port": "80",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.1/24")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					LoadBalancer:   nil,
+				},
+			},
+			networks: map[string]*docker.NetworkResource{
+				"1": {
+					Name: "foo",
+				},
+			},
+		},
+		{
+			services: []swarm.Service{
+				swarmService(
+					serviceName("test"),
+					serviceLabels(map[string]string{
+						"traefik.port":            "80",
+						"traefik.frontend.rule.0": "Host:api.example.com",
+						"traefik.frontend.rule.1": "PathPrefix:/api",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.1/24")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-api-example-com-0": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-api-example-com-0": {
+							Rule: "Host:api.example.com",
+						},
+					},
+				},
+				"frontend-PathPrefix-api-1": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-PathPrefix-api-1": {
+							Rule: "PathPrefix:/api",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					LoadBalancer:   nil,
+				},
+			},
+			networks: map[string]*docker.NetworkResource{
+				"1": {
+					Name: "foo",
+				},
+			},
+		},
+		{
+			services: []swarm.Service{
+				swarmService(
+					serviceName("test1"),
+					serviceLabels(map[string]string{
+						"traefik.port":                 "80",
+						"traefik.backend":              "foobar",
+						"traefik.frontend.entryPoints": "http,https",
+						"traefik.frontend.auth.basic":  "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/,test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.1/24")),
+				),
+				swarmService(
+					serviceName("test2"),
+					serviceLabels(map[string]string{
+						"traefik.port":    "80",
+						"traefik.backend": "foobar",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.2/24")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test1-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{"http", "https"},
+					BasicAuth:       []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test1-docker-localhost": {
+							Rule: "Host:test1.docker.localhost",
+						},
+					},
+				},
+				"frontend-Host-test2-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test2-docker-localhost": {
+							Rule: "Host:test2.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-foobar": {
+					Servers: map[string]types.Server{
+						"server-test1": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+						"server-test2": {
+							URL:    "http://127.0.0.2:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					LoadBalancer:   nil,
+				},
+			},
+			networks: map[string]*docker.NetworkResource{
+				"1": {
+					Name: "foo",
+				},
+			},
+		},
+		{
+			services: []swarm.Service{
+				swarmService(
+					serviceName("test1"),
+					serviceLabels(map[string]string{
+						"traefik.port":                 "80",
+						"traefik.backend":              "foobar",
+						"traefik.frontend.entryPoints": "http,https",
+						"traefik.frontend.auth.digest": "test:traefik:a2688e031edb4be6a3797f3882655c05, test2:traefik:518845800f9e2bfb1f1f740ec24f074e,invalid:a2688e031edb4be6a3797f3882655c05",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.1/24")),
+				),
+				swarmService(
+					serviceName("test2"),
+					serviceLabels(map[string]string{
+						"traefik.port":    "80",
+						"traefik.backend": "foobar",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.2/24")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test1-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{"http", "https"},
+					BasicAuth:       []string{},
+					DigestAuth:      []string{"test:traefik:a2688e031edb4be6a3797f3882655c05", "test2:traefik:518845800f9e2bfb1f1f740ec24f074e"},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test1-docker-localhost": {
+							Rule: "Host:test1.docker.localhost",
+						},
+					},
+				},
+				"frontend-Host-test2-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test2-docker-localhost": {
+							Rule: "Host:test2.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-foobar": {
+					Servers: map[string]types.Server{
+						"server-test1": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+						"server-test2": {
+							URL:    "http://127.0.0.2:80",
+							Weight: 0,
+						},
+					},
+					CircuitBreaker: nil,
+					LoadBalancer:   nil,
+				},
+			},
+			networks: map[string]*docker.NetworkResource{
+				"1": {
+					Name: "foo",
+				},
+			},
+		},
+		{
+			services: []swarm.Service{
+				swarmService(
+					serviceName("test"),
+					serviceLabels(map[string]string{
+						"traefik.port":                 "80",
+						"traefik.frontend.entryPoints": "http,https",
+						"traefik.frontend.redirect":    "https",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.1/24")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					Redirect:        "https",
+					EntryPoints:     []string{"http", "https"},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+			networks: map[string]*docker.NetworkResource{
+				"1": {
+					Name: "foo",
+				},
+			},
+		},
+		{
+			services: []swarm.Service{
+				swarmService(
+					serviceName("test2"),
+					serviceLabels(map[string]string{
+						"traefik.port":           "80",
+						"traefik.backend":        "foobar",
+						"traefik.backend.sticky": "false",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.2/24")),
+				),
+				swarmService(
+					serviceName("test1"),
+					serviceLabels(map[string]string{
+						"traefik.port":           "80",
+						"traefik.backend":        "foobar",
+						"traefik.backend.sticky": "true",
+						"traefik.backend.loadbalancer.stickiness.cookieName": "_app_session",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.1/24")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test1-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test1-docker-localhost": {
+							Rule: "Host:test1.docker.localhost",
+						},
+					},
+				},
+				"frontend-Host-test2-docker-localhost": {
+					Backend:         "backend-foobar",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
 					Routes: map[string]types.Route{
-						"route-frontend-Host-test-docker-localhost": {
-							Rule: "Host:test.docker.localhost",
+						"route-frontend-Host-test2-docker-localhost": {
+							Rule: "Host:test2.docker.localhost",
 						},
 					},
 				},
 			},
 			expectedBackends: map[string]*types.Backend{
-				"backend-test": {
+				"backend-foobar": {
 					Servers: map[string]types.Server{
-						"server-test": {
+						"server-test1": {
 							URL:    "http://127.0.0.1:80",
 							Weight: 0,
 						},
+						"server-test2": {
+							URL:    "http://127.0.0.2:80",
+							Weight: 0,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method:     "wrr",
+						Sticky:     true,
+						CookieName: "_app_session",
 					},
-					CircuitBreaker: nil,
-					LoadBalancer:   nil,
 				},
 			},
 			networks: map[string]*docker.NetworkResource{
@@ -664,9 +1711,7 @@ func TestSwarmLoadDockerConfig(t *testing.T) {
 					serviceName("test1"),
 					serviceLabels(map[string]string{
 						"traefik.port":                 "80",
-						"traefik.backend":              "foobar",
-						"traefik.frontend.entryPoints": "http,https",
-						"traefik.frontend.auth.basic":  "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/,test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0",
+						"traefik.frontend.passTLSCert": "true",
 					}),
 					withEndpointSpec(modeVIP),
 					withEndpoint(virtualIP("1", "127.0.0.1/24")),
@@ -674,19 +1719,22 @@ func TestSwarmLoadDockerConfig(t *testing.T) {
 				swarmService(
 					serviceName("test2"),
 					serviceLabels(map[string]string{
-						"traefik.port":    "80",
-						"traefik.backend": "foobar",
+						"traefik.port":                     "80",
+						"traefik.frontend.passTLSCert":     "false",
+						"traefik.frontend.passTLSCert.pem": "true",
 					}),
 					withEndpointSpec(modeVIP),
-					withEndpoint(virtualIP("1", "127.0.0.1/24")),
+					withEndpoint(virtualIP("1", "127.0.0.2/24")),
 				),
 			},
 			expectedFrontends: map[string]*types.Frontend{
 				"frontend-Host-test1-docker-localhost": {
-					Backend:        "backend-foobar",
-					PassHostHeader: true,
-					EntryPoints:    []string{"http", "https"},
-					BasicAuth:      []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test2:$apr1$d9hr9HBB$4HxwgUir3HP4EsggP/QNo0"},
+					Backend:         "backend-test1",
+					PassHostHeader:  true,
+					PassTLSCert:     true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
 					Routes: map[string]types.Route{
 						"route-frontend-Host-test1-docker-localhost": {
 							Rule: "Host:test1.docker.localhost",
@@ -694,10 +1742,12 @@ func TestSwarmLoadDockerConfig(t *testing.T) {
 					},
 				},
 				"frontend-Host-test2-docker-localhost": {
-					Backend:        "backend-foobar",
-					PassHostHeader: true,
-					EntryPoints:    []string{},
-					BasicAuth:      []string{},
+					Backend:         "backend-test2",
+					PassHostHeader:  true,
+					PassTLSCertPEM:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
 					Routes: map[string]types.Route{
 						"route-frontend-Host-test2-docker-localhost": {
 							Rule: "Host:test2.docker.localhost",
@@ -706,19 +1756,62 @@ func TestSwarmLoadDockerConfig(t *testing.T) {
 				},
 			},
 			expectedBackends: map[string]*types.Backend{
-				"backend-foobar": {
+				"backend-test1": {
 					Servers: map[string]types.Server{
 						"server-test1": {
 							URL:    "http://127.0.0.1:80",
 							Weight: 0,
 						},
+					},
+				},
+				"backend-test2": {
+					Servers: map[string]types.Server{
 						"server-test2": {
-							URL:    "http://127.0.0.1:80",
+							URL:    "http://127.0.0.2:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+			networks: map[string]*docker.NetworkResource{
+				"1": {
+					Name: "foo",
+				},
+			},
+		},
+		{
+			services: []swarm.Service{
+				swarmService(
+					serviceName("test"),
+					serviceLabels(map[string]string{
+						"traefik.backend.server.url": "https://10.0.0.10:8443",
+					}),
+					withEndpointSpec(modeVIP),
+					withEndpoint(virtualIP("1", "127.0.0.1/24")),
+				),
+			},
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "https://10.0.0.10:8443",
 							Weight: 0,
 						},
 					},
-					CircuitBreaker: nil,
-					LoadBalancer:   nil,
 				},
 			},
 			networks: map[string]*docker.NetworkResource{
@@ -735,7 +1828,7 @@ func TestSwarmLoadDockerConfig(t *testing.T) {
 			t.Parallel()
 			var dockerDataList []dockerData
 			for _, service := range c.services {
-				dockerData := parseService(service, c.networks)
+				dockerData, _ := parseService(service, c.networks)
 				dockerDataList = append(dockerDataList, dockerData)
 			}
 
@@ -756,16 +1849,126 @@ func TestSwarmLoadDockerConfig(t *testing.T) {
 	}
 }
 
+func TestSwarmLoadDockerConfigStrictLabelParsing(t *testing.T) {
+	service := swarmService(
+		serviceName("test"),
+		serviceLabels(map[string]string{
+			"traefik.port":                    "80",
+			"traefik.weight":                  "ten",
+			"traefik.frontend.passHostHeader": "yes",
+		}),
+		withEndpointSpec(modeVIP),
+		withEndpoint(virtualIP("1", "127.0.0.1/24")),
+	)
+	networks := map[string]*docker.NetworkResource{
+		"1": {
+			Name: "foo",
+		},
+	}
+
+	cases := []struct {
+		strictLabelParsing bool
+		expectedFrontends  map[string]*types.Frontend
+		expectedBackends   map[string]*types.Backend
+	}{
+		{
+			strictLabelParsing: false,
+			expectedFrontends: map[string]*types.Frontend{
+				"frontend-Host-test-docker-localhost": {
+					Backend:         "backend-test",
+					PassHostHeader:  true,
+					RequestIDHeader: "X-Request-ID",
+					EntryPoints:     []string{},
+					BasicAuth:       []string{},
+					Routes: map[string]types.Route{
+						"route-frontend-Host-test-docker-localhost": {
+							Rule: "Host:test.docker.localhost",
+						},
+					},
+				},
+			},
+			expectedBackends: map[string]*types.Backend{
+				"backend-test": {
+					Servers: map[string]types.Server{
+						"server-test": {
+							URL:    "http://127.0.0.1:80",
+							Weight: 0,
+						},
+					},
+				},
+			},
+		},
+		{
+			strictLabelParsing: true,
+			expectedFrontends:  map[string]*types.Frontend{},
+			expectedBackends:   map[string]*types.Backend{},
+		},
+	}
+
+	for caseID, c := range cases {
+		c := c
+		t.Run(strconv.Itoa(caseID), func(t *testing.T) {
+			t.Parallel()
+			serviceData, labelErrors := parseService(service, networks)
+			serviceData.LabelErrors = labelErrors
+
+			provider := &Provider{
+				Domain:             "docker.localhost",
+				ExposedByDefault:   true,
+				SwarmMode:          true,
+				StrictLabelParsing: c.strictLabelParsing,
+			}
+			actualConfig := provider.loadDockerConfig([]dockerData{serviceData})
+			if !reflect.DeepEqual(actualConfig.Backends, c.expectedBackends) {
+				t.Errorf("expected %#v, got %#v", c.expectedBackends, actualConfig.Backends)
+			}
+			if !reflect.DeepEqual(actualConfig.Frontends, c.expectedFrontends) {
+				t.Errorf("expected %#v, got %#v", c.expectedFrontends, actualConfig.Frontends)
+			}
+		})
+	}
+}
+
+func TestParseServiceLabelErrors(t *testing.T) {
+	service := swarmService(
+		serviceName("test"),
+		serviceLabels(map[string]string{
+			"traefik.port":              "http",
+			"traefik.weight":            "ten",
+			"traefik.frontend.priority": "10",
+			"traefik.backend.tls":       "yes",
+			"ext.weight":                "heavy",
+			"com.example.weight":        "heavy",
+		}),
+	)
+
+	_, labelErrors := parseService(service, map[string]*docker.NetworkResource{})
+	var actual []string
+	for _, err := range labelErrors {
+		actual = append(actual, err.Error())
+	}
+	expected := []string{
+		`container test, label ext.weight: invalid integer "heavy"`,
+		`container test, label traefik.backend.tls: invalid boolean "yes"`,
+		`container test, label traefik.port: invalid port "http"`,
+		`container test, label traefik.weight: invalid integer "ten"`,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
 func TestSwarmTaskParsing(t *testing.T) {
 	cases := []struct {
 		service       swarm.Service
 		tasks         []swarm.Task
 		isGlobalSVC   bool
+		nodeHostnames map[string]string
 		expectedNames map[string]string
 		networks      map[string]*docker.NetworkResource
 	}{
 		{
-			service: swarmService(serviceName("container")),
+			service: swarmService(serviceName("container"), serviceImage("acme/api:1.2"), serviceReplicas(3)),
 			tasks: []swarm.Task{
 				swarmTask("id1", taskSlot(1)),
 				swarmTask("id2", taskSlot(2)),
@@ -802,19 +2005,67 @@ func TestSwarmTaskParsing(t *testing.T) {
 				},
 			},
 		},
+		{
+			// the old and new tasks of a node during a rolling update
+			service: swarmService(serviceName("container")),
+			tasks: []swarm.Task{
+				swarmTask("id1"),
+				swarmTask("id2"),
+			},
+			isGlobalSVC: true,
+			nodeHostnames: map[string]string{
+				"id1": "node-1",
+				"id2": "node-1",
+			},
+			expectedNames: map[string]string{
+				"id1": "container.node-1.id1",
+				"id2": "container.node-1.id2",
+			},
+			networks: map[string]*docker.NetworkResource{
+				"1": {
+					Name: "foo",
+				},
+			},
+		},
+		{
+			service: swarmService(serviceName("container")),
+			tasks: []swarm.Task{
+				swarmTask("id1"),
+				swarmTask("id2"),
+				swarmTask("id3"),
+			},
+			isGlobalSVC: true,
+			nodeHostnames: map[string]string{
+				"id1": "node-1",
+				"id2": "node-2",
+			},
+			expectedNames: map[string]string{
+				"id1": "container.node-1.id1",
+				"id2": "container.node-2.id2",
+				"id3": "container.id3",
+			},
+			networks: map[string]*docker.NetworkResource{
+				"1": {
+					Name: "foo",
+				},
+			},
+		},
 	}
 
 	for caseID, e := range cases {
 		e := e
 		t.Run(strconv.Itoa(caseID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 
 			for _, task := range e.tasks {
-				taskDockerData := parseTasks(task, dockerData, map[string]*docker.NetworkResource{}, e.isGlobalSVC)
+				taskDockerData := parseTasks(task, dockerData, map[string]*docker.NetworkResource{}, e.isGlobalSVC, e.nodeHostnames)
 				if !reflect.DeepEqual(taskDockerData.Name, e.expectedNames[task.ID]) {
 					t.Errorf("expect %v, got %v", e.expectedNames[task.ID], taskDockerData.Name)
 				}
+				if taskDockerData.Image != dockerData.Image || taskDockerData.Replicas != dockerData.Replicas {
+					t.Errorf("expect service image %q and replicas %d, got %q and %d", dockerData.Image, dockerData.Replicas, taskDockerData.Image, taskDockerData.Replicas)
+				}
 			}
 		})
 	}
@@ -822,14 +2073,76 @@ func TestSwarmTaskParsing(t *testing.T) {
 
 type fakeTasksClient struct {
 	dockerclient.APIClient
-	tasks []swarm.Task
-	err   error
+	tasks   []swarm.Task
+	nodes   []swarm.Node
+	err     error
+	options dockertypes.TaskListOptions
 }
 
 func (c *fakeTasksClient) TaskList(ctx context.Context, options dockertypes.TaskListOptions) ([]swarm.Task, error) {
+	c.options = options
 	return c.tasks, c.err
 }
 
+func (c *fakeTasksClient) NodeList(ctx context.Context, options dockertypes.NodeListOptions) ([]swarm.Node, error) {
+	return c.nodes, c.err
+}
+
+// fakeServicesClient lists services and records the task list requests
+type fakeServicesClient struct {
+	dockerclient.APIClient
+	services  []swarm.Service
+	taskLists []string
+}
+
+func (c *fakeServicesClient) ServiceList(ctx context.Context, options dockertypes.ServiceListOptions) ([]swarm.Service, error) {
+	return c.services, nil
+}
+
+func (c *fakeServicesClient) NetworkList(ctx context.Context, options dockertypes.NetworkListOptions) ([]dockertypes.NetworkResource, error) {
+	return []dockertypes.NetworkResource{}, nil
+}
+
+func (c *fakeServicesClient) TaskList(ctx context.Context, options dockertypes.TaskListOptions) ([]swarm.Task, error) {
+	c.taskLists = append(c.taskLists, options.Filter.Get("service")...)
+	return []swarm.Task{}, nil
+}
+
+func TestListServicesSwarmLB(t *testing.T) {
+	swarmLB := swarmService(
+		serviceName("swarmlb"),
+		serviceLabels(map[string]string{
+			"traefik.port":                       "80",
+			"traefik.backend.loadbalancer.swarm": "true",
+		}),
+		withEndpointSpec(modeDNSSR),
+	)
+	swarmLB.ID = "swarmlb"
+	tasks := swarmService(serviceName("tasks"), serviceLabels(map[string]string{"traefik.port": "80"}))
+	tasks.ID = "tasks"
+
+	dockerClient := &fakeServicesClient{services: []swarm.Service{swarmLB}}
+	provider := &Provider{SwarmMode: true}
+	dockerDataList, err := provider.listServices(context.Background(), dockerClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dockerClient.taskLists) != 0 {
+		t.Errorf("expected no task list request, got requests for %v", dockerClient.taskLists)
+	}
+	if len(dockerDataList) != 1 || provider.getServerURL(dockerDataList[0]) != "http://swarmlb:80" {
+		t.Errorf("expected a single server resolved by the Swarm DNS, got %+v", dockerDataList)
+	}
+
+	dockerClient = &fakeServicesClient{services: []swarm.Service{swarmLB, tasks}}
+	if _, err := provider.listServices(context.Background(), dockerClient); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(dockerClient.taskLists, []string{"tasks"}) {
+		t.Errorf("expected task list requests for [tasks] only, got %v", dockerClient.taskLists)
+	}
+}
+
 func TestListTasks(t *testing.T) {
 	cases := []struct {
 		service       swarm.Service
@@ -864,9 +2177,9 @@ func TestListTasks(t *testing.T) {
 		e := e
 		t.Run(strconv.Itoa(caseID), func(t *testing.T) {
 			t.Parallel()
-			dockerData := parseService(e.service, e.networks)
+			dockerData, _ := parseService(e.service, e.networks)
 			dockerClient := &fakeTasksClient{tasks: e.tasks}
-			taskDockerData, _ := listTasks(context.Background(), dockerClient, e.service.ID, dockerData, map[string]*docker.NetworkResource{}, e.isGlobalSVC)
+			taskDockerData, _ := listTasks(context.Background(), dockerClient, e.service.ID, dockerData, map[string]*docker.NetworkResource{}, e.isGlobalSVC, nil, nil, 0)
 
 			if len(e.expectedTasks) != len(taskDockerData) {
 				t.Errorf("expected tasks %v, got %v", spew.Sdump(e.expectedTasks), spew.Sdump(taskDockerData))
@@ -880,3 +2193,259 @@ func TestListTasks(t *testing.T) {
 		})
 	}
 }
+
+func TestListTasksSharedAddress(t *testing.T) {
+	networks := map[string]*docker.NetworkResource{
+		"1": {
+			Name: "foo",
+		},
+	}
+	tasks := []swarm.Task{
+		swarmTask("id1", taskSlot(1), taskStatus(taskState(swarm.TaskStateRunning)), taskNetworkAttachment("1", "10.0.0.2/24")),
+		swarmTask("id2", taskSlot(2), taskStatus(taskState(swarm.TaskStateRunning)), taskNetworkAttachment("1", "10.0.0.2/24")),
+		swarmTask("id3", taskSlot(3), taskStatus(taskState(swarm.TaskStateRunning)), taskNetworkAttachment("1", "10.0.0.2/24")),
+	}
+	labels := serviceLabels(map[string]string{
+		"traefik.port":   "80",
+		"traefik.weight": "10",
+	})
+
+	cases := []struct {
+		desc            string
+		service         swarm.Service
+		expectedTasks   int
+		expectedServers map[string]types.Server
+	}{
+		{
+			desc:          "VIP",
+			service:       swarmService(serviceName("test"), labels, withEndpointSpec(modeVIP), withEndpoint(virtualIP("1", "10.0.0.1/24"))),
+			expectedTasks: 1,
+			expectedServers: map[string]types.Server{
+				"server-test-1": {URL: "http://10.0.0.2:80", Weight: 30},
+			},
+		},
+		{
+			desc:          "DNSRR",
+			service:       swarmService(serviceName("test"), labels, withEndpointSpec(modeDNSSR)),
+			expectedTasks: 3,
+			expectedServers: map[string]types.Server{
+				"server-test-1": {URL: "http://10.0.0.2:80", Weight: 10},
+			},
+		},
+	}
+
+	for _, e := range cases {
+		dockerData, _ := parseService(e.service, networks)
+		dockerClient := &fakeTasksClient{tasks: tasks}
+		taskDockerData, err := listTasks(context.Background(), dockerClient, e.service.ID, dockerData, networks, false, nil, nil, 0)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", e.desc, err)
+		}
+		if len(taskDockerData) != e.expectedTasks {
+			t.Errorf("%s: expected %d tasks, got %d", e.desc, e.expectedTasks, len(taskDockerData))
+		}
+		provider := &Provider{
+			Domain:           "docker.localhost",
+			ExposedByDefault: true,
+			SwarmMode:        true,
+		}
+		config := provider.loadDockerConfig(taskDockerData)
+		backend, ok := config.Backends["backend-test"]
+		if !ok {
+			t.Fatalf("%s: expected backend-test, got %v", e.desc, config.Backends)
+		}
+		if !reflect.DeepEqual(backend.Servers, e.expectedServers) {
+			t.Errorf("%s: expected servers %+v, got %+v", e.desc, e.expectedServers, backend.Servers)
+		}
+	}
+}
+
+func TestListTasksGlobalService(t *testing.T) {
+	service := swarmService(serviceName("container"))
+	dockerData, _ := parseService(service, map[string]*docker.NetworkResource{})
+	node := swarm.Node{ID: "node1"}
+	node.Description.Hostname = "worker-1"
+	dockerClient := &fakeTasksClient{
+		tasks: []swarm.Task{
+			swarmTask("id1", taskNode("node1"), taskStatus(taskState(swarm.TaskStateRunning))),
+			swarmTask("id2", taskNode("node2"), taskStatus(taskState(swarm.TaskStateRunning))),
+		},
+		nodes: []swarm.Node{node},
+	}
+
+	taskDockerData, err := listTasks(context.Background(), dockerClient, service.ID, dockerData, map[string]*docker.NetworkResource{}, true, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, task := range taskDockerData {
+		names = append(names, task.Name)
+	}
+	if expected := []string{"container.worker-1.id1", "container.id2"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected tasks %v, got %v", expected, names)
+	}
+}
+
+func TestListTasksWarmup(t *testing.T) {
+	now := time.Now()
+	service := swarmService(serviceName("container"))
+	dockerData, _ := parseService(service, map[string]*docker.NetworkResource{})
+	dockerClient := &fakeTasksClient{tasks: []swarm.Task{
+		swarmTask("id1", taskSlot(1), taskStatus(taskState(swarm.TaskStateRunning), taskTimestamp(now.Add(-time.Hour)))),
+		swarmTask("id2", taskSlot(2), taskStatus(taskState(swarm.TaskStatePreparing), taskTimestamp(now.Add(-5*time.Second)))),
+		swarmTask("id3", taskSlot(3), taskStatus(taskState(swarm.TaskStateStarting), taskTimestamp(now.Add(-20*time.Second)))),
+		swarmTask("id4", taskSlot(4), taskStatus(taskState(swarm.TaskStateStarting), taskTimestamp(now.Add(-45*time.Second)))),
+		swarmTask("id5", taskSlot(5), taskStatus(taskState(swarm.TaskStatePreparing), taskTimestamp(now.Add(-10*time.Minute)))),
+		swarmTask("id6", taskSlot(6), taskStatus(taskState(swarm.TaskStatePending), taskTimestamp(now))),
+	}}
+
+	cases := []struct {
+		warmupSeconds int
+		expectedTasks []string
+	}{
+		{warmupSeconds: 0, expectedTasks: []string{"container.1"}},
+		{warmupSeconds: 30, expectedTasks: []string{"container.1", "container.2", "container.3"}},
+		{warmupSeconds: 60, expectedTasks: []string{"container.1", "container.2", "container.3", "container.4"}},
+	}
+
+	for _, e := range cases {
+		taskDockerData, err := listTasks(context.Background(), dockerClient, service.ID, dockerData, map[string]*docker.NetworkResource{}, false, nil, nil, e.warmupSeconds)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var names []string
+		for _, task := range taskDockerData {
+			names = append(names, task.Name)
+		}
+		if !reflect.DeepEqual(names, e.expectedTasks) {
+			t.Errorf("warm-up of %ds: expected tasks %v, got %v", e.warmupSeconds, e.expectedTasks, names)
+		}
+	}
+}
+
+func TestListTasksWithSwarmTaskFilter(t *testing.T) {
+	service := swarmService(serviceName("container"))
+	dockerData, _ := parseService(service, map[string]*docker.NetworkResource{})
+	running := taskStatus(taskState(swarm.TaskStateRunning))
+	dockerClient := &fakeTasksClient{tasks: []swarm.Task{
+		swarmTask("id1", taskSlot(1), running, taskNode("node-1")),
+		swarmTask("id2", taskSlot(2), running, taskNode("node-2")),
+		swarmTask("id3", taskSlot(3), running, taskNode("node-3")),
+		swarmTask("id4", taskSlot(4), taskStatus(taskState(swarm.TaskStateFailed)), taskNode("node-1")),
+	}}
+
+	cases := []struct {
+		desc          string
+		filter        string
+		expectedTasks []string
+	}{
+		{
+			desc:          "no filter",
+			expectedTasks: []string{"container.1", "container.2", "container.3"},
+		},
+		{
+			desc:          "drained node",
+			filter:        `{{if eq .NodeID "node-2"}}false{{end}}`,
+			expectedTasks: []string{"container.1", "container.3"},
+		},
+		{
+			desc:          "single node",
+			filter:        `{{eq .NodeID "node-3"}}`,
+			expectedTasks: []string{"container.3"},
+		},
+		{
+			desc:          "filter failing on the tasks",
+			filter:        `{{index .Spec.Networks 0}}`,
+			expectedTasks: []string{"container.1", "container.2", "container.3"},
+		},
+	}
+
+	for _, e := range cases {
+		provider := &Provider{SwarmTaskFilter: e.filter}
+		taskFilter, err := provider.parseSwarmTaskFilter()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", e.desc, err)
+		}
+		taskDockerData, err := listTasks(context.Background(), dockerClient, service.ID, dockerData, map[string]*docker.NetworkResource{}, false, nil, taskFilter, 0)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", e.desc, err)
+		}
+		var names []string
+		for _, task := range taskDockerData {
+			names = append(names, task.Name)
+		}
+		if !reflect.DeepEqual(names, e.expectedTasks) {
+			t.Errorf("%s: expected tasks %v, got %v", e.desc, e.expectedTasks, names)
+		}
+	}
+}
+
+func TestParseSwarmTaskFilter(t *testing.T) {
+	if taskFilter, err := (&Provider{}).parseSwarmTaskFilter(); taskFilter != nil || err != nil {
+		t.Errorf("expected no task filter, got %v, %v", taskFilter, err)
+	}
+	if _, err := (&Provider{SwarmTaskFilter: "{{if .NodeID}}"}).parseSwarmTaskFilter(); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestListTasksWithTaskFilters(t *testing.T) {
+	service := swarmService(serviceName("container"))
+	dockerData, _ := parseService(service, map[string]*docker.NetworkResource{})
+	dockerClient := &fakeTasksClient{}
+	taskFilters := map[string][]string{
+		"node": {"node-1", "node-2"},
+	}
+
+	if _, err := listTasks(context.Background(), dockerClient, service.ID, dockerData, map[string]*docker.NetworkResource{}, false, taskFilters, nil, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	filter := dockerClient.options.Filter
+	if values := filter.Get("service"); !reflect.DeepEqual(values, []string{"serviceID"}) {
+		t.Errorf("expected service filter [serviceID], got %v", values)
+	}
+	if values := filter.Get("desired-state"); !reflect.DeepEqual(values, []string{"running"}) {
+		t.Errorf("expected desired-state filter [running], got %v", values)
+	}
+	nodes := filter.Get("node")
+	sort.Strings(nodes)
+	if !reflect.DeepEqual(nodes, []string{"node-1", "node-2"}) {
+		t.Errorf("expected node filter [node-1 node-2], got %v", nodes)
+	}
+}
+
+func TestSwarmGetPollInterval(t *testing.T) {
+	cases := []struct {
+		pollInterval flaeg.Duration
+		expected     time.Duration
+	}{
+		{
+			pollInterval: 0,
+			expected:     SwarmDefaultWatchTime,
+		},
+		{
+			pollInterval: flaeg.Duration(-time.Second),
+			expected:     SwarmDefaultWatchTime,
+		},
+		{
+			pollInterval: flaeg.Duration(2 * time.Minute),
+			expected:     2 * time.Minute,
+		},
+	}
+
+	for caseID, e := range cases {
+		e := e
+		t.Run(strconv.Itoa(caseID), func(t *testing.T) {
+			t.Parallel()
+			provider := &Provider{
+				SwarmMode:         true,
+				SwarmPollInterval: e.pollInterval,
+			}
+			actual := provider.getSwarmPollInterval()
+			if actual != e.expected {
+				t.Errorf("expected %v, got %v", e.expected, actual)
+			}
+		})
+	}
+}
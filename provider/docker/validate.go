@@ -0,0 +1,167 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// labelError is a configuration error of a container reported by ValidateConfig
+type labelError struct {
+	container string
+	label     string
+	message   string
+	// critical is set for the errors of the labels without default value, see parseLabelValues
+	critical bool
+}
+
+func (e *labelError) Error() string {
+	if len(e.label) == 0 {
+		return fmt.Sprintf("container %s: %s", e.container, e.message)
+	}
+	return fmt.Sprintf("container %s, label %s: %s", e.container, e.label, e.message)
+}
+
+// the labels inserted as-is in the configuration, which has to be valid TOML
+var (
+	validatedIntLabels  = []string{"traefik.weight", "traefik.frontend.priority"}
+	validatedBoolLabels = []string{
+		"traefik.frontend.passHostHeader",
+		"traefik.frontend.passTLSCert",
+		"traefik.frontend.passTLSCert.pem",
+		"traefik.backend.tls",
+		"traefik.backend.tls.insecureSkipVerify",
+		"traefik.frontend.auth.forward.trustForwardHeader",
+	}
+)
+
+// Validate lists the containers, or the services in Swarm mode, and returns the
+// configuration errors of their labels found by ValidateConfig
+func (p *Provider) Validate() ([]error, error) {
+	if _, err := p.TLS.CreateTLSConfig(); err != nil {
+		return nil, fmt.Errorf("invalid docker TLS configuration: %v", err)
+	}
+	dockerClient, _, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	var dockerDataList []dockerData
+	if p.SwarmMode {
+		dockerDataList, err = p.listServices(ctx, dockerClient)
+	} else {
+		dockerDataList, err = listContainers(ctx, dockerClient)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list docker containers: %v", err)
+	}
+	return p.ValidateConfig(dockerDataList), nil
+}
+
+// ValidateConfig runs the parsing of the labels of the enabled containers done by
+// loadDockerConfig, and returns the errors it found instead of logging them or
+// ignoring the containers. The configuration of traefik is left untouched.
+func (p *Provider) ValidateConfig(dockerDataList []dockerData) []error {
+	var errs []error
+	for _, container := range p.applyLabelPrefix(dockerDataList) {
+		if !isContainerEnabled(container, p.ExposedByDefault) {
+			continue
+		}
+		for _, err := range p.validateLabels(container) {
+			errs = append(errs, err)
+		}
+	}
+	// not counted in the filtered containers metric, unlike filterContainers
+	var filteredContainers []dockerData
+	for _, container := range p.applyLabelPrefix(dockerDataList) {
+		if len(p.filterReason(container)) == 0 {
+			filteredContainers = append(filteredContainers, container)
+		}
+	}
+	for _, configError := range p.getConfigErrors(filteredContainers) {
+		errs = append(errs, &labelError{container: configError.Source, message: configError.Message})
+	}
+	return errs
+}
+
+// validateLabels returns the errors of the labels of a container
+func (p *Provider) validateLabels(container dockerData) []*labelError {
+	var errs []*labelError
+	addError := func(label string, format string, args ...interface{}) {
+		errs = append(errs, &labelError{container: container.Name, label: label, message: fmt.Sprintf(format, args...)})
+	}
+
+	// the port of the container is not used with a custom server URL
+	hasServerURL := false
+	if label, err := getLabel(container, "traefik.backend.server.url"); err == nil {
+		if err := checkServerURL(label); err != nil {
+			addError("traefik.backend.server.url", "%v", err)
+		} else {
+			hasServerURL = true
+		}
+	}
+	if !hasServerURL {
+		if label, err := getLabel(container, "traefik.port"); err == nil {
+			if port, errConv := strconv.Atoi(label); errConv != nil || port <= 0 || port > 65535 {
+				addError("traefik.port", "invalid port %q", label)
+			}
+		} else if p.SwarmMode && len(container.NetworkSettings.Ports) == 0 {
+			addError("traefik.port", "missing label, which is required in Swarm mode without endpoint port")
+		} else if label, err := getLabel(container, "traefik.backend"); err == nil && len(splitBackends(label)) > 1 && !p.hasServices(container) {
+			addError("traefik.port", "missing label, which is required by the several backends of traefik.backend")
+		}
+	}
+	for _, name := range validatedIntLabels {
+		if label, err := getLabel(container, name); err == nil {
+			if _, errConv := strconv.Atoi(label); errConv != nil {
+				addError(name, "invalid integer %q", label)
+			}
+		}
+	}
+	for _, name := range validatedBoolLabels {
+		if label, err := getLabel(container, name); err == nil {
+			if _, errConv := strconv.ParseBool(label); errConv != nil {
+				addError(name, "invalid boolean %q", label)
+			}
+		}
+	}
+
+	for _, frontend := range p.getRuleFrontends(container) {
+		if _, err := p.getFrontendRule(frontend); err != nil {
+			name := "traefik.frontend.rule"
+			if len(frontend.RuleIndex) > 0 {
+				name += "." + frontend.RuleIndex
+			}
+			addError(name, "%v", err)
+		}
+	}
+	if _, err := parseWhitelistSourceRange(container); err != nil {
+		addError("traefik.frontend.whitelistSourceRange", "%v", err)
+	}
+	if _, err := parseRateLimits(container); err != nil {
+		addError("traefik.frontend.ratelimit", "%v", err)
+	}
+	if label, err := getLabel(container, "traefik.frontend.auth.digest"); err == nil {
+		for _, user := range strings.Split(label, ",") {
+			if user = strings.TrimSpace(user); len(user) > 0 && strings.Count(user, ":") != 2 {
+				addError("traefik.frontend.auth.digest", "invalid user %q, expected user:realm:hash", user)
+			}
+		}
+	}
+	if label, err := getLabel(container, "traefik.frontend.auth.forward.address"); err == nil {
+		if address, errURL := url.Parse(label); errURL != nil || (address.Scheme != "http" && address.Scheme != "https") || len(address.Host) == 0 {
+			addError("traefik.frontend.auth.forward.address", "invalid address %q, expected an http or https URL", label)
+		}
+	}
+	if _, err := getLabel(container, "traefik.backend.circuitbreaker.expression"); err == nil {
+		if err := p.checkCircuitBreakerExpression(container); err != nil {
+			addError("traefik.backend.circuitbreaker.expression", "%v", err)
+		}
+	}
+	if label, err := getLabel(container, "traefik.backend.maxconn.extractorfunc"); err == nil && len(label) > 0 && !isKnownExtractorFunc(label) {
+		addError("traefik.backend.maxconn.extractorfunc", "unknown extractor function %q", label)
+	}
+	return errs
+}
@@ -0,0 +1,179 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+
+	docker "github.com/docker/engine-api/types"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestDockerValidateConfig(t *testing.T) {
+	cases := []struct {
+		desc       string
+		containers []docker.ContainerJSON
+		swarmMode  bool
+		expected   []string
+	}{
+		{
+			desc: "valid labels",
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.port":                          "80",
+						"traefik.weight":                        "10",
+						"traefik.frontend.rule":                 "Host:foo.bar",
+						"traefik.frontend.passHostHeader":       "false",
+						"traefik.frontend.auth.digest":          "test:traefik:a2688e031edb4be6a3797f3882655c05",
+						"traefik.frontend.auth.forward.address": "http://auth:9000/verify",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+		},
+		{
+			desc: "invalid labels",
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.port":                              "http",
+						"traefik.frontend.priority":                 "high",
+						"traefik.backend.tls":                       "yes",
+						"traefik.frontend.rule.1":                   "Host foo.bar",
+						"traefik.frontend.whitelistSourceRange":     "10.0.0.0/33",
+						"traefik.frontend.auth.digest":              "test:a2688e031edb4be6a3797f3882655c05",
+						"traefik.backend.maxconn.extractorfunc":     "client.address",
+						"traefik.backend.circuitbreaker.expression": "NetworkErrorRatio() >> 0.5",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expected: []string{
+				`container test, label traefik.port: invalid port "http"`,
+				`container test, label traefik.frontend.priority: invalid integer "high"`,
+				`container test, label traefik.backend.tls: invalid boolean "yes"`,
+				`container test, label traefik.frontend.rule.1: missing colon in "Host foo.bar" of frontend rule "Host foo.bar", expected Type:value`,
+				`container test, label traefik.frontend.whitelistSourceRange: invalid CIDR "10.0.0.0/33"`,
+				`container test, label traefik.frontend.auth.digest: invalid user "test:a2688e031edb4be6a3797f3882655c05", expected user:realm:hash`,
+				`container test, label traefik.backend.circuitbreaker.expression: >> is not supported`,
+				`container test, label traefik.backend.maxconn.extractorfunc: unknown extractor function "client.address"`,
+			},
+		},
+		{
+			desc: "disabled container",
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.enable": "false",
+						"traefik.port":   "http",
+					}),
+				),
+			},
+		},
+		{
+			desc: "missing network",
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.docker.network": "web",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expected: []string{
+				"container test: network web set by traefik.docker.network not found",
+			},
+		},
+		{
+			desc: "several backends without port",
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend": "api1,api2",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expected: []string{
+				"container test, label traefik.port: missing label, which is required by the several backends of traefik.backend",
+			},
+		},
+		{
+			desc: "custom server URL",
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+					labels(map[string]string{
+						"traefik.backend":            "api1,api2",
+						"traefik.backend.server.url": "http://my-service:8080",
+					}),
+				),
+				containerJSON(
+					name("invalid"),
+					labels(map[string]string{
+						"traefik.backend.server.url": "my-service:8080",
+					}),
+					ports(nat.PortMap{
+						"80/tcp": {},
+					}),
+					withNetwork("bridge", ipv4("127.0.0.1")),
+				),
+			},
+			expected: []string{
+				`container invalid, label traefik.backend.server.url: invalid server URL "my-service:8080", expected an http or https URL`,
+			},
+		},
+		{
+			desc: "missing port in Swarm mode",
+			containers: []docker.ContainerJSON{
+				containerJSON(
+					name("test"),
+				),
+			},
+			swarmMode: true,
+			expected: []string{
+				"container test, label traefik.port: missing label, which is required in Swarm mode without endpoint port",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.desc, func(t *testing.T) {
+			t.Parallel()
+			var dockerDataList []dockerData
+			for _, container := range c.containers {
+				dockerDataList = append(dockerDataList, parseContainer(container))
+			}
+			provider := &Provider{
+				Domain:           "docker.localhost",
+				ExposedByDefault: true,
+				SwarmMode:        c.swarmMode,
+			}
+			var actual []string
+			for _, err := range provider.ValidateConfig(dockerDataList) {
+				actual = append(actual, err.Error())
+			}
+			if !reflect.DeepEqual(actual, c.expected) {
+				t.Errorf("expected %q, got %q", c.expected, actual)
+			}
+		})
+	}
+}
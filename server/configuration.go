@@ -354,6 +354,10 @@ func NewTraefikDefaultPointersConfiguration() *TraefikConfiguration {
 	defaultDocker.ExposedByDefault = true
 	defaultDocker.Endpoint = "unix:///var/run/docker.sock"
 	defaultDocker.SwarmMode = false
+	defaultDocker.EventDebounceMs = 500
+	defaultDocker.SwarmPollInterval = flaeg.Duration(docker.SwarmDefaultWatchTime)
+	defaultDocker.EndpointTimeout = flaeg.Duration(docker.DockerDefaultEndpointTimeout)
+	defaultDocker.LabelPrefix = "traefik"
 
 	// default File
 	var defaultFile file.Provider
@@ -507,3 +511,5 @@ func NewTraefikConfiguration() *TraefikConfiguration {
 }
 
 type configs map[string]*types.Configuration
+
+type providerErrors map[string][]types.ConfigError
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/containous/traefik/log"
+)
+
+const fcgiScheme = "fcgi"
+
+// FastCGI record types and constants, see https://fast-cgi.github.io/spec
+const (
+	fcgiVersion       = 1
+	fcgiBeginRequest  = 1
+	fcgiEndRequest    = 3
+	fcgiParams        = 4
+	fcgiStdin         = 5
+	fcgiStdout        = 6
+	fcgiStderr        = 7
+	fcgiResponder     = 1
+	fcgiRequestID     = 1
+	fcgiHeaderLength  = 8
+	fcgiMaxContentLen = 65535
+)
+
+// fcgiTransport forwards the requests targeting fcgi:// servers to FastCGI backends
+// and the other ones to the next transport.
+// The path of the server URL is the document root of the FastCGI application.
+type fcgiTransport struct {
+	next http.RoundTripper
+	dial dialContextFunc
+}
+
+func (t *fcgiTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != fcgiScheme {
+		return t.next.RoundTrip(req)
+	}
+	conn, err := t.dial(req.Context(), "tcp", req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFastCGIRequest(conn, req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := readFastCGIResponse(conn, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the transports of the backend
+func (t *fcgiTransport) CloseIdleConnections() {
+	closeIdleConnections(t.next)
+}
+
+// forwardedRequestURI returns the URI requested by the client, the path of the forwarded
+// request URL being the one of the server URL
+func forwardedRequestURI(req *http.Request) string {
+	requestURI := req.URL.Opaque
+	if requestURI == "" {
+		requestURI = req.URL.RequestURI()
+	} else if u, err := url.ParseRequestURI(requestURI); err == nil && u.IsAbs() {
+		// absolute-form request target
+		requestURI = u.RequestURI()
+	}
+	return requestURI
+}
+
+// fastCGIParams translates the HTTP request to the CGI environment of the FastCGI application.
+// The script is looked up from the decoded and cleaned request path, and must stay under the document root.
+func fastCGIParams(req *http.Request) (map[string]string, error) {
+	requestURI := forwardedRequestURI(req)
+	u, err := url.ParseRequestURI(requestURI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request URI %q: %v", requestURI, err)
+	}
+	scriptName := path.Clean("/" + u.Path)
+	documentRoot := req.URL.Path
+	if documentRoot == "" {
+		documentRoot = "/"
+	}
+	documentRoot = path.Clean(documentRoot)
+	scriptFilename := path.Join(documentRoot, scriptName)
+	if scriptFilename != documentRoot && !strings.HasPrefix(scriptFilename, strings.TrimSuffix(documentRoot, "/")+"/") {
+		return nil, fmt.Errorf("script %q is outside of the document root %q", scriptFilename, documentRoot)
+	}
+	serverName, serverPort, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		serverName, serverPort = req.Host, "80"
+	}
+	params := map[string]string{
+		"GATEWAY_INTERFACE": "CGI/1.1",
+		"SERVER_SOFTWARE":   "traefik",
+		"SERVER_PROTOCOL":   req.Proto,
+		"SERVER_NAME":       serverName,
+		"SERVER_PORT":       serverPort,
+		"REQUEST_METHOD":    req.Method,
+		"REQUEST_URI":       requestURI,
+		"QUERY_STRING":      u.RawQuery,
+		"DOCUMENT_ROOT":     documentRoot,
+		"SCRIPT_NAME":       scriptName,
+		"SCRIPT_FILENAME":   scriptFilename,
+	}
+	if remoteAddr, remotePort, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		params["REMOTE_ADDR"] = remoteAddr
+		params["REMOTE_PORT"] = remotePort
+	}
+	if req.ContentLength > 0 {
+		params["CONTENT_LENGTH"] = strconv.FormatInt(req.ContentLength, 10)
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		params["CONTENT_TYPE"] = contentType
+	}
+	for name, values := range req.Header {
+		name = strings.ToUpper(strings.Replace(name, "-", "_", -1))
+		if name == "CONTENT_TYPE" || name == "CONTENT_LENGTH" || name == "PROXY" {
+			continue
+		}
+		params["HTTP_"+name] = strings.Join(values, ", ")
+	}
+	params["HTTP_HOST"] = req.Host
+	return params, nil
+}
+
+func writeFastCGIRequest(w io.Writer, req *http.Request) error {
+	env, err := fastCGIParams(req)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(w)
+	// the connection is closed by the application once the request is complete
+	begin := []byte{0, fcgiResponder, 0, 0, 0, 0, 0, 0}
+	if err := writeFastCGIRecord(writer, fcgiBeginRequest, begin); err != nil {
+		return err
+	}
+
+	var params bytes.Buffer
+	for name, value := range env {
+		writeFastCGILength(&params, len(name))
+		writeFastCGILength(&params, len(value))
+		params.WriteString(name)
+		params.WriteString(value)
+	}
+	if err := writeFastCGIStream(writer, fcgiParams, &params); err != nil {
+		return err
+	}
+
+	body := req.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	if err := writeFastCGIStream(writer, fcgiStdin, body); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// writeFastCGIStream writes the content of r as a stream of records ended by an empty record
+func writeFastCGIStream(w io.Writer, recordType byte, r io.Reader) error {
+	buf := make([]byte, fcgiMaxContentLen)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if errWrite := writeFastCGIRecord(w, recordType, buf[:n]); errWrite != nil {
+				return errWrite
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writeFastCGIRecord(w, recordType, nil)
+}
+
+func writeFastCGIRecord(w io.Writer, recordType byte, content []byte) error {
+	header := make([]byte, fcgiHeaderLength)
+	header[0] = fcgiVersion
+	header[1] = recordType
+	binary.BigEndian.PutUint16(header[2:], fcgiRequestID)
+	binary.BigEndian.PutUint16(header[4:], uint16(len(content)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(content)
+	return err
+}
+
+func writeFastCGILength(buf *bytes.Buffer, length int) {
+	if length < 128 {
+		buf.WriteByte(byte(length))
+		return
+	}
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(length)|1<<31)
+	buf.Write(b)
+}
+
+// readFastCGIResponse parses the CGI response sent by the application on its standard output
+func readFastCGIResponse(conn net.Conn, req *http.Request) (*http.Response, error) {
+	reader := bufio.NewReader(&fastCGIStdoutReader{conn: conn})
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("invalid FastCGI response headers: %v", err)
+	}
+	resp := &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header(header),
+		ContentLength: -1,
+		Request:       req,
+		Body: struct {
+			io.Reader
+			io.Closer
+		}{reader, conn},
+	}
+	if status := header.Get("Status"); status != "" {
+		code, err := strconv.Atoi(strings.SplitN(status, " ", 2)[0])
+		if err != nil {
+			return nil, errors.New("invalid FastCGI response status " + status)
+		}
+		resp.Status, resp.StatusCode = status, code
+		resp.Header.Del("Status")
+	}
+	if length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64); err == nil {
+		resp.ContentLength = length
+	}
+	return resp, nil
+}
+
+// fastCGIStdoutReader reads the content of the stdout records until the end of the request
+type fastCGIStdoutReader struct {
+	conn      net.Conn
+	remaining int
+	padding   int
+	done      bool
+}
+
+func (r *fastCGIStdoutReader) Read(p []byte) (int, error) {
+	for r.remaining == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.nextStdoutRecord(); err != nil {
+			return 0, err
+		}
+	}
+	if len(p) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.conn.Read(p)
+	r.remaining -= n
+	if r.remaining == 0 && err == nil {
+		err = r.discard(r.padding)
+	}
+	return n, err
+}
+
+func (r *fastCGIStdoutReader) nextStdoutRecord() error {
+	header := make([]byte, fcgiHeaderLength)
+	if _, err := io.ReadFull(r.conn, header); err != nil {
+		return err
+	}
+	contentLength := int(binary.BigEndian.Uint16(header[4:]))
+	r.padding = int(header[6])
+	switch header[1] {
+	case fcgiStdout:
+		r.remaining = contentLength
+		if contentLength == 0 {
+			return r.discard(r.padding)
+		}
+		return nil
+	case fcgiStderr:
+		content := make([]byte, contentLength)
+		if _, err := io.ReadFull(r.conn, content); err != nil {
+			return err
+		}
+		if len(content) > 0 {
+			log.Warnf("FastCGI application error: %s", content)
+		}
+		return r.discard(r.padding)
+	case fcgiEndRequest:
+		r.done = true
+		return r.discard(contentLength + r.padding)
+	default:
+		return r.discard(contentLength + r.padding)
+	}
+}
+
+func (r *fastCGIStdoutReader) discard(n int) error {
+	_, err := io.CopyN(ioutil.Discard, r.conn, int64(n))
+	return err
+}
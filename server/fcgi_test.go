@@ -0,0 +1,166 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/forward"
+)
+
+func TestFastCGITransport(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	params := make(chan map[string]string, 1)
+	bodies := make(chan string, 1)
+	go fcgi.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		env := fcgi.ProcessEnv(r)
+		// the standard CGI variables are used to build the request
+		env["REQUEST_METHOD"] = r.Method
+		env["REQUEST_URI"] = r.URL.RequestURI()
+		env["QUERY_STRING"] = r.URL.RawQuery
+		env["CONTENT_TYPE"] = r.Header.Get("Content-Type")
+		env["CONTENT_LENGTH"] = strconv.FormatInt(r.ContentLength, 10)
+		env["HTTP_X_CUSTOM"] = r.Header.Get("X-Custom")
+		params <- env
+		bodies <- string(body)
+		w.Header().Set("X-Powered-By", "fcgi")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	backend := &types.Backend{
+		Servers: map[string]types.Server{
+			"server-php": {URL: "fcgi://" + listener.Addr().String() + "/var/www"},
+		},
+	}
+	transport := createHTTPTransport(backend, nil)
+	if _, ok := transport.(*fcgiTransport); !ok {
+		t.Fatalf("got transport of type %T, want *fcgiTransport", transport)
+	}
+	fwd, err := forward.New(forward.RoundTripper(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest("POST", "http://foo.bar/index.php?id=42", strings.NewReader("name=traefik"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("X-Custom", "custom")
+	request.URL, _ = request.URL.Parse(backend.Servers["server-php"].URL)
+	recorder := httptest.NewRecorder()
+	fwd.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("got status code %d, want %d", recorder.Code, http.StatusCreated)
+	}
+	if recorder.Body.String() != "created" {
+		t.Errorf("got body %q, want %q", recorder.Body.String(), "created")
+	}
+	if recorder.Header().Get("X-Powered-By") != "fcgi" {
+		t.Errorf("got headers %v, want X-Powered-By header", recorder.Header())
+	}
+
+	if body := <-bodies; body != "name=traefik" {
+		t.Errorf("got request body %q, want %q", body, "name=traefik")
+	}
+	env := <-params
+	expected := map[string]string{
+		"REQUEST_METHOD":  "POST",
+		"REQUEST_URI":     "/index.php?id=42",
+		"QUERY_STRING":    "id=42",
+		"SCRIPT_FILENAME": "/var/www/index.php",
+		"DOCUMENT_ROOT":   "/var/www",
+		"CONTENT_TYPE":    "application/x-www-form-urlencoded",
+		"CONTENT_LENGTH":  "12",
+		"HTTP_X_CUSTOM":   "custom",
+	}
+	for name, value := range expected {
+		if env[name] != value {
+			t.Errorf("got param %s=%q, want %q", name, env[name], value)
+		}
+	}
+}
+
+func TestFastCGITransportForwardsOtherSchemes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("http"))
+	}))
+	defer server.Close()
+
+	transport := &fcgiTransport{next: http.DefaultTransport}
+	request, _ := http.NewRequest("GET", server.URL, nil)
+	response, err := transport.RoundTrip(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if body, _ := ioutil.ReadAll(response.Body); string(body) != "http" {
+		t.Errorf("got body %q, want %q", body, "http")
+	}
+}
+
+func TestFastCGIParamsScriptName(t *testing.T) {
+	tests := []struct {
+		requestURI             string
+		expectedScriptName     string
+		expectedScriptFilename string
+		expectedQueryString    string
+	}{
+		{
+			requestURI:             "/index.php?id=42",
+			expectedScriptName:     "/index.php",
+			expectedScriptFilename: "/var/www/index.php",
+			expectedQueryString:    "id=42",
+		},
+		{
+			requestURI:             "/my%20page.php",
+			expectedScriptName:     "/my page.php",
+			expectedScriptFilename: "/var/www/my page.php",
+		},
+		{
+			requestURI:             "/%2e%2e/%2e%2e/etc/passwd",
+			expectedScriptName:     "/etc/passwd",
+			expectedScriptFilename: "/var/www/etc/passwd",
+		},
+		{
+			requestURI:             "/app/../../../etc/passwd?x=1",
+			expectedScriptName:     "/etc/passwd",
+			expectedScriptFilename: "/var/www/etc/passwd",
+			expectedQueryString:    "x=1",
+		},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest("GET", "http://foo.bar/", nil)
+		request.URL, _ = request.URL.Parse("fcgi://127.0.0.1:9000/var/www")
+		request.URL.Opaque = test.requestURI
+		params, err := fastCGIParams(request)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.requestURI, err)
+			continue
+		}
+		if params["SCRIPT_NAME"] != test.expectedScriptName {
+			t.Errorf("%s: got SCRIPT_NAME %q, want %q", test.requestURI, params["SCRIPT_NAME"], test.expectedScriptName)
+		}
+		if params["SCRIPT_FILENAME"] != test.expectedScriptFilename {
+			t.Errorf("%s: got SCRIPT_FILENAME %q, want %q", test.requestURI, params["SCRIPT_FILENAME"], test.expectedScriptFilename)
+		}
+		if params["QUERY_STRING"] != test.expectedQueryString {
+			t.Errorf("%s: got QUERY_STRING %q, want %q", test.requestURI, params["QUERY_STRING"], test.expectedQueryString)
+		}
+		if params["REQUEST_URI"] != test.requestURI {
+			t.Errorf("%s: got REQUEST_URI %q, want %q", test.requestURI, params["REQUEST_URI"], test.requestURI)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	grpcScheme      = "grpc"
+	grpcsScheme     = "grpcs"
+	grpcContentType = "application/grpc"
+)
+
+// grpcTransport forwards the requests targeting grpc:// servers over cleartext HTTP/2 (h2c)
+// and the requests targeting grpcs:// servers over HTTP/2 with TLS, the other ones being
+// forwarded to the next transport.
+type grpcTransport struct {
+	next http.RoundTripper
+	h2c  http.RoundTripper
+	h2   http.RoundTripper
+}
+
+func newGRPCTransport(next http.RoundTripper, dial dialContextFunc) *grpcTransport {
+	return &grpcTransport{
+		next: next,
+		h2c: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return dial(context.Background(), network, addr)
+			},
+		},
+		h2: &http2.Transport{},
+	}
+}
+
+func (t *grpcTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var transport http.RoundTripper
+	var scheme string
+	switch req.URL.Scheme {
+	case grpcScheme:
+		transport, scheme = t.h2c, "http"
+	case grpcsScheme:
+		transport, scheme = t.h2, "https"
+	default:
+		return t.next.RoundTrip(req)
+	}
+
+	outReq := cloneH2Request(req, scheme)
+	if outReq.Header.Get("Content-Type") == "" {
+		outReq.Header.Set("Content-Type", grpcContentType)
+	}
+	// gRPC servers expect the client to accept trailers, the header is removed by the forwarder
+	outReq.Header.Set("Te", "trailers")
+	return transport.RoundTrip(outReq)
+}
+
+// CloseIdleConnections closes the idle connections of the transports of the backend
+func (t *grpcTransport) CloseIdleConnections() {
+	closeIdleConnections(t.next)
+	closeIdleConnections(t.h2c)
+	closeIdleConnections(t.h2)
+}
+
+// cloneH2Request returns a copy of the request targeting the given scheme, suitable for HTTP/2 transports
+func cloneH2Request(req *http.Request, scheme string) *http.Request {
+	outReq := new(http.Request)
+	*outReq = *req
+	outURL := new(url.URL)
+	*outURL = *req.URL
+	outURL.Scheme = scheme
+	if u, err := url.ParseRequestURI(outURL.Opaque); err == nil && u.IsAbs() {
+		// absolute-form request target, HTTP/2 requests only carry the path
+		outURL.Opaque = u.RequestURI()
+	}
+	outReq.URL = outURL
+	outReq.Header = cloneHeader(req.Header)
+	return outReq
+}
+
+func cloneHeader(header http.Header) http.Header {
+	clone := make(http.Header, len(header))
+	for name, values := range header {
+		clone[name] = append([]string(nil), values...)
+	}
+	return clone
+}
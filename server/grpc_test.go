@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/forward"
+	"golang.org/x/net/http2"
+)
+
+func TestCreateHTTPTransportGRPC(t *testing.T) {
+	tests := []struct {
+		desc     string
+		url      string
+		expected string
+	}{
+		{desc: "grpc server", url: "grpc://backend:50051", expected: "*server.grpcTransport"},
+		{desc: "grpcs server", url: "grpcs://backend:50051", expected: "*server.grpcTransport"},
+		{desc: "http server", url: "http://backend:80", expected: "*http.Transport"},
+	}
+
+	for _, test := range tests {
+		backend := &types.Backend{
+			Servers: map[string]types.Server{"server": {URL: test.url}},
+		}
+		transport := createHTTPTransport(backend, nil)
+		if actual := typeName(transport); actual != test.expected {
+			t.Errorf("%s: got transport of type %s, want %s", test.desc, actual, test.expected)
+		}
+	}
+}
+
+func TestGRPCTransportH2C(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	type received struct {
+		proto       string
+		contentType string
+		te          string
+	}
+	requests := make(chan received, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- received{proto: r.Proto, contentType: r.Header.Get("Content-Type"), te: r.Header.Get("Te")}
+		w.Header().Set("Content-Type", grpcContentType)
+		w.Write([]byte("grpc"))
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+
+	backend := &types.Backend{
+		Servers: map[string]types.Server{"server-grpc": {URL: "grpc://" + listener.Addr().String()}},
+	}
+	fwd, err := forward.New(forward.RoundTripper(createHTTPTransport(backend, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest("POST", "http://foo.bar/helloworld.Greeter/SayHello", nil)
+	request.URL, _ = request.URL.Parse(backend.Servers["server-grpc"].URL + "/helloworld.Greeter/SayHello")
+	recorder := httptest.NewRecorder()
+	fwd.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", recorder.Code, http.StatusOK)
+	}
+	if body, _ := ioutil.ReadAll(recorder.Body); string(body) != "grpc" {
+		t.Errorf("got body %q, want %q", body, "grpc")
+	}
+	actual := <-requests
+	if actual.proto != "HTTP/2.0" {
+		t.Errorf("got protocol %s, want HTTP/2.0", actual.proto)
+	}
+	if actual.contentType != grpcContentType {
+		t.Errorf("got Content-Type %q, want %q", actual.contentType, grpcContentType)
+	}
+	if actual.te != "trailers" {
+		t.Errorf("got Te %q, want trailers", actual.te)
+	}
+}
+
+func typeName(v interface{}) string {
+	return fmt.Sprintf("%T", v)
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/containous/traefik/types"
+	"golang.org/x/net/http2"
+)
+
+// defaultMaxConcurrentStreams is the number of concurrent streams of the multiplexed HTTP/2
+// connections when the backend does not set it
+const defaultMaxConcurrentStreams = 100
+
+// h2Transport multiplexes the requests on HTTP/2 connections to the backend servers, using
+// cleartext HTTP/2 (h2c) for http:// servers and HTTP/2 negotiated by ALPN for https:// ones.
+// Server push is never enabled by the HTTP/2 client transport. Requests beyond the maximum
+// number of concurrent streams of a server wait for a stream to end instead of opening new
+// connections. The other requests are forwarded to the next transport.
+type h2Transport struct {
+	next       http.RoundTripper
+	h2c        http.RoundTripper
+	h2         http.RoundTripper
+	maxStreams int
+
+	lock    sync.Mutex
+	streams map[string]chan struct{}
+}
+
+func newH2Transport(next http.RoundTripper, dial dialContextFunc, tlsConfig *tls.Config, options *types.H2Options) *h2Transport {
+	maxStreams := defaultMaxConcurrentStreams
+	if options.MaxConcurrentStreams > 0 {
+		maxStreams = int(options.MaxConcurrentStreams)
+	}
+	return &h2Transport{
+		next: next,
+		h2c: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return dial(context.Background(), network, addr)
+			},
+		},
+		h2: &http2.Transport{
+			TLSClientConfig: tlsConfig,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				conn, err := dial(context.Background(), network, addr)
+				if err != nil {
+					return nil, err
+				}
+				tlsConn := tls.Client(conn, cfg)
+				if err := tlsConn.Handshake(); err != nil {
+					conn.Close()
+					return nil, err
+				}
+				return tlsConn, nil
+			},
+		},
+		maxStreams: maxStreams,
+		streams:    map[string]chan struct{}{},
+	}
+}
+
+func (t *h2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var transport http.RoundTripper
+	switch req.URL.Scheme {
+	case "http":
+		transport = t.h2c
+	case "https":
+		transport = t.h2
+	default:
+		return t.next.RoundTrip(req)
+	}
+
+	release, err := t.acquireStream(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := transport.RoundTrip(cloneH2Request(req, req.URL.Scheme))
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// the stream ends once the response body is consumed
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: release}
+	return resp, nil
+}
+
+// CloseIdleConnections closes the idle connections of the transports of the backend
+func (t *h2Transport) CloseIdleConnections() {
+	closeIdleConnections(t.next)
+	closeIdleConnections(t.h2c)
+	closeIdleConnections(t.h2)
+}
+
+// acquireStream waits for a free stream to the given host, the returned function releasing it
+func (t *h2Transport) acquireStream(ctx context.Context, host string) (func(), error) {
+	t.lock.Lock()
+	streams, ok := t.streams[host]
+	if !ok {
+		streams = make(chan struct{}, t.maxStreams)
+		t.streams[host] = streams
+	}
+	t.lock.Unlock()
+
+	select {
+	case streams <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-streams })
+	}, nil
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+	"golang.org/x/net/http2"
+)
+
+// h2cBackend serves cleartext HTTP/2 and records the connections and the concurrent requests
+type h2cBackend struct {
+	listener net.Listener
+
+	lock        sync.Mutex
+	connections int
+	inFlight    int
+	maxInFlight int
+	protos      []string
+}
+
+func newH2CBackend(t *testing.T) *h2cBackend {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := &h2cBackend{listener: listener}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backend.lock.Lock()
+		backend.inFlight++
+		if backend.inFlight > backend.maxInFlight {
+			backend.maxInFlight = backend.inFlight
+		}
+		backend.protos = append(backend.protos, r.Proto)
+		backend.lock.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("h2"))
+
+		backend.lock.Lock()
+		backend.inFlight--
+		backend.lock.Unlock()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			backend.lock.Lock()
+			backend.connections++
+			backend.lock.Unlock()
+			go (&http2.Server{}).ServeConn(conn, &http2.ServeConnOpts{Handler: handler})
+		}
+	}()
+	return backend
+}
+
+func TestCreateHTTPTransportH2(t *testing.T) {
+	backend := &types.Backend{H2Options: &types.H2Options{}}
+	transport, ok := createHTTPTransport(backend, nil).(*h2Transport)
+	if !ok {
+		t.Fatalf("got transport of type %s, want *server.h2Transport", typeName(createHTTPTransport(backend, nil)))
+	}
+	if transport.maxStreams != defaultMaxConcurrentStreams {
+		t.Errorf("got %d max streams, want %d", transport.maxStreams, defaultMaxConcurrentStreams)
+	}
+}
+
+func TestH2TransportMultiplexing(t *testing.T) {
+	server := newH2CBackend(t)
+	defer server.listener.Close()
+
+	backend := &types.Backend{H2Options: &types.H2Options{MaxConcurrentStreams: 2}}
+	transport := createHTTPTransport(backend, nil)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, "http://"+server.listener.Addr().String()+"/", nil)
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer resp.Body.Close()
+			if _, err := ioutil.ReadAll(resp.Body); err != nil {
+				errs <- err
+				return
+			}
+			if resp.ProtoMajor != 2 {
+				t.Errorf("got protocol %s, want HTTP/2", resp.Proto)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	for _, proto := range server.protos {
+		if proto != "HTTP/2.0" {
+			t.Errorf("backend got protocol %s, want HTTP/2.0", proto)
+		}
+	}
+	if server.connections != 1 {
+		t.Errorf("got %d connections, want a single multiplexed connection", server.connections)
+	}
+	if server.maxInFlight > 2 {
+		t.Errorf("got %d concurrent streams, want at most 2", server.maxInFlight)
+	}
+}
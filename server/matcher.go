@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+)
+
+// MatcherPlugin is a user-defined request matcher used by the CustomMatcher rule
+type MatcherPlugin interface {
+	// Name returns the default name of the plugin
+	Name() string
+	// Match returns true if the request matches, config being the configuration
+	// given to the rule
+	Match(req *http.Request, config map[string]string) bool
+}
+
+var (
+	matchersLock sync.RWMutex
+	matchers     = map[string]MatcherPlugin{}
+)
+
+// RegisterMatcher registers a matcher plugin under the given name, which defaults to the plugin name.
+// Plugins must be registered at startup, before the configuration is loaded.
+func RegisterMatcher(name string, plugin MatcherPlugin) {
+	if len(name) == 0 {
+		name = plugin.Name()
+	}
+	matchersLock.Lock()
+	defer matchersLock.Unlock()
+	matchers[name] = plugin
+}
+
+func getMatcher(name string) (MatcherPlugin, bool) {
+	matchersLock.RLock()
+	defer matchersLock.RUnlock()
+	plugin, ok := matchers[name]
+	return plugin, ok
+}
@@ -0,0 +1,380 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	natsScheme            = "nats"
+	natsDefaultPort       = "4222"
+	natsDefaultTimeout    = 30 * time.Second
+	natsWriteTimeout      = 10 * time.Second
+	natsDefaultMaxPayload = 1024 * 1024
+)
+
+// natsTransport forwards the requests targeting nats:// servers to the NATS subject given by
+// the path of the server URL, and the other ones to the next transport.
+// The request is published as a JSON message with a per-request inbox as reply subject, the
+// response being built from the JSON message replied by the service.
+// One connection is kept per NATS server and shared by the requests.
+type natsTransport struct {
+	next http.RoundTripper
+	dial dialContextFunc
+	// timeout bounds the wait for the reply of the service, natsDefaultTimeout if not set
+	timeout time.Duration
+
+	lock  sync.Mutex
+	conns map[string]*natsConn
+}
+
+// natsRequest is the message published to the subject of the service
+type natsRequest struct {
+	Method string      `json:"method"`
+	URI    string      `json:"uri"`
+	Host   string      `json:"host"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// natsReply is the message replied by the service, a zero status standing for 200 OK
+type natsReply struct {
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// natsInfo is the part of the INFO message of the server used by the client
+type natsInfo struct {
+	MaxPayload int `json:"max_payload"`
+}
+
+func (t *natsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != natsScheme {
+		return t.next.RoundTrip(req)
+	}
+	subject := strings.Trim(req.URL.Path, "/")
+	if subject == "" {
+		return nil, errors.New("missing NATS subject in server URL " + req.URL.String())
+	}
+	payload, err := encodeNATSRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := t.timeout
+	if timeout <= 0 {
+		timeout = natsDefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+
+	address := req.URL.Host
+	if req.URL.Port() == "" {
+		address = net.JoinHostPort(req.URL.Hostname(), natsDefaultPort)
+	}
+	conn, err := t.getConn(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	message, err := conn.request(ctx, subject, payload)
+	if err != nil {
+		return nil, err
+	}
+	return decodeNATSReply(message, req)
+}
+
+// getConn returns the connection to the NATS server, connecting again if it has been closed
+func (t *natsTransport) getConn(ctx context.Context, address string) (*natsConn, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if conn, ok := t.conns[address]; ok && conn.closeErr() == nil {
+		return conn, nil
+	}
+	conn, err := dialNATS(ctx, t.dial, address)
+	if err != nil {
+		return nil, err
+	}
+	if t.conns == nil {
+		t.conns = make(map[string]*natsConn)
+	}
+	t.conns[address] = conn
+	return conn, nil
+}
+
+// CloseIdleConnections closes the idle connections of the transports of the backend,
+// and the NATS connections without pending requests
+func (t *natsTransport) CloseIdleConnections() {
+	t.lock.Lock()
+	for address, conn := range t.conns {
+		if conn.idle() {
+			conn.close(errors.New("NATS connection closed"))
+			delete(t.conns, address)
+		}
+	}
+	t.lock.Unlock()
+	closeIdleConnections(t.next)
+}
+
+func encodeNATSRequest(req *http.Request) ([]byte, error) {
+	message := natsRequest{
+		Method: req.Method,
+		URI:    forwardedRequestURI(req),
+		Host:   req.Host,
+		Header: req.Header,
+	}
+	if req.Body != nil {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		message.Body = body
+	}
+	return json.Marshal(message)
+}
+
+func decodeNATSReply(message []byte, req *http.Request) (*http.Response, error) {
+	var reply natsReply
+	if err := json.Unmarshal(message, &reply); err != nil {
+		return nil, fmt.Errorf("invalid NATS reply: %v", err)
+	}
+	if reply.Status == 0 {
+		reply.Status = http.StatusOK
+	}
+	if reply.Header == nil {
+		reply.Header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(reply.Status) + " " + http.StatusText(reply.Status),
+		StatusCode:    reply.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        reply.Header,
+		ContentLength: int64(len(reply.Body)),
+		Request:       req,
+		Body:          ioutil.NopCloser(bytes.NewReader(reply.Body)),
+	}, nil
+}
+
+// natsConn is a connection to a NATS server, following the NATS client protocol,
+// see https://nats.io/documentation/internals/nats-protocol/
+// Each request subscribes to its own inbox, the replies being dispatched by subscription ID.
+type natsConn struct {
+	conn       net.Conn
+	maxPayload int
+	writeLock  sync.Mutex
+
+	lock    sync.Mutex
+	lastSID int
+	replies map[string]chan []byte
+	err     error
+	done    chan struct{}
+}
+
+// dialNATS connects to the NATS server and starts reading the messages it sends
+func dialNATS(ctx context.Context, dial dialContextFunc, address string) (*natsConn, error) {
+	conn, err := dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+	line, err := readNATSLine(reader)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return nil, errors.New("unexpected NATS server greeting " + line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "INFO"))), &info); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("invalid NATS server greeting: %v", err)
+	}
+	if info.MaxPayload <= 0 {
+		info.MaxPayload = natsDefaultMaxPayload
+	}
+	if _, err := conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"traefik\"}\r\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &natsConn{
+		conn:       conn,
+		maxPayload: info.MaxPayload,
+		replies:    make(map[string]chan []byte),
+		done:       make(chan struct{}),
+	}
+	go c.readLoop(reader)
+	return c, nil
+}
+
+// request publishes the payload to the subject and returns the first reply
+func (c *natsConn) request(ctx context.Context, subject string, payload []byte) ([]byte, error) {
+	if len(payload) > c.maxPayload {
+		return nil, fmt.Errorf("NATS message of %d bytes exceeds the maximum payload of %d bytes", len(payload), c.maxPayload)
+	}
+	inbox, err := newNATSInbox()
+	if err != nil {
+		return nil, err
+	}
+
+	c.lock.Lock()
+	if c.err != nil {
+		c.lock.Unlock()
+		return nil, c.err
+	}
+	c.lastSID++
+	sid := strconv.Itoa(c.lastSID)
+	reply := make(chan []byte, 1)
+	c.replies[sid] = reply
+	c.lock.Unlock()
+
+	var request bytes.Buffer
+	// the subscription is removed by the server once the reply is delivered
+	fmt.Fprintf(&request, "SUB %s %s\r\nUNSUB %s 1\r\n", inbox, sid, sid)
+	fmt.Fprintf(&request, "PUB %s %s %d\r\n", subject, inbox, len(payload))
+	request.Write(payload)
+	request.WriteString("\r\n")
+	deadline, _ := ctx.Deadline()
+	if err := c.write(request.Bytes(), deadline); err != nil {
+		c.close(err)
+		return nil, err
+	}
+
+	select {
+	case message := <-reply:
+		return message, nil
+	case <-c.done:
+		select {
+		case message := <-reply:
+			return message, nil
+		default:
+		}
+		return nil, c.closeErr()
+	case <-ctx.Done():
+		c.lock.Lock()
+		delete(c.replies, sid)
+		c.lock.Unlock()
+		if err := c.write([]byte("UNSUB "+sid+"\r\n"), time.Now().Add(natsWriteTimeout)); err != nil {
+			c.close(err)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (c *natsConn) readLoop(reader *bufio.Reader) {
+	for {
+		line, err := readNATSLine(reader)
+		if err != nil {
+			c.close(err)
+			return
+		}
+		switch {
+		case line == "PING":
+			if err := c.write([]byte("PONG\r\n"), time.Now().Add(natsWriteTimeout)); err != nil {
+				c.close(err)
+				return
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			c.close(errors.New("NATS server error: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR"))))
+			return
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				c.close(errors.New("invalid NATS message " + line))
+				return
+			}
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil || size < 0 {
+				c.close(errors.New("invalid NATS message " + line))
+				return
+			}
+			if size > c.maxPayload {
+				c.close(fmt.Errorf("NATS message of %d bytes exceeds the maximum payload of %d bytes", size, c.maxPayload))
+				return
+			}
+			message := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, message); err != nil {
+				c.close(err)
+				return
+			}
+			c.lock.Lock()
+			reply, ok := c.replies[fields[2]]
+			delete(c.replies, fields[2])
+			c.lock.Unlock()
+			if ok {
+				reply <- message[:size]
+			}
+		}
+	}
+}
+
+func (c *natsConn) write(data []byte, deadline time.Time) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	c.conn.SetWriteDeadline(deadline)
+	_, err := c.conn.Write(data)
+	return err
+}
+
+// close closes the connection, the pending requests failing with the given error
+func (c *natsConn) close(err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	c.conn.Close()
+	close(c.done)
+}
+
+func (c *natsConn) closeErr() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.err
+}
+
+func (c *natsConn) idle() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.replies) == 0
+}
+
+func readNATSLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func newNATSInbox() (string, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return "_INBOX." + hex.EncodeToString(id), nil
+}
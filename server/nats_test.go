@@ -0,0 +1,217 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/forward"
+)
+
+type natsPublication struct {
+	subject string
+	request natsRequest
+}
+
+// natsServer mocks a NATS server where a service replies to the messages published on any subject
+func natsServer(t *testing.T, reply natsReply, serverErr string) (net.Listener, chan natsPublication) {
+	publications := make(chan natsPublication, 1)
+	listener, _ := natsRawServer(t, 1048576, func(w io.Writer, publication natsPublication, inbox string, sid string) bool {
+		publications <- publication
+		if serverErr != "" {
+			fmt.Fprintf(w, "-ERR '%s'\r\n", serverErr)
+			return false
+		}
+		message, _ := json.Marshal(reply)
+		// the server checks the liveness of the client before delivering the reply
+		fmt.Fprintf(w, "PING\r\nMSG %s %s %d\r\n%s\r\n", inbox, sid, len(message), message)
+		return true
+	})
+	return listener, publications
+}
+
+// natsRawServer mocks a NATS server calling publish for each published message, the connection
+// being closed when it returns false. The number of accepted connections is counted.
+func natsRawServer(t *testing.T, maxPayload int, publish func(w io.Writer, publication natsPublication, inbox string, sid string) bool) (net.Listener, *int32) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var connections int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&connections, 1)
+			go func(conn net.Conn) {
+				defer conn.Close()
+				fmt.Fprintf(conn, "INFO {\"server_id\":\"mock\",\"max_payload\":%d}\r\n", maxPayload)
+				reader := bufio.NewReader(conn)
+				sids := map[string]string{}
+				for {
+					line, err := readNATSLine(reader)
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					switch fields[0] {
+					case "SUB":
+						sids[fields[1]] = fields[2]
+					case "PUB":
+						size, _ := strconv.Atoi(fields[3])
+						payload := make([]byte, size+2)
+						if _, err := io.ReadFull(reader, payload); err != nil {
+							return
+						}
+						publication := natsPublication{subject: fields[1]}
+						json.Unmarshal(payload[:size], &publication.request)
+						if !publish(conn, publication, fields[2], sids[fields[2]]) {
+							return
+						}
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener, &connections
+}
+
+func TestNATSTransport(t *testing.T) {
+	listener, publications := natsServer(t, natsReply{
+		Status: http.StatusCreated,
+		Header: http.Header{"X-Service": {"orders"}},
+		Body:   []byte("created"),
+	}, "")
+	defer listener.Close()
+
+	backend := &types.Backend{
+		Servers: map[string]types.Server{
+			"server-nats": {URL: "nats://" + listener.Addr().String() + "/orders.create"},
+		},
+	}
+	transport := createHTTPTransport(backend, nil)
+	if _, ok := transport.(*natsTransport); !ok {
+		t.Fatalf("got transport of type %T, want *natsTransport", transport)
+	}
+	fwd, err := forward.New(forward.RoundTripper(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest("POST", "http://foo.bar/orders?id=42", strings.NewReader(`{"item":"traefik"}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.URL, _ = request.URL.Parse(backend.Servers["server-nats"].URL)
+	recorder := httptest.NewRecorder()
+	fwd.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("got status code %d, want %d", recorder.Code, http.StatusCreated)
+	}
+	if recorder.Body.String() != "created" {
+		t.Errorf("got body %q, want %q", recorder.Body.String(), "created")
+	}
+	if recorder.Header().Get("X-Service") != "orders" {
+		t.Errorf("got headers %v, want X-Service header", recorder.Header())
+	}
+
+	publication := <-publications
+	if publication.subject != "orders.create" {
+		t.Errorf("got subject %q, want %q", publication.subject, "orders.create")
+	}
+	if publication.request.Method != "POST" || publication.request.URI != "/orders?id=42" {
+		t.Errorf("got request %s %s, want POST /orders?id=42", publication.request.Method, publication.request.URI)
+	}
+	if publication.request.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("got request headers %v, want Content-Type header", publication.request.Header)
+	}
+	if string(publication.request.Body) != `{"item":"traefik"}` {
+		t.Errorf("got request body %q", publication.request.Body)
+	}
+}
+
+func TestNATSTransportServerError(t *testing.T) {
+	listener, publications := natsServer(t, natsReply{}, "Permissions Violation for Publish")
+	defer listener.Close()
+
+	transport := &natsTransport{next: http.DefaultTransport, dial: (&net.Dialer{}).DialContext}
+	request, _ := http.NewRequest("GET", "nats://"+listener.Addr().String()+"/orders.list", nil)
+	if _, err := transport.RoundTrip(request); err == nil || !strings.Contains(err.Error(), "Permissions Violation") {
+		t.Errorf("got error %v, want NATS server error", err)
+	}
+	<-publications
+
+	request, _ = http.NewRequest("GET", "nats://"+listener.Addr().String(), nil)
+	if _, err := transport.RoundTrip(request); err == nil {
+		t.Error("expected an error for a server URL without subject")
+	}
+}
+
+func TestNATSTransportReusesConnection(t *testing.T) {
+	listener, connections := natsRawServer(t, 1048576, func(w io.Writer, publication natsPublication, inbox string, sid string) bool {
+		fmt.Fprintf(w, "MSG %s %s 15\r\n{\"body\":\"b2s=\"}\r\n", inbox, sid)
+		return true
+	})
+	defer listener.Close()
+
+	transport := &natsTransport{next: http.DefaultTransport, dial: (&net.Dialer{}).DialContext}
+	for i := 0; i < 3; i++ {
+		request, _ := http.NewRequest("GET", "nats://"+listener.Addr().String()+"/orders.list", nil)
+		response, err := transport.RoundTrip(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body, _ := ioutil.ReadAll(response.Body); string(body) != "ok" {
+			t.Errorf("got body %q, want %q", body, "ok")
+		}
+	}
+	if count := atomic.LoadInt32(connections); count != 1 {
+		t.Errorf("got %d connections to the NATS server, want 1", count)
+	}
+}
+
+func TestNATSTransportTimeout(t *testing.T) {
+	listener, _ := natsRawServer(t, 1048576, func(w io.Writer, publication natsPublication, inbox string, sid string) bool {
+		// the service never replies
+		return true
+	})
+	defer listener.Close()
+
+	transport := &natsTransport{next: http.DefaultTransport, dial: (&net.Dialer{}).DialContext, timeout: 50 * time.Millisecond}
+	request, _ := http.NewRequest("GET", "nats://"+listener.Addr().String()+"/orders.list", nil)
+	if _, err := transport.RoundTrip(request); err != context.DeadlineExceeded {
+		t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestNATSTransportMaxPayload(t *testing.T) {
+	listener, _ := natsRawServer(t, 256, func(w io.Writer, publication natsPublication, inbox string, sid string) bool {
+		fmt.Fprintf(w, "MSG %s %s 2147483647\r\n", inbox, sid)
+		return true
+	})
+	defer listener.Close()
+
+	transport := &natsTransport{next: http.DefaultTransport, dial: (&net.Dialer{}).DialContext}
+	request, _ := http.NewRequest("GET", "nats://"+listener.Addr().String()+"/orders.list", nil)
+	if _, err := transport.RoundTrip(request); err == nil || !strings.Contains(err.Error(), "maximum payload") {
+		t.Errorf("got error %v, want maximum payload error", err)
+	}
+
+	request, _ = http.NewRequest("POST", "nats://"+listener.Addr().String()+"/orders.create", strings.NewReader(strings.Repeat("a", 256)))
+	if _, err := transport.RoundTrip(request); err == nil || !strings.Contains(err.Error(), "maximum payload") {
+		t.Errorf("got error %v, want maximum payload error", err)
+	}
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+)
+
+const tlsScheme = "tls"
+
+var errListenerClosed = errors.New("listener closed")
+
+// tcpRoutes holds the tls:// servers of the TCP passthrough backends, by SNI host
+type tcpRoutes map[string][]tcpTarget
+
+// tcpTarget is a tls:// server of a TCP passthrough backend, dialed with the TLS configuration
+// of the backend if any
+type tcpTarget struct {
+	url       *url.URL
+	tlsConfig *tls.Config
+}
+
+// isTCPPassthrough returns true if the backend servers are raw TLS servers
+func isTCPPassthrough(backend *types.Backend) bool {
+	if backend == nil {
+		return false
+	}
+	if backend.TCPPassthrough {
+		return true
+	}
+	for _, server := range backend.Servers {
+		if strings.HasPrefix(server.URL, tlsScheme+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+// addTCPRoutes registers the tls:// servers of the backend for the Host rules of the frontend
+func addTCPRoutes(routes tcpRoutes, frontend *types.Frontend, backend *types.Backend) error {
+	tlsConfig, err := backend.TLS.CreateTLSConfig()
+	if err != nil {
+		return fmt.Errorf("invalid TLS configuration: %v", err)
+	}
+	var targets []tcpTarget
+	for name, server := range backend.Servers {
+		serverURL, err := url.Parse(server.URL)
+		if err != nil || serverURL.Scheme != tlsScheme {
+			log.Warnf("Skipping server %s of TCP passthrough backend %s: expected a tls:// URL, got %s", name, frontend.Backend, server.URL)
+			continue
+		}
+		targets = append(targets, tcpTarget{url: serverURL, tlsConfig: tlsConfig})
+	}
+	rules := &Rules{}
+	for _, route := range frontend.Routes {
+		hosts, err := rules.ParseDomains(route.Rule)
+		if err != nil {
+			return err
+		}
+		for _, host := range hosts {
+			routes[host] = append(routes[host], targets...)
+		}
+	}
+	return nil
+}
+
+// serveTLS serves the TLS entrypoint, tunneling the connections routed to TCP passthrough backends
+func serveTLS(srv *http.Server, routes *safe.Safe, insecureSkipVerify bool) error {
+	config := srv.TLSConfig.Clone()
+	// the protocols negotiated by http.Server.ListenAndServeTLS
+	if !containsString(config.NextProtos, "http/1.1") {
+		config.NextProtos = append(config.NextProtos, "http/1.1")
+	}
+	if srv.TLSNextProto == nil && !containsString(config.NextProtos, "h2") {
+		config.NextProtos = append([]string{"h2"}, config.NextProtos...)
+	}
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		return err
+	}
+	return srv.Serve(newPassthroughListener(listener, config, routes, insecureSkipVerify))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// passthroughListener performs the TLS handshake of the accepted connections. The connections
+// whose SNI host is routed to a TCP passthrough backend are tunneled to one of its servers, the
+// other ones are returned by Accept to be served over HTTP.
+type passthroughListener struct {
+	net.Listener
+	config             *tls.Config
+	routes             *safe.Safe
+	insecureSkipVerify bool
+
+	conns     chan net.Conn
+	errs      chan error
+	closeOnce sync.Once
+	closed    chan struct{}
+	next      uint32
+}
+
+func newPassthroughListener(listener net.Listener, config *tls.Config, routes *safe.Safe, insecureSkipVerify bool) *passthroughListener {
+	l := &passthroughListener{
+		Listener:           listener,
+		config:             config,
+		routes:             routes,
+		insecureSkipVerify: insecureSkipVerify,
+		conns:              make(chan net.Conn),
+		errs:               make(chan error, 1),
+		closed:             make(chan struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+func (l *passthroughListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				time.Sleep(5 * time.Millisecond)
+				continue
+			}
+			select {
+			case l.errs <- err:
+			case <-l.closed:
+			}
+			return
+		}
+		go l.handshake(tls.Server(conn, l.config))
+	}
+}
+
+func (l *passthroughListener) handshake(conn *tls.Conn) {
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if err := conn.Handshake(); err != nil {
+		log.Debugf("TLS handshake error from %s: %v", conn.RemoteAddr(), err)
+		conn.Close()
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	host := types.CanonicalDomain(conn.ConnectionState().ServerName)
+	if targets := l.routes.Get().(tcpRoutes)[host]; len(targets) > 0 {
+		target := targets[int(atomic.AddUint32(&l.next, 1))%len(targets)]
+		l.tunnel(conn, target)
+		return
+	}
+	select {
+	case l.conns <- conn:
+	case <-l.closed:
+		conn.Close()
+	}
+}
+
+// tunnel forwards the raw bytes of the client connection to the TLS connection of the server
+func (l *passthroughListener) tunnel(conn *tls.Conn, target tcpTarget) {
+	defer conn.Close()
+	config := &tls.Config{InsecureSkipVerify: l.insecureSkipVerify}
+	if target.tlsConfig != nil {
+		config = target.tlsConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = target.url.Hostname()
+	}
+	backendConn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", target.url.Host, config)
+	if err != nil {
+		log.Errorf("Error dialing TCP passthrough server %s: %v", target.url.Host, err)
+		return
+	}
+	defer backendConn.Close()
+
+	errc := make(chan error, 2)
+	replicate := func(dst io.Writer, src io.Reader) {
+		_, err := io.Copy(dst, src)
+		errc <- err
+	}
+	go replicate(backendConn, conn)
+	go replicate(conn, backendConn)
+	<-errc
+}
+
+// Accept returns the next connection to be served over HTTP
+func (l *passthroughListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.closed:
+		return nil, errListenerClosed
+	}
+}
+
+// Close stops the listener
+func (l *passthroughListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.closed)
+	})
+	return l.Listener.Close()
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/containous/traefik/safe"
+	"github.com/containous/traefik/types"
+)
+
+func testCertificates() []tls.Certificate {
+	ts := httptest.NewTLSServer(nil)
+	defer ts.Close()
+	return ts.TLS.Certificates
+}
+
+// rawTLSBackend echoes the bytes received on its TLS connections
+func rawTLSBackend(t *testing.T, certificates []tls.Certificate) net.Listener {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: certificates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+func TestPassthroughListener(t *testing.T) {
+	certificates := testCertificates()
+	backend := rawTLSBackend(t, certificates)
+	defer backend.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := safe.New(tcpRoutes{
+		"binary.example.com": {{url: &url.URL{Scheme: tlsScheme, Host: backend.Addr().String()}}},
+	})
+	passthrough := newPassthroughListener(listener, &tls.Config{Certificates: certificates}, routes, true)
+	defer passthrough.Close()
+
+	// the routed connection is tunneled to the backend
+	conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{ServerName: "binary.example.com", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	payload := []byte{0x00, 0x01, 0xff, 'G', 'E', 'T', ' ', '\r', '\n', 0x7f}
+	if _, err := conn.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	echoed := make([]byte, len(payload))
+	if _, err := io.ReadFull(conn, echoed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(echoed, payload) {
+		t.Errorf("got %v through the tunnel, want %v", echoed, payload)
+	}
+
+	// the other connections are accepted to be served over HTTP
+	httpConn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{ServerName: "www.example.com", InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpConn.Close()
+	accepted, err := passthrough.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer accepted.Close()
+	if serverName := accepted.(*tls.Conn).ConnectionState().ServerName; serverName != "www.example.com" {
+		t.Errorf("got accepted connection for %s, want www.example.com", serverName)
+	}
+}
+
+func TestAddTCPRoutes(t *testing.T) {
+	backend := &types.Backend{
+		Servers: map[string]types.Server{
+			"server1": {URL: "tls://10.0.0.1:9000"},
+			"server2": {URL: "http://10.0.0.2:80"},
+		},
+	}
+	if !isTCPPassthrough(backend) {
+		t.Fatal("expected a TCP passthrough backend")
+	}
+	if isTCPPassthrough(&types.Backend{Servers: map[string]types.Server{"server": {URL: "http://10.0.0.2:80"}}}) {
+		t.Error("expected an HTTP backend")
+	}
+
+	frontend := &types.Frontend{
+		Backend: "backend1",
+		Routes: map[string]types.Route{
+			"route": {Rule: "Host:Binary.example.com,binary.example.org"},
+		},
+	}
+	routes := tcpRoutes{}
+	if err := addTCPRoutes(routes, frontend, backend); err != nil {
+		t.Fatal(err)
+	}
+	target := tcpTarget{url: &url.URL{Scheme: tlsScheme, Host: "10.0.0.1:9000"}}
+	expected := tcpRoutes{
+		"binary.example.com": {target},
+		"binary.example.org": {target},
+	}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("got TCP routes %v, want %v", routes, expected)
+	}
+}
+
+func TestAddTCPRoutesInvalidTLS(t *testing.T) {
+	backend := &types.Backend{
+		Servers: map[string]types.Server{
+			"server1": {URL: "tls://10.0.0.1:9000"},
+		},
+		TLS: &types.ClientTLS{Key: "/certs/key.pem"},
+	}
+	frontend := &types.Frontend{
+		Backend: "backend1",
+		Routes: map[string]types.Route{
+			"route": {Rule: "Host:binary.example.com"},
+		},
+	}
+	if err := addTCPRoutes(tcpRoutes{}, frontend, backend); err == nil {
+		t.Error("expected an error for a TLS key without cert")
+	}
+}
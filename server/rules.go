@@ -1,19 +1,33 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
 	"net"
 	"net/http"
+	"net/url"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/BurntSushi/ty/fun"
 	"github.com/containous/mux"
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/middlewares"
 	"github.com/containous/traefik/types"
 )
 
+// defaultMaxBodyBuffer is the maximum size of the request body buffered by RequestBodyContains rules
+const defaultMaxBodyBuffer = 1 << 20
+
 // Rules holds rule parsing and configuration
 type Rules struct {
 	route *serverRoute
@@ -46,7 +60,7 @@ func (r *Rules) hostRegexp(hosts ...string) *mux.Route {
 func (r *Rules) path(paths ...string) *mux.Route {
 	router := r.route.route.Subrouter()
 	for _, path := range paths {
-		router.Path(strings.TrimSpace(path))
+		r.addPath(router, path, false)
 	}
 	return r.route.route
 }
@@ -54,11 +68,62 @@ func (r *Rules) path(paths ...string) *mux.Route {
 func (r *Rules) pathPrefix(paths ...string) *mux.Route {
 	router := r.route.route.Subrouter()
 	for _, path := range paths {
-		router.PathPrefix(strings.TrimSpace(path))
+		r.addPath(router, path, true)
 	}
 	return r.route.route
 }
 
+// addPath registers the path template on the router. Case insensitive routes match the
+// lowercased template against the lowercased request path.
+func (r *Rules) addPath(router *mux.Router, path string, prefix bool) {
+	path = strings.TrimSpace(path)
+	if !r.route.caseInsensitive {
+		if prefix {
+			router.PathPrefix(path)
+		} else {
+			router.Path(path)
+		}
+		return
+	}
+	lowerRoute := mux.NewRouter().NewRoute()
+	if prefix {
+		lowerRoute.PathPrefix(strings.ToLower(path))
+	} else {
+		lowerRoute.Path(strings.ToLower(path))
+	}
+	router.MatcherFunc(func(req *http.Request, match *mux.RouteMatch) bool {
+		lowerReq := new(http.Request)
+		*lowerReq = *req
+		lowerURL := new(url.URL)
+		*lowerURL = *req.URL
+		lowerURL.Path = strings.ToLower(req.URL.Path)
+		lowerReq.URL = lowerURL
+		lowerMatch := &mux.RouteMatch{}
+		if !lowerRoute.Match(lowerReq, lowerMatch) {
+			return false
+		}
+		if match.Vars == nil {
+			match.Vars = make(map[string]string)
+		}
+		for name, value := range lowerMatch.Vars {
+			match.Vars[name] = value
+		}
+		return true
+	})
+}
+
+// caseStripPrefixes returns the prefixes to strip from the request path, lowercased by case insensitive routes
+func (r *Rules) caseStripPrefixes(paths []string) []string {
+	if !r.route.caseInsensitive {
+		return paths
+	}
+	prefixes := make([]string, len(paths))
+	for i, path := range paths {
+		prefixes[i] = strings.ToLower(path)
+	}
+	return prefixes
+}
+
 type bySize []string
 
 func (a bySize) Len() int           { return len(a) }
@@ -67,10 +132,10 @@ func (a bySize) Less(i, j int) bool { return len(a[i]) > len(a[j]) }
 
 func (r *Rules) pathStrip(paths ...string) *mux.Route {
 	sort.Sort(bySize(paths))
-	r.route.stripPrefixes = paths
+	r.route.stripPrefixes = r.caseStripPrefixes(paths)
 	router := r.route.route.Subrouter()
 	for _, path := range paths {
-		router.Path(strings.TrimSpace(path))
+		r.addPath(router, path, false)
 	}
 	return r.route.route
 }
@@ -101,10 +166,10 @@ func (r *Rules) addPrefix(paths ...string) *mux.Route {
 
 func (r *Rules) pathPrefixStrip(paths ...string) *mux.Route {
 	sort.Sort(bySize(paths))
-	r.route.stripPrefixes = paths
+	r.route.stripPrefixes = r.caseStripPrefixes(paths)
 	router := r.route.route.Subrouter()
 	for _, path := range paths {
-		router.PathPrefix(strings.TrimSpace(path))
+		r.addPath(router, path, true)
 	}
 	return r.route.route
 }
@@ -119,18 +184,440 @@ func (r *Rules) pathPrefixStripRegex(paths ...string) *mux.Route {
 	return r.route.route
 }
 
+func (r *Rules) rateLimit(rates ...string) *mux.Route {
+	for _, rate := range rates {
+		parsedRate, err := parseRate(rate)
+		if err != nil {
+			r.err = err
+			return r.route.route
+		}
+		r.route.rateLimit = parsedRate
+	}
+	return r.route.route
+}
+
+// parseRate parses a rate formatted as <amount>/<unit>, unit being one of s, m or h
+func parseRate(rate string) (*middlewares.Rate, error) {
+	parts := strings.Split(rate, "/")
+	if len(parts) != 2 {
+		return nil, errors.New("Invalid rate '" + rate + "', expected <amount>/<unit>")
+	}
+	average, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil || average <= 0 {
+		return nil, errors.New("Invalid rate amount '" + parts[0] + "'")
+	}
+	var period time.Duration
+	switch strings.TrimSpace(parts[1]) {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return nil, errors.New("Invalid rate unit '" + parts[1] + "', expected one of s, m, h")
+	}
+	return &middlewares.Rate{Average: average, Period: period}, nil
+}
+
 func (r *Rules) methods(methods ...string) *mux.Route {
 	return r.route.route.Methods(methods...)
 }
 
+func (r *Rules) methodPrefix(methodPrefix ...string) *mux.Route {
+	if len(methodPrefix) < 2 {
+		r.err = errors.New("MethodPrefix rule needs at least one method and a path prefix")
+		return r.route.route
+	}
+	methods, prefix := methodPrefix[:len(methodPrefix)-1], methodPrefix[len(methodPrefix)-1]
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		if !strings.HasPrefix(req.URL.Path, prefix) {
+			return false
+		}
+		for _, method := range methods {
+			if strings.EqualFold(req.Method, method) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
 func (r *Rules) headers(headers ...string) *mux.Route {
 	return r.route.route.Headers(headers...)
 }
 
+func (r *Rules) header(header ...string) *mux.Route {
+	if len(header) < 2 {
+		r.err = errors.New("Header rule needs a header name and at least one value")
+		return r.route.route
+	}
+	name, values := header[0], header[1:]
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		reqValue := req.Header.Get(name)
+		for _, value := range values {
+			if reqValue == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (r *Rules) trailers(trailer ...string) *mux.Route {
+	if len(trailer) < 2 {
+		r.err = errors.New("Trailers rule needs a trailer name and at least one value")
+		return r.route.route
+	}
+	r.route.trailerCondition = &middlewares.TrailerCondition{Name: trailer[0], Values: trailer[1:]}
+	return r.route.route
+}
+
+func (r *Rules) requestBodyContains(substring string) *mux.Route {
+	maxBodyBuffer := r.route.maxBodyBuffer
+	if maxBodyBuffer <= 0 {
+		maxBodyBuffer = defaultMaxBodyBuffer
+	}
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		if req.Body == nil {
+			return false
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxBodyBuffer))
+		// the backend still receives the whole body
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+		if err != nil {
+			log.Errorf("Error reading request body: %v", err)
+			return false
+		}
+		return bytes.Contains(body, []byte(substring))
+	})
+}
+
+func (r *Rules) probability(probabilities ...string) *mux.Route {
+	if len(probabilities) != 1 {
+		r.err = errors.New("Probability rule needs a single probability")
+		return r.route.route
+	}
+	probability, err := strconv.ParseFloat(probabilities[0], 64)
+	if err != nil || probability < 0 || probability > 1 {
+		r.err = errors.New("Invalid probability '" + probabilities[0] + "', expected a number between 0 and 1")
+		return r.route.route
+	}
+	seed := r.route.seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	source := rand.New(rand.NewSource(seed))
+	var lock sync.Mutex
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return source.Float64() < probability
+	})
+}
+
+func (r *Rules) hostIP(ips ...string) *mux.Route {
+	var localIPs []net.IP
+	for _, ip := range ips {
+		localIP := net.ParseIP(ip)
+		if localIP == nil {
+			r.err = errors.New("Invalid IP '" + ip + "'")
+			return r.route.route
+		}
+		localIPs = append(localIPs, localIP)
+	}
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		// the local address of the connection is set by the http server
+		localAddr, ok := req.Context().Value(http.LocalAddrContextKey).(net.Addr)
+		if !ok {
+			return false
+		}
+		host, _, err := net.SplitHostPort(localAddr.String())
+		if err != nil {
+			return false
+		}
+		reqIP := net.ParseIP(host)
+		for _, localIP := range localIPs {
+			if localIP.Equal(reqIP) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// customMatcher calls the registered matcher plugin, the rule arguments being the plugin
+// name optionally followed by a JSON object configuring it: CustomMatcher:myPlugin:{"claim":"admin"}
+func (r *Rules) customMatcher(arg string) *mux.Route {
+	parts := strings.SplitN(arg, ":", 2)
+	name := strings.TrimSpace(parts[0])
+	plugin, ok := getMatcher(name)
+	if !ok {
+		r.err = errors.New("Unknown matcher plugin '" + name + "'")
+		return r.route.route
+	}
+	config := map[string]string{}
+	if len(parts) == 2 && len(strings.TrimSpace(parts[1])) > 0 {
+		if err := json.Unmarshal([]byte(parts[1]), &config); err != nil {
+			r.err = fmt.Errorf("Invalid configuration of matcher plugin '%s': %v", name, err)
+			return r.route.route
+		}
+	}
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		return plugin.Match(req, config)
+	})
+}
+
+// xff matches the rightmost IP of the X-Forwarded-For header which is not a trusted proxy
+// of the frontend against the given IPs and CIDRs. The header is only read if the request
+// comes from a trusted proxy, clients being able to send any header.
+func (r *Rules) xff(ips ...string) *mux.Route {
+	matched, err := parseIPNets(ips)
+	if err != nil {
+		r.err = err
+		return r.route.route
+	}
+	trusted, err := parseIPNets(r.route.trustedIPs)
+	if err != nil {
+		r.err = err
+		return r.route.route
+	}
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			return false
+		}
+		if remoteIP := net.ParseIP(host); remoteIP == nil || !containsIP(trusted, remoteIP) {
+			return false
+		}
+		var chain []string
+		for _, value := range req.Header["X-Forwarded-For"] {
+			chain = append(chain, strings.Split(value, ",")...)
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(chain[i]))
+			if ip == nil {
+				return false
+			}
+			if !containsIP(trusted, ip) {
+				return containsIP(matched, ip)
+			}
+		}
+		return false
+	})
+}
+
+// parseIPNets parses IPs and CIDRs, IPs being single address networks
+func parseIPNets(values []string) ([]*net.IPNet, error) {
+	var ipNets []*net.IPNet
+	for _, value := range values {
+		if strings.Contains(value, "/") {
+			_, ipNet, err := net.ParseCIDR(value)
+			if err != nil {
+				return nil, errors.New("Invalid CIDR '" + value + "'")
+			}
+			ipNets = append(ipNets, ipNet)
+			continue
+		}
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, errors.New("Invalid IP '" + value + "'")
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		ipNets = append(ipNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return ipNets, nil
+}
+
+func containsIP(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodySizeUnknown is the BodySize rule argument matching requests without Content-Length
+const bodySizeUnknown = "unknown"
+
+type bodySizeRange struct {
+	min int64
+	max int64 // exclusive, -1 when unbounded
+}
+
+func (r *Rules) bodySize(sizes ...string) *mux.Route {
+	var ranges []bodySizeRange
+	matchUnknown := false
+	for _, size := range sizes {
+		if size == bodySizeUnknown {
+			matchUnknown = true
+			continue
+		}
+		sizeRange, err := parseBodySizeRange(size)
+		if err != nil {
+			r.err = err
+			return r.route.route
+		}
+		ranges = append(ranges, sizeRange)
+	}
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		if req.ContentLength < 0 {
+			return matchUnknown
+		}
+		for _, sizeRange := range ranges {
+			if req.ContentLength >= sizeRange.min && (sizeRange.max < 0 || req.ContentLength < sizeRange.max) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// parseBodySizeRange parses a size range formatted as <min>-<max>, max being optional
+func parseBodySizeRange(size string) (bodySizeRange, error) {
+	bounds := strings.SplitN(size, "-", 2)
+	if len(bounds) != 2 {
+		return bodySizeRange{}, errors.New("Invalid body size range '" + size + "', expected <min>-<max>")
+	}
+	min, err := strconv.ParseInt(strings.TrimSpace(bounds[0]), 10, 64)
+	if err != nil || min < 0 {
+		return bodySizeRange{}, errors.New("Invalid body size range '" + size + "'")
+	}
+	max := int64(-1)
+	if len(strings.TrimSpace(bounds[1])) > 0 {
+		max, err = strconv.ParseInt(strings.TrimSpace(bounds[1]), 10, 64)
+		if err != nil || max <= min {
+			return bodySizeRange{}, errors.New("Invalid body size range '" + size + "'")
+		}
+	}
+	return bodySizeRange{min: min, max: max}, nil
+}
+
+func (r *Rules) cookiePresent(names ...string) *mux.Route {
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		for _, name := range names {
+			if cookie, err := req.Cookie(name); err == nil && cookie != nil {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// acceptEncoding matches the requests accepting one of the given content codings
+func (r *Rules) acceptEncoding(encodings ...string) *mux.Route {
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		for _, value := range req.Header["Accept-Encoding"] {
+			for _, accepted := range strings.Split(value, ",") {
+				parts := strings.Split(accepted, ";")
+				coding := strings.TrimSpace(parts[0])
+				// a zero quality value means "not acceptable"
+				if len(parts) > 1 {
+					if q := strings.Replace(strings.TrimSpace(parts[1]), " ", "", -1); strings.HasPrefix(q, "q=") {
+						if quality, err := strconv.ParseFloat(q[2:], 64); err == nil && quality == 0 {
+							continue
+						}
+					}
+				}
+				for _, encoding := range encodings {
+					if strings.EqualFold(coding, encoding) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	})
+}
+
+// contentType matches the requests whose media type is one of the given ones, ignoring its parameters
+func (r *Rules) contentType(mediaTypes ...string) *mux.Route {
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		mediaType := strings.TrimSpace(strings.Split(req.Header.Get("Content-Type"), ";")[0])
+		for _, accepted := range mediaTypes {
+			if strings.EqualFold(mediaType, accepted) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// httpVersion matches the requests whose HTTP major version is one of the given ones
+func (r *Rules) httpVersion(versions ...string) *mux.Route {
+	majors := make([]int, len(versions))
+	for i, version := range versions {
+		major, err := strconv.Atoi(version)
+		if err != nil || major < 1 {
+			r.err = errors.New("Invalid HTTP version '" + version + "', expected a major version such as 1 or 2")
+			return r.route.route
+		}
+		majors[i] = major
+	}
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		for _, major := range majors {
+			if req.ProtoMajor == major {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// remotePortRange matches the requests whose client source port is in one of the given
+// inclusive ranges, such as 1024-65535
+func (r *Rules) remotePortRange(portRanges ...string) *mux.Route {
+	ranges := make([][2]int, len(portRanges))
+	for i, portRange := range portRanges {
+		bounds := strings.SplitN(portRange, "-", 2)
+		if len(bounds) != 2 {
+			r.err = errors.New("Invalid port range '" + portRange + "', expected min-max")
+			return r.route.route
+		}
+		min, errMin := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		max, errMax := strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if errMin != nil || errMax != nil || min < 0 || max > 65535 || min > max {
+			r.err = errors.New("Invalid port range '" + portRange + "', expected min-max between 0 and 65535")
+			return r.route.route
+		}
+		ranges[i] = [2]int{min, max}
+	}
+	return r.route.route.MatcherFunc(func(req *http.Request, route *mux.RouteMatch) bool {
+		_, portValue, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			return false
+		}
+		port, err := strconv.Atoi(portValue)
+		if err != nil {
+			return false
+		}
+		for _, portRange := range ranges {
+			if port >= portRange[0] && port <= portRange[1] {
+				return true
+			}
+		}
+		return false
+	})
+}
+
 func (r *Rules) headersRegexp(headers ...string) *mux.Route {
 	return r.route.route.HeadersRegexp(headers...)
 }
 
+// rawArgumentFunctions are the rule functions given the whole remainder of the rule as single
+// argument, instead of the arguments split on commas
+var rawArgumentFunctions = map[string]bool{
+	"RequestBodyContains": true,
+	"CustomMatcher":       true,
+}
+
 func (r *Rules) parseRules(expression string, onRule func(functionName string, function interface{}, arguments []string) error) error {
 	functions := map[string]interface{}{
 		"Host":                 r.host,
@@ -139,13 +626,29 @@ func (r *Rules) parseRules(expression string, onRule func(functionName string, f
 		"PathStrip":            r.pathStrip,
 		"PathStripRegex":       r.pathStripRegex,
 		"PathPrefix":           r.pathPrefix,
+		"PathPrefixRegex":      r.pathPrefix,
 		"PathPrefixStrip":      r.pathPrefixStrip,
 		"PathPrefixStripRegex": r.pathPrefixStripRegex,
 		"Method":               r.methods,
+		"MethodPrefix":         r.methodPrefix,
 		"Headers":              r.headers,
+		"Header":               r.header,
 		"HeadersRegexp":        r.headersRegexp,
+		"CookiePresent":        r.cookiePresent,
+		"RequestBodyContains":  r.requestBodyContains,
+		"Probability":          r.probability,
+		"HostIP":               r.hostIP,
+		"BodySize":             r.bodySize,
+		"XFF":                  r.xff,
+		"AcceptEncoding":       r.acceptEncoding,
+		"ContentType":          r.contentType,
+		"HTTPVersion":          r.httpVersion,
+		"RemotePortRange":      r.remotePortRange,
+		"CustomMatcher":        r.customMatcher,
 		"AddPrefix":            r.addPrefix,
 		"ReplacePath":          r.replacePath,
+		"RateLimit":            r.rateLimit,
+		"Trailers":             r.trailers,
 	}
 
 	if len(expression) == 0 {
@@ -156,12 +659,12 @@ func (r *Rules) parseRules(expression string, onRule func(functionName string, f
 		return c == ':'
 	}
 
-	// Allow multiple rules separated by ;
+	// Allow multiple rules separated by ; or &&
 	splitRule := func(c rune) bool {
 		return c == ';'
 	}
 
-	parsedRules := strings.FieldsFunc(expression, splitRule)
+	parsedRules := strings.FieldsFunc(strings.Replace(expression, "&&", ";", -1), splitRule)
 
 	for _, rule := range parsedRules {
 		// get function
@@ -180,6 +683,13 @@ func (r *Rules) parseRules(expression string, onRule func(functionName string, f
 		}
 		// get function
 		parsedArgs := strings.FieldsFunc(strings.Join(parsedFunctions, ":"), fargs)
+		if rawArgumentFunctions[functionName] {
+			// the argument may hold commas and colons, e.g. a JSON snippet
+			parsedArgs = []string{rule[strings.Index(rule, ":")+1:]}
+			if len(strings.TrimSpace(parsedArgs[0])) == 0 {
+				parsedArgs = nil
+			}
+		}
 		if len(parsedArgs) == 0 {
 			return errors.New("Error parsing args from rule: '" + rule + "'")
 		}
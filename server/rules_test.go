@@ -1,12 +1,18 @@
 package server
 
 import (
+	"context"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/containous/mux"
+	"github.com/containous/traefik/middlewares"
 )
 
 func TestParseOneRule(t *testing.T) {
@@ -61,6 +67,185 @@ func TestParseTwoRules(t *testing.T) {
 	}
 }
 
+func TestParsePathPrefixRegex(t *testing.T) {
+	router := mux.NewRouter()
+	route := router.NewRoute()
+	serverRoute := &serverRoute{route: route}
+	rules := &Rules{route: serverRoute}
+
+	expression := "PathPrefixRegex:/api/{version:v[0-9]+}/{resource}"
+	routeResult, err := rules.Parse(expression)
+	if err != nil {
+		t.Fatalf("Error while building route for %s: %v", expression, err)
+	}
+
+	request, _ := http.NewRequest("GET", "http://foo.bar/api/v1/resource", nil)
+	routeMatch := &mux.RouteMatch{Route: routeResult}
+	if !routeResult.Match(request, routeMatch) {
+		t.Fatalf("Rule %s doesn't match", expression)
+	}
+	expectedVars := map[string]string{"version": "v1", "resource": "resource"}
+	if !reflect.DeepEqual(routeMatch.Vars, expectedVars) {
+		t.Fatalf("Error capturing variables: expected %+v, got %+v", expectedVars, routeMatch.Vars)
+	}
+
+	request, _ = http.NewRequest("GET", "http://foo.bar/api/latest/resource", nil)
+	if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) {
+		t.Fatalf("Rule %s shouldn't match", expression)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	router := mux.NewRouter()
+	route := router.NewRoute()
+	serverRoute := &serverRoute{route: route}
+	rules := &Rules{route: serverRoute}
+
+	expression := "RateLimit:10/s&&Host:api.example.com"
+	routeResult, err := rules.Parse(expression)
+	if err != nil {
+		t.Fatalf("Error while building route for %s: %v", expression, err)
+	}
+
+	expectedRate := &middlewares.Rate{Average: 10, Period: time.Second}
+	if !reflect.DeepEqual(serverRoute.rateLimit, expectedRate) {
+		t.Fatalf("Error parsing rate limit: expected %+v, got %+v", expectedRate, serverRoute.rateLimit)
+	}
+
+	request, _ := http.NewRequest("GET", "http://api.example.com", nil)
+	if !routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) {
+		t.Fatalf("Rule %s doesn't match", expression)
+	}
+	request, _ = http.NewRequest("GET", "http://foo.bar", nil)
+	if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) {
+		t.Fatalf("Rule %s shouldn't match", expression)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		rate     string
+		expected *middlewares.Rate
+	}{
+		{rate: "10/s", expected: &middlewares.Rate{Average: 10, Period: time.Second}},
+		{rate: "100/m", expected: &middlewares.Rate{Average: 100, Period: time.Minute}},
+		{rate: "5/h", expected: &middlewares.Rate{Average: 5, Period: time.Hour}},
+		{rate: "10", expected: nil},
+		{rate: "0/s", expected: nil},
+		{rate: "ten/s", expected: nil},
+		{rate: "10/d", expected: nil},
+	}
+
+	for _, test := range tests {
+		rate, err := parseRate(test.rate)
+		if test.expected == nil {
+			if err == nil {
+				t.Errorf("Expected an error parsing rate %s", test.rate)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Error parsing rate %s: %v", test.rate, err)
+			continue
+		}
+		if !reflect.DeepEqual(rate, test.expected) {
+			t.Errorf("Error parsing rate %s: expected %+v, got %+v", test.rate, test.expected, rate)
+		}
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	router := mux.NewRouter()
+	rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+
+	expression := "Header:X-Version,v1,v2,v3"
+	routeResult, err := rules.Parse(expression)
+	if err != nil {
+		t.Fatalf("Error while building route for %s: %v", expression, err)
+	}
+
+	for _, value := range []string{"v1", "v2", "v3"} {
+		request, _ := http.NewRequest("GET", "http://foo.bar", nil)
+		request.Header.Set("X-Version", value)
+		if !routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) {
+			t.Errorf("Rule %s doesn't match X-Version: %s", expression, value)
+		}
+	}
+
+	request, _ := http.NewRequest("GET", "http://foo.bar", nil)
+	request.Header.Set("X-Version", "v4")
+	if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) {
+		t.Errorf("Rule %s shouldn't match X-Version: v4", expression)
+	}
+
+	request, _ = http.NewRequest("GET", "http://foo.bar", nil)
+	if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) {
+		t.Errorf("Rule %s shouldn't match without X-Version header", expression)
+	}
+
+	invalidRules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+	if _, err := invalidRules.Parse("Header:X-Version"); err == nil {
+		t.Error("Expected an error for a Header rule without values")
+	}
+}
+
+func TestParseMethodPrefix(t *testing.T) {
+	router := mux.NewRouter()
+	rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+
+	expression := "MethodPrefix:GET,/api"
+	routeResult, err := rules.Parse(expression)
+	if err != nil {
+		t.Fatalf("Error while building route for %s: %v", expression, err)
+	}
+
+	tests := []struct {
+		method   string
+		url      string
+		expected bool
+	}{
+		{method: "GET", url: "http://foo.bar/api", expected: true},
+		{method: "GET", url: "http://foo.bar/api/users", expected: true},
+		{method: "POST", url: "http://foo.bar/api/users", expected: false},
+		{method: "GET", url: "http://foo.bar/web", expected: false},
+	}
+
+	for _, test := range tests {
+		request, _ := http.NewRequest(test.method, test.url, nil)
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule %s match for %s %s: expected %t", expression, test.method, test.url, test.expected)
+		}
+	}
+
+	invalidRules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+	if _, err := invalidRules.Parse("MethodPrefix:GET"); err == nil {
+		t.Error("Expected an error for a MethodPrefix rule without path prefix")
+	}
+}
+
+func TestParseCookiePresent(t *testing.T) {
+	router := mux.NewRouter()
+	rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+
+	expression := "CookiePresent:session_id"
+	routeResult, err := rules.Parse(expression)
+	if err != nil {
+		t.Fatalf("Error while building route for %s: %v", expression, err)
+	}
+
+	request, _ := http.NewRequest("GET", "http://foo.bar", nil)
+	request.AddCookie(&http.Cookie{Name: "session_id", Value: ""})
+	if !routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) {
+		t.Errorf("Rule %s doesn't match request with session_id cookie", expression)
+	}
+
+	request, _ = http.NewRequest("GET", "http://foo.bar", nil)
+	request.AddCookie(&http.Cookie{Name: "other", Value: "value"})
+	if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) {
+		t.Errorf("Rule %s shouldn't match request without session_id cookie", expression)
+	}
+}
+
 func TestParseDomains(t *testing.T) {
 	rules := &Rules{}
 	expressionsSlice := []string{
@@ -163,3 +348,513 @@ type fakeHandler struct {
 func (h *fakeHandler) ServeHTTP(http.ResponseWriter, *http.Request) {
 
 }
+
+func TestParseTrailers(t *testing.T) {
+	router := mux.NewRouter()
+	route := &serverRoute{route: router.NewRoute()}
+	rules := &Rules{route: route}
+
+	expression := "Host:foo.bar;Trailers:grpc-status,0"
+	if _, err := rules.Parse(expression); err != nil {
+		t.Fatalf("Error while building route for %s: %v", expression, err)
+	}
+	expected := &middlewares.TrailerCondition{Name: "grpc-status", Values: []string{"0"}}
+	if !reflect.DeepEqual(route.trailerCondition, expected) {
+		t.Errorf("Expected trailer condition %+v, got %+v", expected, route.trailerCondition)
+	}
+
+	invalidRules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+	if _, err := invalidRules.Parse("Trailers:grpc-status"); err == nil {
+		t.Error("Expected an error for a Trailers rule without values")
+	}
+}
+
+func TestParseRequestBodyContains(t *testing.T) {
+	router := mux.NewRouter()
+	rules := &Rules{route: &serverRoute{route: router.NewRoute(), maxBodyBuffer: 64}}
+
+	expression := `RequestBodyContains:"event_type":"payment","currency":"EUR"`
+	routeResult, err := rules.Parse(expression)
+	if err != nil {
+		t.Fatalf("Error while building route for %s: %v", expression, err)
+	}
+
+	tests := []struct {
+		body     string
+		expected bool
+	}{
+		{body: `{"event_type":"payment","currency":"EUR"}`, expected: true},
+		{body: `{"event_type":"payment","currency":"USD"}`, expected: false},
+		{body: `{"event_type":"refund","currency":"EUR"}`, expected: false},
+		{body: `{"padding":"` + strings.Repeat("x", 64) + `","event_type":"payment","currency":"EUR"}`, expected: false},
+		{body: "", expected: false},
+	}
+
+	for _, test := range tests {
+		request, _ := http.NewRequest("POST", "http://foo.bar/webhook", strings.NewReader(test.body))
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule %s match of body %q should be %v", expression, test.body, test.expected)
+		}
+		body, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			t.Fatalf("Error reading body: %v", err)
+		}
+		if string(body) != test.body {
+			t.Errorf("Expected body %q to be passed through, got %q", test.body, body)
+		}
+	}
+}
+
+func TestParseProbability(t *testing.T) {
+	router := mux.NewRouter()
+	matches := func(seed int64) []bool {
+		rules := &Rules{route: &serverRoute{route: router.NewRoute(), seed: seed}}
+		routeResult, err := rules.Parse("Probability:0.1")
+		if err != nil {
+			t.Fatalf("Error while building route for Probability:0.1: %v", err)
+		}
+		var results []bool
+		for i := 0; i < 1000; i++ {
+			request, _ := http.NewRequest("GET", "http://foo.bar", nil)
+			results = append(results, routeResult.Match(request, &mux.RouteMatch{Route: routeResult}))
+		}
+		return results
+	}
+
+	results := matches(42)
+	matched := 0
+	for _, result := range results {
+		if result {
+			matched++
+		}
+	}
+	if matched < 50 || matched > 150 {
+		t.Errorf("Expected about 100 matches out of 1000 requests, got %d", matched)
+	}
+	if !reflect.DeepEqual(results, matches(42)) {
+		t.Error("Expected the same split for the same seed")
+	}
+
+	for _, expression := range []string{"Probability:1.5", "Probability:abc", "Probability:0.1,0.2"} {
+		invalidRules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+		if _, err := invalidRules.Parse(expression); err == nil {
+			t.Errorf("Expected an error for %s", expression)
+		}
+	}
+}
+
+func TestParseHostIP(t *testing.T) {
+	router := mux.NewRouter()
+	rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+
+	expression := "HostIP:192.168.1.1,10.0.0.1"
+	routeResult, err := rules.Parse(expression)
+	if err != nil {
+		t.Fatalf("Error while building route for %s: %v", expression, err)
+	}
+
+	tests := []struct {
+		localAddr net.Addr
+		expected  bool
+	}{
+		{localAddr: &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 80}, expected: true},
+		{localAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 443}, expected: true},
+		{localAddr: &net.TCPAddr{IP: net.ParseIP("192.168.1.2"), Port: 80}, expected: false},
+		{localAddr: nil, expected: false},
+	}
+
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", "http://foo.bar", nil)
+		if test.localAddr != nil {
+			request = request.WithContext(context.WithValue(request.Context(), http.LocalAddrContextKey, test.localAddr))
+		}
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule %s match of local address %v should be %v", expression, test.localAddr, test.expected)
+		}
+	}
+
+	invalidRules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+	if _, err := invalidRules.Parse("HostIP:foo"); err == nil {
+		t.Error("Expected an error for an invalid HostIP rule")
+	}
+}
+
+func TestParseBodySize(t *testing.T) {
+	router := mux.NewRouter()
+
+	tests := []struct {
+		expression    string
+		contentLength int64
+		expected      bool
+	}{
+		{expression: "BodySize:0-1048576", contentLength: 0, expected: true},
+		{expression: "BodySize:0-1048576", contentLength: 1048575, expected: true},
+		{expression: "BodySize:0-1048576", contentLength: 1048576, expected: false},
+		{expression: "BodySize:0-1048576", contentLength: -1, expected: false},
+		{expression: "BodySize:1048576-", contentLength: 1048576, expected: true},
+		{expression: "BodySize:1048576-", contentLength: 1 << 30, expected: true},
+		{expression: "BodySize:1048576-", contentLength: 1024, expected: false},
+		{expression: "BodySize:1048576-", contentLength: -1, expected: false},
+		{expression: "BodySize:1048576-,unknown", contentLength: -1, expected: true},
+		{expression: "BodySize:0-10,100-1000", contentLength: 500, expected: true},
+		{expression: "BodySize:0-10,100-1000", contentLength: 50, expected: false},
+	}
+
+	for _, test := range tests {
+		rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+		routeResult, err := rules.Parse(test.expression)
+		if err != nil {
+			t.Fatalf("Error while building route for %s: %v", test.expression, err)
+		}
+		request, _ := http.NewRequest("POST", "http://foo.bar/upload", nil)
+		request.ContentLength = test.contentLength
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule %s match of Content-Length %d should be %v", test.expression, test.contentLength, test.expected)
+		}
+	}
+
+	for _, expression := range []string{"BodySize:10", "BodySize:a-b", "BodySize:10-5", "BodySize:-10"} {
+		invalidRules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+		if _, err := invalidRules.Parse(expression); err == nil {
+			t.Errorf("Expected an error for %s", expression)
+		}
+	}
+}
+
+type claimMatcher struct{}
+
+func (claimMatcher) Name() string {
+	return "claim"
+}
+
+func (claimMatcher) Match(req *http.Request, config map[string]string) bool {
+	return req.Header.Get("X-Claim") == config["claim"] && req.Header.Get("X-Scope") == config["scope"]
+}
+
+func TestParseCustomMatcher(t *testing.T) {
+	RegisterMatcher("", claimMatcher{})
+	RegisterMatcher("myPlugin", claimMatcher{})
+	router := mux.NewRouter()
+
+	tests := []struct {
+		expression string
+		claim      string
+		scope      string
+		expected   bool
+	}{
+		{expression: `CustomMatcher:myPlugin:{"claim":"admin"}`, claim: "admin", expected: true},
+		{expression: `CustomMatcher:myPlugin:{"claim":"admin"}`, claim: "user", expected: false},
+		{expression: `CustomMatcher:claim:{"claim":"admin", "scope":"write"}`, claim: "admin", scope: "write", expected: true},
+		{expression: `CustomMatcher:claim:{"claim":"admin", "scope":"write"}`, claim: "admin", scope: "read", expected: false},
+		{expression: `CustomMatcher:claim`, expected: true},
+		{expression: `CustomMatcher:myPlugin:{"claim":"admin,,user"}`, claim: "admin,,user", expected: true},
+	}
+
+	for _, test := range tests {
+		rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+		routeResult, err := rules.Parse(test.expression)
+		if err != nil {
+			t.Fatalf("Error while building route for %s: %v", test.expression, err)
+		}
+		request, _ := http.NewRequest("GET", "http://foo.bar/", nil)
+		request.Header.Set("X-Claim", test.claim)
+		request.Header.Set("X-Scope", test.scope)
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule %s match of claim %q and scope %q should be %v", test.expression, test.claim, test.scope, test.expected)
+		}
+	}
+
+	for _, expression := range []string{`CustomMatcher:unknown`, `CustomMatcher:myPlugin:{"claim"}`} {
+		invalidRules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+		if _, err := invalidRules.Parse(expression); err == nil {
+			t.Errorf("Expected an error for %s", expression)
+		}
+	}
+}
+
+func TestParseXFF(t *testing.T) {
+	router := mux.NewRouter()
+
+	tests := []struct {
+		expression string
+		trustedIPs []string
+		remoteAddr string
+		xff        []string
+		expected   bool
+	}{
+		{expression: "XFF:10.0.0.1", trustedIPs: []string{"172.16.0.0/12"}, xff: []string{"10.0.0.1"}, expected: true},
+		{expression: "XFF:10.0.0.1", trustedIPs: []string{"172.16.0.0/12"}, xff: []string{"10.0.0.2"}, expected: false},
+		{expression: "XFF:10.0.0.1", trustedIPs: []string{"172.16.0.0/12"}, xff: nil, expected: false},
+		{expression: "XFF:10.0.0.0/24", trustedIPs: []string{"172.16.0.0/12"}, xff: []string{"192.168.0.1, 10.0.0.42"}, expected: true},
+		{expression: "XFF:10.0.0.1", trustedIPs: []string{"172.16.0.9"}, xff: []string{"10.0.0.1, 172.16.0.1"}, expected: false},
+		{expression: "XFF:10.0.0.1", trustedIPs: []string{"172.16.0.0/12"}, xff: []string{"10.0.0.1, 172.16.0.1"}, expected: true},
+		{expression: "XFF:10.0.0.1", trustedIPs: []string{"172.16.0.1", "172.16.0.2", "172.16.0.9"}, xff: []string{"10.0.0.1, 172.16.0.1", "172.16.0.2"}, expected: true},
+		{expression: "XFF:10.0.0.1", trustedIPs: []string{"172.16.0.0/12"}, xff: []string{"172.16.0.1"}, expected: false},
+		{expression: "XFF:10.0.0.1", trustedIPs: []string{"172.16.0.0/12"}, xff: []string{"10.0.0.1, unknown"}, expected: false},
+		// the header sent by a client is not trusted
+		{expression: "XFF:10.0.0.1", remoteAddr: "192.168.0.1:1234", xff: []string{"10.0.0.1"}, expected: false},
+		{expression: "XFF:10.0.0.1", trustedIPs: []string{"172.16.0.0/12"}, remoteAddr: "192.168.0.1:1234", xff: []string{"10.0.0.1"}, expected: false},
+	}
+
+	for _, test := range tests {
+		rules := &Rules{route: &serverRoute{route: router.NewRoute(), trustedIPs: test.trustedIPs}}
+		routeResult, err := rules.Parse(test.expression)
+		if err != nil {
+			t.Fatalf("Error while building route for %s: %v", test.expression, err)
+		}
+		request, _ := http.NewRequest("GET", "http://foo.bar/", nil)
+		request.RemoteAddr = "172.16.0.9:1234"
+		if test.remoteAddr != "" {
+			request.RemoteAddr = test.remoteAddr
+		}
+		for _, value := range test.xff {
+			request.Header.Add("X-Forwarded-For", value)
+		}
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule %s match of X-Forwarded-For %v from %s with trusted IPs %v should be %v", test.expression, test.xff, request.RemoteAddr, test.trustedIPs, test.expected)
+		}
+	}
+
+	for _, trustedIPs := range [][]string{nil, {"172.16.0.0/33"}} {
+		expression := "XFF:10.0.0.1.1"
+		if trustedIPs != nil {
+			expression = "XFF:10.0.0.1"
+		}
+		invalidRules := &Rules{route: &serverRoute{route: router.NewRoute(), trustedIPs: trustedIPs}}
+		if _, err := invalidRules.Parse(expression); err == nil {
+			t.Errorf("Expected an error for %s with trusted IPs %v", expression, trustedIPs)
+		}
+	}
+}
+
+func TestParseAcceptEncoding(t *testing.T) {
+	router := mux.NewRouter()
+
+	tests := []struct {
+		acceptEncoding []string
+		expected       bool
+	}{
+		{acceptEncoding: []string{"gzip"}, expected: true},
+		{acceptEncoding: []string{"deflate, GZIP;q=0.8"}, expected: true},
+		{acceptEncoding: []string{"br", "gzip"}, expected: true},
+		{acceptEncoding: []string{"gzip;q=0"}, expected: false},
+		{acceptEncoding: []string{"gzip; q=0.0, deflate"}, expected: false},
+		{acceptEncoding: []string{"x-gzip-like"}, expected: false},
+		{acceptEncoding: nil, expected: false},
+	}
+
+	rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+	routeResult, err := rules.Parse("AcceptEncoding:gzip")
+	if err != nil {
+		t.Fatalf("Error while building route for AcceptEncoding:gzip: %v", err)
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", "http://foo.bar/", nil)
+		for _, value := range test.acceptEncoding {
+			request.Header.Add("Accept-Encoding", value)
+		}
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule AcceptEncoding:gzip match of Accept-Encoding %v should be %v", test.acceptEncoding, test.expected)
+		}
+	}
+}
+
+func TestParseContentType(t *testing.T) {
+	router := mux.NewRouter()
+
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{contentType: "application/json", expected: true},
+		{contentType: "application/json; charset=utf-8", expected: true},
+		{contentType: "Application/JSON;charset=utf-8", expected: true},
+		{contentType: "application/x-www-form-urlencoded", expected: false},
+		{contentType: "application/jsonp", expected: false},
+		{contentType: "", expected: false},
+	}
+
+	rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+	routeResult, err := rules.Parse("ContentType:application/json")
+	if err != nil {
+		t.Fatalf("Error while building route for ContentType:application/json: %v", err)
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("POST", "http://foo.bar/", nil)
+		if test.contentType != "" {
+			request.Header.Set("Content-Type", test.contentType)
+		}
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule ContentType:application/json match of Content-Type %q should be %v", test.contentType, test.expected)
+		}
+	}
+}
+
+func TestParseHTTPVersion(t *testing.T) {
+	router := mux.NewRouter()
+
+	tests := []struct {
+		expression string
+		protoMajor int
+		expected   bool
+	}{
+		{expression: "HTTPVersion:2", protoMajor: 2, expected: true},
+		{expression: "HTTPVersion:2", protoMajor: 1, expected: false},
+		{expression: "HTTPVersion:1", protoMajor: 1, expected: true},
+		{expression: "HTTPVersion:1", protoMajor: 2, expected: false},
+		{expression: "HTTPVersion:1,2", protoMajor: 2, expected: true},
+		{expression: "Host:foo.bar;HTTPVersion:2", protoMajor: 2, expected: true},
+	}
+
+	for _, test := range tests {
+		rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+		routeResult, err := rules.Parse(test.expression)
+		if err != nil {
+			t.Fatalf("Error while building route for %s: %v", test.expression, err)
+		}
+		request, _ := http.NewRequest("GET", "http://foo.bar/", nil)
+		request.ProtoMajor = test.protoMajor
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule %s match of HTTP/%d request should be %v", test.expression, test.protoMajor, test.expected)
+		}
+	}
+
+	for _, expression := range []string{"HTTPVersion:two", "HTTPVersion:0"} {
+		rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+		if _, err := rules.Parse(expression); err == nil {
+			t.Errorf("Expected an error for %s", expression)
+		}
+	}
+}
+
+func TestParseRemotePortRange(t *testing.T) {
+	router := mux.NewRouter()
+
+	tests := []struct {
+		remoteAddr string
+		expected   bool
+	}{
+		{remoteAddr: "10.0.0.1:1023", expected: false},
+		{remoteAddr: "10.0.0.1:1024", expected: true},
+		{remoteAddr: "[2001:db8::1]:8080", expected: true},
+		{remoteAddr: "10.0.0.1:65535", expected: true},
+		{remoteAddr: "10.0.0.1:65536", expected: false},
+		{remoteAddr: "10.0.0.1", expected: false},
+	}
+
+	rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+	routeResult, err := rules.Parse("RemotePortRange:1024-65535")
+	if err != nil {
+		t.Fatalf("Error while building route for RemotePortRange:1024-65535: %v", err)
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", "http://foo.bar/", nil)
+		request.RemoteAddr = test.remoteAddr
+		if routeResult.Match(request, &mux.RouteMatch{Route: routeResult}) != test.expected {
+			t.Errorf("Rule RemotePortRange:1024-65535 match of remote address %s should be %v", test.remoteAddr, test.expected)
+		}
+	}
+
+	for _, expression := range []string{"RemotePortRange:1024", "RemotePortRange:2000-1000", "RemotePortRange:0-65536", "RemotePortRange:a-b"} {
+		rules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+		if _, err := rules.Parse(expression); err == nil {
+			t.Errorf("Expected an error for %s", expression)
+		}
+	}
+}
+
+func TestParseCaseInsensitivePath(t *testing.T) {
+	router := mux.NewRouter()
+
+	tests := []struct {
+		expression      string
+		caseInsensitive bool
+		path            string
+		expected        bool
+	}{
+		{expression: "Path:/Api/V1", caseInsensitive: false, path: "/api/v1", expected: false},
+		{expression: "Path:/Api/V1", caseInsensitive: false, path: "/Api/V1", expected: true},
+		{expression: "Path:/Api/V1", caseInsensitive: true, path: "/api/v1", expected: true},
+		{expression: "Path:/Api/V1", caseInsensitive: true, path: "/API/V1", expected: true},
+		{expression: "Path:/Api/V1", caseInsensitive: true, path: "/api/v2", expected: false},
+		{expression: "PathPrefix:/Api", caseInsensitive: true, path: "/API/users", expected: true},
+		{expression: "PathStrip:/Api", caseInsensitive: true, path: "/aPI", expected: true},
+		{expression: "PathPrefixStrip:/Api", caseInsensitive: true, path: "/apI/users", expected: true},
+		{expression: "Path:/users/{id:[0-9]+}", caseInsensitive: true, path: "/Users/42", expected: true},
+	}
+
+	for _, test := range tests {
+		rules := &Rules{route: &serverRoute{route: router.NewRoute(), caseInsensitive: test.caseInsensitive}}
+		routeResult, err := rules.Parse(test.expression)
+		if err != nil {
+			t.Fatalf("Error while building route for %s: %v", test.expression, err)
+		}
+		request, _ := http.NewRequest("GET", "http://foo.bar"+test.path, nil)
+		routeMatch := &mux.RouteMatch{Route: routeResult}
+		if routeResult.Match(request, routeMatch) != test.expected {
+			t.Errorf("Rule %s (case insensitive %v) match of %s should be %v", test.expression, test.caseInsensitive, test.path, test.expected)
+		}
+	}
+
+	rules := &Rules{route: &serverRoute{route: router.NewRoute(), caseInsensitive: true}}
+	if _, err := rules.Parse("PathPrefixStrip:/Api"); err != nil {
+		t.Fatalf("Error while building route for PathPrefixStrip:/Api: %v", err)
+	}
+	if !reflect.DeepEqual(rules.route.stripPrefixes, []string{"/api"}) {
+		t.Errorf("got strip prefixes %v, want the lowercased prefix /api", rules.route.stripPrefixes)
+	}
+
+	rules = &Rules{route: &serverRoute{route: router.NewRoute(), caseInsensitive: true}}
+	routeResult, err := rules.Parse("Path:/api/users/{id:[0-9]+}")
+	if err != nil {
+		t.Fatalf("Error while building route for Path:/api/users/{id:[0-9]+}: %v", err)
+	}
+	request, _ := http.NewRequest("GET", "http://foo.bar/API/users/42", nil)
+	routeMatch := &mux.RouteMatch{Route: routeResult}
+	if !routeResult.Match(request, routeMatch) || routeMatch.Vars["id"] != "42" {
+		t.Errorf("got route variables %v, want id=42", routeMatch.Vars)
+	}
+}
+
+func TestAcceptEncodingFallback(t *testing.T) {
+	router := mux.NewRouter()
+
+	gzipRules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+	gzipRoute, err := gzipRules.Parse("AcceptEncoding:gzip;Path:/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzipHandler := &fakeHandler{name: "gzipHandler"}
+	gzipRoute.Handler(gzipHandler).Priority(20)
+
+	fallbackRules := &Rules{route: &serverRoute{route: router.NewRoute()}}
+	fallbackRoute, err := fallbackRules.Parse("Path:/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallbackHandler := &fakeHandler{name: "fallbackHandler"}
+	fallbackRoute.Handler(fallbackHandler).Priority(10)
+	router.SortRoutes()
+
+	tests := []struct {
+		acceptEncoding string
+		expected       *fakeHandler
+	}{
+		{acceptEncoding: "gzip, deflate", expected: gzipHandler},
+		{acceptEncoding: "identity", expected: fallbackHandler},
+		{acceptEncoding: "", expected: fallbackHandler},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", "http://foo.bar/api", nil)
+		if len(test.acceptEncoding) > 0 {
+			request.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		match := &mux.RouteMatch{}
+		if !router.Match(request, match) {
+			t.Fatalf("Error matching route for Accept-Encoding %q", test.acceptEncoding)
+		}
+		if match.Handler != test.expected {
+			t.Errorf("Accept-Encoding %q routed to %v, want %s", test.acceptEncoding, match.Handler, test.expected.name)
+		}
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -14,6 +16,8 @@ import (
 	"reflect"
 	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -47,11 +51,21 @@ type Server struct {
 	stopChan                   chan bool
 	providers                  []provider.Provider
 	currentConfigurations      safe.Safe
+	configErrors               safe.Safe
 	globalConfiguration        GlobalConfiguration
 	loggerMiddleware           *middlewares.Logger
 	accessLoggerMiddleware     *accesslog.LogHandler
 	routinesPool               *safe.Pool
 	leadership                 *cluster.Leadership
+	backendTransports          map[string]*backendTransport
+}
+
+// backendTransport is the transport forwarding the requests to a backend, shared by its
+// frontends and reused by the next configurations as long as the backend is unchanged
+type backendTransport struct {
+	backend   *types.Backend
+	transport http.RoundTripper
+	tlsConfig *tls.Config
 }
 
 type serverEntryPoints map[string]*serverEntryPoint
@@ -59,6 +73,7 @@ type serverEntryPoints map[string]*serverEntryPoint
 type serverEntryPoint struct {
 	httpServer *http.Server
 	httpRouter *middlewares.HandlerSwitcher
+	tcpRoutes  *safe.Safe
 }
 
 type serverRoute struct {
@@ -67,6 +82,19 @@ type serverRoute struct {
 	stripPrefixesRegex []string
 	addPrefix          string
 	replacePath        string
+	forwardCaptures    bool
+	rateLimit          *middlewares.Rate
+	trailerCondition   *middlewares.TrailerCondition
+	maxBodyBuffer      int64
+	seed               int64
+	requestIDHeader    string
+	trustedIPs         []string
+	caseInsensitive    bool
+	ipWhitelist        []*net.IPNet
+	rateLimitSource    utils.SourceExtractor
+	rateLimitRates     []middlewares.Rate
+	headers            *types.Headers
+	handlers           []negroni.Handler
 }
 
 // NewServer returns an initialized Server.
@@ -82,6 +110,7 @@ func NewServer(globalConfiguration GlobalConfiguration) *Server {
 	signal.Notify(server.signals, syscall.SIGINT, syscall.SIGTERM)
 	currentConfigurations := make(configs)
 	server.currentConfigurations.Set(currentConfigurations)
+	server.configErrors.Set(make(providerErrors))
 	server.globalConfiguration = globalConfiguration
 	server.loggerMiddleware = middlewares.NewLogger(globalConfiguration.AccessLogsFile)
 	server.accessLoggerMiddleware = accesslog.NewLogHandler()
@@ -204,7 +233,7 @@ func (server *Server) startHTTPServers() {
 		}
 		serverEntryPoint := server.serverEntryPoints[newServerEntryPointName]
 		serverEntryPoint.httpServer = newsrv
-		go server.startServer(serverEntryPoint.httpServer, server.globalConfiguration)
+		go server.startServer(serverEntryPoint, server.globalConfiguration)
 	}
 }
 
@@ -219,6 +248,7 @@ func (server *Server) listenProviders(stop chan bool) {
 			if !ok {
 				return
 			}
+			server.setConfigErrors(configMsg.ProviderName, configMsg.Errors)
 			server.defaultConfigurationValues(configMsg.Configuration)
 			currentConfigurations := server.currentConfigurations.Get().(configs)
 			jsonConf, _ := json.Marshal(configMsg.Configuration)
@@ -254,6 +284,24 @@ func (server *Server) listenProviders(stop chan bool) {
 	}
 }
 
+// setConfigErrors replaces the configuration errors reported by the given provider
+func (server *Server) setConfigErrors(providerName string, configErrors []types.ConfigError) {
+	currentErrors := server.configErrors.Get().(providerErrors)
+	newErrors := make(providerErrors, len(currentErrors)+1)
+	for name, errs := range currentErrors {
+		newErrors[name] = errs
+	}
+	if len(configErrors) > 0 {
+		for _, configError := range configErrors {
+			log.Warnf("Configuration error from provider %s on %s: %s", providerName, configError.Source, configError.Message)
+		}
+		newErrors[providerName] = configErrors
+	} else {
+		delete(newErrors, providerName)
+	}
+	server.configErrors.Set(newErrors)
+}
+
 func (server *Server) defaultConfigurationValues(configuration *types.Configuration) {
 	if configuration == nil || configuration.Frontends == nil {
 		return
@@ -295,6 +343,7 @@ func (server *Server) listenConfigurations(stop chan bool) {
 			if err == nil {
 				for newServerEntryPointName, newServerEntryPoint := range newServerEntryPoints {
 					server.serverEntryPoints[newServerEntryPointName].httpRouter.UpdateHandler(newServerEntryPoint.httpRouter.GetHandler())
+					server.serverEntryPoints[newServerEntryPointName].tcpRoutes.Set(newServerEntryPoint.tcpRoutes.Get())
 					log.Infof("Server configuration reloaded on %s", server.serverEntryPoints[newServerEntryPointName].httpServer.Addr)
 				}
 				server.currentConfigurations.Set(newConfigurations)
@@ -501,11 +550,12 @@ func (server *Server) createTLSConfig(entryPointName string, tlsOption *TLS, rou
 	return config, nil
 }
 
-func (server *Server) startServer(srv *http.Server, globalConfiguration GlobalConfiguration) {
+func (server *Server) startServer(serverEntryPoint *serverEntryPoint, globalConfiguration GlobalConfiguration) {
+	srv := serverEntryPoint.httpServer
 	log.Infof("Starting server on %s", srv.Addr)
 	var err error
 	if srv.TLSConfig != nil {
-		err = srv.ListenAndServeTLS("", "")
+		err = serveTLS(srv, serverEntryPoint.tcpRoutes, globalConfiguration.InsecureSkipVerify)
 	} else {
 		err = srv.ListenAndServe()
 	}
@@ -542,6 +592,7 @@ func (server *Server) buildEntryPoints(globalConfiguration GlobalConfiguration)
 		router := server.buildDefaultHTTPRouter()
 		serverEntryPoints[entryPointName] = &serverEntryPoint{
 			httpRouter: middlewares.NewHandlerSwitcher(router),
+			tcpRoutes:  safe.New(tcpRoutes{}),
 		}
 	}
 	return serverEntryPoints
@@ -554,6 +605,7 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 	redirectHandlers := make(map[string]http.Handler)
 
 	backends := map[string]http.Handler{}
+	backendTransports := map[string]*backendTransport{}
 
 	backendsHealthcheck := map[string]*healthcheck.BackendHealthCheck{}
 
@@ -565,7 +617,13 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 
 			log.Debugf("Creating frontend %s", frontendName)
 
-			fwd, err := forward.New(forward.Logger(oxyLogger), forward.PassHostHeader(frontend.PassHostHeader))
+			transport, err := server.getBackendTransport(backendTransports, frontend.Backend, configuration.Backends[frontend.Backend])
+			if err != nil {
+				log.Errorf("Error creating transport of backend %s for frontend %s: %v", frontend.Backend, frontendName, err)
+				log.Errorf("Skipping frontend %s...", frontendName)
+				continue frontend
+			}
+			fwd, err := forward.New(forward.Logger(oxyLogger), forward.PassHostHeader(frontend.PassHostHeader), forward.RoundTripper(transport.transport))
 			if err != nil {
 				log.Errorf("Error creating forwarder for frontend %s: %v", frontendName, err)
 				log.Errorf("Skipping frontend %s...", frontendName)
@@ -585,7 +643,99 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 					continue frontend
 				}
 
-				newServerRoute := &serverRoute{route: serverEntryPoints[entryPointName].httpRouter.GetHandler().NewRoute().Name(frontendName)}
+				if backend := configuration.Backends[frontend.Backend]; isTCPPassthrough(backend) {
+					if globalConfiguration.EntryPoints[entryPointName].TLS == nil {
+						log.Errorf("TCP passthrough backend %s needs a TLS entrypoint, %s is not", frontend.Backend, entryPointName)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					if err := addTCPRoutes(serverEntryPoints[entryPointName].tcpRoutes.Get().(tcpRoutes), frontend, backend); err != nil {
+						log.Errorf("Error creating TCP passthrough routes for frontend %s: %v", frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					log.Debugf("Wiring frontend %s to TCP passthrough backend %s", frontendName, frontend.Backend)
+					continue
+				}
+
+				newServerRoute := &serverRoute{
+					route:           serverEntryPoints[entryPointName].httpRouter.GetHandler().NewRoute().Name(frontendName),
+					forwardCaptures: frontend.ForwardCaptures,
+					maxBodyBuffer:   frontend.MaxBodyBuffer,
+					seed:            frontend.Seed,
+					requestIDHeader: frontend.RequestIDHeader,
+					trustedIPs:      frontend.TrustedIPs,
+					caseInsensitive: frontend.CaseInsensitive,
+					headers:         frontend.Headers,
+				}
+				if len(frontend.WhitelistSourceRange) > 0 {
+					sourceRange, err := middlewares.ParseSourceRange(frontend.WhitelistSourceRange)
+					if err != nil {
+						log.Errorf("Error creating IP whitelist for frontend %s: %v", frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					newServerRoute.ipWhitelist = sourceRange
+				}
+				if frontend.RateLimit != nil {
+					extractor, rates, err := parseRateLimit(frontend.RateLimit)
+					if err != nil {
+						log.Errorf("Error creating rate limit for frontend %s: %v", frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					newServerRoute.rateLimitSource, newServerRoute.rateLimitRates = extractor, rates
+				}
+				if len(frontend.BasicAuth) > 0 {
+					users := types.Users{}
+					for _, user := range frontend.BasicAuth {
+						users = append(users, user)
+					}
+
+					auth := &types.Auth{}
+					auth.Basic = &types.Basic{
+						Users: users,
+					}
+					authMiddleware, err := middlewares.NewAuthenticator(auth)
+					if err != nil {
+						log.Fatal("Error creating Auth: ", err)
+					}
+					newServerRoute.handlers = append(newServerRoute.handlers, authMiddleware)
+				}
+
+				if len(frontend.DigestAuth) > 0 {
+					users := types.Users{}
+					for _, user := range frontend.DigestAuth {
+						users = append(users, user)
+					}
+
+					auth := &types.Auth{}
+					auth.Digest = &types.Digest{
+						Users: users,
+					}
+					authMiddleware, err := middlewares.NewAuthenticator(auth)
+					if err != nil {
+						log.Errorf("Error creating digest auth for frontend %s: %v", frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					newServerRoute.handlers = append(newServerRoute.handlers, authMiddleware)
+				}
+
+				if frontend.ForwardAuth != nil {
+					forwardAuth, err := middlewares.NewForwardAuth(frontend.ForwardAuth)
+					if err != nil {
+						log.Errorf("Error creating forward auth for frontend %s: %v", frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					newServerRoute.handlers = append(newServerRoute.handlers, forwardAuth)
+				}
+
+				if frontend.PassTLSCert || frontend.PassTLSCertPEM {
+					newServerRoute.handlers = append(newServerRoute.handlers, middlewares.NewTLSClientHeaders(frontend.PassTLSCert, frontend.PassTLSCertPEM))
+				}
+
 				for routeName, route := range frontend.Routes {
 					err := getRoute(newServerRoute, &route)
 					if err != nil {
@@ -609,10 +759,18 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 						newServerRoute.route.Handler(saveFrontend)
 						redirectHandlers[entryPointName] = saveFrontend
 					}
+				} else if len(frontend.Redirect) > 0 && frontend.Redirect != entryPointName {
+					handler, err := server.buildRedirectHandler(frontend.Redirect)
+					if err != nil {
+						log.Errorf("Error creating redirect to entrypoint %s for frontend %s: %v", frontend.Redirect, frontendName, err)
+						log.Errorf("Skipping frontend %s...", frontendName)
+						continue frontend
+					}
+					newServerRoute.route.Handler(accesslog.NewSaveFrontend(handler, frontendName))
 				} else {
 					if backends[frontend.Backend] == nil {
 						log.Debugf("Creating backend %s", frontend.Backend)
-						saveBackend := accesslog.NewSaveBackend(fwd, frontend.Backend)
+						saveBackend := accesslog.NewSaveBackend(newWebsocketHandler(fwd, configuration.Backends[frontend.Backend], transport.tlsConfig, frontend.PassHostHeader), frontend.Backend)
 						saveFrontend := accesslog.NewSaveFrontend(saveBackend, frontendName)
 						rr, _ := roundrobin.New(saveFrontend)
 						if configuration.Backends[frontend.Backend] == nil {
@@ -630,6 +788,9 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 
 						stickysession := configuration.Backends[frontend.Backend].LoadBalancer.Sticky
 						cookiename := "_TRAEFIK_BACKEND"
+						if len(configuration.Backends[frontend.Backend].LoadBalancer.CookieName) > 0 {
+							cookiename = configuration.Backends[frontend.Backend].LoadBalancer.CookieName
+						}
 						var sticky *roundrobin.StickySession
 
 						if stickysession {
@@ -637,6 +798,7 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 						}
 
 						var lb http.Handler
+						var fallbackURLs []*url.URL
 						switch lbMethod {
 						case types.Drr:
 							log.Debugf("Creating load-balancer drr")
@@ -653,17 +815,25 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 									log.Errorf("Skipping frontend %s...", frontendName)
 									continue frontend
 								}
+								if server.Fallback {
+									log.Debugf("Creating fallback server %s at %s", serverName, url.String())
+									fallbackURLs = append(fallbackURLs, url)
+									continue
+								}
 								log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
 								if err := rebalancer.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
 									log.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
 									log.Errorf("Skipping frontend %s...", frontendName)
 									continue frontend
 								}
-								hcOpts := parseHealthCheckOptions(rebalancer, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, *globalConfiguration.HealthCheck)
-								if hcOpts != nil {
-									log.Debugf("Setting up backend health check %s", *hcOpts)
-									backendsHealthcheck[frontend.Backend] = healthcheck.NewBackendHealthCheck(*hcOpts)
-								}
+							}
+							hcOpts := parseHealthCheckOptions(rebalancer, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, *globalConfiguration.HealthCheck)
+							if hcOpts != nil {
+								hcOpts.FallbackURLs = fallbackURLs
+								log.Debugf("Setting up backend health check %s", *hcOpts)
+								backendsHealthcheck[frontend.Backend] = healthcheck.NewBackendHealthCheck(*hcOpts)
+							} else if len(fallbackURLs) > 0 && len(configuration.Backends[frontend.Backend].FallbackStatusCodes) == 0 {
+								log.Warnf("Fallback servers of backend %s are never used without a health check", frontend.Backend)
 							}
 						case types.Wrr:
 							log.Debugf("Creating load-balancer wrr")
@@ -679,6 +849,11 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 									log.Errorf("Skipping frontend %s...", frontendName)
 									continue frontend
 								}
+								if server.Fallback {
+									log.Debugf("Creating fallback server %s at %s", serverName, url.String())
+									fallbackURLs = append(fallbackURLs, url)
+									continue
+								}
 								log.Debugf("Creating server %s at %s with weight %d", serverName, url.String(), server.Weight)
 								if err := rr.UpsertServer(url, roundrobin.Weight(server.Weight)); err != nil {
 									log.Errorf("Error adding server %s to load balancer: %v", server.URL, err)
@@ -688,8 +863,11 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 							}
 							hcOpts := parseHealthCheckOptions(rr, frontend.Backend, configuration.Backends[frontend.Backend].HealthCheck, *globalConfiguration.HealthCheck)
 							if hcOpts != nil {
+								hcOpts.FallbackURLs = fallbackURLs
 								log.Debugf("Setting up backend health check %s", *hcOpts)
 								backendsHealthcheck[frontend.Backend] = healthcheck.NewBackendHealthCheck(*hcOpts)
+							} else if len(fallbackURLs) > 0 && len(configuration.Backends[frontend.Backend].FallbackStatusCodes) == 0 {
+								log.Warnf("Fallback servers of backend %s are never used without a health check", frontend.Backend)
 							}
 						}
 						maxConns := configuration.Backends[frontend.Backend].MaxConn
@@ -708,6 +886,34 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 								continue frontend
 							}
 						}
+						if statusCodes := configuration.Backends[frontend.Backend].FallbackStatusCodes; len(statusCodes) > 0 {
+							if len(fallbackURLs) == 0 {
+								log.Warnf("Backend %s has fallback status codes but no fallback server", frontend.Backend)
+							} else {
+								log.Debugf("Creating fallback on status codes %v", statusCodes)
+								lb = middlewares.NewStatusFallback(lb, saveFrontend, fallbackURLs, statusCodes)
+							}
+						}
+						if responseForwarding := configuration.Backends[frontend.Backend].ResponseForwarding; responseForwarding != nil && len(responseForwarding.FlushInterval) > 0 {
+							flushInterval, err := time.ParseDuration(responseForwarding.FlushInterval)
+							if err != nil {
+								log.Errorf("Error parsing flush interval %s of backend %s: %v", responseForwarding.FlushInterval, frontend.Backend, err)
+								log.Errorf("Skipping frontend %s...", frontendName)
+								continue frontend
+							}
+							log.Debugf("Creating response flushes every %s", flushInterval)
+							lb = middlewares.NewFlushInterval(lb, flushInterval)
+						}
+						if buffering := configuration.Backends[frontend.Backend].Buffering; buffering != nil {
+							bufferedLb, err := middlewares.NewBuffering(lb, buffering)
+							if err != nil {
+								log.Errorf("Error creating buffering for backend %s: %v", frontend.Backend, err)
+								log.Errorf("Skipping frontend %s...", frontendName)
+								continue frontend
+							}
+							log.Debugf("Creating buffering %+v", *buffering)
+							lb = bufferedLb
+						}
 						// retry ?
 						if globalConfiguration.Retry != nil {
 							retries := len(configuration.Backends[frontend.Backend].Servers)
@@ -726,26 +932,11 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 							}
 						}
 
-						if len(frontend.BasicAuth) > 0 {
-							users := types.Users{}
-							for _, user := range frontend.BasicAuth {
-								users = append(users, user)
-							}
-
-							auth := &types.Auth{}
-							auth.Basic = &types.Basic{
-								Users: users,
-							}
-							authMiddleware, err := middlewares.NewAuthenticator(auth)
-							if err != nil {
-								log.Fatal("Error creating Auth: ", err)
-							}
-							negroni.Use(authMiddleware)
-						}
-
-						if configuration.Backends[frontend.Backend].CircuitBreaker != nil {
-							log.Debugf("Creating circuit breaker %s", configuration.Backends[frontend.Backend].CircuitBreaker.Expression)
-							cbreaker, err := middlewares.NewCircuitBreaker(lb, configuration.Backends[frontend.Backend].CircuitBreaker.Expression, cbreaker.Logger(oxyLogger))
+						if circuitBreaker := configuration.Backends[frontend.Backend].CircuitBreaker; circuitBreaker != nil {
+							// the trailers rules of the frontends are checked per request
+							lb = middlewares.NewTrailerCheck(lb)
+							log.Debugf("Creating circuit breaker %s", circuitBreaker.Expression)
+							cbreaker, err := middlewares.NewBackendCircuitBreaker(lb, circuitBreaker, cbreaker.Logger(oxyLogger))
 							if err != nil {
 								log.Errorf("Error creating circuit breaker: %v", err)
 								log.Errorf("Skipping frontend %s...", frontendName)
@@ -759,6 +950,13 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 					} else {
 						log.Debugf("Reusing backend %s", frontend.Backend)
 					}
+					if newServerRoute.trailerCondition != nil {
+						if configuration.Backends[frontend.Backend].CircuitBreaker == nil {
+							log.Warnf("Trailers rule of frontend %s has no effect without a circuit breaker on backend %s", frontendName, frontend.Backend)
+						} else {
+							log.Debugf("Checking response trailer %s of frontend %s", newServerRoute.trailerCondition.Name, frontendName)
+						}
+					}
 					if frontend.Priority > 0 {
 						newServerRoute.route.Priority(frontend.Priority)
 					}
@@ -772,6 +970,7 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 		}
 	}
 	healthcheck.GetHealthCheck().SetBackendsConfiguration(server.routinesPool.Ctx(), backendsHealthcheck)
+	server.setBackendTransports(backendTransports)
 	//sort routes
 	for _, serverEntryPoint := range serverEntryPoints {
 		serverEntryPoint.httpRouter.GetHandler().SortRoutes()
@@ -780,6 +979,21 @@ func (server *Server) loadConfig(configurations configs, globalConfiguration Glo
 }
 
 func (server *Server) wireFrontendBackend(serverRoute *serverRoute, handler http.Handler) {
+	// authentication and client certificate headers of the frontend, not shared with the other frontends of the backend
+	if len(serverRoute.handlers) > 0 {
+		negroni := negroni.New(serverRoute.handlers...)
+		negroni.UseHandler(handler)
+		handler = negroni
+	}
+
+	// response trailer checked by the circuit breaker of the backend
+	if serverRoute.trailerCondition != nil {
+		handler = &middlewares.TrailerRule{
+			Handler:   handler,
+			Condition: *serverRoute.trailerCondition,
+		}
+	}
+
 	// add prefix
 	if len(serverRoute.addPrefix) > 0 {
 		handler = &middlewares.AddPrefix{
@@ -809,9 +1023,88 @@ func (server *Server) wireFrontendBackend(serverRoute *serverRoute, handler http
 		}
 	}
 
+	// lowercase the path of case insensitive routes, before it is stripped
+	if serverRoute.caseInsensitive {
+		handler = &middlewares.LowercasePath{
+			Handler: handler,
+		}
+	}
+
+	// rate limit
+	if serverRoute.rateLimit != nil {
+		handler = middlewares.NewRateLimit(handler, *serverRoute.rateLimit)
+	}
+
+	// rate limit per source
+	if serverRoute.rateLimitSource != nil {
+		handler = middlewares.NewSourceRateLimit(handler, serverRoute.rateLimitSource, serverRoute.rateLimitRates)
+	}
+
+	// forward captured route variables as headers
+	if serverRoute.forwardCaptures {
+		handler = &middlewares.ForwardCaptures{
+			Handler: handler,
+		}
+	}
+
+	// set a request ID header
+	if len(serverRoute.requestIDHeader) > 0 {
+		handler = &middlewares.RequestID{
+			Handler: handler,
+			Header:  serverRoute.requestIDHeader,
+		}
+	}
+
+	// custom and security headers
+	if serverRoute.headers != nil {
+		handler = &middlewares.Headers{
+			Handler: handler,
+			Headers: serverRoute.headers,
+		}
+	}
+
+	// reject the clients out of the whitelist source range first
+	if len(serverRoute.ipWhitelist) > 0 {
+		handler = &middlewares.IPWhitelist{
+			Handler:     handler,
+			SourceRange: serverRoute.ipWhitelist,
+		}
+	}
+
 	serverRoute.route.Handler(handler)
 }
 
+// parseRateLimit returns the source extractor and the rates of a frontend rate limit,
+// ordered by rate set name
+func parseRateLimit(rateLimit *types.RateLimit) (utils.SourceExtractor, []middlewares.Rate, error) {
+	extractor, err := utils.NewExtractor(rateLimit.ExtractorFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rateLimit.RateSet) == 0 {
+		return nil, nil, errors.New("No rate set")
+	}
+	var names []string
+	for name := range rateLimit.RateSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var rates []middlewares.Rate
+	for _, name := range names {
+		rate := rateLimit.RateSet[name]
+		period, err := time.ParseDuration(rate.Period)
+		if err != nil || period <= 0 {
+			return nil, nil, errors.New("Invalid period '" + rate.Period + "' of rate set " + name)
+		}
+		if rate.Average <= 0 {
+			return nil, nil, errors.New("Invalid average " + strconv.FormatInt(rate.Average, 10) + " of rate set " + name)
+		}
+		rates = append(rates, middlewares.Rate{Average: rate.Average, Period: period})
+	}
+	return extractor, rates, nil
+}
+
 func (server *Server) loadEntryPointConfig(entryPointName string, entryPoint *EntryPoint) (http.Handler, error) {
 	regex := entryPoint.Redirect.Regex
 	replacement := entryPoint.Redirect.Replacement
@@ -841,6 +1134,23 @@ func (server *Server) loadEntryPointConfig(entryPointName string, entryPoint *En
 	return negroni, nil
 }
 
+func (server *Server) buildRedirectHandler(entryPointName string) (http.Handler, error) {
+	entryPoint := server.globalConfiguration.EntryPoints[entryPointName]
+	if entryPoint == nil {
+		return nil, errors.New("Unknown entrypoint " + entryPointName)
+	}
+	protocol := "http"
+	if entryPoint.TLS != nil {
+		protocol = "https"
+	}
+	redirect, err := middlewares.NewRedirect(protocol, entryPoint.Address)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Creating frontend redirect to entryPoint %s", entryPointName)
+	return redirect, nil
+}
+
 func (server *Server) buildDefaultHTTPRouter() *mux.Router {
 	router := mux.NewRouter()
 	router.NotFoundHandler = http.HandlerFunc(notFoundHandler)
@@ -867,10 +1177,206 @@ func parseHealthCheckOptions(lb healthcheck.LoadBalancer, backend string, hc *ty
 		}
 	}
 
+	failureAction := healthcheck.FailureActionRemove
+	switch hc.FailureAction {
+	case "", healthcheck.FailureActionRemove:
+	case healthcheck.FailureActionAlert:
+		failureAction = hc.FailureAction
+	default:
+		log.Errorf("Illegal healthcheck failure action for backend '%s': %s", backend, hc.FailureAction)
+	}
+
 	return &healthcheck.Options{
-		Path:     hc.Path,
-		Interval: interval,
-		LB:       lb,
+		Path:          hc.Path,
+		Interval:      interval,
+		LB:            lb,
+		FailureAction: failureAction,
+	}
+}
+
+// getBackendTransport returns the transport of the given backend, reusing the transport of the
+// previous configuration if the backend is unchanged
+func (server *Server) getBackendTransport(backendTransports map[string]*backendTransport, backendName string, backend *types.Backend) (*backendTransport, error) {
+	if current, ok := backendTransports[backendName]; ok && reflect.DeepEqual(current.backend, backend) {
+		return current, nil
+	}
+	current, ok := server.backendTransports[backendName]
+	if !ok || !reflect.DeepEqual(current.backend, backend) {
+		var tlsConfig *tls.Config
+		if backend != nil {
+			var err error
+			if tlsConfig, err = backend.TLS.CreateTLSConfig(); err != nil {
+				return nil, fmt.Errorf("invalid TLS configuration: %v", err)
+			}
+		}
+		current = &backendTransport{backend: backend, transport: createHTTPTransport(backend, tlsConfig), tlsConfig: tlsConfig}
+	}
+	backendTransports[backendName] = current
+	return current, nil
+}
+
+// setBackendTransports replaces the transports of the backends, closing the idle connections
+// of the transports no longer used
+func (server *Server) setBackendTransports(backendTransports map[string]*backendTransport) {
+	for backendName, previous := range server.backendTransports {
+		if current, ok := backendTransports[backendName]; !ok || current != previous {
+			closeIdleConnections(previous.transport)
+		}
+	}
+	server.backendTransports = backendTransports
+}
+
+type idleConnectionsCloser interface {
+	CloseIdleConnections()
+}
+
+// closeIdleConnections closes the idle connections of the given transport, unless it is the default transport
+func closeIdleConnections(transport http.RoundTripper) {
+	if transport == http.DefaultTransport {
+		return
+	}
+	if closer, ok := transport.(idleConnectionsCloser); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// createHTTPTransport returns the transport used to forward requests to the given backend,
+// with the TLS configuration of the backend if any.
+// Backends without specific transport settings share the default transport.
+func createHTTPTransport(backend *types.Backend, tlsConfig *tls.Config) http.RoundTripper {
+	if backend == nil {
+		return http.DefaultTransport
+	}
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	dialContext := dialer.DialContext
+	var resolver hostResolver = net.DefaultResolver
+	if backend.DNSResolver != "" {
+		resolver = newDNSResolver(backend.DNSResolver, dialer.DialContext)
+	}
+	if backend.DNSRetryCount > 0 || backend.DNSResolver != "" {
+		dialContext = retryDNSDialContext(dialContext, resolver, backend.DNSRetryCount, parseDNSRetryDelay(backend))
+	}
+	readHeaderTimeout := parseBackendTimeout(backend.ReadHeaderTimeout, "read header timeout")
+	transport := http.DefaultTransport
+	if backend.DisableKeepAlives || backend.DNSRetryCount > 0 || backend.DNSResolver != "" || readHeaderTimeout > 0 || tlsConfig != nil {
+		if tlsConfig == nil {
+			// keep the global TLS settings, e.g. insecureSkipVerify, of the default transport
+			if defaultTransport, ok := http.DefaultTransport.(*http.Transport); ok && defaultTransport.TLSClientConfig != nil {
+				tlsConfig = defaultTransport.TLSClientConfig.Clone()
+			}
+		}
+		transport = &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			ResponseHeaderTimeout: readHeaderTimeout,
+			DisableKeepAlives:     backend.DisableKeepAlives,
+			TLSClientConfig:       tlsConfig,
+		}
+	}
+	if backend.H2Options != nil {
+		transport = newH2Transport(transport, dialContext, tlsConfig, backend.H2Options)
+	}
+	if bodyTimeout := parseBackendTimeout(backend.ResponseBodyTimeout, "response body timeout"); bodyTimeout > 0 {
+		transport = &bodyTimeoutTransport{next: transport, timeout: bodyTimeout}
+	}
+	var fcgi, grpc, nats bool
+	for _, server := range backend.Servers {
+		switch {
+		case strings.HasPrefix(server.URL, fcgiScheme+"://"):
+			fcgi = true
+		case strings.HasPrefix(server.URL, natsScheme+"://"):
+			nats = true
+		case strings.HasPrefix(server.URL, grpcScheme+"://"), strings.HasPrefix(server.URL, grpcsScheme+"://"):
+			grpc = true
+		}
+	}
+	if grpc {
+		transport = newGRPCTransport(transport, dialContext)
+	}
+	if fcgi {
+		transport = &fcgiTransport{next: transport, dial: dialContext}
+	}
+	if nats {
+		transport = &natsTransport{next: transport, dial: dialContext, timeout: parseBackendTimeout(backend.ResponseTimeout, "response timeout")}
+	}
+	return transport
+}
+
+const defaultDNSRetryDelay = time.Second
+
+func parseDNSRetryDelay(backend *types.Backend) time.Duration {
+	if backend.DNSRetryDelay == "" {
+		return defaultDNSRetryDelay
+	}
+	delay, err := time.ParseDuration(backend.DNSRetryDelay)
+	switch {
+	case err != nil:
+		log.Errorf("Illegal DNS retry delay %s: %s", backend.DNSRetryDelay, err)
+	case delay < 0:
+		log.Errorf("DNS retry delay smaller than zero: %s", backend.DNSRetryDelay)
+	default:
+		return delay
+	}
+	return defaultDNSRetryDelay
+}
+
+type dialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+type hostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// newDNSResolver returns a resolver sending its queries to the given DNS server, port 53 by default
+func newDNSResolver(server string, dial dialContextFunc) *net.Resolver {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dial(ctx, network, server)
+		},
+	}
+}
+
+// retryDNSDialContext wraps dial so that the host of the dialed address is resolved beforehand,
+// retrying the resolution up to retryCount times with retryDelay between attempts.
+func retryDNSDialContext(dial dialContextFunc, resolver hostResolver, retryCount int, retryDelay time.Duration) dialContextFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) != nil {
+			return dial(ctx, network, address)
+		}
+		var addrs []string
+		for attempt := 0; ; attempt++ {
+			addrs, err = resolver.LookupHost(ctx, host)
+			if err == nil && len(addrs) == 0 {
+				err = errors.New("no address found for host " + host)
+			}
+			if err == nil {
+				break
+			}
+			if attempt >= retryCount {
+				return nil, err
+			}
+			log.Debugf("Failed to resolve %s, retrying in %s: %v", host, retryDelay, err)
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		return dial(ctx, network, net.JoinHostPort(addrs[0], port))
 	}
 }
 
@@ -1,14 +1,22 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/containous/flaeg"
+	"github.com/containous/mux"
 	"github.com/containous/traefik/healthcheck"
+	"github.com/containous/traefik/middlewares"
+	"github.com/containous/traefik/middlewares/accesslog"
 	"github.com/containous/traefik/types"
 	"github.com/vulcand/oxy/roundrobin"
 )
@@ -115,9 +123,10 @@ func TestServerParseHealthCheckOptions(t *testing.T) {
 				Interval: "unparseable",
 			},
 			wantOpts: &healthcheck.Options{
-				Path:     "/path",
-				Interval: globalInterval,
-				LB:       lb,
+				Path:          "/path",
+				Interval:      globalInterval,
+				LB:            lb,
+				FailureAction: healthcheck.FailureActionRemove,
 			},
 		},
 		{
@@ -127,9 +136,10 @@ func TestServerParseHealthCheckOptions(t *testing.T) {
 				Interval: "-42s",
 			},
 			wantOpts: &healthcheck.Options{
-				Path:     "/path",
-				Interval: globalInterval,
-				LB:       lb,
+				Path:          "/path",
+				Interval:      globalInterval,
+				LB:            lb,
+				FailureAction: healthcheck.FailureActionRemove,
 			},
 		},
 		{
@@ -139,9 +149,36 @@ func TestServerParseHealthCheckOptions(t *testing.T) {
 				Interval: "5m",
 			},
 			wantOpts: &healthcheck.Options{
-				Path:     "/path",
-				Interval: 5 * time.Minute,
-				LB:       lb,
+				Path:          "/path",
+				Interval:      5 * time.Minute,
+				LB:            lb,
+				FailureAction: healthcheck.FailureActionRemove,
+			},
+		},
+		{
+			desc: "alert failure action",
+			hc: &types.HealthCheck{
+				Path:          "/path",
+				FailureAction: "alert",
+			},
+			wantOpts: &healthcheck.Options{
+				Path:          "/path",
+				Interval:      globalInterval,
+				LB:            lb,
+				FailureAction: healthcheck.FailureActionAlert,
+			},
+		},
+		{
+			desc: "unknown failure action",
+			hc: &types.HealthCheck{
+				Path:          "/path",
+				FailureAction: "restart",
+			},
+			wantOpts: &healthcheck.Options{
+				Path:          "/path",
+				Interval:      globalInterval,
+				LB:            lb,
+				FailureAction: healthcheck.FailureActionRemove,
 			},
 		},
 	}
@@ -158,3 +195,506 @@ func TestServerParseHealthCheckOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestServerCreateHTTPTransport(t *testing.T) {
+	config := &types.Configuration{
+		Backends: map[string]*types.Backend{
+			"keepalive": {},
+			"nokeepalive": {
+				DisableKeepAlives: true,
+			},
+		},
+	}
+
+	keepAliveTransport := createHTTPTransport(config.Backends["keepalive"], nil)
+	noKeepAliveTransport := createHTTPTransport(config.Backends["nokeepalive"], nil)
+
+	if keepAliveTransport == noKeepAliveTransport {
+		t.Fatal("expected distinct transports for backends with different keep-alive settings")
+	}
+	if keepAliveTransport != http.DefaultTransport {
+		t.Errorf("got transport %+v, want default transport", keepAliveTransport)
+	}
+	transport, ok := noKeepAliveTransport.(*http.Transport)
+	if !ok {
+		t.Fatalf("got transport of type %T, want *http.Transport", noKeepAliveTransport)
+	}
+	if !transport.DisableKeepAlives {
+		t.Error("expected keep-alive to be disabled")
+	}
+	if createHTTPTransport(nil, nil) != http.DefaultTransport {
+		t.Error("expected default transport for undefined backend")
+	}
+}
+
+func TestServerCreateHTTPTransportTLS(t *testing.T) {
+	backendServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backendServer.Close()
+
+	tests := []struct {
+		desc        string
+		tls         *types.ClientTLS
+		expectedErr bool
+	}{
+		{
+			desc:        "default verification",
+			expectedErr: true,
+		},
+		{
+			desc: "insecure skip verify",
+			tls:  &types.ClientTLS{InsecureSkipVerify: true},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			tlsConfig, err := test.tls.CreateTLSConfig()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			transport := createHTTPTransport(&types.Backend{TLS: test.tls}, tlsConfig)
+			req, _ := http.NewRequest("GET", backendServer.URL, nil)
+			resp, err := transport.RoundTrip(req)
+			if test.expectedErr {
+				if err == nil {
+					t.Error("expected a certificate verification error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestServerCreateHTTPTransportGlobalTLS(t *testing.T) {
+	backendServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backendServer.Close()
+
+	defaultTransport := http.DefaultTransport.(*http.Transport)
+	defaultTLSConfig := defaultTransport.TLSClientConfig
+	defaultTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	defer func() { defaultTransport.TLSClientConfig = defaultTLSConfig }()
+
+	// the global insecureSkipVerify applies to the backends with a transport of their own
+	transport := createHTTPTransport(&types.Backend{DisableKeepAlives: true}, nil)
+	req, _ := http.NewRequest("GET", backendServer.URL, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestServerGetBackendTransport(t *testing.T) {
+	srv := &Server{}
+	backend := &types.Backend{DisableKeepAlives: true}
+
+	transports := map[string]*backendTransport{}
+	transport, err := srv.getBackendTransport(transports, "backend", backend)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if shared, _ := srv.getBackendTransport(transports, "backend", backend); shared != transport {
+		t.Error("expected the frontends of a backend to share its transport")
+	}
+	srv.setBackendTransports(transports)
+
+	transports = map[string]*backendTransport{}
+	if reused, _ := srv.getBackendTransport(transports, "backend", &types.Backend{DisableKeepAlives: true}); reused != transport {
+		t.Error("expected the transport of an unchanged backend to be reused")
+	}
+	srv.setBackendTransports(transports)
+
+	transports = map[string]*backendTransport{}
+	if changed, _ := srv.getBackendTransport(transports, "backend", &types.Backend{DisableKeepAlives: true, ReadHeaderTimeout: "1s"}); changed == transport {
+		t.Error("expected a new transport for a changed backend")
+	}
+
+	// the backends with an invalid TLS configuration are rejected
+	invalidTLS := &types.Backend{TLS: &types.ClientTLS{Cert: "/certs/cert.pem"}}
+	if _, err := srv.getBackendTransport(transports, "invalid", invalidTLS); err == nil {
+		t.Error("expected an error for a TLS cert without key")
+	}
+}
+
+func TestServerGetRouteDefaultPriority(t *testing.T) {
+	rules := []string{"Host:foo.bar", "Host:foo.bar;PathPrefix:/api"}
+	for _, rule := range rules {
+		serverRoute := &serverRoute{route: mux.NewRouter().NewRoute()}
+		if err := getRoute(serverRoute, &types.Route{Rule: rule}); err != nil {
+			t.Fatalf("%s: unexpected error %v", rule, err)
+		}
+		if priority := serverRoute.route.GetPriority(); priority != len(rule) {
+			t.Errorf("%s: got priority %d, want %d", rule, priority, len(rule))
+		}
+	}
+}
+
+type fakeResolver struct {
+	failures int
+	lookups  int
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.lookups++
+	if r.lookups <= r.failures {
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	return []string{"10.0.0.1"}, nil
+}
+
+func TestServerRetryDNSDialContext(t *testing.T) {
+	tests := []struct {
+		desc            string
+		failures        int
+		retryCount      int
+		wantErr         bool
+		wantLookups     int
+		wantDialAddress string
+	}{
+		{
+			desc:            "resolved at first attempt",
+			failures:        0,
+			retryCount:      3,
+			wantLookups:     1,
+			wantDialAddress: "10.0.0.1:8080",
+		},
+		{
+			desc:            "resolved after retries",
+			failures:        2,
+			retryCount:      3,
+			wantLookups:     3,
+			wantDialAddress: "10.0.0.1:8080",
+		},
+		{
+			desc:        "retries exhausted",
+			failures:    5,
+			retryCount:  3,
+			wantErr:     true,
+			wantLookups: 4,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			resolver := &fakeResolver{failures: test.failures}
+			var dialAddress string
+			dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+				dialAddress = address
+				return nil, nil
+			}
+
+			_, err := retryDNSDialContext(dial, resolver, test.retryCount, time.Millisecond)(context.Background(), "tcp", "backend.mesh:8080")
+			if test.wantErr != (err != nil) {
+				t.Errorf("got error %v, want error %t", err, test.wantErr)
+			}
+			if resolver.lookups != test.wantLookups {
+				t.Errorf("got %d lookups, want %d", resolver.lookups, test.wantLookups)
+			}
+			if dialAddress != test.wantDialAddress {
+				t.Errorf("got dial address %q, want %q", dialAddress, test.wantDialAddress)
+			}
+		})
+	}
+}
+
+func TestServerNewDNSResolver(t *testing.T) {
+	tests := []struct {
+		server      string
+		wantAddress string
+	}{
+		{server: "10.0.0.53:53", wantAddress: "10.0.0.53:53"},
+		{server: "10.0.0.53:5353", wantAddress: "10.0.0.53:5353"},
+		{server: "10.0.0.53", wantAddress: "10.0.0.53:53"},
+	}
+
+	for _, test := range tests {
+		var dialAddresses []string
+		dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialAddresses = append(dialAddresses, address)
+			return nil, fmt.Errorf("unreachable DNS server %s", address)
+		}
+
+		resolver := newDNSResolver(test.server, dial)
+		if _, err := resolver.LookupHost(context.Background(), "backend.internal"); err == nil {
+			t.Errorf("expected a resolution error with unreachable DNS server %s", test.server)
+		}
+		if len(dialAddresses) == 0 {
+			t.Fatalf("expected the DNS server %s to be dialed", test.server)
+		}
+		for _, address := range dialAddresses {
+			if address != test.wantAddress {
+				t.Errorf("got dial address %q, want %q", address, test.wantAddress)
+			}
+		}
+	}
+}
+
+func TestServerParseDNSRetryDelay(t *testing.T) {
+	tests := []struct {
+		delay string
+		want  time.Duration
+	}{
+		{delay: "", want: defaultDNSRetryDelay},
+		{delay: "unparseable", want: defaultDNSRetryDelay},
+		{delay: "-1s", want: defaultDNSRetryDelay},
+		{delay: "250ms", want: 250 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		if got := parseDNSRetryDelay(&types.Backend{DNSRetryDelay: test.delay}); got != test.want {
+			t.Errorf("got delay %s for %q, want %s", got, test.delay, test.want)
+		}
+	}
+}
+
+func TestServerParseRateLimit(t *testing.T) {
+	tests := []struct {
+		desc      string
+		rateLimit *types.RateLimit
+		want      []middlewares.Rate
+	}{
+		{
+			desc: "single rate set",
+			rateLimit: &types.RateLimit{
+				ExtractorFunc: "client.ip",
+				RateSet: map[string]*types.Rate{
+					"default": {Period: "10s", Average: 100},
+				},
+			},
+			want: []middlewares.Rate{{Average: 100, Period: 10 * time.Second}},
+		},
+		{
+			desc: "rate sets ordered by name",
+			rateLimit: &types.RateLimit{
+				ExtractorFunc: "request.header.X-Api-Key",
+				RateSet: map[string]*types.Rate{
+					"second": {Period: "1s", Average: 5},
+					"hour":   {Period: "1h", Average: 1000},
+				},
+			},
+			want: []middlewares.Rate{{Average: 1000, Period: time.Hour}, {Average: 5, Period: time.Second}},
+		},
+		{
+			desc:      "unknown extractor",
+			rateLimit: &types.RateLimit{ExtractorFunc: "client.port", RateSet: map[string]*types.Rate{"default": {Period: "1s", Average: 5}}},
+		},
+		{
+			desc:      "no rate set",
+			rateLimit: &types.RateLimit{ExtractorFunc: "client.ip"},
+		},
+		{
+			desc:      "zero period",
+			rateLimit: &types.RateLimit{ExtractorFunc: "client.ip", RateSet: map[string]*types.Rate{"default": {Period: "0s", Average: 5}}},
+		},
+		{
+			desc:      "missing average",
+			rateLimit: &types.RateLimit{ExtractorFunc: "client.ip", RateSet: map[string]*types.Rate{"default": {Period: "1s"}}},
+		},
+	}
+
+	for _, test := range tests {
+		extractor, rates, err := parseRateLimit(test.rateLimit)
+		if test.want == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.desc)
+			}
+			continue
+		}
+		if err != nil || extractor == nil {
+			t.Errorf("%s: unexpected error %v", test.desc, err)
+			continue
+		}
+		if !reflect.DeepEqual(rates, test.want) {
+			t.Errorf("%s: got rates %+v, want %+v", test.desc, rates, test.want)
+		}
+	}
+}
+
+func TestServerSetConfigErrors(t *testing.T) {
+	server := NewServer(GlobalConfiguration{})
+	configErrors := []types.ConfigError{{Source: "foo", Message: "no IP address found"}}
+
+	server.setConfigErrors("docker", configErrors)
+	if got := server.configErrors.Get().(providerErrors)["docker"]; !reflect.DeepEqual(got, configErrors) {
+		t.Errorf("got errors %+v, want %+v", got, configErrors)
+	}
+
+	server.setConfigErrors("docker", nil)
+	if got, ok := server.configErrors.Get().(providerErrors)["docker"]; ok {
+		t.Errorf("got errors %+v, want none", got)
+	}
+}
+
+func TestServerLoadConfigFrontendAuth(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer backendServer.Close()
+
+	authServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusForbidden)
+	}))
+	defer authServer.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{},
+		},
+		HealthCheck: &HealthCheckConfig{Interval: flaeg.Duration(5 * time.Second)},
+	}
+
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend-forward": {
+					EntryPoints: []string{"http"},
+					Backend:     "backend",
+					ForwardAuth: &types.ForwardAuth{Address: authServer.URL},
+					Routes: map[string]types.Route{
+						"route": {Rule: "Host:forward.localhost"},
+					},
+				},
+				"frontend-basic": {
+					EntryPoints: []string{"http"},
+					Backend:     "backend",
+					BasicAuth:   []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"},
+					Routes: map[string]types.Route{
+						"route": {Rule: "Host:basic.localhost"},
+					},
+				},
+				"frontend-digest": {
+					EntryPoints: []string{"http"},
+					Backend:     "backend",
+					DigestAuth:  []string{"test:traefik:a2688e031edb4be6a3797f3882655c05"},
+					Routes: map[string]types.Route{
+						"route": {Rule: "Host:digest.localhost"},
+					},
+				},
+				"frontend-public": {
+					EntryPoints: []string{"http"},
+					Backend:     "backend",
+					Routes: map[string]types.Route{
+						"route": {Rule: "Host:public.localhost"},
+					},
+				},
+			},
+			Backends: map[string]*types.Backend{
+				"backend": {
+					Servers: map[string]types.Server{
+						"server": {
+							URL: backendServer.URL,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method: "Wrr",
+					},
+				},
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	tests := []struct {
+		host         string
+		expectedCode int
+	}{
+		{host: "basic.localhost", expectedCode: http.StatusUnauthorized},
+		{host: "digest.localhost", expectedCode: http.StatusUnauthorized},
+		{host: "forward.localhost", expectedCode: http.StatusForbidden},
+		{host: "public.localhost", expectedCode: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://"+test.host+"/", nil)
+		recorder := httptest.NewRecorder()
+		accesslog.NewLogHandler().ServeHTTP(recorder, req, entryPoints["http"].httpRouter.ServeHTTP)
+		if recorder.Code != test.expectedCode {
+			t.Errorf("got code %d for host %s, want %d", recorder.Code, test.host, test.expectedCode)
+		}
+	}
+}
+
+func TestServerLoadConfigFrontendTLSClientHeaders(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("X-Seen-Cert", r.Header.Get(middlewares.TLSClientCertHeader))
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer backendServer.Close()
+
+	globalConfig := GlobalConfiguration{
+		EntryPoints: EntryPoints{
+			"http": &EntryPoint{},
+		},
+		HealthCheck: &HealthCheckConfig{Interval: flaeg.Duration(5 * time.Second)},
+	}
+
+	dynamicConfigs := configs{
+		"config": &types.Configuration{
+			Frontends: map[string]*types.Frontend{
+				"frontend-a": {
+					EntryPoints: []string{"http"},
+					Backend:     "backend",
+					Routes: map[string]types.Route{
+						"route": {Rule: "Host:a.localhost"},
+					},
+				},
+				"frontend-b": {
+					EntryPoints:    []string{"http"},
+					Backend:        "backend",
+					PassTLSCertPEM: true,
+					Routes: map[string]types.Route{
+						"route": {Rule: "Host:b.localhost"},
+					},
+				},
+			},
+			Backends: map[string]*types.Backend{
+				"backend": {
+					Servers: map[string]types.Server{
+						"server": {
+							URL: backendServer.URL,
+						},
+					},
+					LoadBalancer: &types.LoadBalancer{
+						Method: "Wrr",
+					},
+				},
+			},
+		},
+	}
+
+	srv := NewServer(globalConfig)
+	entryPoints, err := srv.loadConfig(dynamicConfigs, globalConfig)
+	if err != nil {
+		t.Fatalf("got error: %s", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://b.localhost/", nil)
+	req.Header.Set(middlewares.TLSClientCertHeader, "forged")
+	recorder := httptest.NewRecorder()
+	accesslog.NewLogHandler().ServeHTTP(recorder, req, entryPoints["http"].httpRouter.ServeHTTP)
+	if seen := recorder.Header().Get("X-Seen-Cert"); seen != "" {
+		t.Errorf("got client certificate header %q forwarded by frontend-b, want none", seen)
+	}
+}
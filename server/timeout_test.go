@@ -0,0 +1,78 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+)
+
+// slowBackend waits before sending the response headers, then streams the body in chunks
+func slowBackend(headerDelay time.Duration, chunks int, chunkDelay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		time.Sleep(headerDelay)
+		rw.WriteHeader(http.StatusOK)
+		rw.(http.Flusher).Flush()
+		for i := 0; i < chunks; i++ {
+			time.Sleep(chunkDelay)
+			rw.Write([]byte("data\n"))
+			rw.(http.Flusher).Flush()
+		}
+	}))
+}
+
+func roundTrip(t *testing.T, backend *types.Backend, url string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := createHTTPTransport(backend, nil).RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestReadHeaderTimeout(t *testing.T) {
+	server := slowBackend(200*time.Millisecond, 0, 0)
+	defer server.Close()
+
+	backend := &types.Backend{ReadHeaderTimeout: "50ms"}
+	if _, err := roundTrip(t, backend, server.URL); err == nil {
+		t.Error("expected the slow response headers to time out")
+	}
+}
+
+func TestReadHeaderTimeoutStreamingBody(t *testing.T) {
+	server := slowBackend(0, 4, 50*time.Millisecond)
+	defer server.Close()
+
+	backend := &types.Backend{ReadHeaderTimeout: "100ms"}
+	body, err := roundTrip(t, backend, server.URL)
+	if err != nil {
+		t.Fatalf("expected the streaming body to be read without timeout, got %v", err)
+	}
+	if body != "data\ndata\ndata\ndata\n" {
+		t.Errorf("got body %q", body)
+	}
+}
+
+func TestResponseBodyTimeout(t *testing.T) {
+	server := slowBackend(0, 4, 50*time.Millisecond)
+	defer server.Close()
+
+	backend := &types.Backend{ReadHeaderTimeout: "100ms", ResponseBodyTimeout: "120ms"}
+	if _, err := roundTrip(t, backend, server.URL); err == nil {
+		t.Error("expected the streaming body to time out")
+	}
+
+	backend = &types.Backend{ResponseBodyTimeout: "1s"}
+	if _, err := roundTrip(t, backend, server.URL); err != nil {
+		t.Errorf("expected the streaming body to be read before the timeout, got %v", err)
+	}
+}
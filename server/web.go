@@ -113,6 +113,7 @@ func (provider *WebProvider) Provide(configurationChan chan<- types.ConfigMessag
 			http.Error(response, fmt.Sprintf("%+v", err), http.StatusBadRequest)
 		}
 	})
+	systemRouter.Methods("GET").Path(provider.Path + "api/providers/{provider}/errors").HandlerFunc(provider.getErrorsHandler)
 	systemRouter.Methods("GET").Path(provider.Path + "api/providers/{provider}/backends").HandlerFunc(provider.getBackendsHandler)
 	systemRouter.Methods("GET").Path(provider.Path + "api/providers/{provider}/backends/{backend}").HandlerFunc(provider.getBackendHandler)
 	systemRouter.Methods("GET").Path(provider.Path + "api/providers/{provider}/backends/{backend}/servers").HandlerFunc(provider.getServersHandler)
@@ -204,6 +205,20 @@ func (provider *WebProvider) getProviderHandler(response http.ResponseWriter, re
 	}
 }
 
+func (provider *WebProvider) getErrorsHandler(response http.ResponseWriter, request *http.Request) {
+	vars := mux.Vars(request)
+	providerID := vars["provider"]
+	configErrors := provider.server.configErrors.Get().(providerErrors)
+	currentConfigurations := provider.server.currentConfigurations.Get().(configs)
+	if errs, ok := configErrors[providerID]; ok {
+		templatesRenderer.JSON(response, http.StatusOK, errs)
+	} else if _, ok := currentConfigurations[providerID]; ok {
+		templatesRenderer.JSON(response, http.StatusOK, []types.ConfigError{})
+	} else {
+		http.NotFound(response, request)
+	}
+}
+
 func (provider *WebProvider) getBackendsHandler(response http.ResponseWriter, request *http.Request) {
 	vars := mux.Vars(request)
 	providerID := vars["provider"]
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bufio"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/containous/traefik/log"
+	"github.com/containous/traefik/types"
+	"github.com/vulcand/oxy/utils"
+)
+
+// websocketHandler forwards the websocket requests with a deadline on the upgrade exchange,
+// so that backends hanging during the handshake do not block the connection forever.
+// The other requests are forwarded to the next handler.
+type websocketHandler struct {
+	next           http.Handler
+	passHost       bool
+	tlsConfig      *tls.Config
+	upgradeTimeout time.Duration
+	tunnelTimeout  time.Duration
+}
+
+// newWebsocketHandler returns the next handler wrapped by a websocketHandler if the
+// backend has a websocket timeout. The wss:// servers are dialed with the TLS configuration
+// of the backend, if any.
+func newWebsocketHandler(next http.Handler, backend *types.Backend, tlsConfig *tls.Config, passHost bool) http.Handler {
+	if backend == nil || backend.WebsocketTimeout == "" {
+		return next
+	}
+	upgradeTimeout := parseBackendTimeout(backend.WebsocketTimeout, "websocket timeout")
+	if upgradeTimeout == 0 {
+		return next
+	}
+	return &websocketHandler{
+		next:           next,
+		passHost:       passHost,
+		tlsConfig:      tlsConfig,
+		upgradeTimeout: upgradeTimeout,
+		tunnelTimeout:  parseBackendTimeout(backend.ResponseTimeout, "response timeout"),
+	}
+}
+
+// parseBackendTimeout returns the parsed duration, or 0 if it is invalid
+func parseBackendTimeout(value string, name string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	timeout, err := time.ParseDuration(value)
+	switch {
+	case err != nil:
+		log.Errorf("Illegal %s %s: %s", name, value, err)
+	case timeout < 0:
+		log.Errorf("Illegal %s %s: smaller than zero", name, value)
+	default:
+		return timeout
+	}
+	return 0
+}
+
+func (h *websocketHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if !isWebsocketUpgrade(req) {
+		h.next.ServeHTTP(rw, req)
+		return
+	}
+
+	targetConn, err := h.dial(req.URL)
+	if err != nil {
+		log.Errorf("Error dialing websocket backend %s: %v", req.URL.Host, err)
+		rw.WriteHeader(statusForError(err))
+		return
+	}
+	defer targetConn.Close()
+
+	deadline := time.Now().Add(h.upgradeTimeout)
+	targetConn.SetDeadline(deadline)
+	if err := h.outRequest(req).Write(targetConn); err != nil {
+		log.Errorf("Error writing websocket upgrade request to %s: %v", req.URL.Host, err)
+		rw.WriteHeader(statusForError(err))
+		return
+	}
+	targetReader := bufio.NewReader(targetConn)
+	resp, err := http.ReadResponse(targetReader, req)
+	if err != nil {
+		log.Errorf("Error reading websocket upgrade response from %s: %v", req.URL.Host, err)
+		rw.WriteHeader(statusForError(err))
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// the backend refused the upgrade, its response is forwarded as is
+		utils.CopyHeaders(rw.Header(), resp.Header)
+		rw.WriteHeader(resp.StatusCode)
+		io.Copy(rw, resp.Body)
+		return
+	}
+
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		log.Errorf("Unable to hijack the websocket connection: %T", rw)
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	clientConn, _, err := hijacker.Hijack()
+	if err != nil {
+		log.Errorf("Unable to hijack the websocket connection: %v", err)
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer clientConn.Close()
+
+	// the upgrade response has no body, its headers are written as is
+	if _, err := io.WriteString(clientConn, "HTTP/1.1 "+resp.Status+"\r\n"); err != nil {
+		log.Errorf("Error writing websocket upgrade response: %v", err)
+		return
+	}
+	if err := writeHeaderBlock(clientConn, resp.Header); err != nil {
+		log.Errorf("Error writing websocket upgrade response: %v", err)
+		return
+	}
+
+	// the upgrade is complete, the tunnel now lasts until the response timeout if any
+	deadline = time.Time{}
+	if h.tunnelTimeout > 0 {
+		deadline = time.Now().Add(h.tunnelTimeout)
+	}
+	targetConn.SetDeadline(deadline)
+	clientConn.SetDeadline(deadline)
+
+	errc := make(chan error, 2)
+	replicate := func(dst io.Writer, src io.Reader) {
+		_, err := io.Copy(dst, src)
+		errc <- err
+	}
+	go replicate(targetConn, clientConn)
+	go replicate(clientConn, targetReader)
+	<-errc
+}
+
+func (h *websocketHandler) dial(target *url.URL) (net.Conn, error) {
+	host := target.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		if target.Scheme == "wss" || target.Scheme == "https" {
+			host = net.JoinHostPort(host, "443")
+		} else {
+			host = net.JoinHostPort(host, "80")
+		}
+	}
+	dialer := &net.Dialer{Timeout: h.upgradeTimeout}
+	if target.Scheme == "wss" || target.Scheme == "https" {
+		config := &tls.Config{}
+		if h.tlsConfig != nil {
+			config = h.tlsConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = target.Hostname()
+		}
+		return tls.DialWithDialer(dialer, "tcp", host, config)
+	}
+	return dialer.Dial("tcp", host)
+}
+
+// outRequest returns the upgrade request sent to the backend
+func (h *websocketHandler) outRequest(req *http.Request) *http.Request {
+	outReq := new(http.Request)
+	*outReq = *req
+	outReq.URL = utils.CopyURL(req.URL)
+	outReq.URL.Opaque = req.RequestURI
+	outReq.URL.RawQuery = ""
+	if !h.passHost {
+		outReq.Host = req.URL.Host
+	}
+	outReq.Header = make(http.Header)
+	utils.CopyHeaders(outReq.Header, req.Header)
+	return outReq
+}
+
+func writeHeaderBlock(w io.Writer, header http.Header) error {
+	if err := header.Write(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+func isWebsocketUpgrade(req *http.Request) bool {
+	containsToken := func(values []string, token string) bool {
+		for _, value := range values {
+			for _, part := range strings.Split(value, ",") {
+				if strings.EqualFold(strings.TrimSpace(part), token) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return containsToken(req.Header["Connection"], "upgrade") && containsToken(req.Header["Upgrade"], "websocket")
+}
+
+func statusForError(err error) int {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
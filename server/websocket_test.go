@@ -0,0 +1,173 @@
+package server
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/containous/traefik/types"
+)
+
+// websocketBackend accepts raw connections and answers the upgrade requests after the given delay,
+// echoing the following data
+func websocketBackend(t *testing.T, delay time.Duration) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				if _, err := http.ReadRequest(reader); err != nil {
+					return
+				}
+				time.Sleep(delay)
+				conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+				buf := make([]byte, 4)
+				if _, err := reader.Read(buf); err == nil {
+					conn.Write(buf)
+				}
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+// websocketProxy serves the websocket handler of a backend, forwarding to the given target
+func websocketProxy(backend *types.Backend, target string) *httptest.Server {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusTeapot)
+	})
+	handler := newWebsocketHandler(next, backend, nil, false)
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		req.URL, _ = url.Parse("http://" + target + req.URL.RequestURI())
+		handler.ServeHTTP(rw, req)
+	}))
+}
+
+func upgrade(t *testing.T, proxyURL string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", proxyURL[len("http://"):])
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: foo.bar\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, reader, resp
+}
+
+func TestWebsocketHandlerUpgrade(t *testing.T) {
+	listener := websocketBackend(t, 0)
+	defer listener.Close()
+	proxy := websocketProxy(&types.Backend{WebsocketTimeout: "1s"}, listener.Addr().String())
+	defer proxy.Close()
+
+	conn, reader, resp := upgrade(t, proxy.URL)
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := reader.Read(buf); err != nil || string(buf) != "ping" {
+		t.Errorf("got %q (%v) through the tunnel, want ping", buf, err)
+	}
+}
+
+func TestWebsocketHandlerUpgradeTimeout(t *testing.T) {
+	listener := websocketBackend(t, 2*time.Second)
+	defer listener.Close()
+	proxy := websocketProxy(&types.Backend{WebsocketTimeout: "100ms"}, listener.Addr().String())
+	defer proxy.Close()
+
+	start := time.Now()
+	conn, _, resp := upgrade(t, proxy.URL)
+	defer conn.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusGatewayTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("upgrade timeout fired after %s, want about 100ms", elapsed)
+	}
+}
+
+func TestWebsocketHandlerTunnelTimeout(t *testing.T) {
+	listener := websocketBackend(t, 0)
+	defer listener.Close()
+	proxy := websocketProxy(&types.Backend{WebsocketTimeout: "1s", ResponseTimeout: "100ms"}, listener.Addr().String())
+	defer proxy.Close()
+
+	conn, reader, resp := upgrade(t, proxy.URL)
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := reader.ReadByte(); err == nil {
+		t.Fatal("expected the tunnel to be closed")
+	} else if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		t.Fatal("the tunnel was not closed by the response timeout")
+	}
+}
+
+func TestWebsocketHandlerOtherRequests(t *testing.T) {
+	proxy := websocketProxy(&types.Backend{WebsocketTimeout: "1s"}, "127.0.0.1:1")
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTeapot {
+		t.Errorf("got status %d, want %d", resp.StatusCode, http.StatusTeapot)
+	}
+}
+
+func TestWebsocketHandlerDialTLS(t *testing.T) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: testCertificates()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.(*tls.Conn).Handshake()
+			}(conn)
+		}
+	}()
+	target := &url.URL{Scheme: "wss", Host: listener.Addr().String()}
+
+	// the self-signed certificate is only accepted with the TLS configuration of the backend
+	handler := &websocketHandler{upgradeTimeout: time.Second}
+	if conn, err := handler.dial(target); err == nil {
+		conn.Close()
+		t.Error("expected a certificate verification error")
+	}
+	handler.tlsConfig = &tls.Config{InsecureSkipVerify: true}
+	conn, err := handler.dial(target)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	conn.Close()
+}
@@ -1,23 +1,64 @@
 package types
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/containous/traefik/log"
 	"github.com/docker/libkv/store"
 	"github.com/ryanuber/go-glob"
 )
 
 // Backend holds backend configuration.
 type Backend struct {
-	Servers        map[string]Server `json:"servers,omitempty"`
-	CircuitBreaker *CircuitBreaker   `json:"circuitBreaker,omitempty"`
-	LoadBalancer   *LoadBalancer     `json:"loadBalancer,omitempty"`
-	MaxConn        *MaxConn          `json:"maxConn,omitempty"`
-	HealthCheck    *HealthCheck      `json:"healthCheck,omitempty"`
+	Servers             map[string]Server   `json:"servers,omitempty"`
+	CircuitBreaker      *CircuitBreaker     `json:"circuitBreaker,omitempty"`
+	LoadBalancer        *LoadBalancer       `json:"loadBalancer,omitempty"`
+	MaxConn             *MaxConn            `json:"maxConn,omitempty"`
+	HealthCheck         *HealthCheck        `json:"healthCheck,omitempty"`
+	DisableKeepAlives   bool                `json:"disableKeepAlives,omitempty"`
+	DNSRetryCount       int                 `json:"dnsRetryCount,omitempty"`
+	DNSRetryDelay       string              `json:"dnsRetryDelay,omitempty"`
+	DNSResolver         string              `json:"dnsResolver,omitempty"`
+	WebsocketTimeout    string              `json:"websocketTimeout,omitempty"`
+	ResponseTimeout     string              `json:"responseTimeout,omitempty"`
+	ReadHeaderTimeout   string              `json:"readHeaderTimeout,omitempty"`
+	ResponseBodyTimeout string              `json:"responseBodyTimeout,omitempty"`
+	TCPPassthrough      bool                `json:"tcpPassthrough,omitempty"`
+	H2Options           *H2Options          `json:"h2Options,omitempty"`
+	FallbackStatusCodes []int               `json:"fallbackStatusCodes,omitempty"`
+	Buffering           *Buffering          `json:"buffering,omitempty"`
+	ResponseForwarding  *ResponseForwarding `json:"responseForwarding,omitempty"`
+	TLS                 *ClientTLS          `json:"tls,omitempty"`
+}
+
+// ResponseForwarding holds the configuration of the forwarding of the backend responses
+type ResponseForwarding struct {
+	// FlushInterval is the interval between two flushes of the response body to the client (e.g. 100ms)
+	FlushInterval string `json:"flushInterval,omitempty"`
+}
+
+// Buffering holds the request and response buffering configuration of a backend, the bodies
+// exceeding the memory limits being buffered to disk. Zero max limits mean no limit.
+type Buffering struct {
+	MaxRequestBodyBytes  int64  `json:"maxRequestBodyBytes,omitempty"`
+	MemRequestBodyBytes  int64  `json:"memRequestBodyBytes,omitempty"`
+	MaxResponseBodyBytes int64  `json:"maxResponseBodyBytes,omitempty"`
+	MemResponseBodyBytes int64  `json:"memResponseBodyBytes,omitempty"`
+	RetryExpression      string `json:"retryExpression,omitempty"`
+}
+
+// H2Options holds the HTTP/2 multiplexing configuration of a backend
+type H2Options struct {
+	MaxConcurrentStreams uint32 `json:"maxConcurrentStreams,omitempty"`
 }
 
 // MaxConn holds maximum connection configuration
@@ -28,25 +69,207 @@ type MaxConn struct {
 
 // LoadBalancer holds load balancing configuration.
 type LoadBalancer struct {
-	Method string `json:"method,omitempty"`
-	Sticky bool   `json:"sticky,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Sticky     bool   `json:"sticky,omitempty"`
+	CookieName string `json:"cookieName,omitempty"`
 }
 
 // CircuitBreaker holds circuit breaker configuration.
 type CircuitBreaker struct {
-	Expression string `json:"expression,omitempty"`
+	Expression       string            `json:"expression,omitempty"`
+	StatusCodeRanges []StatusCodeRange `json:"statusCodeRanges,omitempty"`
+}
+
+// StatusCodeRange holds an inclusive range of HTTP status codes.
+type StatusCodeRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+var responseCodeRatioPlaceholder = regexp.MustCompile(`ResponseCodeRatio\(\)\s*(>=|>)\s*([0-9.]+)`)
+
+var responseHeaderRatioFunction = regexp.MustCompile(`ResponseHeaderRatio\(\s*"([^"]*)"\s*,\s*"([^"]*)"\s*,\s*([0-9]+)\s*,\s*([0-9]+)\s*\)`)
+
+var expressionFunction = regexp.MustCompile(`[A-Za-z]+\(`)
+
+// ResponseMatchCodeOffset is added to the status code recorded by the circuit breaker of a
+// ResponseMatch for the responses it matches.
+const ResponseMatchCodeOffset = 1000
+
+// ResponseMatch holds the responses counted apart by one of the circuit breakers of a backend:
+// the responses carrying a header, for the ResponseHeaderRatio functions
+type ResponseMatch struct {
+	HeaderName  string
+	HeaderValue string
+}
+
+// CircuitBreakerExpression holds the expression of one of the circuit breakers of a backend,
+// and the responses it counts apart, if any
+type CircuitBreakerExpression struct {
+	Expression string
+	Match      *ResponseMatch
+}
+
+// BuildExpressions returns the expressions of the circuit breakers of the backend, which trips
+// when any of them trips.
+// The argument-less `ResponseCodeRatio() > threshold` shorthand is expanded using the status code
+// ranges: the circuit trips when the ratio of responses within any of the ranges exceeds the threshold.
+// The `ResponseHeaderRatio("name", "value", from, to)` functions are evaluated by a circuit breaker of
+// their own, recording the status code of the responses carrying the header with ResponseMatchCodeOffset,
+// so the expression is split on its top-level `||` operators and these functions cannot be combined
+// with the functions of other headers or with other functions by a `&&` operator.
+func (c *CircuitBreaker) BuildExpressions() ([]CircuitBreakerExpression, error) {
+	expression := c.Expression
+	if len(c.StatusCodeRanges) > 0 {
+		expression = responseCodeRatioPlaceholder.ReplaceAllStringFunc(expression, func(match string) string {
+			parts := responseCodeRatioPlaceholder.FindStringSubmatch(match)
+			var ratios []string
+			for _, codeRange := range c.StatusCodeRanges {
+				ratios = append(ratios, fmt.Sprintf("ResponseCodeRatio(%d, %d, 0, 600) %s %s", codeRange.Min, codeRange.Max+1, parts[1], parts[2]))
+			}
+			return "(" + strings.Join(ratios, " || ") + ")"
+		})
+	}
+	if !responseHeaderRatioFunction.MatchString(expression) {
+		return []CircuitBreakerExpression{{Expression: expression}}, nil
+	}
+
+	var plain []string
+	var matches []ResponseMatch
+	terms := map[ResponseMatch][]string{}
+	for _, term := range splitExpression(expression, "||") {
+		functions := responseHeaderRatioFunction.FindAllStringSubmatch(term, -1)
+		if len(functions) == 0 {
+			plain = append(plain, term)
+			continue
+		}
+		match := ResponseMatch{HeaderName: functions[0][1], HeaderValue: functions[0][2]}
+		for _, parts := range functions[1:] {
+			if parts[1] != match.HeaderName || parts[2] != match.HeaderValue {
+				return nil, fmt.Errorf("ResponseHeaderRatio functions of different headers combined in %q", term)
+			}
+		}
+		if expressionFunction.MatchString(responseHeaderRatioFunction.ReplaceAllString(term, "")) {
+			return nil, fmt.Errorf("ResponseHeaderRatio function combined with other functions in %q", term)
+		}
+		if _, ok := terms[match]; !ok {
+			matches = append(matches, match)
+		}
+		terms[match] = append(terms[match], responseHeaderRatioFunction.ReplaceAllStringFunc(term, func(function string) string {
+			parts := responseHeaderRatioFunction.FindStringSubmatch(function)
+			from, _ := strconv.Atoi(parts[3])
+			to, _ := strconv.Atoi(parts[4])
+			return fmt.Sprintf("ResponseCodeRatio(%d, %d, 0, %d)", from+ResponseMatchCodeOffset, to+ResponseMatchCodeOffset, 2*ResponseMatchCodeOffset)
+		}))
+	}
+
+	var expressions []CircuitBreakerExpression
+	if len(plain) > 0 {
+		expressions = append(expressions, CircuitBreakerExpression{Expression: strings.Join(plain, " || ")})
+	}
+	for i := range matches {
+		expressions = append(expressions, CircuitBreakerExpression{Expression: strings.Join(terms[matches[i]], " || "), Match: &matches[i]})
+	}
+	return expressions, nil
+}
+
+// splitExpression splits an expression on the given operator, outside of the parentheses and of
+// the quoted strings. The parentheses enclosing a whole term are removed and the term split again.
+func splitExpression(expression string, operator string) []string {
+	var terms []string
+	depth := 0
+	quoted := false
+	start := 0
+	for i := 0; i < len(expression); i++ {
+		switch {
+		case expression[i] == '"' && (i == 0 || expression[i-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case expression[i] == '(':
+			depth++
+		case expression[i] == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(expression[i:], operator):
+			terms = append(terms, expression[start:i])
+			start = i + len(operator)
+			i += len(operator) - 1
+		}
+	}
+	terms = append(terms, expression[start:])
+
+	var result []string
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if isEnclosed(term) {
+			result = append(result, splitExpression(term[1:len(term)-1], operator)...)
+		} else {
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+// isEnclosed returns whether the whole expression is enclosed in parentheses
+func isEnclosed(expression string) bool {
+	if !strings.HasPrefix(expression, "(") || !strings.HasSuffix(expression, ")") {
+		return false
+	}
+	depth := 0
+	quoted := false
+	for i := 0; i < len(expression)-1; i++ {
+		switch {
+		case expression[i] == '"' && (i == 0 || expression[i-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case expression[i] == '(':
+			depth++
+		case expression[i] == ')':
+			depth--
+			if depth == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ParseStatusCodeRanges parses a comma-separated list of status codes and status code ranges (e.g. 500-503,429)
+func ParseStatusCodeRanges(str string) ([]StatusCodeRange, error) {
+	var ranges []StatusCodeRange
+	for _, item := range strings.Split(str, ",") {
+		item = strings.TrimSpace(item)
+		bounds := strings.SplitN(item, "-", 2)
+		min, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, errors.New("Invalid status code range: " + item)
+		}
+		max := min
+		if len(bounds) == 2 {
+			max, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil {
+				return nil, errors.New("Invalid status code range: " + item)
+			}
+		}
+		if min < 100 || max > 599 || min > max {
+			return nil, errors.New("Invalid status code range: " + item)
+		}
+		ranges = append(ranges, StatusCodeRange{Min: min, Max: max})
+	}
+	return ranges, nil
 }
 
 // HealthCheck holds HealthCheck configuration
 type HealthCheck struct {
-	Path     string `json:"path,omitempty"`
-	Interval string `json:"interval,omitempty"`
+	Path          string `json:"path,omitempty"`
+	Interval      string `json:"interval,omitempty"`
+	FailureAction string `json:"failureAction,omitempty"`
 }
 
 // Server holds server configuration.
 type Server struct {
-	URL    string `json:"url,omitempty"`
-	Weight int    `json:"weight"`
+	URL      string `json:"url,omitempty"`
+	Weight   int    `json:"weight"`
+	Fallback bool   `json:"fallback,omitempty"`
 }
 
 // Route holds route configuration.
@@ -56,12 +279,71 @@ type Route struct {
 
 // Frontend holds frontend configuration.
 type Frontend struct {
-	EntryPoints    []string         `json:"entryPoints,omitempty"`
-	Backend        string           `json:"backend,omitempty"`
-	Routes         map[string]Route `json:"routes,omitempty"`
-	PassHostHeader bool             `json:"passHostHeader,omitempty"`
-	Priority       int              `json:"priority"`
-	BasicAuth      []string         `json:"basicAuth"`
+	EntryPoints          []string         `json:"entryPoints,omitempty"`
+	Backend              string           `json:"backend,omitempty"`
+	Routes               map[string]Route `json:"routes,omitempty"`
+	PassHostHeader       bool             `json:"passHostHeader,omitempty"`
+	PassTLSCert          bool             `json:"passTLSCert,omitempty"`
+	PassTLSCertPEM       bool             `json:"passTLSCertPEM,omitempty"`
+	Priority             int              `json:"priority"`
+	BasicAuth            []string         `json:"basicAuth"`
+	DigestAuth           []string         `json:"digestAuth,omitempty"`
+	ForwardAuth          *ForwardAuth     `json:"forwardAuth,omitempty"`
+	ForwardCaptures      bool             `json:"forwardCaptures,omitempty"`
+	Redirect             string           `json:"redirect,omitempty"`
+	MaxBodyBuffer        int64            `json:"maxBodyBuffer,omitempty"`
+	Seed                 int64            `json:"seed,omitempty"`
+	RequestIDHeader      string           `json:"requestIDHeader,omitempty"`
+	TrustedIPs           []string         `json:"trustedIPs,omitempty"`
+	CaseInsensitive      bool             `json:"caseInsensitive,omitempty"`
+	WhitelistSourceRange []string         `json:"whitelistSourceRange,omitempty"`
+	RateLimit            *RateLimit       `json:"ratelimit,omitempty"`
+	Headers              *Headers         `json:"headers,omitempty"`
+}
+
+// ForwardAuth holds the configuration of the delegation of the authentication of a frontend
+// to an external service
+type ForwardAuth struct {
+	Address             string   `json:"address,omitempty"`
+	TrustForwardHeader  bool     `json:"trustForwardHeader,omitempty"`
+	AuthResponseHeaders []string `json:"authResponseHeaders,omitempty"`
+}
+
+// Headers holds the custom and security headers of a frontend
+type Headers struct {
+	CustomRequestHeaders    map[string]string `json:"customRequestHeaders,omitempty"`
+	CustomResponseHeaders   map[string]string `json:"customResponseHeaders,omitempty"`
+	AllowedHosts            []string          `json:"allowedHosts,omitempty"`
+	HostsProxyHeaders       []string          `json:"hostsProxyHeaders,omitempty"`
+	SSLRedirect             bool              `json:"sslRedirect,omitempty"`
+	SSLTemporaryRedirect    bool              `json:"sslTemporaryRedirect,omitempty"`
+	SSLHost                 string            `json:"sslHost,omitempty"`
+	SSLProxyHeaders         map[string]string `json:"sslProxyHeaders,omitempty"`
+	STSSeconds              int64             `json:"stsSeconds,omitempty"`
+	STSIncludeSubdomains    bool              `json:"stsIncludeSubdomains,omitempty"`
+	STSPreload              bool              `json:"stsPreload,omitempty"`
+	ForceSTSHeader          bool              `json:"forceSTSHeader,omitempty"`
+	FrameDeny               bool              `json:"frameDeny,omitempty"`
+	CustomFrameOptionsValue string            `json:"customFrameOptionsValue,omitempty"`
+	ContentTypeNosniff      bool              `json:"contentTypeNosniff,omitempty"`
+	BrowserXSSFilter        bool              `json:"browserXssFilter,omitempty"`
+	ContentSecurityPolicy   string            `json:"contentSecurityPolicy,omitempty"`
+	PublicKey               string            `json:"publicKey,omitempty"`
+	ReferrerPolicy          string            `json:"referrerPolicy,omitempty"`
+	IsDevelopment           bool              `json:"isDevelopment,omitempty"`
+}
+
+// RateLimit holds the rate limiting configuration of a frontend, the rates being applied to
+// each source of the requests as given by the extractor function (e.g. client.ip)
+type RateLimit struct {
+	RateSet       map[string]*Rate `json:"rateset,omitempty"`
+	ExtractorFunc string           `json:"extractorFunc,omitempty"`
+}
+
+// Rate holds the average number of requests allowed over a period of time (e.g. 10s)
+type Rate struct {
+	Period  string `json:"period,omitempty"`
+	Average int64  `json:"average,omitempty"`
 }
 
 // LoadBalancerMethod holds the method of load balancing to use.
@@ -104,6 +386,13 @@ type Configuration struct {
 type ConfigMessage struct {
 	ProviderName  string
 	Configuration *Configuration
+	Errors        []ConfigError
+}
+
+// ConfigError holds a problem found by a provider while building its configuration.
+type ConfigError struct {
+	Source  string `json:"source"`
+	Message string `json:"message"`
 }
 
 // Constraint hold a parsed constraint expresssion
@@ -303,3 +592,97 @@ func (b *Buckets) String() string { return fmt.Sprintf("%v", *b) }
 func (b *Buckets) SetValue(val interface{}) {
 	*b = Buckets(val.(Buckets))
 }
+
+// ClientTLS holds TLS specific configurations as client
+// CA, Cert and Key can be either path or file contents
+type ClientTLS struct {
+	CA                 string `description:"TLS CA"`
+	Cert               string `description:"TLS cert"`
+	Key                string `description:"TLS key"`
+	InsecureSkipVerify bool   `description:"TLS insecure skip verify"`
+}
+
+// CreateTLSConfig creates a TLS config from ClientTLS structures.
+// The client certificate is optional and the system CAs are trusted when no CA is set,
+// as expected by the backends and the docker endpoint.
+func (clientTLS *ClientTLS) CreateTLSConfig() (*tls.Config, error) {
+	var err error
+	if clientTLS == nil {
+		return nil, nil
+	}
+	caPool := x509.NewCertPool()
+	if clientTLS.CA != "" {
+		var ca []byte
+		if _, errCA := os.Stat(clientTLS.CA); errCA == nil {
+			ca, err = ioutil.ReadFile(clientTLS.CA)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read CA. %s", err)
+			}
+		} else {
+			ca = []byte(clientTLS.CA)
+		}
+		caPool.AppendCertsFromPEM(ca)
+	}
+
+	TLSConfig := &tls.Config{
+		InsecureSkipVerify: clientTLS.InsecureSkipVerify,
+	}
+	if clientTLS.CA != "" {
+		TLSConfig.RootCAs = caPool
+	}
+
+	// a client certificate is optional, e.g. for the TLS connections to the backends
+	if clientTLS.Cert == "" && clientTLS.Key == "" {
+		return TLSConfig, nil
+	}
+	if clientTLS.Cert == "" || clientTLS.Key == "" {
+		return nil, fmt.Errorf("tls cert and tls key must be both set or both empty")
+	}
+
+	cert := tls.Certificate{}
+	_, errKeyIsFile := os.Stat(clientTLS.Key)
+
+	if _, errCertIsFile := os.Stat(clientTLS.Cert); errCertIsFile == nil {
+		if errKeyIsFile == nil {
+			cert, err = tls.LoadX509KeyPair(clientTLS.Cert, clientTLS.Key)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to load TLS keypair: %v", err)
+			}
+		} else {
+			return nil, fmt.Errorf("tls cert is a file, but tls key is not")
+		}
+	} else {
+		if errKeyIsFile != nil {
+			cert, err = tls.X509KeyPair([]byte(clientTLS.Cert), []byte(clientTLS.Key))
+			if err != nil {
+				return nil, fmt.Errorf("Failed to load TLS keypair: %v", err)
+
+			}
+		} else {
+			return nil, fmt.Errorf("tls key is a file, but tls cert is not")
+		}
+	}
+
+	TLSConfig.Certificates = []tls.Certificate{cert}
+	return TLSConfig, nil
+}
+
+// CreateClientCertTLSConfig creates a TLS config from ClientTLS structures requiring
+// the client certificate and trusting the CA only, as expected by the kv and marathon endpoints
+func (clientTLS *ClientTLS) CreateClientCertTLSConfig() (*tls.Config, error) {
+	if clientTLS == nil {
+		log.Warnf("clientTLS is nil")
+		return nil, nil
+	}
+	if clientTLS.Cert == "" || clientTLS.Key == "" {
+		return nil, fmt.Errorf("tls cert and tls key are required")
+	}
+	config, err := clientTLS.CreateTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config.RootCAs == nil {
+		config.RootCAs = x509.NewCertPool()
+	}
+	return config, nil
+}
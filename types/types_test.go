@@ -0,0 +1,183 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStatusCodeRanges(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected []StatusCodeRange
+		wantErr  bool
+	}{
+		{value: "500-503,429", expected: []StatusCodeRange{{Min: 500, Max: 503}, {Min: 429, Max: 429}}},
+		{value: " 502 ", expected: []StatusCodeRange{{Min: 502, Max: 502}}},
+		{value: "503-500", wantErr: true},
+		{value: "5xx", wantErr: true},
+		{value: "500-", wantErr: true},
+		{value: "99", wantErr: true},
+	}
+
+	for _, test := range tests {
+		ranges, err := ParseStatusCodeRanges(test.value)
+		if test.wantErr != (err != nil) {
+			t.Errorf("got error %v parsing %q, want error %t", err, test.value, test.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(ranges, test.expected) {
+			t.Errorf("got ranges %+v parsing %q, want %+v", ranges, test.value, test.expected)
+		}
+	}
+}
+
+func TestCircuitBreakerBuildExpressions(t *testing.T) {
+	tests := []struct {
+		circuitBreaker CircuitBreaker
+		expected       string
+	}{
+		{
+			circuitBreaker: CircuitBreaker{Expression: "NetworkErrorRatio() > 0.5"},
+			expected:       "NetworkErrorRatio() > 0.5",
+		},
+		{
+			circuitBreaker: CircuitBreaker{
+				Expression:       "ResponseCodeRatio() > 0.25",
+				StatusCodeRanges: []StatusCodeRange{{Min: 500, Max: 503}, {Min: 429, Max: 429}},
+			},
+			expected: "(ResponseCodeRatio(500, 504, 0, 600) > 0.25 || ResponseCodeRatio(429, 430, 0, 600) > 0.25)",
+		},
+		{
+			circuitBreaker: CircuitBreaker{
+				Expression:       "NetworkErrorRatio() > 0.5 || ResponseCodeRatio() >= 0.3",
+				StatusCodeRanges: []StatusCodeRange{{Min: 500, Max: 599}},
+			},
+			expected: "NetworkErrorRatio() > 0.5 || (ResponseCodeRatio(500, 600, 0, 600) >= 0.3)",
+		},
+	}
+
+	for _, test := range tests {
+		expressions, err := test.circuitBreaker.BuildExpressions()
+		if err != nil {
+			t.Fatalf("got error: %v", err)
+		}
+		expected := []CircuitBreakerExpression{{Expression: test.expected}}
+		if !reflect.DeepEqual(expressions, expected) {
+			t.Errorf("got expressions %+v, want %+v", expressions, expected)
+		}
+	}
+}
+
+func TestCircuitBreakerResponseHeaderRatio(t *testing.T) {
+	tests := []struct {
+		desc          string
+		expression    string
+		expected      []CircuitBreakerExpression
+		expectedError bool
+	}{
+		{
+			desc:       "header ratio with other functions",
+			expression: `NetworkErrorRatio() > 0.5 || ResponseHeaderRatio("X-Error", "true", 0, 600) > 0.25 || ResponseCodeRatio(500, 600, 0, 600) > 0.5`,
+			expected: []CircuitBreakerExpression{
+				{Expression: "NetworkErrorRatio() > 0.5 || ResponseCodeRatio(500, 600, 0, 600) > 0.5"},
+				{Expression: "ResponseCodeRatio(1000, 1600, 0, 2000) > 0.25", Match: &ResponseMatch{HeaderName: "X-Error", HeaderValue: "true"}},
+			},
+		},
+		{
+			desc:       "ratios of two headers",
+			expression: `(ResponseHeaderRatio("X-Error", "true", 0, 600) > 0.25 && ResponseHeaderRatio("X-Error", "true", 500, 600) > 0.1) || ResponseHeaderRatio("X-Retry", "yes", 200, 300) > 0.5`,
+			expected: []CircuitBreakerExpression{
+				{Expression: "ResponseCodeRatio(1000, 1600, 0, 2000) > 0.25 && ResponseCodeRatio(1500, 1600, 0, 2000) > 0.1", Match: &ResponseMatch{HeaderName: "X-Error", HeaderValue: "true"}},
+				{Expression: "ResponseCodeRatio(1200, 1300, 0, 2000) > 0.5", Match: &ResponseMatch{HeaderName: "X-Retry", HeaderValue: "yes"}},
+			},
+		},
+		{
+			desc:          "header ratio combined with another function",
+			expression:    `ResponseHeaderRatio("X-Error", "true", 0, 600) > 0.25 && NetworkErrorRatio() > 0.5`,
+			expectedError: true,
+		},
+		{
+			desc:          "ratios of two headers combined",
+			expression:    `ResponseHeaderRatio("X-Error", "true", 0, 600) > 0.25 && ResponseHeaderRatio("X-Retry", "yes", 0, 600) > 0.5`,
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		circuitBreaker := CircuitBreaker{Expression: test.expression}
+		expressions, err := circuitBreaker.BuildExpressions()
+		if test.expectedError {
+			if err == nil {
+				t.Errorf("%s: expected an error, got expressions %+v", test.desc, expressions)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: got error: %v", test.desc, err)
+			continue
+		}
+		if !reflect.DeepEqual(expressions, test.expected) {
+			t.Errorf("%s: got expressions %+v, want %+v", test.desc, expressions, test.expected)
+		}
+	}
+}
+
+func TestClientTLSCreateTLSConfig(t *testing.T) {
+	tests := []struct {
+		desc      string
+		clientTLS *ClientTLS
+		wantErr   bool
+	}{
+		{desc: "nil", clientTLS: nil},
+		{desc: "no certificate", clientTLS: &ClientTLS{InsecureSkipVerify: true}},
+		{desc: "cert without key", clientTLS: &ClientTLS{Cert: "/certs/cert.pem"}, wantErr: true},
+		{desc: "key without cert", clientTLS: &ClientTLS{Key: "/certs/key.pem"}, wantErr: true},
+		{desc: "invalid keypair", clientTLS: &ClientTLS{Cert: "cert", Key: "key"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		config, err := test.clientTLS.CreateTLSConfig()
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.desc, err)
+			continue
+		}
+		if test.clientTLS != nil && (config == nil || config.InsecureSkipVerify != test.clientTLS.InsecureSkipVerify || config.RootCAs != nil) {
+			t.Errorf("%s: got TLS config %+v", test.desc, config)
+		}
+	}
+}
+
+func TestClientTLSCreateClientCertTLSConfig(t *testing.T) {
+	tests := []struct {
+		desc      string
+		clientTLS *ClientTLS
+		wantErr   bool
+	}{
+		{desc: "nil", clientTLS: nil},
+		{desc: "no certificate", clientTLS: &ClientTLS{InsecureSkipVerify: true}, wantErr: true},
+		{desc: "cert without key", clientTLS: &ClientTLS{Cert: "/certs/cert.pem"}, wantErr: true},
+		{desc: "invalid keypair", clientTLS: &ClientTLS{Cert: "cert", Key: "key"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		config, err := test.clientTLS.CreateClientCertTLSConfig()
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.desc, err)
+		}
+		if config != nil {
+			t.Errorf("%s: got TLS config %+v", test.desc, config)
+		}
+	}
+}